@@ -5,19 +5,28 @@ package main
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/zeebo/clingy"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
 
+	"storj.io/common/memory"
 	client "storj.io/gateway-mt/internal/authadminclient"
+	"storj.io/gateway-mt/pkg/auth"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/badgerauth"
+	"storj.io/private/dbutil"
 )
 
 var logger *log.Logger
@@ -37,10 +46,18 @@ func main() {
 
 		cmds.Group("record", "record commands", func() {
 			cmds.New("show", "show a record", new(cmdShow))
+			cmds.New("list", "list records matching filters", new(cmdList))
 			cmds.New("invalidate", "invalidate a record", new(cmdInvalidate))
 			cmds.New("unpublish", "unpublish a record", new(cmdUnpublish))
 			cmds.New("delete", "delete a record", new(cmdDelete))
 		})
+		cmds.Group("cluster", "cluster commands", func() {
+			cmds.New("status", "show the cluster status as seen by each node", new(cmdClusterStatus))
+			cmds.New("add-peer", "add a peer to the cluster", new(cmdAddPeer))
+			cmds.New("remove-peer", "remove a peer from the cluster", new(cmdRemovePeer))
+			cmds.New("decommission", "permanently decommission a node ID", new(cmdDecommission))
+		})
+		cmds.New("fsck", "check the consistency of a stopped node's storage", new(cmdFsck))
 	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%+v\n", err)
@@ -86,6 +103,96 @@ func (cmd *cmdShow) Execute(ctx context.Context) error {
 	}
 }
 
+type cmdList struct {
+	clientConfig client.Config
+	kvBackend    string
+	filter       authdb.ListFilter
+	cursor       authdb.ListCursor
+	limit        int
+	output       string
+}
+
+func (cmd *cmdList) Setup(params clingy.Parameters) {
+	setupClientConfig(params, &cmd.clientConfig)
+
+	cmd.kvBackend = params.Flag("kv-backend", "list records directly from this SQL key/value store backend url (e.g., postgres://...) instead of a node", "").(string)
+	cmd.filter.MacaroonHead = params.Flag("macaroon-head", "only list records with this macaroon head (hex)", []byte(nil),
+		clingy.Transform(hex.DecodeString)).([]byte)
+	cmd.filter.SatelliteAddress = params.Flag("satellite", "only list records for this satellite address", "").(string)
+	cmd.filter.CreatedAfter = params.Flag("created-after", "only list records created at or after this time (RFC3339)", time.Time{},
+		clingy.Transform(parseTime)).(time.Time)
+	cmd.filter.CreatedBefore = params.Flag("created-before", "only list records created before this time (RFC3339)", time.Time{},
+		clingy.Transform(parseTime)).(time.Time)
+	cmd.filter.Public = params.Flag("public", "only list public (true) or private (false) records", (*bool)(nil),
+		clingy.Transform(parseOptionalBool)).(*bool)
+	cmd.filter.Invalidated = params.Flag("invalidated", "only list invalidated (true) or valid (false) records", (*bool)(nil),
+		clingy.Transform(parseOptionalBool)).(*bool)
+	cmd.cursor = params.Flag("cursor", "list records after this cursor, as printed by a previous listing", authdb.ListCursor{},
+		clingy.Transform(authdb.ParseListCursor)).(authdb.ListCursor)
+	cmd.limit = params.Flag("limit", "maximum number of records to list", 100,
+		clingy.Transform(strconv.Atoi)).(int)
+	cmd.output = params.Flag("output", "output format (valid options: tabbed, json)", "tabbed",
+		clingy.Short('o'),
+	).(string)
+}
+
+func (cmd *cmdList) Execute(ctx context.Context) (err error) {
+	var (
+		records []client.ListedRecord
+		next    authdb.ListCursor
+	)
+	if cmd.kvBackend != "" {
+		records, next, err = cmd.listFromKV(ctx)
+	} else {
+		records, next, err = client.New(cmd.clientConfig, logger).List(ctx, cmd.filter, cmd.cursor, cmd.limit)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch cmd.output {
+	case "tabbed", "":
+		return printTabbedRecords(records, next)
+	case "json":
+		out := struct {
+			Records    []client.ListedRecord `json:"records"`
+			NextCursor string                `json:"next_cursor,omitempty"`
+		}{Records: records}
+		if !next.IsZero() {
+			out.NextCursor = next.String()
+		}
+		return json.NewEncoder(os.Stdout).Encode(out)
+	default:
+		return fmt.Errorf("unsupported output %q (valid options: tabbed, json)", cmd.output)
+	}
+}
+
+// listFromKV lists records directly from the SQL backend at cmd.kvBackend,
+// which, unlike badgerauth, has no admin API.
+func (cmd *cmdList) listFromKV(ctx context.Context) (_ []client.ListedRecord, _ authdb.ListCursor, err error) {
+	driver, _, _, err := dbutil.SplitConnStr(cmd.kvBackend)
+	if err != nil {
+		return nil, authdb.ListCursor{}, err
+	}
+	switch driver {
+	case "pgxcockroach", "postgres", "cockroach", "pgx", "sqlite", "sqlite3":
+	default:
+		return nil, authdb.ListCursor{}, errs.New("unsupported kv backend %q (list badger nodes with --node-addresses instead)", driver)
+	}
+
+	kv, err := auth.OpenKV(ctx, zap.NewNop(), auth.Config{KVBackend: cmd.kvBackend})
+	if err != nil {
+		return nil, authdb.ListCursor{}, err
+	}
+	defer func() { err = errs.Combine(err, kv.Close()) }()
+
+	lister, ok := kv.(authdb.Lister)
+	if !ok {
+		return nil, authdb.ListCursor{}, errs.New("kv backend %q doesn't support listing", driver)
+	}
+	return client.ListFrom(ctx, lister, cmd.filter, cmd.cursor, cmd.limit)
+}
+
 type cmdInvalidate struct {
 	clientConfig client.Config
 	key          string
@@ -133,6 +240,145 @@ func (cmd *cmdDelete) Execute(ctx context.Context) error {
 	return client.New(cmd.clientConfig, logger).Delete(ctx, cmd.key)
 }
 
+type cmdClusterStatus struct {
+	clientConfig client.Config
+	output       string
+}
+
+func (cmd *cmdClusterStatus) Setup(params clingy.Parameters) {
+	setupClientConfig(params, &cmd.clientConfig)
+
+	cmd.output = params.Flag("output", "output format (valid options: tabbed, json)", "tabbed",
+		clingy.Short('o'),
+	).(string)
+}
+
+func (cmd *cmdClusterStatus) Execute(ctx context.Context) error {
+	status, err := client.New(cmd.clientConfig, logger).ClusterStatus(ctx)
+	if err != nil {
+		return err
+	}
+
+	switch cmd.output {
+	case "tabbed", "":
+		return printTabbedClusterStatus(status)
+	case "json":
+		return json.NewEncoder(os.Stdout).Encode(status)
+	default:
+		return fmt.Errorf("unsupported output %q (valid options: tabbed, json)", cmd.output)
+	}
+}
+
+type cmdAddPeer struct {
+	clientConfig client.Config
+	address      string
+}
+
+func (cmd *cmdAddPeer) Setup(params clingy.Parameters) {
+	setupClientConfig(params, &cmd.clientConfig)
+
+	cmd.address = params.Arg("address", "peer address (host:port)").(string)
+}
+
+func (cmd *cmdAddPeer) Execute(ctx context.Context) error {
+	return client.New(cmd.clientConfig, logger).AddPeer(ctx, cmd.address)
+}
+
+type cmdRemovePeer struct {
+	clientConfig client.Config
+	address      string
+}
+
+func (cmd *cmdRemovePeer) Setup(params clingy.Parameters) {
+	setupClientConfig(params, &cmd.clientConfig)
+
+	cmd.address = params.Arg("address", "peer address (host:port)").(string)
+}
+
+func (cmd *cmdRemovePeer) Execute(ctx context.Context) error {
+	return client.New(cmd.clientConfig, logger).RemovePeer(ctx, cmd.address)
+}
+
+type cmdDecommission struct {
+	clientConfig client.Config
+	nodeID       string
+}
+
+func (cmd *cmdDecommission) Setup(params clingy.Parameters) {
+	setupClientConfig(params, &cmd.clientConfig)
+
+	cmd.nodeID = params.Arg("node-id", "ID of the node to decommission").(string)
+}
+
+func (cmd *cmdDecommission) Execute(ctx context.Context) error {
+	return client.New(cmd.clientConfig, logger).Decommission(ctx, cmd.nodeID)
+}
+
+type cmdFsck struct {
+	config badgerauth.Config
+	repair bool
+	output string
+}
+
+func (cmd *cmdFsck) Setup(params clingy.Parameters) {
+	cmd.config.Path = params.Flag("path", "path where the node stores data", "").(string)
+	cmd.config.ID = params.Flag("node-id", "ID the node is configured with (not checked if empty)", badgerauth.NodeID{},
+		clingy.Transform(func(s string) (id badgerauth.NodeID, err error) {
+			return id, id.Set(s)
+		})).(badgerauth.NodeID)
+	cmd.config.EncryptionKeyFile = params.Flag("encryption-key-file", "path to the key the storage is encrypted with", "").(string)
+	cmd.config.EncryptionKeyRotationDuration = 240 * time.Hour
+	cmd.config.BlockCacheSize = 256 * memory.MiB
+	cmd.config.IndexCacheSize = 64 * memory.MiB
+	cmd.repair = params.Flag("repair", "rebuild missing replication log entries, clocks and index entries, and delete orphaned replication log and index entries", false,
+		clingy.Transform(strconv.ParseBool), clingy.Boolean,
+	).(bool)
+	cmd.output = params.Flag("output", "output format (valid options: tabbed, json)", "tabbed",
+		clingy.Short('o'),
+	).(string)
+}
+
+func (cmd *cmdFsck) Execute(ctx context.Context) error {
+	if cmd.config.Path == "" {
+		return errs.New("--path must be set")
+	}
+
+	zlog := zap.NewNop()
+	if logger.Writer() != io.Discard {
+		var err error
+		if zlog, err = zap.NewDevelopment(); err != nil {
+			return err
+		}
+	}
+
+	report, err := badgerauth.Fsck(ctx, zlog, cmd.config, cmd.repair)
+	if err != nil {
+		return err
+	}
+
+	switch cmd.output {
+	case "tabbed", "":
+		if err = printTabbedFsckReport(report); err != nil {
+			return err
+		}
+	case "json":
+		if err = json.NewEncoder(os.Stdout).Encode(struct {
+			NodeID string
+			*badgerauth.FsckReport
+		}{report.NodeID.String(), report}); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported output %q (valid options: tabbed, json)", cmd.output)
+	}
+
+	if !report.OK() {
+		return errs.New("storage is inconsistent")
+	}
+
+	return nil
+}
+
 func setupClientConfig(params clingy.Parameters, config *client.Config) {
 	config.NodeAddresses = params.Flag("node-addresses", "comma delimited list of node addresses", []string{},
 		clingy.Transform(func(s string) ([]string, error) {
@@ -176,3 +422,146 @@ func printTabbedRecord(r *client.Record, expanded bool) error {
 	fmt.Fprintln(w, strings.Join(values, "\t"))
 	return w.Flush()
 }
+
+func printTabbedRecords(records []client.ListedRecord, next authdb.ListCursor) error {
+	w := tabwriter.NewWriter(os.Stdout, 2, 2, 2, ' ', 0)
+
+	fmt.Fprintln(w, "KEY HASH\tCREATED\tPUBLIC\tSATELLITE\tMACAROON HEAD\tEXPIRES\tINVALIDATED")
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\t%s\t%s\n",
+			r.KeyHash, formatUnix(r.CreatedAtUnix), r.Public, r.SatelliteAddress, r.MacaroonHeadHex,
+			orDash(formatUnix(r.ExpiresAtUnix)), orDash(formatUnix(r.InvalidatedAtUnix)))
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if !next.IsZero() {
+		fmt.Printf("\nmore records match; continue with --cursor %s\n", next)
+	}
+
+	return nil
+}
+
+func printTabbedClusterStatus(s *client.ClusterStatus) error {
+	w := tabwriter.NewWriter(os.Stdout, 2, 2, 2, ' ', 0)
+
+	fmt.Fprintln(w, "NODE\tADDRESS\tPEER\tPEER NODE\tUP\tLAST SYNCED\tLAST ERROR")
+	for _, n := range s.Nodes {
+		if n.Error != "" {
+			fmt.Fprintf(w, "%s\t%s\t\t\t\t\t%s\n", orDash(n.NodeID), n.Address, n.Error)
+			continue
+		}
+		if len(n.Peers) == 0 {
+			fmt.Fprintf(w, "%s\t%s\t-\t\t\t\t\n", n.NodeID, n.Address)
+		}
+		for _, p := range n.Peers {
+			lastSynced := "-"
+			if p.LastSynced != nil {
+				lastSynced = p.LastSynced.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\t%s\n",
+				n.NodeID, n.Address, p.Address, orDash(p.NodeID), p.Up, lastSynced, orDash(p.LastError))
+		}
+	}
+	fmt.Fprintln(w)
+
+	// The clock matrix: one row per origin node, one column per queried node.
+	max := s.MaxClocks()
+
+	var origins []string
+	for id := range max {
+		origins = append(origins, id)
+	}
+	sort.Strings(origins)
+
+	headers := []string{"ORIGIN"}
+	for _, n := range s.Nodes {
+		headers = append(headers, orDash(n.NodeID))
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+
+	divergent := make(map[string]bool)
+	for _, id := range s.Divergent {
+		divergent[id] = true
+	}
+
+	for _, origin := range origins {
+		values := []string{origin}
+		for _, n := range s.Nodes {
+			if n.Error != "" {
+				values = append(values, "-")
+				continue
+			}
+			var clock uint64
+			for _, c := range n.Clocks {
+				if c.NodeID == origin {
+					clock = c.Clock
+				}
+			}
+			value := strconv.FormatUint(clock, 10)
+			if divergent[origin] && clock < max[origin] {
+				value += "*"
+			}
+			values = append(values, value)
+		}
+		fmt.Fprintln(w, strings.Join(values, "\t"))
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(s.Divergent) > 0 {
+		fmt.Printf("\n* clock is behind other nodes (divergent origins: %s)\n", strings.Join(s.Divergent, ", "))
+	}
+
+	return nil
+}
+
+func printTabbedFsckReport(r *badgerauth.FsckReport) error {
+	w := tabwriter.NewWriter(os.Stdout, 2, 2, 2, ' ', 0)
+
+	fmt.Fprintln(w, "NODE\tRECORDS\tLOG ENTRIES\tCLOCKS\tPROBLEMS")
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", r.NodeID, r.Records, r.LogEntries, r.Clocks, len(r.Problems))
+
+	if len(r.Problems) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "PROBLEM\tREPAIRED\tDESCRIPTION")
+		for _, p := range r.Problems {
+			fmt.Fprintf(w, "%s\t%t\t%s\n", p.Kind, p.Repaired, p.Description)
+		}
+	}
+
+	return w.Flush()
+}
+
+func formatUnix(sec int64) string {
+	if sec == 0 {
+		return ""
+	}
+	return time.Unix(sec, 0).UTC().Format(time.RFC3339)
+}
+
+func parseTime(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339, s)
+}
+
+func parseOptionalBool(s string) (*bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
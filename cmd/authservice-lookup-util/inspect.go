@@ -0,0 +1,301 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/common/encryption"
+	"storj.io/common/grant"
+	"storj.io/common/macaroon"
+	"storj.io/common/paths"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/httpauth"
+	"storj.io/gateway-mt/pkg/authclient"
+)
+
+// inspection is everything inspect finds out about an access key ID or an
+// access grant.
+type inspection struct {
+	AccessKeyID string `json:"access_key_id,omitempty"`
+	// EncryptionKeyHash and MacaroonHead are in the form gateway-mt logs them
+	// (encryption-key-hash and macaroon-head).
+	EncryptionKeyHash string  `json:"encryption_key_hash,omitempty"`
+	Record            *record `json:"record,omitempty"`
+
+	AccessGrant      string   `json:"access_grant,omitempty"`
+	SatelliteAddress string   `json:"satellite_address,omitempty"`
+	APIKey           string   `json:"api_key,omitempty"`
+	MacaroonHead     string   `json:"macaroon_head,omitempty"`
+	Caveats          []caveat `json:"caveats,omitempty"`
+}
+
+// record is the state of the authservice record of an access key ID.
+type record struct {
+	Found              bool       `json:"found"`
+	Invalidated        bool       `json:"invalidated"`
+	InvalidationReason string     `json:"invalidation_reason,omitempty"`
+	Public             bool       `json:"public"`
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+}
+
+// caveat is a decoded macaroon caveat.
+type caveat struct {
+	AllowedOperations []string      `json:"allowed_operations"`
+	Paths             []allowedPath `json:"paths,omitempty"`
+	NotBefore         *time.Time    `json:"not_before,omitempty"`
+	NotAfter          *time.Time    `json:"not_after,omitempty"`
+}
+
+// allowedPath is a path prefix a caveat allows. EncryptedPrefix is hex encoded,
+// and Prefix is set if the access grant can decrypt it.
+type allowedPath struct {
+	Bucket          string `json:"bucket"`
+	EncryptedPrefix string `json:"encrypted_prefix,omitempty"`
+	Prefix          string `json:"prefix,omitempty"`
+	Decrypted       bool   `json:"decrypted"`
+}
+
+// inspect inspects arg, an access key ID or an access grant. Access key IDs are
+// looked up at the authservice at baseURL.
+func inspect(ctx context.Context, baseURL, token, arg string) (*inspection, error) {
+	if _, err := grant.ParseAccess(arg); err == nil {
+		result := new(inspection)
+		return result, result.decodeAccessGrant(arg)
+	}
+
+	var key authdb.EncryptionKey
+	if err := key.FromBase32(arg); err != nil {
+		return nil, errs.New("neither an access key ID nor an access grant: %w", err)
+	}
+
+	result := &inspection{
+		AccessKeyID:       arg,
+		EncryptionKeyHash: key.Hash().ToHex(),
+	}
+
+	resp, rec, err := lookup(ctx, baseURL, token, arg)
+	if err != nil {
+		return nil, err
+	}
+	result.Record = rec
+	if !rec.Found || rec.Invalidated {
+		return result, nil
+	}
+
+	return result, result.decodeAccessGrant(resp.AccessGrant)
+}
+
+// lookup looks accessKeyID up at the authservice at baseURL. It doesn't use
+// authclient as it needs to tell invalidated records apart.
+func lookup(ctx context.Context, baseURL, token, accessKeyID string) (_ authclient.AuthServiceResponse, _ *record, err error) {
+	reqURL, err := url.Parse(baseURL)
+	if err != nil {
+		return authclient.AuthServiceResponse{}, nil, errs.Wrap(err)
+	}
+	reqURL.Path = path.Join(reqURL.Path, "/v1/access", accessKeyID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
+	if err != nil {
+		return authclient.AuthServiceResponse{}, nil, errs.Wrap(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return authclient.AuthServiceResponse{}, nil, errs.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, resp.Body.Close()) }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var authResp authclient.AuthServiceResponse
+		if err = json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
+			return authclient.AuthServiceResponse{}, nil, errs.Wrap(err)
+		}
+		return authResp, &record{
+			Found:     true,
+			Public:    authResp.Public,
+			ExpiresAt: authResp.ExpiresAt,
+		}, nil
+	case http.StatusUnauthorized:
+		// authservice doesn't tell records that don't exist and expired ones
+		// apart.
+		return authclient.AuthServiceResponse{}, &record{}, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return authclient.AuthServiceResponse{}, nil, errs.Wrap(err)
+	}
+	msg := strings.TrimSpace(string(body))
+
+	if reason, ok := invalidReason(resp, msg); ok {
+		return authclient.AuthServiceResponse{}, &record{
+			Found:              true,
+			Invalidated:        true,
+			InvalidationReason: reason,
+		}, nil
+	}
+
+	return authclient.AuthServiceResponse{}, nil, errs.New("unexpected status code %d: %s", resp.StatusCode, msg)
+}
+
+// invalidReason returns the reason the record is invalid if resp, with msg in
+// its body, is authservice's response to an invalid record.
+func invalidReason(resp *http.Response, msg string) (string, bool) {
+	if resp.StatusCode != http.StatusInternalServerError {
+		return "", false
+	}
+	if reasons := resp.Header.Values(httpauth.InvalidReasonHeader); len(reasons) > 0 {
+		return reasons[0], true
+	}
+	// older authservices only respond with the error, in which the invalid
+	// class might be wrapped by the backend's (e.g., "badgerauth: invalid:").
+	const class = "invalid: "
+	if i := strings.Index(msg, class); i >= 0 {
+		return msg[i+len(class):], true
+	}
+	return "", false
+}
+
+// decodeAccessGrant decodes accessGrant into the inspection.
+func (i *inspection) decodeAccessGrant(accessGrant string) error {
+	access, err := grant.ParseAccess(accessGrant)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+
+	i.AccessGrant = accessGrant
+	i.SatelliteAddress = access.SatelliteAddress
+	i.APIKey = access.APIKey.Serialize()
+	i.MacaroonHead = hex.EncodeToString(access.APIKey.Head())
+
+	mac, err := macaroon.ParseMacaroon(access.APIKey.SerializeRaw())
+	if err != nil {
+		return errs.Wrap(err)
+	}
+
+	for _, data := range mac.Caveats() {
+		c, err := macaroon.ParseCaveat(data)
+		if err != nil {
+			return errs.Wrap(err)
+		}
+
+		decoded := caveat{
+			NotBefore: c.NotBefore,
+			NotAfter:  c.NotAfter,
+		}
+		for _, op := range []struct {
+			name       string
+			disallowed bool
+		}{
+			{"read", c.DisallowReads},
+			{"write", c.DisallowWrites},
+			{"list", c.DisallowLists},
+			{"delete", c.DisallowDeletes},
+		} {
+			if !op.disallowed {
+				decoded.AllowedOperations = append(decoded.AllowedOperations, op.name)
+			}
+		}
+
+		for _, p := range c.AllowedPaths {
+			decoded.Paths = append(decoded.Paths, decodePath(access, p))
+		}
+
+		i.Caveats = append(i.Caveats, decoded)
+	}
+
+	return nil
+}
+
+// decodePath decrypts the prefix of p with access's encryption keys if it
+// can.
+func decodePath(access *grant.Access, p *macaroon.Caveat_Path) allowedPath {
+	decoded := allowedPath{
+		Bucket:          string(p.Bucket),
+		EncryptedPrefix: hex.EncodeToString(p.EncryptedPathPrefix),
+	}
+	if len(p.EncryptedPathPrefix) == 0 {
+		decoded.Decrypted = true
+		return decoded
+	}
+
+	prefix, err := encryption.DecryptPathWithStoreCipher(decoded.Bucket, paths.NewEncrypted(string(p.EncryptedPathPrefix)), access.EncAccess.Store)
+	if err == nil {
+		decoded.Prefix = prefix.Raw()
+		decoded.Decrypted = true
+	}
+	return decoded
+}
+
+// print prints the inspection either as JSON or as text.
+func (i *inspection) print(w io.Writer, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return errs.Wrap(enc.Encode(i))
+	}
+
+	var b strings.Builder
+
+	if i.AccessKeyID != "" {
+		fmt.Fprintf(&b, "access key id: %q\n", i.AccessKeyID)
+		fmt.Fprintf(&b, "encryption key hash: %q\n", i.EncryptionKeyHash)
+	}
+	if i.Record != nil {
+		switch {
+		case !i.Record.Found:
+			fmt.Fprintf(&b, "record: not found or expired\n")
+		case i.Record.Invalidated:
+			fmt.Fprintf(&b, "record: invalidated (%s)\n", i.Record.InvalidationReason)
+		default:
+			fmt.Fprintf(&b, "record: valid\n")
+			fmt.Fprintf(&b, "public: %v\n", i.Record.Public)
+			fmt.Fprintf(&b, "expires at: %s\n", formatTime(i.Record.ExpiresAt, "never"))
+		}
+	}
+	if i.AccessGrant != "" {
+		fmt.Fprintf(&b, "access grant: %q\n", i.AccessGrant)
+		fmt.Fprintf(&b, "satellite: %q\n", i.SatelliteAddress)
+		fmt.Fprintf(&b, "api key: %q\n", i.APIKey)
+		fmt.Fprintf(&b, "macaroon head: %q\n", i.MacaroonHead)
+	}
+	for n, c := range i.Caveats {
+		fmt.Fprintf(&b, "caveat %d:\n", n+1)
+		fmt.Fprintf(&b, "  allowed operations: %s\n", strings.Join(c.AllowedOperations, ", "))
+		fmt.Fprintf(&b, "  not before: %s\n", formatTime(c.NotBefore, "none"))
+		fmt.Fprintf(&b, "  not after: %s\n", formatTime(c.NotAfter, "none"))
+		for _, p := range c.Paths {
+			if p.Decrypted {
+				fmt.Fprintf(&b, "  path: %q prefix %q\n", p.Bucket, p.Prefix)
+			} else {
+				fmt.Fprintf(&b, "  path: %q encrypted prefix %s\n", p.Bucket, p.EncryptedPrefix)
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return errs.Wrap(err)
+}
+
+// formatTime formats t, or returns unset if t is nil.
+func formatTime(t *time.Time, unset string) string {
+	if t == nil {
+		return unset
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,218 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/common/grant"
+	"storj.io/common/macaroon"
+	"storj.io/common/memory"
+	"storj.io/common/storj"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/badgerauth"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/badgerauthtest"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/pb"
+	"storj.io/gateway-mt/pkg/auth/httpauth"
+	"storj.io/gateway-mt/pkg/auth/memauth"
+)
+
+func TestInspect(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	satellite := storj.NodeURL{ID: testrand.NodeID(), Address: "127.0.0.1:7777"}
+
+	apiKey, err := macaroon.NewAPIKey(testrand.Bytes(32))
+	require.NoError(t, err)
+	encKey := testrand.Key()
+	encAccess := grant.NewEncryptionAccessWithDefaultKey(&encKey)
+	encAccess.SetDefaultPathCipher(storj.EncAESGCM)
+	access := &grant.Access{
+		SatelliteAddress: satellite.String(),
+		APIKey:           apiKey,
+		EncAccess:        encAccess,
+	}
+
+	notAfter := time.Now().Add(time.Hour).Truncate(time.Second)
+	restricted, err := access.Restrict(grant.Permission{
+		AllowDownload: true,
+		AllowList:     true,
+		NotAfter:      notAfter,
+	}, grant.SharePrefix{Bucket: "bucket", Prefix: "prefix/"})
+	require.NoError(t, err)
+	accessGrant, err := restricted.Serialize()
+	require.NoError(t, err)
+
+	kv := memauth.New()
+	adb := authdb.NewDatabase(kv, map[storj.NodeURL]struct{}{satellite: {}})
+	res := httpauth.New(zaptest.NewLogger(t), adb, nil, "token", 4*memory.KiB)
+	ts := httptest.NewServer(res)
+	defer ts.Close()
+
+	t.Run("access grant", func(t *testing.T) {
+		result, err := inspect(ctx, ts.URL, "token", accessGrant)
+		require.NoError(t, err)
+
+		assert.Empty(t, result.AccessKeyID)
+		assert.Nil(t, result.Record)
+		assert.Equal(t, satellite.String(), result.SatelliteAddress)
+		assert.Equal(t, hex.EncodeToString(restricted.APIKey.Head()), result.MacaroonHead)
+
+		require.Len(t, result.Caveats, 1)
+		c := result.Caveats[0]
+		assert.Equal(t, []string{"read", "list"}, c.AllowedOperations)
+		assert.Nil(t, c.NotBefore)
+		require.NotNil(t, c.NotAfter)
+		assert.True(t, notAfter.Equal(*c.NotAfter))
+		require.Len(t, c.Paths, 1)
+		assert.Equal(t, "bucket", c.Paths[0].Bucket)
+		assert.NotEmpty(t, c.Paths[0].EncryptedPrefix)
+		assert.True(t, c.Paths[0].Decrypted)
+		assert.Equal(t, "prefix", c.Paths[0].Prefix)
+	})
+
+	key, err := authdb.NewEncryptionKey()
+	require.NoError(t, err)
+
+	t.Run("not found", func(t *testing.T) {
+		result, err := inspect(ctx, ts.URL, "token", key.ToBase32())
+		require.NoError(t, err)
+
+		assert.Equal(t, key.Hash().ToHex(), result.EncryptionKeyHash)
+		require.NotNil(t, result.Record)
+		assert.False(t, result.Record.Found)
+		assert.Empty(t, result.AccessGrant)
+	})
+
+	_, err = adb.Put(ctx, key, accessGrant, true)
+	require.NoError(t, err)
+
+	t.Run("access key id", func(t *testing.T) {
+		result, err := inspect(ctx, ts.URL, "token", key.ToBase32())
+		require.NoError(t, err)
+
+		assert.Equal(t, key.ToBase32(), result.AccessKeyID)
+		assert.Equal(t, key.Hash().ToHex(), result.EncryptionKeyHash)
+		require.NotNil(t, result.Record)
+		assert.True(t, result.Record.Found)
+		assert.False(t, result.Record.Invalidated)
+		assert.True(t, result.Record.Public)
+		require.NotNil(t, result.Record.ExpiresAt)
+		assert.True(t, notAfter.Equal(*result.Record.ExpiresAt))
+		assert.Equal(t, accessGrant, result.AccessGrant)
+		require.Len(t, result.Caveats, 1)
+
+		var out bytes.Buffer
+		require.NoError(t, result.print(&out, true))
+		var decoded inspection
+		require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+		assert.Equal(t, result.MacaroonHead, decoded.MacaroonHead)
+		assert.Equal(t, result.Caveats[0].Paths, decoded.Caveats[0].Paths)
+
+		out.Reset()
+		require.NoError(t, result.print(&out, false))
+		assert.Contains(t, out.String(), `macaroon head: "`+result.MacaroonHead+`"`)
+		assert.Contains(t, out.String(), `path: "bucket" prefix "prefix"`)
+	})
+
+	require.NoError(t, kv.Invalidate(ctx, key.Hash(), "abuse"))
+
+	t.Run("invalidated", func(t *testing.T) {
+		result, err := inspect(ctx, ts.URL, "token", key.ToBase32())
+		require.NoError(t, err)
+
+		require.NotNil(t, result.Record)
+		assert.True(t, result.Record.Found)
+		assert.True(t, result.Record.Invalidated)
+		assert.Equal(t, "abuse", result.Record.InvalidationReason)
+		assert.Empty(t, result.AccessGrant)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		_, err := inspect(ctx, ts.URL, "wrong", "invalid")
+		require.Error(t, err)
+	})
+}
+
+func TestInspect_Badgerauth(t *testing.T) {
+	badgerauthtest.RunSingleNode(t, badgerauth.Config{
+		ID: badgerauth.NodeID{'i', 'n', 's', 'p'},
+	}, func(ctx *testcontext.Context, t *testing.T, log *zap.Logger, node *badgerauth.Node) {
+		satellite := storj.NodeURL{ID: testrand.NodeID(), Address: "127.0.0.1:7777"}
+		apiKey, err := macaroon.NewAPIKey(testrand.Bytes(32))
+		require.NoError(t, err)
+		encKey := testrand.Key()
+		accessGrant, err := (&grant.Access{
+			SatelliteAddress: satellite.String(),
+			APIKey:           apiKey,
+			EncAccess:        grant.NewEncryptionAccessWithDefaultKey(&encKey),
+		}).Serialize()
+		require.NoError(t, err)
+
+		adb := authdb.NewDatabase(node, map[storj.NodeURL]struct{}{satellite: {}})
+		ts := httptest.NewServer(httpauth.New(log, adb, nil, "token", 4*memory.KiB))
+		defer ts.Close()
+
+		key, err := authdb.NewEncryptionKey()
+		require.NoError(t, err)
+		_, err = adb.Put(ctx, key, accessGrant, false)
+		require.NoError(t, err)
+
+		// badgerauth wraps the invalid error in its own class.
+		_, err = badgerauth.NewAdmin(node.UnderlyingDB()).InvalidateRecord(ctx, &pb.InvalidateRecordRequest{
+			Key:    key.Hash().Bytes(),
+			Reason: "abuse: spam",
+		})
+		require.NoError(t, err)
+
+		result, err := inspect(ctx, ts.URL, "token", key.ToBase32())
+		require.NoError(t, err)
+
+		require.NotNil(t, result.Record)
+		assert.True(t, result.Record.Found)
+		assert.True(t, result.Record.Invalidated)
+		assert.Equal(t, "abuse: spam", result.Record.InvalidationReason)
+		assert.Empty(t, result.AccessGrant)
+	})
+}
+
+func TestInvalidReason(t *testing.T) {
+	for _, tt := range []struct {
+		status int
+		header []string
+		msg    string
+		reason string
+		ok     bool
+	}{
+		{status: http.StatusInternalServerError, header: []string{"abuse"}, msg: "badgerauth: invalid: abuse", reason: "abuse", ok: true},
+		{status: http.StatusInternalServerError, header: []string{""}, msg: "badgerauth: invalid", reason: "", ok: true},
+		// older authservices don't return the header.
+		{status: http.StatusInternalServerError, msg: "invalid: abuse", reason: "abuse", ok: true},
+		{status: http.StatusInternalServerError, msg: "badgerauth: invalid: abuse: spam", reason: "abuse: spam", ok: true},
+		{status: http.StatusInternalServerError, msg: "badgerauth: timeout"},
+		{status: http.StatusBadRequest, msg: "invalid: abuse"},
+	} {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		if tt.header != nil {
+			resp.Header[http.CanonicalHeaderKey(httpauth.InvalidReasonHeader)] = tt.header
+		}
+		reason, ok := invalidReason(resp, tt.msg)
+		assert.Equal(t, tt.ok, ok, tt.msg)
+		assert.Equal(t, tt.reason, reason, tt.msg)
+	}
+}
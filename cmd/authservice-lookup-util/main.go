@@ -5,6 +5,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 
@@ -19,13 +20,26 @@ var config struct {
 	AuthServiceToken   string `default:"" help:"authservice token"`
 }
 
+var inspectJSON bool
+
 func main() {
 	cmd := &cobra.Command{
 		Use:  "authservice-lookup-util [access-key]",
 		RunE: cmdRun,
 		Args: cobra.ExactArgs(1),
 	}
-	process.Bind(cmd, &config, cfgstruct.DefaultsFlag(cmd))
+	inspectCmd := &cobra.Command{
+		Use:   "inspect [access-key-or-grant]",
+		Short: "Decode the access grant of an access key ID (looked up at authservice) or an access grant",
+		RunE:  cmdInspect,
+		Args:  cobra.ExactArgs(1),
+	}
+	cmd.AddCommand(inspectCmd)
+
+	defaults := cfgstruct.DefaultsFlag(cmd)
+	process.Bind(cmd, &config, defaults)
+	process.Bind(inspectCmd, &config, defaults)
+	inspectCmd.Flags().BoolVar(&inspectJSON, "json", false, "print the inspection as JSON")
 	process.Exec(cmd)
 }
 
@@ -59,3 +73,15 @@ api key: %q
 
 	return err
 }
+
+func cmdInspect(cmd *cobra.Command, args []string) (err error) {
+	ctx, cancel := process.Ctx(cmd)
+	defer cancel()
+
+	result, err := inspect(ctx, config.AuthServiceBaseURL, config.AuthServiceToken, args[0])
+	if err != nil {
+		return err
+	}
+
+	return result.print(os.Stdout, inspectJSON)
+}
@@ -6,6 +6,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -18,6 +19,9 @@ import (
 	"storj.io/common/fpath"
 	"storj.io/gateway-mt/internal/register"
 	"storj.io/gateway-mt/pkg/auth"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/authexport"
+	"storj.io/gateway-mt/pkg/auth/badgerauth"
 	"storj.io/private/cfgstruct"
 	"storj.io/private/process"
 )
@@ -46,6 +50,30 @@ var (
 		RunE:        cmdSetup,
 		Hidden:      true,
 	}
+	encryptStorageCmd = &cobra.Command{
+		Use:   "encrypt-storage <unencrypted-path>",
+		Short: "Copy unencrypted badgerauth storage to node.path, encrypting it with node.encryption-key-file, then quit",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmdEncryptStorage,
+	}
+	rotateStorageKeyCmd = &cobra.Command{
+		Use:   "rotate-storage-key <old-key-file>",
+		Short: "Re-encrypt badgerauth storage at node.path with node.encryption-key-file instead of the old key, then quit",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmdRotateStorageKey,
+	}
+	exportCmd = &cobra.Command{
+		Use:   "export <file>",
+		Short: "Export records from the configured key/value store backend as JSON Lines to file (- for stdout), then quit",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmdExport,
+	}
+	importCmd = &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import records exported as JSON Lines from file (- for stdin) into the configured key/value store backend, then quit",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmdImport,
+	}
 	registerCmd = &cobra.Command{
 		Use:    "register",
 		Short:  "Register credentials @ authservice via HTTP or DRPC",
@@ -59,6 +87,8 @@ var (
 
 	confDir string
 
+	importConflict string
+
 	registerCfg struct {
 		Address   string `help:"authservice to register access to" dev:"drpc://localhost:20002" release:"drpcs://auth.storjshare.io:7777"`
 		Public    bool   `help:"whether access grant can be retrieved from authservice by providing only Access Key ID without Secret Access Key" default:"false"`
@@ -74,12 +104,21 @@ func init() {
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(setupCmd)
 	rootCmd.AddCommand(registerCmd)
+	rootCmd.AddCommand(encryptStorageCmd)
+	rootCmd.AddCommand(rotateStorageKeyCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
 
 	runCmd.AddCommand(runMigrationCmd)
 
 	process.Bind(runCmd, &runCfg, defaults, cfgstruct.ConfDir(confDir))
 	process.Bind(runMigrationCmd, &runCfg, defaults, cfgstruct.ConfDir(confDir))
 	process.Bind(setupCmd, &setupCfg, defaults, cfgstruct.ConfDir(confDir), cfgstruct.SetupMode())
+	process.Bind(encryptStorageCmd, &runCfg, defaults, cfgstruct.ConfDir(confDir))
+	process.Bind(rotateStorageKeyCmd, &runCfg, defaults, cfgstruct.ConfDir(confDir))
+	process.Bind(exportCmd, &runCfg, defaults, cfgstruct.ConfDir(confDir))
+	process.Bind(importCmd, &runCfg, defaults, cfgstruct.ConfDir(confDir))
+	importCmd.Flags().StringVar(&importConflict, "conflict", string(authexport.ConflictFail), "what to do if a record already exists: skip, overwrite-if-equal or fail")
 	process.Bind(registerCmd, &registerCfg, defaults)
 }
 
@@ -148,6 +187,97 @@ func cmdMigrationRun(cmd *cobra.Command, _ []string) (err error) {
 	return g.Wait()
 }
 
+func cmdEncryptStorage(cmd *cobra.Command, args []string) error {
+	ctx, _ := process.Ctx(cmd)
+
+	return badgerauth.MigrateToEncrypted(ctx, zap.L().Named("encrypt-storage"), args[0], runCfg.Node)
+}
+
+func cmdRotateStorageKey(cmd *cobra.Command, args []string) error {
+	return badgerauth.RotateEncryptionKey(runCfg.Node, args[0])
+}
+
+func cmdExport(cmd *cobra.Command, args []string) (err error) {
+	ctx, _ := process.Ctx(cmd)
+	log := zap.L().Named("export")
+
+	kv, err := auth.OpenKV(ctx, log, runCfg)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, kv.Close()) }()
+
+	ranger, ok := kv.(authdb.Ranger)
+	if !ok {
+		return errs.New("database backend %T does not support export", kv)
+	}
+
+	w := io.Writer(os.Stdout)
+	if args[0] != "-" {
+		f, err := os.Create(args[0])
+		if err != nil {
+			return errs.Wrap(err)
+		}
+		defer func() { err = errs.Combine(err, f.Close()) }()
+		w = f
+	}
+
+	count, err := authexport.Export(ctx, ranger, w)
+	if err != nil {
+		return err
+	}
+
+	log.Info("exported records", zap.Int64("count", count))
+
+	return nil
+}
+
+func cmdImport(cmd *cobra.Command, args []string) (err error) {
+	ctx, _ := process.Ctx(cmd)
+	log := zap.L().Named("import")
+
+	conflict, err := authexport.ParseConflict(importConflict)
+	if err != nil {
+		return err
+	}
+
+	if runCfg.Node.ReadOnly {
+		return errs.New("importing to read-only nodes isn't supported")
+	}
+
+	kv, err := auth.OpenKV(ctx, log, runCfg)
+	if err != nil {
+		return errs.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, kv.Close()) }()
+
+	var dst authexport.Store = kv
+	if node, ok := kv.(*badgerauth.Node); ok {
+		// Peers aren't running, so write quorum couldn't be reached anyway.
+		// Imported records get fresh replication log entries either way.
+		dst = node.UnderlyingDB()
+	}
+
+	r := io.Reader(os.Stdin)
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return errs.Wrap(err)
+		}
+		defer func() { err = errs.Combine(err, f.Close()) }()
+		r = f
+	}
+
+	stats, err := authexport.Import(ctx, dst, r, conflict)
+
+	log.Info("imported records",
+		zap.Int64("imported", stats.Imported),
+		zap.Int64("existing", stats.Existing),
+		zap.Int64("expired", stats.Expired))
+
+	return err
+}
+
 func cmdSetup(cmd *cobra.Command, _ []string) error {
 	setupDir, err := filepath.Abs(confDir)
 	if err != nil {
@@ -0,0 +1,283 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"storj.io/common/errs2"
+	"storj.io/common/fpath"
+	"storj.io/gateway-mt/internal/register"
+	"storj.io/gateway-mt/pkg/auth"
+	"storj.io/gateway-mt/pkg/authclient"
+	"storj.io/gateway-mt/pkg/httpserver"
+	"storj.io/gateway-mt/pkg/linksharing"
+	"storj.io/gateway-mt/pkg/linksharing/sharing"
+	"storj.io/gateway-mt/pkg/server"
+	"storj.io/gateway-mt/pkg/trustedip"
+	"storj.io/private/cfgstruct"
+	"storj.io/private/process"
+	"storj.io/uplink"
+)
+
+// Error is the default edge errs class.
+var Error = errs.Class("edge")
+
+// Config holds the configuration of all services edge runs. Auth tokens and
+// URLs the services use to talk to each other are wired automatically.
+type Config struct {
+	Gateway     server.Config
+	Auth        auth.Config
+	Linksharing LinksharingConfig
+
+	Access string `help:"access grant to register once services start, printing credentials for it" default:""`
+	Public bool   `help:"whether the access grant registered with --access is public, e.g., to share it with linksharing" default:"true"`
+}
+
+// LinksharingConfig holds the subset of linksharing's configuration that
+// makes sense for a local stack. It serves plain HTTP only.
+type LinksharingConfig struct {
+	Address                string        `user:"true" help:"public address to listen on" default:"127.0.0.1:20020"`
+	PublicURL              string        `user:"true" help:"public url for the server (defaults to the address)" default:""`
+	TXTRecordTTL           time.Duration `user:"true" help:"max ttl (seconds) for website hosting txt record cache" default:"10s"`
+	DNSServer              string        `user:"true" help:"dns server address to use for TXT resolution" default:"1.1.1.1:53"`
+	StaticSourcesPath      string        `user:"true" help:"the path to where web assets are located" default:"./pkg/linksharing/web/static"`
+	Templates              string        `user:"true" help:"the path to where renderable templates are located" default:"./pkg/linksharing/web"`
+	LandingRedirectTarget  string        `user:"true" help:"the url to redirect empty requests to" default:"https://www.storj.io/"`
+	DialTimeout            time.Duration `help:"timeout for dials" default:"10s"`
+	StandardRendersContent bool          `user:"true" help:"enable standard (non-hosting) requests to render content and not only download it" default:"false"`
+	StandardViewsHTML      bool          `user:"true" help:"serve HTML as text/html instead of text/plain for standard (non-hosting) requests" default:"false"`
+}
+
+var (
+	rootCmd = &cobra.Command{
+		Use:   "edge",
+		Short: "Gateway-MT, authservice and linksharing in a single process (for development)",
+	}
+	runCmd = &cobra.Command{
+		Use:   "run",
+		Short: "Run all services",
+		Args:  cobra.ExactArgs(0),
+		RunE:  cmdRun,
+	}
+	setupCmd = &cobra.Command{
+		Use:         "setup",
+		Short:       "Create configuration file",
+		Args:        cobra.ExactArgs(0),
+		Annotations: map[string]string{"type": "setup"},
+		RunE:        cmdSetup,
+		Hidden:      true,
+	}
+
+	runCfg   Config
+	setupCfg Config
+
+	confDir string
+)
+
+func init() {
+	defaultConfDir := fpath.ApplicationDir("storj", "edge")
+	cfgstruct.SetupFlag(zap.L(), rootCmd, &confDir, "config-dir", defaultConfDir, "main directory for edge configuration")
+	defaults := cfgstruct.DefaultsFlag(rootCmd)
+
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(setupCmd)
+
+	process.Bind(runCmd, &runCfg, defaults, cfgstruct.ConfDir(confDir))
+	process.Bind(setupCmd, &setupCfg, defaults, cfgstruct.ConfDir(confDir), cfgstruct.SetupMode())
+}
+
+func main() {
+	process.Exec(rootCmd)
+}
+
+func cmdRun(cmd *cobra.Command, _ []string) (err error) {
+	ctx, cancel := process.Ctx(cmd)
+	defer cancel()
+
+	log := zap.L()
+
+	if runCfg.Gateway.EmbeddedAuth.Enabled {
+		return Error.New("--gateway.embedded-auth.enabled can't be used; edge runs authservice already")
+	}
+
+	// setup environment variables for Minio
+	set := func(value, envName string) {
+		err = errs.Combine(err, Error.Wrap(os.Setenv(envName, value)))
+	}
+	// edge serves plain HTTP only.
+	runCfg.Gateway.InsecureDisableTLS = true
+	if runCfg.Gateway.DomainName == "" {
+		runCfg.Gateway.DomainName = "localhost"
+	}
+	set(runCfg.Gateway.DomainName, "MINIO_DOMAIN")
+	set("off", "MINIO_BROWSER")
+	set("dummy-key-to-satisfy-minio", "MINIO_ACCESS_KEY")
+	set("dummy-key-to-satisfy-minio", "MINIO_SECRET_KEY")
+	if err != nil {
+		return err
+	}
+
+	if runCfg.Auth.AuthToken == "" {
+		var token [32]byte
+		if _, err = rand.Read(token[:]); err != nil {
+			return Error.Wrap(err)
+		}
+		runCfg.Auth.AuthToken = hex.EncodeToString(token[:])
+	}
+	if runCfg.Auth.KVBackend == "" {
+		runCfg.Auth.KVBackend = "memory://"
+	}
+	if runCfg.Auth.Endpoint == "" {
+		runCfg.Auth.Endpoint = localURL(runCfg.Gateway.Server.Address)
+	}
+	if runCfg.Linksharing.PublicURL == "" {
+		runCfg.Linksharing.PublicURL = localURL(runCfg.Linksharing.Address)
+	}
+
+	authPeer, err := auth.New(ctx, log.Named("auth"), runCfg.Auth, confDir)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errs.Combine(err, authPeer.Close()) }()
+
+	// gateway-mt and linksharing talk to authservice over its HTTP listener.
+	authURL := localURL(authPeer.Address())
+	runCfg.Gateway.Auth.BaseURL = authURL
+	runCfg.Gateway.Auth.Token = runCfg.Auth.AuthToken
+	runCfg.Gateway.Auth.FailoverURLs = nil
+	authServiceConfig := runCfg.Gateway.Auth
+	if err = authServiceConfig.Validate(); err != nil {
+		return err
+	}
+
+	gatewayPeer, err := server.New(runCfg.Gateway, log.Named("gateway"), trustedip.NewListTrustAll(),
+		strings.Split(runCfg.Gateway.CorsOrigins, ","), authclient.New(authServiceConfig),
+		strings.Split(runCfg.Gateway.DomainName, ","), runCfg.Gateway.ConcurrentAllowed)
+	if err != nil {
+		return err
+	}
+
+	linksharingPeer, err := linksharing.New(log.Named("linksharing"), linksharing.Config{
+		Server: httpserver.Config{
+			Name:            "Link Sharing",
+			Address:         runCfg.Linksharing.Address,
+			TrafficLogging:  true,
+			ShutdownTimeout: -1,
+		},
+		Handler: sharing.Config{
+			URLBases:               []string{runCfg.Linksharing.PublicURL},
+			Templates:              runCfg.Linksharing.Templates,
+			StaticSourcesPath:      runCfg.Linksharing.StaticSourcesPath,
+			LandingRedirectTarget:  runCfg.Linksharing.LandingRedirectTarget,
+			TXTRecordTTL:           runCfg.Linksharing.TXTRecordTTL,
+			AuthServiceConfig:      authServiceConfig,
+			DNSServer:              runCfg.Linksharing.DNSServer,
+			ConnectionPool:         sharing.ConnectionPoolConfig(runCfg.Gateway.ConnectionPool),
+			UseQosAndCC:            runCfg.Gateway.Client.UseQosAndCC,
+			UseClientIPHeaders:     true,
+			StandardViewsHTML:      runCfg.Linksharing.StandardViewsHTML,
+			StandardRendersContent: runCfg.Linksharing.StandardRendersContent,
+			Uplink: &uplink.Config{
+				UserAgent:   "linksharing",
+				DialTimeout: runCfg.Linksharing.DialTimeout,
+			},
+		},
+	})
+	if err != nil {
+		return errs.Combine(err, gatewayPeer.Close())
+	}
+
+	log.Info("Starting edge",
+		zap.String("gateway", localURL(gatewayPeer.Address())),
+		zap.String("auth", authURL),
+		zap.String("auth-drpc", authPeer.DRPCAddress()),
+		zap.String("linksharing", runCfg.Linksharing.PublicURL))
+
+	g, gCtx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		<-gCtx.Done()
+		return errs2.IgnoreCanceled(errs.Combine(gatewayPeer.Close(), linksharingPeer.Close()))
+	})
+	g.Go(func() error {
+		return errs2.IgnoreCanceled(authPeer.Run(gCtx))
+	})
+	g.Go(func() error {
+		return errs2.IgnoreCanceled(gatewayPeer.Run(gCtx))
+	})
+	g.Go(func() error {
+		return errs2.IgnoreCanceled(linksharingPeer.Run(gCtx))
+	})
+
+	if runCfg.Access != "" {
+		g.Go(func() error {
+			return printCredentials(gCtx, authURL, runCfg.Access, runCfg.Public, runCfg.Linksharing.PublicURL)
+		})
+	}
+
+	return g.Wait()
+}
+
+// printCredentials registers access at authservice at authURL and prints
+// credentials for it in the environmental-variable format.
+func printCredentials(ctx context.Context, authURL, access string, public bool, linksharingURL string) error {
+	res, err := register.Access(ctx, authURL, access, public)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("AWS_ACCESS_KEY_ID=%s\nAWS_SECRET_ACCESS_KEY=%s\nAWS_ENDPOINT=%s\n",
+		res.AccessKeyID, res.SecretKey, res.Endpoint)
+	if public {
+		fmt.Printf("LINKSHARING_URL=%s/s/%s/\n", strings.TrimSuffix(linksharingURL, "/"), res.AccessKeyID)
+	}
+
+	return nil
+}
+
+// localURL returns the HTTP URL of a local listener at addr, replacing
+// unspecified hosts with localhost.
+func localURL(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "http://" + addr
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	return (&url.URL{Scheme: "http", Host: net.JoinHostPort(host, port)}).String()
+}
+
+func cmdSetup(cmd *cobra.Command, _ []string) error {
+	setupDir, err := filepath.Abs(confDir)
+	if err != nil {
+		return err
+	}
+
+	valid, _ := fpath.IsValidSetupDir(setupDir)
+	if !valid {
+		return errs.New("configuration already exists (%v)", setupDir)
+	}
+
+	if err = os.MkdirAll(setupDir, 0700); err != nil {
+		return err
+	}
+
+	return process.SaveConfig(cmd, filepath.Join(setupDir, "config.yaml"))
+}
@@ -129,8 +129,11 @@ func cmdRun(cmd *cobra.Command, _ []string) (err error) {
 
 	corsAllowedOrigins := strings.Split(runCfg.CorsOrigins, ",")
 
-	if err := runCfg.Auth.Validate(); err != nil {
-		return err
+	// the auth service isn't requested if it's embedded.
+	if !runCfg.EmbeddedAuth.Enabled {
+		if err := runCfg.Auth.Validate(); err != nil {
+			return err
+		}
 	}
 	peer, err := server.New(runCfg, log, trustedClientIPs, corsAllowedOrigins,
 		authclient.New(runCfg.Auth), strings.Split(runCfg.DomainName, ","), runCfg.ConcurrentAllowed)
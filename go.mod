@@ -9,6 +9,7 @@ require (
 	github.com/jackc/pgconn v1.11.0
 	github.com/jackc/pgx/v4 v4.15.0
 	github.com/jtolio/eventkit v0.0.0-20221007130042-690145affff8
+	github.com/mattn/go-sqlite3 v1.14.12
 	github.com/mholt/acmez v1.0.4
 	github.com/miekg/dns v1.1.50
 	github.com/minio/minio-go/v7 v7.0.11-0.20210302210017-6ae69c73ce78
@@ -9,6 +9,7 @@ import (
 	"encoding/hex"
 	"log"
 	"net"
+	"sort"
 	"time"
 
 	"github.com/zeebo/errs"
@@ -101,6 +102,126 @@ func (c *AuthAdminClient) Get(ctx context.Context, encodedKey string) (record *R
 	}))
 }
 
+// ListedRecord is a representation of pb.ListedRecord for display purposes.
+// Listed records don't include encrypted fields.
+type ListedRecord struct {
+	KeyHash string `json:"key_hash"`
+	Record
+}
+
+// List lists up to limit records matching filter after cursor from the first
+// configured node address. next is the cursor to continue listing from, or
+// zero if no more records match. A page can have fewer than limit records
+// (even none) while next is non-zero if the node bounds how many records it
+// scans.
+func (c *AuthAdminClient) List(ctx context.Context, filter authdb.ListFilter, cursor authdb.ListCursor, limit int) (records []ListedRecord, next authdb.ListCursor, err error) {
+	req := &pb.ListRecordsRequest{
+		MacaroonHead:     filter.MacaroonHead,
+		SatelliteAddress: filter.SatelliteAddress,
+		Public:           filterToMatch(filter.Public),
+		Invalidated:      filterToMatch(filter.Invalidated),
+		Limit:            int32(limit),
+	}
+	if !filter.CreatedAfter.IsZero() {
+		req.CreatedAfterUnix = filter.CreatedAfter.Unix()
+	}
+	if !filter.CreatedBefore.IsZero() {
+		req.CreatedBeforeUnix = filter.CreatedBefore.Unix()
+	}
+	if !cursor.IsZero() {
+		req.CursorCreatedAtUnix = cursor.CreatedAt.Unix()
+		req.CursorKey = cursor.KeyHash.Bytes()
+	}
+
+	var addresses []string
+	if len(c.config.NodeAddresses) > 0 {
+		addresses = c.config.NodeAddresses[:1]
+	}
+
+	return records, next, Error.Wrap(c.withAdminClient(ctx, addresses, func(ctx context.Context, client pb.DRPCAdminServiceClient) error {
+		resp, err := client.ListRecords(ctx, req)
+		if err != nil {
+			return errs.New("list records: %w", err)
+		}
+
+		for _, r := range resp.Records {
+			var keyHash authdb.KeyHash
+			if err = keyHash.SetBytes(r.Key); err != nil {
+				return errs.New("key hash: %w", err)
+			}
+
+			listed := ListedRecord{KeyHash: keyHash.ToHex()}
+			if err = listed.updateFromProto(r.Record, authdb.EncryptionKey{}); err != nil {
+				return errs.New("update from proto: %w", err)
+			}
+			records = append(records, listed)
+
+			if resp.More {
+				next = authdb.ListCursor{CreatedAt: time.Unix(r.Record.CreatedAtUnix, 0).UTC(), KeyHash: keyHash}
+			}
+		}
+
+		// nodes that bound scans return where to continue from.
+		if resp.More && (resp.NextCursorCreatedAtUnix != 0 || len(resp.NextCursorKey) > 0) {
+			next = authdb.ListCursor{CreatedAt: time.Unix(resp.NextCursorCreatedAtUnix, 0).UTC()}
+			if err = next.KeyHash.SetBytes(resp.NextCursorKey); err != nil {
+				return errs.New("next cursor key: %w", err)
+			}
+		}
+
+		return nil
+	}))
+}
+
+// ListFrom lists up to limit records matching filter after cursor directly
+// from lister, e.g., a sqlauth KV that has no admin API. next is the cursor to
+// continue listing from, or zero if no more records match.
+func ListFrom(ctx context.Context, lister authdb.Lister, filter authdb.ListFilter, cursor authdb.ListCursor, limit int) (records []ListedRecord, next authdb.ListCursor, err error) {
+	listed, next, err := lister.List(ctx, filter, cursor, limit)
+	if err != nil {
+		return nil, authdb.ListCursor{}, Error.Wrap(err)
+	}
+
+	for _, r := range listed {
+		record := ListedRecord{KeyHash: r.KeyHash.ToHex()}
+		if err = record.updateFromProto(listedRecordToProto(r), authdb.EncryptionKey{}); err != nil {
+			return nil, authdb.ListCursor{}, Error.New("update from proto: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return records, next, nil
+}
+
+func listedRecordToProto(r authdb.ListedRecord) *pb.Record {
+	record := &pb.Record{
+		CreatedAtUnix:      r.CreatedAt.Unix(),
+		Public:             r.Public,
+		SatelliteAddress:   r.SatelliteAddress,
+		MacaroonHead:       r.MacaroonHead,
+		InvalidationReason: r.InvalidationReason,
+		State:              pb.Record_CREATED,
+	}
+	if r.ExpiresAt != nil {
+		record.ExpiresAtUnix = r.ExpiresAt.Unix()
+	}
+	if r.InvalidatedAt != nil {
+		record.InvalidatedAtUnix = r.InvalidatedAt.Unix()
+	}
+	return record
+}
+
+func filterToMatch(v *bool) pb.ListRecordsRequest_Match {
+	switch {
+	case v == nil:
+		return pb.ListRecordsRequest_ANY
+	case *v:
+		return pb.ListRecordsRequest_ONLY
+	default:
+		return pb.ListRecordsRequest_EXCLUDE
+	}
+}
+
 // Invalidate invalidates a record on all configured node addresses.
 func (c *AuthAdminClient) Invalidate(ctx context.Context, encodedKey, reason string) error {
 	keyHash, _, err := keyFromInput(encodedKey)
@@ -152,6 +273,222 @@ func (c *AuthAdminClient) Delete(ctx context.Context, encodedKey string) error {
 	}))
 }
 
+// AddPeer adds a peer to the cluster membership on all configured node
+// addresses.
+func (c *AuthAdminClient) AddPeer(ctx context.Context, address string) error {
+	return Error.Wrap(c.withAdminClient(ctx, c.config.NodeAddresses, func(ctx context.Context, client pb.DRPCAdminServiceClient) error {
+		_, err := client.AddPeer(ctx, &pb.AddPeerRequest{Address: address})
+		if err != nil {
+			return errs.New("add peer: %w", err)
+		}
+		return nil
+	}))
+}
+
+// RemovePeer removes a peer from the cluster membership on all configured node
+// addresses.
+func (c *AuthAdminClient) RemovePeer(ctx context.Context, address string) error {
+	return Error.Wrap(c.withAdminClient(ctx, c.config.NodeAddresses, func(ctx context.Context, client pb.DRPCAdminServiceClient) error {
+		_, err := client.RemovePeer(ctx, &pb.RemovePeerRequest{Address: address})
+		if err != nil {
+			return errs.New("remove peer: %w", err)
+		}
+		return nil
+	}))
+}
+
+// Decommission permanently decommissions a node ID on all configured node
+// addresses.
+func (c *AuthAdminClient) Decommission(ctx context.Context, nodeID string) error {
+	var id badgerauth.NodeID
+	if err := id.Set(nodeID); err != nil {
+		return Error.New("node ID from input: %w", err)
+	}
+
+	return Error.Wrap(c.withAdminClient(ctx, c.config.NodeAddresses, func(ctx context.Context, client pb.DRPCAdminServiceClient) error {
+		_, err := client.DecommissionNode(ctx, &pb.DecommissionNodeRequest{NodeId: id.Bytes()})
+		if err != nil {
+			return errs.New("decommission node: %w", err)
+		}
+		return nil
+	}))
+}
+
+// ClusterStatus is a representation of the cluster's status, as seen by all
+// configured node addresses, for display purposes.
+type ClusterStatus struct {
+	Nodes []NodeStatus `json:"nodes"`
+	// Divergent lists origin node IDs whose clocks differ between nodes.
+	Divergent []string `json:"divergent,omitempty"`
+}
+
+// NodeStatus is a representation of the cluster's status as seen by a single
+// node.
+type NodeStatus struct {
+	Address string       `json:"address"`
+	NodeID  string       `json:"node_id,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Clocks  []Clock      `json:"clocks,omitempty"`
+	Peers   []PeerStatus `json:"peers,omitempty"`
+}
+
+// Clock is a clock known by a node for an origin node.
+type Clock struct {
+	NodeID         string `json:"node_id"`
+	Clock          uint64 `json:"clock"`
+	Decommissioned bool   `json:"decommissioned,omitempty"`
+}
+
+// PeerStatus is a peer's status as seen by a node.
+type PeerStatus struct {
+	Address     string     `json:"address"`
+	NodeID      string     `json:"node_id,omitempty"`
+	Up          bool       `json:"up"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastUpdated *time.Time `json:"last_updated,omitempty"`
+	LastSynced  *time.Time `json:"last_synced,omitempty"`
+	Clock       uint64     `json:"clock"`
+}
+
+// ClusterStatus queries all configured node addresses for their view of the
+// cluster. Nodes that fail to respond are reported in the result, not as an
+// error.
+func (c *AuthAdminClient) ClusterStatus(ctx context.Context) (*ClusterStatus, error) {
+	if len(c.config.NodeAddresses) == 0 {
+		return nil, Error.New("node addresses unspecified")
+	}
+
+	status := &ClusterStatus{
+		Nodes: make([]NodeStatus, len(c.config.NodeAddresses)),
+	}
+
+	var group errgroup.Group
+	for i, address := range c.config.NodeAddresses {
+		i, address := i, address
+		group.Go(func() error {
+			status.Nodes[i].Address = address
+			err := c.withAdminClient(ctx, []string{address}, func(ctx context.Context, client pb.DRPCAdminServiceClient) error {
+				resp, err := client.ClusterStatus(ctx, &pb.ClusterStatusRequest{})
+				if err != nil {
+					return errs.New("cluster status: %w", err)
+				}
+				status.Nodes[i].updateFromProto(resp)
+				return nil
+			})
+			if err != nil {
+				status.Nodes[i].Error = err.Error()
+			}
+			return nil
+		})
+	}
+	_ = group.Wait()
+
+	status.Divergent = divergentClocks(status.Nodes)
+
+	return status, nil
+}
+
+func (s *NodeStatus) updateFromProto(resp *pb.ClusterStatusResponse) {
+	s.NodeID = nodeIDString(resp.NodeId)
+	for _, c := range resp.Clocks {
+		s.Clocks = append(s.Clocks, Clock{
+			NodeID:         nodeIDString(c.NodeId),
+			Clock:          c.Clock,
+			Decommissioned: c.Decommissioned,
+		})
+	}
+	for _, p := range resp.Peers {
+		s.Peers = append(s.Peers, PeerStatus{
+			Address:     p.Address,
+			NodeID:      nodeIDString(p.NodeId),
+			Up:          p.Up,
+			LastError:   p.LastError,
+			LastUpdated: unixToTime(p.LastUpdatedUnix),
+			LastSynced:  unixToTime(p.LastSyncedUnix),
+			Clock:       p.Clock,
+		})
+	}
+}
+
+// MaxClocks returns the highest clock known by any of the nodes per origin node
+// ID, skipping decommissioned nodes.
+func (s *ClusterStatus) MaxClocks() map[string]uint64 {
+	max := make(map[string]uint64)
+	for _, n := range s.Nodes {
+		for _, c := range n.Clocks {
+			if c.Decommissioned {
+				continue
+			}
+			if c.Clock >= max[c.NodeID] {
+				max[c.NodeID] = c.Clock
+			}
+		}
+	}
+	return max
+}
+
+// divergentClocks returns sorted origin node IDs for which responding nodes
+// know different clocks. A node that doesn't know an origin at all is treated
+// as if it knew clock 0.
+func divergentClocks(nodes []NodeStatus) (divergent []string) {
+	values := make(map[string]map[uint64]struct{})
+
+	var responding int
+	for _, n := range nodes {
+		if n.Error != "" {
+			continue
+		}
+		responding++
+	}
+
+	counts := make(map[string]int)
+	for _, n := range nodes {
+		if n.Error != "" {
+			continue
+		}
+		for _, c := range n.Clocks {
+			if c.Decommissioned {
+				continue
+			}
+			if values[c.NodeID] == nil {
+				values[c.NodeID] = make(map[uint64]struct{})
+			}
+			values[c.NodeID][c.Clock] = struct{}{}
+			counts[c.NodeID]++
+		}
+	}
+
+	for id, v := range values {
+		if len(v) > 1 || (counts[id] < responding && !isOnly(v, 0)) {
+			divergent = append(divergent, id)
+		}
+	}
+	sort.Strings(divergent)
+
+	return divergent
+}
+
+func isOnly(v map[uint64]struct{}, value uint64) bool {
+	_, ok := v[value]
+	return ok && len(v) == 1
+}
+
+func nodeIDString(b []byte) string {
+	var id badgerauth.NodeID
+	if err := id.SetBytes(b); err != nil {
+		return hex.EncodeToString(b)
+	}
+	return id.String()
+}
+
+func unixToTime(sec int64) *time.Time {
+	if sec == 0 {
+		return nil
+	}
+	t := time.Unix(sec, 0).UTC()
+	return &t
+}
+
 // withAdminClient runs fn concurrently on given node addresses.
 func (c *AuthAdminClient) withAdminClient(ctx context.Context, addresses []string, fn func(ctx context.Context, client pb.DRPCAdminServiceClient) error) error {
 	if len(addresses) == 0 {
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 
 	"storj.io/common/encryption"
 	"storj.io/common/grant"
@@ -166,6 +167,97 @@ func TestDeleteRecord(t *testing.T) {
 	})
 }
 
+func TestListRecords(t *testing.T) {
+	badgerauthtest.RunCluster(t, badgerauthtest.ClusterConfig{
+		NodeCount: 2,
+	}, func(ctx *testcontext.Context, t *testing.T, cluster *badgerauthtest.Cluster) {
+		noAddrClient := client.New(client.Config{}, log.New(io.Discard, "", 0))
+		client := client.New(client.Config{
+			NodeAddresses:      cluster.Addresses(),
+			InsecureDisableTLS: true,
+		}, log.New(io.Discard, "", 0))
+
+		records, keys, _ := badgerauthtest.CreateFullRecords(ctx, t, cluster.Nodes[0], 5)
+		for _, node := range cluster.Nodes {
+			node.SyncCycle.TriggerWait()
+		}
+
+		_, _, err := noAddrClient.List(ctx, authdb.ListFilter{}, authdb.ListCursor{}, 10)
+		require.Error(t, err)
+
+		var (
+			listed []string
+			cursor authdb.ListCursor
+		)
+		for {
+			page, next, err := client.List(ctx, authdb.ListFilter{}, cursor, 2)
+			require.NoError(t, err)
+			for _, r := range page {
+				record := records[keyFromHex(t, r.KeyHash)]
+				require.NotNil(t, record)
+				require.Equal(t, record.SatelliteAddress, r.SatelliteAddress)
+				require.Equal(t, hex.EncodeToString(record.MacaroonHead), r.MacaroonHeadHex)
+				require.Empty(t, r.EncryptedAccessGrant)
+				listed = append(listed, r.KeyHash)
+			}
+			if next.IsZero() {
+				break
+			}
+			cursor = next
+		}
+		require.Len(t, listed, len(keys))
+
+		require.NoError(t, client.Invalidate(ctx, keys[1].ToHex(), "listed"))
+
+		yes := true
+		page, next, err := client.List(ctx, authdb.ListFilter{Invalidated: &yes}, authdb.ListCursor{}, 10)
+		require.NoError(t, err)
+		require.True(t, next.IsZero())
+		require.Len(t, page, 1)
+		require.Equal(t, keys[1].ToHex(), page[0].KeyHash)
+		require.Equal(t, "listed", page[0].InvalidationReason)
+
+		page, _, err = client.List(ctx, authdb.ListFilter{MacaroonHead: records[keys[2]].MacaroonHead}, authdb.ListCursor{}, 10)
+		require.NoError(t, err)
+		require.Len(t, page, 1)
+		require.Equal(t, keys[2].ToHex(), page[0].KeyHash)
+	})
+}
+
+func TestListFrom(t *testing.T) {
+	badgerauthtest.RunSingleNode(t, badgerauth.Config{}, func(ctx *testcontext.Context, t *testing.T, _ *zap.Logger, node *badgerauth.Node) {
+		records, keys, _ := badgerauthtest.CreateFullRecords(ctx, t, node, 3)
+
+		var (
+			listed []string
+			cursor authdb.ListCursor
+		)
+		for {
+			page, next, err := client.ListFrom(ctx, node, authdb.ListFilter{}, cursor, 2)
+			require.NoError(t, err)
+			for _, r := range page {
+				record := records[keyFromHex(t, r.KeyHash)]
+				require.NotNil(t, record)
+				require.Equal(t, record.SatelliteAddress, r.SatelliteAddress)
+				require.Equal(t, hex.EncodeToString(record.MacaroonHead), r.MacaroonHeadHex)
+				require.Equal(t, record.ExpiresAt.Unix(), r.ExpiresAtUnix)
+				require.Empty(t, r.EncryptedAccessGrant)
+				listed = append(listed, r.KeyHash)
+			}
+			if next.IsZero() {
+				break
+			}
+			cursor = next
+		}
+		require.Len(t, listed, len(keys))
+	})
+}
+
+func keyFromHex(t *testing.T, s string) (keyHash authdb.KeyHash) {
+	require.NoError(t, keyHash.FromHex(s))
+	return keyHash
+}
+
 func verifyClusterRecords(
 	ctx *testcontext.Context,
 	t *testing.T,
@@ -185,3 +277,40 @@ func verifyClusterRecords(
 		}.Check(ctx, t, node)
 	}
 }
+
+func TestClusterStatus(t *testing.T) {
+	badgerauthtest.RunCluster(t, badgerauthtest.ClusterConfig{
+		NodeCount: 2,
+		Defaults: badgerauth.Config{
+			ReplicationInterval: time.Hour,
+		},
+	}, func(ctx *testcontext.Context, t *testing.T, cluster *badgerauthtest.Cluster) {
+		client := client.New(client.Config{
+			NodeAddresses:      append(cluster.Addresses(), "127.0.0.1:1"),
+			InsecureDisableTLS: true,
+		}, log.New(io.Discard, "", 0))
+
+		// wait for the initial replication to finish
+		for _, node := range cluster.Nodes {
+			node.SyncCycle.TriggerWait()
+		}
+
+		badgerauthtest.CreateFullRecords(ctx, t, cluster.Nodes[0], 2)
+		cluster.Nodes[0].SyncCycle.TriggerWait()
+
+		status, err := client.ClusterStatus(ctx)
+		require.NoError(t, err)
+		require.Len(t, status.Nodes, 3)
+		require.Equal(t, cluster.Nodes[0].ID().String(), status.Nodes[0].NodeID)
+		require.Equal(t, cluster.Nodes[1].ID().String(), status.Nodes[1].NodeID)
+		require.NotEmpty(t, status.Nodes[2].Error)
+		require.Equal(t, []string{cluster.Nodes[0].ID().String()}, status.Divergent)
+		require.EqualValues(t, 2, status.MaxClocks()[cluster.Nodes[0].ID().String()])
+
+		cluster.Nodes[1].SyncCycle.TriggerWait()
+
+		status, err = client.ClusterStatus(ctx)
+		require.NoError(t, err)
+		require.Empty(t, status.Divergent)
+	})
+}
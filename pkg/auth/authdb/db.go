@@ -201,28 +201,29 @@ func (db *Database) Put(ctx context.Context, key EncryptionKey, accessGrant stri
 }
 
 // Get retrieves an access grant and secret key from the key/value store, looked up by the
-// hash of the access key and then decrypted.
-func (db *Database) Get(ctx context.Context, accessKeyID EncryptionKey) (accessGrant string, public bool, secretKey SecretKey, err error) {
+// hash of the access key and then decrypted. expiresAt is when the record
+// expires or nil if it doesn't.
+func (db *Database) Get(ctx context.Context, accessKeyID EncryptionKey) (accessGrant string, public bool, secretKey SecretKey, expiresAt *time.Time, err error) {
 	defer mon.Task()(&ctx)(&err)
 
 	record, err := db.kv.Get(ctx, accessKeyID.Hash())
 	if err != nil {
-		return "", false, secretKey, errs.Wrap(err)
+		return "", false, secretKey, nil, errs.Wrap(err)
 	} else if record == nil {
-		return "", false, secretKey, NotFound.New("key hash: %x", accessKeyID.Hash())
+		return "", false, secretKey, nil, NotFound.New("key hash: %x", accessKeyID.Hash())
 	}
 
 	storjKey := accessKeyID.ToStorjKey()
 	// note that we currently always use the same nonce here - all zero's for secret keys
 	sk, err := encryption.Decrypt(record.EncryptedSecretKey, storj.EncAESGCM, &storjKey, &storj.Nonce{})
 	if err != nil {
-		return "", false, secretKey, errs.Wrap(err)
+		return "", false, secretKey, nil, errs.Wrap(err)
 	}
 	copy(secretKey[:], sk)
 	// note that we currently always use the same nonce here - one then all zero's for access grants
 	ag, err := encryption.Decrypt(record.EncryptedAccessGrant, storj.EncAESGCM, &storjKey, &storj.Nonce{1})
 	if err != nil {
-		return "", false, secretKey, errs.Wrap(err)
+		return "", false, secretKey, nil, errs.Wrap(err)
 	}
 
 	// log satelliteAddress so we can cross reference if we're actively using the distributed db "globally"
@@ -230,7 +231,7 @@ func (db *Database) Get(ctx context.Context, accessKeyID EncryptionKey) (accessG
 		mon.Event("as_region_use_get", monkit.NewSeriesTag("satellite", grant.SatelliteAddress))
 	}
 
-	return string(ag), record.Public, secretKey, nil
+	return string(ag), record.Public, secretKey, record.ExpiresAt, nil
 }
 
 // DeleteUnused deletes expired and invalid records from the key/value store and
@@ -0,0 +1,146 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package authdb
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/common/grant"
+	"storj.io/common/storj"
+	"storj.io/gateway-mt/pkg/auth/satellitelist"
+)
+
+// Endpoints decides which gateway endpoint is returned to clients registering
+// access grants. Grants of satellites with their own endpoint (e.g., served by
+// a gateway in the same region) get it, and all others get the default.
+// Configured endpoints take precedence over endpoints from the allowed
+// satellite list.
+type Endpoints struct {
+	def         *url.URL
+	bySatellite map[storj.NodeID]*url.URL
+
+	mu     sync.Mutex
+	listed map[storj.NodeID]*url.URL
+}
+
+// NewEndpoints returns Endpoints returning def for satellites missing in
+// bySatellite.
+func NewEndpoints(def *url.URL, bySatellite map[storj.NodeID]*url.URL) *Endpoints {
+	return &Endpoints{def: def, bySatellite: bySatellite}
+}
+
+// ParseEndpoints parses the default endpoint and satellite endpoints given as
+// satellite=endpoint, where satellite is a satellite NodeURL (or a known
+// satellite address).
+func ParseEndpoints(def string, satelliteEndpoints []string) (*Endpoints, error) {
+	defURL, err := parseEndpoint(def)
+	if err != nil {
+		return nil, err
+	}
+
+	bySatellite := make(map[storj.NodeID]*url.URL, len(satelliteEndpoints))
+	for _, s := range satelliteEndpoints {
+		satellite, endpoint, ok := strings.Cut(strings.TrimSpace(s), "=")
+		if !ok {
+			return nil, errs.New("satellite endpoint %q isn't satellite=endpoint", s)
+		}
+
+		nodeURL, err := satellitelist.ParseSatelliteURL(satellite)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := bySatellite[nodeURL.ID]; ok {
+			return nil, errs.New("duplicate endpoint for satellite %q", satellite)
+		}
+
+		if bySatellite[nodeURL.ID], err = parseEndpoint(endpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewEndpoints(defURL, bySatellite), nil
+}
+
+func parseEndpoint(s string) (*url.URL, error) {
+	endpoint, err := url.Parse(s)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+		return nil, errs.New("unexpected scheme found in endpoint %q", s)
+	}
+	return endpoint, nil
+}
+
+// SetListed sets endpoints from the allowed satellite list. Satellites without
+// an endpoint or with an invalid one are skipped.
+func (e *Endpoints) SetListed(satellites map[storj.NodeURL]satellitelist.Satellite) {
+	listed := make(map[storj.NodeID]*url.URL)
+	for url, satellite := range satellites {
+		if satellite.Endpoint == "" {
+			continue
+		}
+		if endpoint, err := parseEndpoint(satellite.Endpoint); err == nil {
+			listed[url.ID] = endpoint
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.listed = listed
+}
+
+// Default returns the default endpoint.
+func (e *Endpoints) Default() *url.URL { return e.def }
+
+// ForSatellite returns the endpoint for the satellite with the address.
+func (e *Endpoints) ForSatellite(satelliteAddress string) *url.URL {
+	if !e.hasSatelliteEndpoints() {
+		return e.def
+	}
+
+	nodeURL, err := satellitelist.ParseSatelliteURL(satelliteAddress)
+	if err != nil {
+		return e.def
+	}
+
+	if endpoint, ok := e.bySatellite[nodeURL.ID]; ok {
+		return endpoint
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if endpoint, ok := e.listed[nodeURL.ID]; ok {
+		return endpoint
+	}
+	return e.def
+}
+
+// hasSatelliteEndpoints returns whether any satellite has its own endpoint.
+func (e *Endpoints) hasSatelliteEndpoints() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return len(e.bySatellite) > 0 || len(e.listed) > 0
+}
+
+// ForAccessGrant returns the endpoint for the satellite of the access grant.
+func (e *Endpoints) ForAccessGrant(accessGrant string) *url.URL {
+	if !e.hasSatelliteEndpoints() {
+		return e.def
+	}
+
+	access, err := grant.ParseAccess(accessGrant)
+	if err != nil {
+		return e.def
+	}
+
+	return e.ForSatellite(access.SatelliteAddress)
+}
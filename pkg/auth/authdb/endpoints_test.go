@@ -0,0 +1,71 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package authdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/storj"
+	"storj.io/gateway-mt/pkg/auth/satellitelist"
+)
+
+func TestParseEndpoints(t *testing.T) {
+	const (
+		us1 = "12EayRS2V1kEsWESU9QMRseFhdxYxKicsiFmxrsLZHeLUtdps3S@us1.storj.io:7777"
+		ap1 = "121RTSDpyNZVcEU84Ticf2L1ntiuUimbWgfATz21tuvgk3vzoA6@ap1.storj.io:7777"
+		eu1 = "12L9ZFwhzVpuEKMUNUqkaTLGzwY9G24tbiigLiXpmZWKwmcNDDs@eu1.storj.io:7777"
+	)
+
+	endpoints, err := ParseEndpoints("https://gateway.us1.test", []string{
+		"asia-east-1.tardigrade.io:7777=https://gateway.ap1.test",
+		" " + eu1 + "=http://gateway.eu1.test/ ",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "https://gateway.us1.test", endpoints.Default().String())
+	assert.Equal(t, "https://gateway.us1.test", endpoints.ForSatellite(us1).String())
+	assert.Equal(t, "https://gateway.ap1.test", endpoints.ForSatellite(ap1).String())
+	assert.Equal(t, "http://gateway.eu1.test/", endpoints.ForSatellite(eu1).String())
+	assert.Equal(t, "https://gateway.us1.test", endpoints.ForSatellite("unknown").String(), "unparsable satellites get the default")
+	assert.Equal(t, "https://gateway.us1.test", endpoints.ForAccessGrant("invalid").String())
+
+	for _, tc := range []struct {
+		def                string
+		satelliteEndpoints []string
+	}{
+		{"", nil},
+		{"gateway.test", nil},
+		{"https://gateway.test", []string{"https://gateway.ap1.test"}},
+		{"https://gateway.test", []string{"unknown.test:7777=https://gateway.ap1.test"}},
+		{"https://gateway.test", []string{ap1 + "=ftp://gateway.ap1.test"}},
+		{"https://gateway.test", []string{ap1 + "=https://a.test", "asia-east-1.tardigrade.io:7777=https://b.test"}},
+	} {
+		_, err := ParseEndpoints(tc.def, tc.satelliteEndpoints)
+		assert.Error(t, err, "%q %q", tc.def, tc.satelliteEndpoints)
+	}
+}
+
+func TestEndpoints_SetListed(t *testing.T) {
+	ap1, err := satellitelist.ParseSatelliteURL("asia-east-1.tardigrade.io:7777")
+	require.NoError(t, err)
+	eu1, err := satellitelist.ParseSatelliteURL("europe-west-1.tardigrade.io:7777")
+	require.NoError(t, err)
+
+	endpoints, err := ParseEndpoints("https://gateway.test", []string{ap1.String() + "=https://configured.test"})
+	require.NoError(t, err)
+
+	endpoints.SetListed(map[storj.NodeURL]satellitelist.Satellite{
+		ap1: {URL: ap1, Endpoint: "https://listed.ap1.test"},
+		eu1: {URL: eu1, Endpoint: "https://listed.eu1.test"},
+	})
+
+	assert.Equal(t, "https://configured.test", endpoints.ForSatellite(ap1.String()).String(), "configured endpoints take precedence")
+	assert.Equal(t, "https://listed.eu1.test", endpoints.ForSatellite(eu1.String()).String())
+
+	endpoints.SetListed(nil)
+	assert.Equal(t, "https://gateway.test", endpoints.ForSatellite(eu1.String()).String())
+}
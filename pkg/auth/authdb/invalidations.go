@@ -0,0 +1,130 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package authdb
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/spacemonkeygo/monkit/v3"
+)
+
+// InvalidationReason is why a record became unusable.
+type InvalidationReason string
+
+const (
+	// InvalidationInvalidated is published when a record is invalidated.
+	InvalidationInvalidated InvalidationReason = "invalidated"
+	// InvalidationUnpublished is published when a record is unpublished.
+	InvalidationUnpublished InvalidationReason = "unpublished"
+	// InvalidationDeleted is published when a record is deleted.
+	InvalidationDeleted InvalidationReason = "deleted"
+)
+
+// Invalidation is a record that became unusable, so caches should evict it.
+type Invalidation struct {
+	Seq     uint64
+	KeyHash KeyHash
+	Reason  InvalidationReason
+}
+
+// InvalidationFeed keeps the most recent invalidations in memory, so that
+// clients caching records can follow them. Invalidations are numbered with
+// sequence numbers starting at 1. Each feed has a random ID, so clients can
+// tell when they follow a different feed (e.g., after a restart). Clients
+// following the feeds of several auth services (e.g., behind a load balancer)
+// keep their position in each feed by its ID.
+//
+// Only key/value stores that see every change in-process publish to a feed;
+// sqlauth records can also be changed by other processes, so it has none.
+//
+// InvalidationFeed is safe for concurrent use.
+type InvalidationFeed struct {
+	id       string
+	capacity int
+
+	mu     sync.Mutex
+	head   uint64
+	events []Invalidation
+	// notify is closed and replaced whenever an invalidation is published.
+	notify chan struct{}
+}
+
+// NewInvalidationFeed returns a new InvalidationFeed keeping capacity most
+// recent invalidations.
+func NewInvalidationFeed(capacity int) *InvalidationFeed {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+
+	return &InvalidationFeed{
+		id:       hex.EncodeToString(id[:]),
+		capacity: capacity,
+		notify:   make(chan struct{}),
+	}
+}
+
+// ID returns the ID of the feed.
+func (f *InvalidationFeed) ID() string { return f.id }
+
+// Publish appends an invalidation of keyHash to the feed.
+func (f *InvalidationFeed) Publish(keyHash KeyHash, reason InvalidationReason) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.head++
+	event := Invalidation{Seq: f.head, KeyHash: keyHash, Reason: reason}
+	if len(f.events) < f.capacity {
+		f.events = append(f.events, event)
+	} else {
+		copy(f.events, f.events[1:])
+		f.events[len(f.events)-1] = event
+	}
+
+	close(f.notify)
+	f.notify = make(chan struct{})
+
+	mon.Event("as_invalidation_published", monkit.NewSeriesTag("reason", string(reason)))
+}
+
+// Wait returns invalidations published after since, waiting up to wait for
+// one if there are none yet. next is the sequence number to continue from.
+//
+// reset is true if feedID isn't this feed's ID or invalidations after since
+// were dropped already. The caller then can't know what it missed and
+// should evict everything; it should continue from next.
+func (f *InvalidationFeed) Wait(ctx context.Context, feedID string, since uint64, wait time.Duration) (_ []Invalidation, next uint64, reset bool) {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		f.mu.Lock()
+		head, notify := f.head, f.notify
+		oldest := head - uint64(len(f.events)) // sequence number before the first retained one
+		if feedID != f.id || since > head || since < oldest {
+			f.mu.Unlock()
+			return nil, head, true
+		}
+		if since < head {
+			events := append([]Invalidation(nil), f.events[len(f.events)-int(head-since):]...)
+			f.mu.Unlock()
+			return events, head, false
+		}
+		f.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			return nil, head, false
+		case <-ctx.Done():
+			return nil, head, false
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package authdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvalidationFeed(t *testing.T) {
+	ctx := context.Background()
+
+	feed := NewInvalidationFeed(2)
+
+	// following a different feed resets.
+	events, next, reset := feed.Wait(ctx, "", 0, 0)
+	assert.Empty(t, events)
+	assert.Zero(t, next)
+	assert.True(t, reset)
+
+	events, next, reset = feed.Wait(ctx, feed.ID(), 0, 0)
+	assert.Empty(t, events)
+	assert.Zero(t, next)
+	assert.False(t, reset)
+
+	feed.Publish(KeyHash{1}, InvalidationInvalidated)
+	feed.Publish(KeyHash{2}, InvalidationDeleted)
+
+	events, next, reset = feed.Wait(ctx, feed.ID(), 0, 0)
+	assert.Equal(t, []Invalidation{
+		{Seq: 1, KeyHash: KeyHash{1}, Reason: InvalidationInvalidated},
+		{Seq: 2, KeyHash: KeyHash{2}, Reason: InvalidationDeleted},
+	}, events)
+	assert.EqualValues(t, 2, next)
+	assert.False(t, reset)
+
+	events, _, _ = feed.Wait(ctx, feed.ID(), 1, 0)
+	assert.Equal(t, []Invalidation{{Seq: 2, KeyHash: KeyHash{2}, Reason: InvalidationDeleted}}, events)
+
+	// the first invalidation is dropped, so following from before it resets.
+	feed.Publish(KeyHash{3}, InvalidationUnpublished)
+
+	events, next, reset = feed.Wait(ctx, feed.ID(), 0, 0)
+	assert.Empty(t, events)
+	assert.EqualValues(t, 3, next)
+	assert.True(t, reset)
+
+	events, _, reset = feed.Wait(ctx, feed.ID(), 1, 0)
+	assert.Len(t, events, 2)
+	assert.False(t, reset)
+
+	// following from the future (e.g., a restarted feed) resets.
+	_, next, reset = feed.Wait(ctx, feed.ID(), 4, 0)
+	assert.EqualValues(t, 3, next)
+	assert.True(t, reset)
+}
+
+func TestInvalidationFeed_Wait(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	feed := NewInvalidationFeed(10)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		feed.Publish(KeyHash{1}, InvalidationInvalidated)
+	}()
+
+	events, next, reset := feed.Wait(ctx, feed.ID(), 0, time.Minute)
+	require.Len(t, events, 1)
+	assert.Equal(t, KeyHash{1}, events[0].KeyHash)
+	assert.EqualValues(t, 1, next)
+	assert.False(t, reset)
+
+	start := time.Now()
+	events, next, reset = feed.Wait(ctx, feed.ID(), 1, 10*time.Millisecond)
+	assert.Empty(t, events)
+	assert.EqualValues(t, 1, next)
+	assert.False(t, reset)
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+}
@@ -4,8 +4,10 @@
 package authdb
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
+	"strings"
 	"time"
 
 	"github.com/zeebo/errs"
@@ -14,6 +16,24 @@ import (
 // Invalid is the class of error that is returned for invalid records.
 var Invalid = errs.Class("invalid")
 
+// InvalidReason returns the reason a record is invalid if err is (possibly
+// wrapped) of the Invalid class.
+func InvalidReason(err error) (reason string, ok bool) {
+	ok = errs.IsFunc(err, func(err error) bool {
+		causer, isCauser := err.(errs.Causer)
+		if !isCauser || !Invalid.Has(err) || Invalid.Has(causer.Cause()) {
+			return false
+		}
+		reason = causer.Cause().Error()
+		return true
+	})
+	return reason, ok
+}
+
+// ErrKeyAlreadyExists is returned (possibly wrapped) by KV.Put when the key
+// already exists.
+var ErrKeyAlreadyExists = errs.New("key already exists")
+
 // KeyHashError is a class of key hash errors.
 var KeyHashError = errs.Class("key hash")
 
@@ -69,11 +89,11 @@ func (kh KeyHash) Bytes() []byte { return kh[:] }
 // KV is an abstract key/value store of KeyHash to Records.
 type KV interface {
 	// Put stores the record in the key/value store.
-	// It is an error if the key already exists.
+	// It is an error (ErrKeyAlreadyExists) if the key already exists.
 	Put(ctx context.Context, keyHash KeyHash, record *Record) (err error)
 
 	// Get retrieves the record from the key/value store.
-	// It returns nil if the key does not exist.
+	// It returns nil if the key does not exist or the record has expired.
 	// If the record is invalid, the error contains why.
 	Get(ctx context.Context, keyHash KeyHash) (record *Record, err error)
 
@@ -94,3 +114,105 @@ type KV interface {
 	// Close closes the database.
 	Close() error
 }
+
+// Ranger is implemented by key/value stores that can list their records.
+type Ranger interface {
+	// Range calls fn for each record in the key/value store, skipping
+	// invalid ones. It stops and returns the error if fn returns one.
+	Range(ctx context.Context, fn func(ctx context.Context, keyHash KeyHash, record *Record) error) error
+}
+
+// ListFilter selects records listed by Lister. Zero fields match any record.
+type ListFilter struct {
+	MacaroonHead     []byte
+	SatelliteAddress string
+	// CreatedAfter is inclusive, and CreatedBefore is exclusive.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// Public and Invalidated, if set, match records that are (or aren't)
+	// public and invalidated.
+	Public      *bool
+	Invalidated *bool
+}
+
+// ListCursor is the position of a record in the order Lister lists records.
+type ListCursor struct {
+	CreatedAt time.Time
+	KeyHash   KeyHash
+}
+
+// IsZero returns whether the cursor is unset.
+func (c ListCursor) IsZero() bool {
+	return c.CreatedAt.IsZero() && c.KeyHash == KeyHash{}
+}
+
+// Less returns whether c is before other.
+func (c ListCursor) Less(other ListCursor) bool {
+	if !c.CreatedAt.Equal(other.CreatedAt) {
+		return c.CreatedAt.Before(other.CreatedAt)
+	}
+	return bytes.Compare(c.KeyHash[:], other.KeyHash[:]) < 0
+}
+
+// String encodes the cursor so it can be passed back to continue listing.
+func (c ListCursor) String() string {
+	return c.CreatedAt.UTC().Format(time.RFC3339Nano) + "/" + c.KeyHash.ToHex()
+}
+
+// ParseListCursor parses a cursor encoded with ListCursor.String.
+func ParseListCursor(s string) (c ListCursor, err error) {
+	createdAt, keyHash, ok := strings.Cut(s, "/")
+	if !ok {
+		return c, errs.New("malformed cursor %q", s)
+	}
+	if c.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return c, errs.New("malformed cursor %q: %w", s, err)
+	}
+	if err = c.KeyHash.FromHex(keyHash); err != nil {
+		return c, errs.New("malformed cursor %q: %w", s, err)
+	}
+	return c, nil
+}
+
+// ListedRecord is a record listed by Lister. It doesn't include encrypted
+// secrets.
+type ListedRecord struct {
+	KeyHash            KeyHash
+	CreatedAt          time.Time
+	SatelliteAddress   string
+	MacaroonHead       []byte
+	ExpiresAt          *time.Time
+	Public             bool
+	InvalidationReason string
+	InvalidatedAt      *time.Time
+}
+
+// Cursor returns the cursor to continue listing after the record.
+func (r ListedRecord) Cursor() ListCursor {
+	return ListCursor{CreatedAt: r.CreatedAt, KeyHash: r.KeyHash}
+}
+
+// Matches returns whether the record matches filter.
+func (r ListedRecord) Matches(filter ListFilter) bool {
+	switch {
+	case len(filter.MacaroonHead) > 0 && !bytes.Equal(r.MacaroonHead, filter.MacaroonHead),
+		filter.SatelliteAddress != "" && r.SatelliteAddress != filter.SatelliteAddress,
+		!filter.CreatedAfter.IsZero() && r.CreatedAt.Before(filter.CreatedAfter),
+		!filter.CreatedBefore.IsZero() && !r.CreatedAt.Before(filter.CreatedBefore),
+		filter.Public != nil && r.Public != *filter.Public,
+		filter.Invalidated != nil && (r.InvalidationReason != "") != *filter.Invalidated:
+		return false
+	}
+	return true
+}
+
+// Lister is implemented by key/value stores that can search their records.
+type Lister interface {
+	// List returns up to limit records matching filter, ordered by creation
+	// time and key hash, after cursor (from the start if it's zero). Expired
+	// records aren't listed. next is the cursor to continue listing from if
+	// more records might match, and zero otherwise. Stores that bound how
+	// many records a call scans might return fewer than limit records (even
+	// none) with next after the last one returned.
+	List(ctx context.Context, filter ListFilter, cursor ListCursor, limit int) (records []ListedRecord, next ListCursor, err error)
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/zeebo/errs"
 
 	"storj.io/common/testrand"
 )
@@ -28,3 +29,18 @@ func TestKeyHash(t *testing.T) {
 	require.NoError(t, kh2.FromHex(encoded))
 	require.Equal(t, kh, kh2)
 }
+
+func TestInvalidReason(t *testing.T) {
+	_, ok := InvalidReason(errs.New("other"))
+	require.False(t, ok)
+
+	backendError := errs.Class("backend")
+	for _, err := range []error{
+		Invalid.New("%s", "some: reason"),
+		backendError.Wrap(Invalid.New("%s", "some: reason")),
+	} {
+		reason, ok := InvalidReason(err)
+		require.True(t, ok)
+		require.Equal(t, "some: reason", reason)
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package kvtest is a conformance test suite for authdb.KV implementations.
+package kvtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeebo/errs"
+	"golang.org/x/sync/errgroup"
+
+	"storj.io/common/errs2"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+)
+
+// OpenFunc creates a new, empty KV for a single test. The test runs and closes
+// it.
+type OpenFunc func(ctx *testcontext.Context, t *testing.T) authdb.KV
+
+// Options configures which optional behaviours are tested.
+type Options struct {
+	// Invalidate invalidates the record with the key hash in kv. Tests of
+	// invalid records are skipped if it's nil.
+	Invalidate func(ctx context.Context, kv authdb.KV, keyHash authdb.KeyHash, reason string) error
+
+	// DeleteUnusedUnsupported is whether DeleteUnused always fails, e.g.,
+	// because expired records are deleted automatically.
+	DeleteUnusedUnsupported bool
+}
+
+// Run runs the conformance tests against KVs created by open.
+//
+// Every test gets a new KV, runs it (KV.Run) for the duration of the test and
+// checks that it stops and closes cleanly afterwards.
+func Run(t *testing.T, open OpenFunc, opts Options) {
+	tests := []struct {
+		name string
+		fn   func(ctx *testcontext.Context, t *testing.T, kv authdb.KV, opts Options)
+	}{
+		{"PingAndClose", testPingAndClose},
+		{"PutGet", testPutGet},
+		{"GetMissing", testGetMissing},
+		{"DuplicatePut", testDuplicatePut},
+		{"Invalid", testInvalid},
+		{"Expiry", testExpiry},
+		{"DeleteUnused", testDeleteUnused},
+		{"Concurrent", testConcurrent},
+		{"List", testList},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := testcontext.New(t)
+			defer ctx.Cleanup()
+
+			kv := open(ctx, t)
+
+			runCtx, cancel := context.WithCancel(ctx)
+			var g errgroup.Group
+			g.Go(func() error {
+				return errs2.IgnoreCanceled(kv.Run(runCtx))
+			})
+			defer ctx.Check(kv.Close)
+			defer ctx.Check(func() error {
+				cancel()
+				return g.Wait()
+			})
+
+			test.fn(ctx, t, kv, opts)
+		})
+	}
+}
+
+// NewRecord returns a random record with the given expiration time.
+func NewRecord(expiresAt *time.Time) *authdb.Record {
+	return &authdb.Record{
+		SatelliteAddress:     testrand.NodeID().String() + "@127.0.0.1:7777",
+		MacaroonHead:         testrand.Bytes(32),
+		EncryptedSecretKey:   testrand.Bytes(32),
+		EncryptedAccessGrant: testrand.Bytes(256),
+		ExpiresAt:            expiresAt,
+		Public:               testrand.Intn(2) == 0,
+	}
+}
+
+// RequireRecordEqual requires records to be equal, comparing expiration times
+// with second precision (the precision all backends keep).
+func RequireRecordEqual(t testing.TB, expected, actual *authdb.Record, msgAndArgs ...interface{}) {
+	t.Helper()
+
+	if expected == nil || actual == nil {
+		require.Equal(t, expected, actual, msgAndArgs...)
+		return
+	}
+
+	e, a := *expected, *actual
+	if e.ExpiresAt != nil && a.ExpiresAt != nil {
+		require.Equal(t, e.ExpiresAt.Unix(), a.ExpiresAt.Unix(), msgAndArgs...)
+		e.ExpiresAt, a.ExpiresAt = nil, nil
+	}
+	require.Equal(t, e, a, msgAndArgs...)
+}
+
+// inAnHour returns a time an hour from now with second precision.
+func inAnHour() *time.Time {
+	t := time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+	return &t
+}
+
+// aMinuteAgo returns a time a minute ago with second precision.
+func aMinuteAgo() *time.Time {
+	t := time.Unix(time.Now().Add(-time.Minute).Unix(), 0)
+	return &t
+}
+
+func testPingAndClose(ctx *testcontext.Context, t *testing.T, kv authdb.KV, _ Options) {
+	require.NoError(t, kv.PingDB(ctx))
+}
+
+func testPutGet(ctx *testcontext.Context, t *testing.T, kv authdb.KV, _ Options) {
+	records := make(map[authdb.KeyHash]*authdb.Record)
+	for i := 0; i < 10; i++ {
+		var expiresAt *time.Time
+		if i%2 == 0 {
+			expiresAt = inAnHour()
+		}
+		records[authdb.KeyHash{byte(i)}] = NewRecord(expiresAt)
+	}
+
+	for keyHash, record := range records {
+		require.NoError(t, kv.Put(ctx, keyHash, record))
+	}
+
+	for keyHash, record := range records {
+		actual, err := kv.Get(ctx, keyHash)
+		require.NoError(t, err)
+		RequireRecordEqual(t, record, actual)
+	}
+}
+
+func testGetMissing(ctx *testcontext.Context, t *testing.T, kv authdb.KV, _ Options) {
+	actual, err := kv.Get(ctx, authdb.KeyHash{'m'})
+	require.NoError(t, err)
+	require.Nil(t, actual)
+}
+
+func testDuplicatePut(ctx *testcontext.Context, t *testing.T, kv authdb.KV, _ Options) {
+	keyHash := authdb.KeyHash{'d'}
+	record := NewRecord(nil)
+
+	require.NoError(t, kv.Put(ctx, keyHash, record))
+
+	err := kv.Put(ctx, keyHash, NewRecord(nil))
+	require.Error(t, err)
+	require.True(t, errs.Is(err, authdb.ErrKeyAlreadyExists), "%+v", err)
+
+	actual, err := kv.Get(ctx, keyHash)
+	require.NoError(t, err)
+	RequireRecordEqual(t, record, actual, "the original record must be kept")
+}
+
+func testInvalid(ctx *testcontext.Context, t *testing.T, kv authdb.KV, opts Options) {
+	if opts.Invalidate == nil {
+		t.Skip("invalidation isn't supported")
+	}
+
+	invalid, valid := authdb.KeyHash{'i'}, authdb.KeyHash{'v'}
+	record := NewRecord(nil)
+
+	require.NoError(t, kv.Put(ctx, invalid, NewRecord(nil)))
+	require.NoError(t, kv.Put(ctx, valid, record))
+	require.NoError(t, opts.Invalidate(ctx, kv, invalid, "conformance"))
+
+	actual, err := kv.Get(ctx, invalid)
+	require.Error(t, err)
+	require.True(t, authdb.Invalid.Has(err), "%+v", err)
+	require.Contains(t, err.Error(), "conformance")
+	require.Nil(t, actual)
+
+	actual, err = kv.Get(ctx, valid)
+	require.NoError(t, err)
+	RequireRecordEqual(t, record, actual)
+}
+
+func testExpiry(ctx *testcontext.Context, t *testing.T, kv authdb.KV, _ Options) {
+	expired, unexpired := authdb.KeyHash{'e'}, authdb.KeyHash{'u'}
+	record := NewRecord(inAnHour())
+
+	require.NoError(t, kv.Put(ctx, expired, NewRecord(aMinuteAgo())))
+	require.NoError(t, kv.Put(ctx, unexpired, record))
+
+	actual, err := kv.Get(ctx, expired)
+	require.NoError(t, err)
+	require.Nil(t, actual, "expired records must not be returned")
+
+	actual, err = kv.Get(ctx, unexpired)
+	require.NoError(t, err)
+	RequireRecordEqual(t, record, actual)
+}
+
+func testDeleteUnused(ctx *testcontext.Context, t *testing.T, kv authdb.KV, opts Options) {
+	valid := make(map[authdb.KeyHash]*authdb.Record)
+	var unused int64
+
+	for i := 0; i < 3; i++ {
+		keyHash := authdb.KeyHash{'v', byte(i)}
+		valid[keyHash] = NewRecord(inAnHour())
+		require.NoError(t, kv.Put(ctx, keyHash, valid[keyHash]))
+
+		require.NoError(t, kv.Put(ctx, authdb.KeyHash{'e', byte(i)}, NewRecord(aMinuteAgo())))
+		unused++
+
+		if opts.Invalidate != nil {
+			keyHash := authdb.KeyHash{'i', byte(i)}
+			require.NoError(t, kv.Put(ctx, keyHash, NewRecord(nil)))
+			require.NoError(t, opts.Invalidate(ctx, kv, keyHash, "unused"))
+			unused++
+		}
+	}
+
+	// Some backends compare invalidation times with the current time.
+	time.Sleep(10 * time.Millisecond)
+
+	count, _, deletesPerHead, err := kv.DeleteUnused(ctx, time.Microsecond, 2, 1)
+	if opts.DeleteUnusedUnsupported {
+		require.Error(t, err)
+	} else {
+		require.NoError(t, err)
+		require.Equal(t, unused, count)
+
+		var perHead int64
+		for _, deletes := range deletesPerHead {
+			perHead += deletes
+		}
+		require.Equal(t, count, perHead)
+
+		count, _, _, err = kv.DeleteUnused(ctx, time.Microsecond, 2, 1)
+		require.NoError(t, err)
+		require.Zero(t, count, "DeleteUnused must be idempotent")
+	}
+
+	for keyHash, record := range valid {
+		actual, err := kv.Get(ctx, keyHash)
+		require.NoError(t, err)
+		RequireRecordEqual(t, record, actual, "valid records must be kept")
+	}
+}
+
+func testConcurrent(ctx *testcontext.Context, t *testing.T, kv authdb.KV, _ Options) {
+	const workers, perWorker = 8, 10
+
+	var g errgroup.Group
+	for w := 0; w < workers; w++ {
+		w := w
+		g.Go(func() error {
+			for i := 0; i < perWorker; i++ {
+				keyHash := authdb.KeyHash{'c', byte(w), byte(i)}
+				record := NewRecord(nil)
+
+				if err := kv.Put(ctx, keyHash, record); err != nil {
+					return err
+				}
+
+				actual, err := kv.Get(ctx, keyHash)
+				if err != nil {
+					return err
+				}
+				if actual == nil || actual.SatelliteAddress != record.SatelliteAddress {
+					return fmt.Errorf("got %v for %x, want %v", actual, keyHash, record)
+				}
+
+				// Reads of missing keys are mixed in.
+				if _, err = kv.Get(ctx, authdb.KeyHash{'m', byte(w), byte(i)}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	require.NoError(t, g.Wait())
+
+	for w := 0; w < workers; w++ {
+		for i := 0; i < perWorker; i++ {
+			actual, err := kv.Get(ctx, authdb.KeyHash{'c', byte(w), byte(i)})
+			require.NoError(t, err)
+			assert.NotNil(t, actual)
+		}
+	}
+}
+
+func testList(ctx *testcontext.Context, t *testing.T, kv authdb.KV, opts Options) {
+	lister, ok := kv.(authdb.Lister)
+	if !ok {
+		t.Skip("listing isn't supported")
+	}
+
+	head := testrand.Bytes(32)
+	records := make(map[authdb.KeyHash]*authdb.Record)
+	for i := 0; i < 5; i++ {
+		record := NewRecord(inAnHour())
+		record.Public = i%2 == 0
+		if i < 3 {
+			record.MacaroonHead = head
+		}
+		records[authdb.KeyHash{'l', byte(i)}] = record
+	}
+	for keyHash, record := range records {
+		require.NoError(t, kv.Put(ctx, keyHash, record))
+	}
+	require.NoError(t, kv.Put(ctx, authdb.KeyHash{'e'}, NewRecord(aMinuteAgo())))
+
+	list := func(filter authdb.ListFilter, limit int) (keyHashes []authdb.KeyHash) {
+		var cursor authdb.ListCursor
+		for {
+			listed, next, err := lister.List(ctx, filter, cursor, limit)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(listed), limit)
+
+			for _, r := range listed {
+				require.True(t, cursor.Less(r.Cursor()), "records must be ordered")
+				cursor = r.Cursor()
+
+				record, ok := records[r.KeyHash]
+				require.True(t, ok, "unexpected record %x", r.KeyHash)
+				require.Equal(t, record.SatelliteAddress, r.SatelliteAddress)
+				require.Equal(t, record.MacaroonHead, r.MacaroonHead)
+				require.Equal(t, record.Public, r.Public)
+				require.NotNil(t, r.ExpiresAt)
+				require.Equal(t, record.ExpiresAt.Unix(), r.ExpiresAt.Unix())
+				keyHashes = append(keyHashes, r.KeyHash)
+			}
+			if next.IsZero() {
+				return keyHashes
+			}
+			require.False(t, next.Less(cursor), "next cursor must not go back")
+			cursor = next
+		}
+	}
+	keys := func(indexes ...byte) (keyHashes []authdb.KeyHash) {
+		for _, i := range indexes {
+			keyHashes = append(keyHashes, authdb.KeyHash{'l', i})
+		}
+		return keyHashes
+	}
+	yes, no := true, false
+
+	require.ElementsMatch(t, keys(0, 1, 2, 3, 4), list(authdb.ListFilter{}, 2), "expired records must not be listed")
+	require.ElementsMatch(t, keys(0, 1, 2), list(authdb.ListFilter{MacaroonHead: head}, 1))
+	require.ElementsMatch(t, keys(0, 2), list(authdb.ListFilter{MacaroonHead: head, Public: &yes}, 10))
+	require.ElementsMatch(t, keys(1, 3), list(authdb.ListFilter{Public: &no}, 10))
+	require.ElementsMatch(t, keys(3), list(authdb.ListFilter{SatelliteAddress: records[authdb.KeyHash{'l', 3}].SatelliteAddress}, 10))
+	require.ElementsMatch(t, keys(0, 1, 2, 3, 4), list(authdb.ListFilter{CreatedAfter: time.Now().Add(-time.Hour), CreatedBefore: time.Now().Add(time.Hour)}, 10))
+	require.Empty(t, list(authdb.ListFilter{CreatedBefore: time.Now().Add(-time.Hour)}, 10))
+
+	if opts.Invalidate != nil {
+		require.NoError(t, opts.Invalidate(ctx, kv, authdb.KeyHash{'l', 1}, "listed"))
+		require.ElementsMatch(t, keys(1), list(authdb.ListFilter{Invalidated: &yes}, 10))
+		require.ElementsMatch(t, keys(0, 2), list(authdb.ListFilter{MacaroonHead: head, Invalidated: &no}, 10))
+	}
+}
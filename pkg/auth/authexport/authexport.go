@@ -0,0 +1,212 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package authexport exports and imports auth records as JSON Lines. It allows
+// moving records between any key/value store backends.
+package authexport
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/spacemonkeygo/monkit/v3"
+	"github.com/zeebo/errs"
+
+	"storj.io/gateway-mt/pkg/auth/authdb"
+)
+
+var mon = monkit.Package()
+
+// Error is the default error class for the authexport package.
+var Error = errs.Class("authexport")
+
+// ConflictError is returned when an imported record conflicts with an existing
+// one.
+var ConflictError = errs.Class("conflict")
+
+// Conflict decides what Import does when a record already exists.
+type Conflict string
+
+const (
+	// ConflictSkip keeps the existing record.
+	ConflictSkip Conflict = "skip"
+	// ConflictOverwriteIfEqual accepts the imported record if it's equal to
+	// the existing one and fails otherwise.
+	ConflictOverwriteIfEqual Conflict = "overwrite-if-equal"
+	// ConflictFail fails the import.
+	ConflictFail Conflict = "fail"
+)
+
+// ParseConflict parses s as Conflict.
+func ParseConflict(s string) (Conflict, error) {
+	switch c := Conflict(s); c {
+	case ConflictSkip, ConflictOverwriteIfEqual, ConflictFail:
+		return c, nil
+	default:
+		return "", Error.New("unknown conflict handling %q (want %q, %q or %q)", s, ConflictSkip, ConflictOverwriteIfEqual, ConflictFail)
+	}
+}
+
+// Entry is a single line of the export.
+type Entry struct {
+	KeyHash              string     `json:"key_hash"`
+	SatelliteAddress     string     `json:"satellite_address"`
+	MacaroonHead         []byte     `json:"macaroon_head"`
+	EncryptedSecretKey   []byte     `json:"encrypted_secret_key"`
+	EncryptedAccessGrant []byte     `json:"encrypted_access_grant"`
+	ExpiresAt            *time.Time `json:"expires_at,omitempty"`
+	Public               bool       `json:"public"`
+}
+
+// NewEntry returns Entry for the record.
+func NewEntry(keyHash authdb.KeyHash, record *authdb.Record) Entry {
+	return Entry{
+		KeyHash:              keyHash.ToHex(),
+		SatelliteAddress:     record.SatelliteAddress,
+		MacaroonHead:         record.MacaroonHead,
+		EncryptedSecretKey:   record.EncryptedSecretKey,
+		EncryptedAccessGrant: record.EncryptedAccessGrant,
+		ExpiresAt:            record.ExpiresAt,
+		Public:               record.Public,
+	}
+}
+
+// Record returns the entry's key hash and record.
+func (e Entry) Record() (keyHash authdb.KeyHash, record *authdb.Record, err error) {
+	if err = keyHash.FromHex(e.KeyHash); err != nil {
+		return keyHash, nil, err
+	}
+	return keyHash, &authdb.Record{
+		SatelliteAddress:     e.SatelliteAddress,
+		MacaroonHead:         e.MacaroonHead,
+		EncryptedSecretKey:   e.EncryptedSecretKey,
+		EncryptedAccessGrant: e.EncryptedAccessGrant,
+		ExpiresAt:            e.ExpiresAt,
+		Public:               e.Public,
+	}, nil
+}
+
+// Export writes unexpired records from src to w, one JSON-encoded Entry per
+// line, and returns how many records it has written.
+func Export(ctx context.Context, src authdb.Ranger, w io.Writer) (count int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	now := time.Now()
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	if err = src.Range(ctx, func(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record) error {
+		if record.ExpiresAt != nil && record.ExpiresAt.Before(now) {
+			return nil
+		}
+		if err := enc.Encode(NewEntry(keyHash, record)); err != nil {
+			return err
+		}
+		count++
+		return nil
+	}); err != nil {
+		return count, Error.Wrap(err)
+	}
+
+	return count, Error.Wrap(bw.Flush())
+}
+
+// Store is a key/value store that records can be imported to.
+type Store interface {
+	Put(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record) error
+	Get(ctx context.Context, keyHash authdb.KeyHash) (*authdb.Record, error)
+}
+
+// ImportStats summarizes Import.
+type ImportStats struct {
+	// Imported is the number of records put into the store.
+	Imported int64
+	// Existing is the number of records that already existed and were
+	// skipped or were equal to the imported ones.
+	Existing int64
+	// Expired is the number of records skipped because they've expired.
+	Expired int64
+}
+
+// Import reads entries written by Export from r and puts them into dst. What
+// happens if a record already exists in dst is decided by conflict.
+func Import(ctx context.Context, dst Store, r io.Reader, conflict Conflict) (stats ImportStats, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if _, err = ParseConflict(string(conflict)); err != nil {
+		return stats, err
+	}
+
+	now := time.Now()
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	for line := 1; ; line++ {
+		if err = ctx.Err(); err != nil {
+			return stats, err
+		}
+
+		var entry Entry
+		if err = dec.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return stats, nil
+			}
+			return stats, Error.New("entry %d: %w", line, err)
+		}
+
+		keyHash, record, err := entry.Record()
+		if err != nil {
+			return stats, Error.New("entry %d: %w", line, err)
+		}
+
+		if record.ExpiresAt != nil && record.ExpiresAt.Before(now) {
+			stats.Expired++
+			continue
+		}
+
+		existing, err := dst.Get(ctx, keyHash)
+		if err != nil && !authdb.Invalid.Has(err) {
+			return stats, Error.New("entry %d: %w", line, err)
+		}
+
+		if existing == nil && err == nil {
+			if err = dst.Put(ctx, keyHash, record); err != nil {
+				return stats, Error.New("entry %d: %w", line, err)
+			}
+			stats.Imported++
+			continue
+		}
+
+		switch {
+		case conflict == ConflictSkip:
+			stats.Existing++
+		case conflict == ConflictOverwriteIfEqual && existing != nil && equal(existing, record):
+			stats.Existing++
+		case err != nil:
+			return stats, ConflictError.New("entry %d: %s: existing record is invalid: %w", line, entry.KeyHash, err)
+		default:
+			return stats, ConflictError.New("entry %d: %s: record already exists", line, entry.KeyHash)
+		}
+	}
+}
+
+// equal compares records with ExpiresAt truncated to seconds as not all stores
+// keep it more precise.
+func equal(a, b *authdb.Record) bool {
+	if (a.ExpiresAt == nil) != (b.ExpiresAt == nil) {
+		return false
+	}
+	if a.ExpiresAt != nil && a.ExpiresAt.Unix() != b.ExpiresAt.Unix() {
+		return false
+	}
+	return a.SatelliteAddress == b.SatelliteAddress &&
+		bytes.Equal(a.MacaroonHead, b.MacaroonHead) &&
+		bytes.Equal(a.EncryptedSecretKey, b.EncryptedSecretKey) &&
+		bytes.Equal(a.EncryptedAccessGrant, b.EncryptedAccessGrant) &&
+		a.Public == b.Public
+}
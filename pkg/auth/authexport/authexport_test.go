@@ -0,0 +1,160 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package authexport_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	badger "github.com/outcaste-io/badger/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/authexport"
+	"storj.io/gateway-mt/pkg/auth/badgerauth"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/badgerauthtest"
+	"storj.io/gateway-mt/pkg/auth/memauth"
+)
+
+func randRecord(expiresAt *time.Time) *authdb.Record {
+	return &authdb.Record{
+		SatelliteAddress:     testrand.NodeID().String() + "@127.0.0.1:7777",
+		MacaroonHead:         testrand.Bytes(32),
+		EncryptedSecretKey:   testrand.Bytes(32),
+		EncryptedAccessGrant: testrand.Bytes(256),
+		ExpiresAt:            expiresAt,
+		Public:               testrand.Intn(2) == 0,
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	src := memauth.New()
+
+	future := time.Unix(time.Now().Add(time.Hour).Unix(), 0).UTC()
+	past := time.Now().Add(-time.Hour)
+
+	records := make(map[authdb.KeyHash]*authdb.Record)
+	for i := 0; i < 10; i++ {
+		kh := authdb.KeyHash{byte(i)}
+		r := randRecord(nil)
+		if i%2 == 0 {
+			r.ExpiresAt = &future
+		}
+		records[kh] = r
+		require.NoError(t, src.Put(ctx, kh, r))
+	}
+	require.NoError(t, src.Put(ctx, authdb.KeyHash{'e'}, randRecord(&past)))
+
+	var buf bytes.Buffer
+	count, err := authexport.Export(ctx, src, &buf)
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, count)
+	assert.Equal(t, 10, strings.Count(buf.String(), "\n"))
+
+	dst := memauth.New()
+	stats, err := authexport.Import(ctx, dst, bytes.NewReader(buf.Bytes()), authexport.ConflictFail)
+	require.NoError(t, err)
+	assert.Equal(t, authexport.ImportStats{Imported: 10}, stats)
+
+	for kh, r := range records {
+		got, err := dst.Get(ctx, kh)
+		require.NoError(t, err)
+		assert.Equal(t, r, got)
+	}
+}
+
+func TestImport_Conflict(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	src := memauth.New()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, src.Put(ctx, authdb.KeyHash{byte(i)}, randRecord(nil)))
+	}
+
+	var buf bytes.Buffer
+	_, err := authexport.Export(ctx, src, &buf)
+	require.NoError(t, err)
+
+	// Importing into the source means all records are equal.
+	stats, err := authexport.Import(ctx, src, bytes.NewReader(buf.Bytes()), authexport.ConflictOverwriteIfEqual)
+	require.NoError(t, err)
+	assert.Equal(t, authexport.ImportStats{Existing: 3}, stats)
+
+	_, err = authexport.Import(ctx, src, bytes.NewReader(buf.Bytes()), authexport.ConflictFail)
+	require.Error(t, err)
+	assert.True(t, authexport.ConflictError.Has(err))
+
+	// A different record with the same key hash.
+	dst := memauth.New()
+	require.NoError(t, dst.Put(ctx, authdb.KeyHash{1}, randRecord(nil)))
+
+	_, err = authexport.Import(ctx, dst, bytes.NewReader(buf.Bytes()), authexport.ConflictOverwriteIfEqual)
+	require.Error(t, err)
+	assert.True(t, authexport.ConflictError.Has(err))
+
+	dst = memauth.New()
+	require.NoError(t, dst.Put(ctx, authdb.KeyHash{1}, randRecord(nil)))
+
+	stats, err = authexport.Import(ctx, dst, bytes.NewReader(buf.Bytes()), authexport.ConflictSkip)
+	require.NoError(t, err)
+	assert.Equal(t, authexport.ImportStats{Imported: 2, Existing: 1}, stats)
+
+	_, err = authexport.Import(ctx, dst, strings.NewReader(`{"key_hash":"00"}`), authexport.ConflictSkip)
+	require.Error(t, err)
+
+	_, err = authexport.ParseConflict("overwrite")
+	require.Error(t, err)
+}
+
+func TestExportImport_Badger(t *testing.T) {
+	badgerauthtest.RunSingleNode(t, badgerauth.Config{}, func(ctx *testcontext.Context, t *testing.T, _ *zap.Logger, node *badgerauth.Node) {
+		src := memauth.New()
+		for i := 0; i < 5; i++ {
+			require.NoError(t, src.Put(ctx, authdb.KeyHash{byte(i)}, randRecord(nil)))
+		}
+
+		var buf bytes.Buffer
+		_, err := authexport.Export(ctx, src, &buf)
+		require.NoError(t, err)
+
+		stats, err := authexport.Import(ctx, node.UnderlyingDB(), bytes.NewReader(buf.Bytes()), authexport.ConflictFail)
+		require.NoError(t, err)
+		assert.EqualValues(t, 5, stats.Imported)
+
+		// Imported records get fresh entries in the replication log.
+		require.NoError(t, node.UnderlyingDB().UnderlyingDB().View(func(txn *badger.Txn) error {
+			clock, err := badgerauth.ReadClock(txn, node.ID())
+			require.NoError(t, err)
+			assert.EqualValues(t, 5, clock)
+			return nil
+		}))
+
+		// Exporting from badgerauth gives the same records.
+		var exported bytes.Buffer
+		count, err := authexport.Export(ctx, node, &exported)
+		require.NoError(t, err)
+		assert.EqualValues(t, 5, count)
+
+		dst := memauth.New()
+		_, err = authexport.Import(ctx, dst, bytes.NewReader(exported.Bytes()), authexport.ConflictFail)
+		require.NoError(t, err)
+		require.NoError(t, src.Range(ctx, func(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record) error {
+			got, err := dst.Get(ctx, keyHash)
+			require.NoError(t, err)
+			assert.Equal(t, record, got)
+			return nil
+		}))
+	})
+}
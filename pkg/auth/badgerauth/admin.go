@@ -4,9 +4,13 @@
 package badgerauth
 
 import (
+	"bytes"
 	"context"
+	"sort"
 	"time"
 
+	"github.com/zeebo/errs"
+
 	"storj.io/common/rpc/rpcstatus"
 	"storj.io/gateway-mt/pkg/auth/authdb"
 	"storj.io/gateway-mt/pkg/auth/badgerauth/pb"
@@ -15,10 +19,18 @@ import (
 // Admin represents a service that allows managing database records directly.
 type Admin struct {
 	db *DB
+	// node is nil if Admin has been created with NewAdmin. In that case,
+	// cluster membership can't be managed.
+	node *Node
 }
 
 var _ pb.DRPCAdminServiceServer = (*Admin)(nil)
 
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
 // NewAdmin creates a new instance of Admin.
 func NewAdmin(db *DB) *Admin {
 	return &Admin{db: db}
@@ -39,10 +51,15 @@ func (admin *Admin) InvalidateRecord(ctx context.Context, req *pb.InvalidateReco
 		return nil, errToRPCStatusErr(err)
 	}
 
-	return &resp, errToRPCStatusErr(admin.db.updateRecord(ctx, keyHash, func(record *pb.Record) {
+	if err = admin.db.updateRecord(ctx, keyHash, func(record *pb.Record) {
 		record.InvalidatedAtUnix = time.Now().Unix()
 		record.InvalidationReason = req.Reason
-	}))
+	}); err != nil {
+		return nil, errToRPCStatusErr(err)
+	}
+	admin.db.publishInvalidation(keyHash, authdb.InvalidationInvalidated)
+
+	return &resp, nil
 }
 
 // UnpublishRecord unpublishes a record.
@@ -56,9 +73,14 @@ func (admin *Admin) UnpublishRecord(ctx context.Context, req *pb.UnpublishRecord
 		return nil, errToRPCStatusErr(err)
 	}
 
-	return &resp, errToRPCStatusErr(admin.db.updateRecord(ctx, keyHash, func(record *pb.Record) {
+	if err = admin.db.updateRecord(ctx, keyHash, func(record *pb.Record) {
 		record.Public = false
-	}))
+	}); err != nil {
+		return nil, errToRPCStatusErr(err)
+	}
+	admin.db.publishInvalidation(keyHash, authdb.InvalidationUnpublished)
+
+	return &resp, nil
 }
 
 // DeleteRecord deletes a database record.
@@ -72,5 +94,210 @@ func (admin *Admin) DeleteRecord(ctx context.Context, req *pb.DeleteRecordReques
 		return nil, errToRPCStatusErr(err)
 	}
 
-	return &resp, errToRPCStatusErr(admin.db.deleteRecord(ctx, keyHash))
+	if err = admin.db.deleteRecord(ctx, keyHash); err != nil {
+		return nil, errToRPCStatusErr(err)
+	}
+	admin.db.publishInvalidation(keyHash, authdb.InvalidationDeleted)
+
+	return &resp, nil
+}
+
+// AddPeer adds a peer to the cluster membership.
+func (admin *Admin) AddPeer(ctx context.Context, req *pb.AddPeerRequest) (_ *pb.AddPeerResponse, err error) {
+	defer mon.Task(admin.db.eventTags()...)(&ctx)(&err)
+
+	if admin.node == nil {
+		return nil, rpcstatus.Error(rpcstatus.FailedPrecondition, "cluster membership is unavailable")
+	}
+
+	if err = admin.node.AddPeer(req.Address); err != nil {
+		return nil, rpcstatus.Error(rpcstatus.InvalidArgument, err.Error())
+	}
+
+	return &pb.AddPeerResponse{}, nil
+}
+
+// RemovePeer removes a peer from the cluster membership.
+func (admin *Admin) RemovePeer(ctx context.Context, req *pb.RemovePeerRequest) (_ *pb.RemovePeerResponse, err error) {
+	defer mon.Task(admin.db.eventTags()...)(&ctx)(&err)
+
+	if admin.node == nil {
+		return nil, rpcstatus.Error(rpcstatus.FailedPrecondition, "cluster membership is unavailable")
+	}
+
+	if err = admin.node.RemovePeer(req.Address); err != nil {
+		if errs.Is(err, ErrUnknownPeer) {
+			return nil, rpcstatus.Error(rpcstatus.NotFound, err.Error())
+		}
+		return nil, rpcstatus.Error(rpcstatus.Internal, err.Error())
+	}
+
+	return &pb.RemovePeerResponse{}, nil
+}
+
+// DecommissionNode permanently decommissions a node ID.
+func (admin *Admin) DecommissionNode(ctx context.Context, req *pb.DecommissionNodeRequest) (_ *pb.DecommissionNodeResponse, err error) {
+	defer mon.Task(admin.db.eventTags()...)(&ctx)(&err)
+
+	if admin.node == nil {
+		return nil, rpcstatus.Error(rpcstatus.FailedPrecondition, "cluster membership is unavailable")
+	}
+
+	var id NodeID
+	if err = id.SetBytes(req.NodeId); err != nil {
+		return nil, rpcstatus.Error(rpcstatus.InvalidArgument, err.Error())
+	}
+	if id == (NodeID{}) {
+		return nil, rpcstatus.Error(rpcstatus.InvalidArgument, "missing node ID")
+	}
+
+	if err = admin.node.Decommission(ctx, id); err != nil {
+		if MembershipError.Has(err) {
+			return nil, rpcstatus.Error(rpcstatus.InvalidArgument, err.Error())
+		}
+		return nil, rpcstatus.Error(rpcstatus.Internal, err.Error())
+	}
+
+	return &pb.DecommissionNodeResponse{}, nil
+}
+
+// ClusterStatus returns the node's view of the cluster.
+func (admin *Admin) ClusterStatus(ctx context.Context, req *pb.ClusterStatusRequest) (_ *pb.ClusterStatusResponse, err error) {
+	defer mon.Task(admin.db.eventTags()...)(&ctx)(&err)
+
+	if admin.node == nil {
+		return nil, rpcstatus.Error(rpcstatus.FailedPrecondition, "cluster membership is unavailable")
+	}
+
+	clocks, decommissioned, err := admin.db.readClusterClocks()
+	if err != nil {
+		return nil, rpcstatus.Error(rpcstatus.Internal, err.Error())
+	}
+
+	resp := &pb.ClusterStatusResponse{
+		NodeId: admin.node.ID().Bytes(),
+	}
+
+	for id, clock := range clocks {
+		_, ok := decommissioned[id]
+		resp.Clocks = append(resp.Clocks, &pb.ClusterClock{
+			NodeId:         id.Bytes(),
+			Clock:          uint64(clock),
+			Decommissioned: ok,
+		})
+	}
+	for id := range decommissioned {
+		if _, ok := clocks[id]; !ok {
+			resp.Clocks = append(resp.Clocks, &pb.ClusterClock{
+				NodeId:         id.Bytes(),
+				Decommissioned: true,
+			})
+		}
+	}
+	sort.Slice(resp.Clocks, func(i, j int) bool {
+		return bytes.Compare(resp.Clocks[i].NodeId, resp.Clocks[j].NodeId) < 0
+	})
+
+	for _, peer := range admin.node.Peers() {
+		status := peer.Status()
+
+		s := &pb.ClusterPeerStatus{
+			Address:         status.Address,
+			Up:              status.LastWasUp,
+			LastUpdatedUnix: nonZeroTimeToTimestamp(status.LastUpdated),
+			LastSyncedUnix:  nonZeroTimeToTimestamp(status.LastSynced),
+			Clock:           uint64(status.Clock),
+		}
+		if status.NodeID != (NodeID{}) {
+			s.NodeId = status.NodeID.Bytes()
+		}
+		if status.LastError != nil {
+			s.LastError = status.LastError.Error()
+		}
+
+		resp.Peers = append(resp.Peers, s)
+	}
+
+	return resp, nil
+}
+
+// ListRecords lists records matching the request's filters.
+func (admin *Admin) ListRecords(ctx context.Context, req *pb.ListRecordsRequest) (_ *pb.ListRecordsResponse, err error) {
+	defer mon.Task(admin.db.eventTags()...)(&ctx)(&err)
+
+	limit := int(req.Limit)
+	switch {
+	case limit < 0:
+		return nil, rpcstatus.Error(rpcstatus.InvalidArgument, "negative limit")
+	case limit == 0:
+		limit = defaultListLimit
+	case limit > maxListLimit:
+		limit = maxListLimit
+	}
+
+	filter := authdb.ListFilter{
+		MacaroonHead:     req.MacaroonHead,
+		SatelliteAddress: req.SatelliteAddress,
+	}
+	if req.CreatedAfterUnix != 0 {
+		filter.CreatedAfter = time.Unix(req.CreatedAfterUnix, 0)
+	}
+	if req.CreatedBeforeUnix != 0 {
+		filter.CreatedBefore = time.Unix(req.CreatedBeforeUnix, 0)
+	}
+	if filter.Public, err = matchToFilter(req.Public); err != nil {
+		return nil, err
+	}
+	if filter.Invalidated, err = matchToFilter(req.Invalidated); err != nil {
+		return nil, err
+	}
+
+	var cursor authdb.ListCursor
+	if req.CursorCreatedAtUnix != 0 || len(req.CursorKey) > 0 {
+		if err = cursor.KeyHash.SetBytes(req.CursorKey); err != nil {
+			return nil, errToRPCStatusErr(err)
+		}
+		cursor.CreatedAt = time.Unix(req.CursorCreatedAtUnix, 0)
+	}
+
+	records, next, err := admin.db.List(ctx, filter, cursor, limit)
+	if err != nil {
+		return nil, errToRPCStatusErr(err)
+	}
+
+	resp := &pb.ListRecordsResponse{More: !next.IsZero()}
+	if resp.More {
+		resp.NextCursorCreatedAtUnix = next.CreatedAt.Unix()
+		resp.NextCursorKey = next.KeyHash.Bytes()
+	}
+	for _, r := range records {
+		resp.Records = append(resp.Records, &pb.ListedRecord{
+			Key: r.KeyHash.Bytes(),
+			Record: &pb.Record{
+				CreatedAtUnix:      r.CreatedAt.Unix(),
+				Public:             r.Public,
+				SatelliteAddress:   r.SatelliteAddress,
+				MacaroonHead:       r.MacaroonHead,
+				ExpiresAtUnix:      timeToTimestamp(r.ExpiresAt),
+				InvalidationReason: r.InvalidationReason,
+				InvalidatedAtUnix:  timeToTimestamp(r.InvalidatedAt),
+				State:              pb.Record_CREATED,
+			},
+		})
+	}
+
+	return resp, nil
+}
+
+// matchToFilter converts a Match of ListRecordsRequest to a ListFilter field.
+func matchToFilter(m pb.ListRecordsRequest_Match) (*bool, error) {
+	switch m {
+	case pb.ListRecordsRequest_ANY:
+		return nil, nil
+	case pb.ListRecordsRequest_ONLY, pb.ListRecordsRequest_EXCLUDE:
+		v := m == pb.ListRecordsRequest_ONLY
+		return &v, nil
+	default:
+		return nil, rpcstatus.Errorf(rpcstatus.InvalidArgument, "unknown match %d", m)
+	}
 }
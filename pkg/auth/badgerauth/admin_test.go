@@ -152,3 +152,128 @@ func TestNodeAdmin_DeleteRecord(t *testing.T) {
 		require.Equal(t, resp.Record.EncryptedAccessGrant, records[keys[1]].EncryptedAccessGrant)
 	})
 }
+
+func TestNodeAdmin_PublishesInvalidations(t *testing.T) {
+	badgerauthtest.RunSingleNode(t, badgerauth.Config{
+		ID: badgerauth.NodeID{'a', 'd', 'm', 'p', 'u', 'b'},
+	}, func(ctx *testcontext.Context, t *testing.T, _ *zap.Logger, node *badgerauth.Node) {
+		feed := authdb.NewInvalidationFeed(10)
+		node.SetInvalidationFeed(feed)
+
+		admin := badgerauth.NewAdmin(node.UnderlyingDB())
+		_, keys, _ := badgerauthtest.CreateFullRecords(ctx, t, node, 3)
+
+		_, err := admin.InvalidateRecord(ctx, &pb.InvalidateRecordRequest{Key: keys[0].Bytes(), Reason: "test"})
+		require.NoError(t, err)
+		_, err = admin.UnpublishRecord(ctx, &pb.UnpublishRecordRequest{Key: keys[1].Bytes()})
+		require.NoError(t, err)
+		_, err = admin.DeleteRecord(ctx, &pb.DeleteRecordRequest{Key: keys[2].Bytes()})
+		require.NoError(t, err)
+
+		// failures aren't published.
+		_, err = admin.DeleteRecord(ctx, &pb.DeleteRecordRequest{Key: keys[2].Bytes()})
+		require.Equal(t, rpcstatus.NotFound, rpcstatus.Code(err))
+
+		events, next, reset := feed.Wait(ctx, feed.ID(), 0, 0)
+		require.False(t, reset)
+		require.EqualValues(t, 3, next)
+		require.Equal(t, []authdb.Invalidation{
+			{Seq: 1, KeyHash: keys[0], Reason: authdb.InvalidationInvalidated},
+			{Seq: 2, KeyHash: keys[1], Reason: authdb.InvalidationUnpublished},
+			{Seq: 3, KeyHash: keys[2], Reason: authdb.InvalidationDeleted},
+		}, events)
+	})
+}
+
+func TestNodeAdmin_ClusterStatus(t *testing.T) {
+	badgerauthtest.RunCluster(t, badgerauthtest.ClusterConfig{
+		NodeCount: 3,
+	}, func(ctx *testcontext.Context, t *testing.T, cluster *badgerauthtest.Cluster) {
+		badgerauthtest.CreateFullRecords(ctx, t, cluster.Nodes[1], 3)
+
+		node := cluster.Nodes[0]
+		node.SyncCycle.TriggerWait()
+
+		_, err := badgerauth.NewAdmin(node.UnderlyingDB()).ClusterStatus(ctx, &pb.ClusterStatusRequest{})
+		require.Equal(t, rpcstatus.FailedPrecondition, rpcstatus.Code(err))
+
+		resp, err := node.Admin().ClusterStatus(ctx, &pb.ClusterStatusRequest{})
+		require.NoError(t, err)
+		require.Equal(t, node.ID().Bytes(), resp.NodeId)
+
+		clocks := make(map[badgerauth.NodeID]uint64)
+		for _, c := range resp.Clocks {
+			var id badgerauth.NodeID
+			require.NoError(t, id.SetBytes(c.NodeId))
+			clocks[id] = c.Clock
+		}
+		require.EqualValues(t, 3, clocks[cluster.Nodes[1].ID()])
+		require.Contains(t, clocks, cluster.Nodes[2].ID())
+
+		require.Len(t, resp.Peers, 2)
+		for i, peer := range resp.Peers {
+			require.Equal(t, cluster.Nodes[i+1].Address(), peer.Address)
+			require.Equal(t, cluster.Nodes[i+1].ID().Bytes(), peer.NodeId)
+			require.True(t, peer.Up)
+			require.Empty(t, peer.LastError)
+			require.NotZero(t, peer.LastSyncedUnix)
+		}
+		require.EqualValues(t, 3, resp.Peers[0].Clock)
+		require.EqualValues(t, 0, resp.Peers[1].Clock)
+	})
+}
+
+func TestNodeAdmin_ListRecords(t *testing.T) {
+	badgerauthtest.RunSingleNode(t, badgerauth.Config{
+		ID: badgerauth.NodeID{'a', 'd', 'm', 'l', 's', 't'},
+	}, func(ctx *testcontext.Context, t *testing.T, _ *zap.Logger, node *badgerauth.Node) {
+		admin := badgerauth.NewAdmin(node.UnderlyingDB())
+		records, keys, _ := badgerauthtest.CreateFullRecords(ctx, t, node, 3)
+
+		_, err := admin.ListRecords(ctx, &pb.ListRecordsRequest{Limit: -1})
+		require.Equal(t, rpcstatus.InvalidArgument, rpcstatus.Code(err))
+
+		_, err = admin.ListRecords(ctx, &pb.ListRecordsRequest{Public: 5})
+		require.Equal(t, rpcstatus.InvalidArgument, rpcstatus.Code(err))
+
+		_, err = admin.ListRecords(ctx, &pb.ListRecordsRequest{CursorKey: make([]byte, 33)})
+		require.Equal(t, rpcstatus.InvalidArgument, rpcstatus.Code(err))
+
+		resp, err := admin.ListRecords(ctx, &pb.ListRecordsRequest{MacaroonHead: records[keys[1]].MacaroonHead})
+		require.NoError(t, err)
+		require.False(t, resp.More)
+		require.Len(t, resp.Records, 1)
+		require.Equal(t, keys[1].Bytes(), resp.Records[0].Key)
+		require.Equal(t, records[keys[1]].SatelliteAddress, resp.Records[0].Record.SatelliteAddress)
+		require.Equal(t, records[keys[1]].ExpiresAt.Unix(), resp.Records[0].Record.ExpiresAtUnix)
+		require.Empty(t, resp.Records[0].Record.EncryptedAccessGrant)
+
+		_, err = admin.InvalidateRecord(ctx, &pb.InvalidateRecordRequest{Key: keys[2].Bytes(), Reason: "test"})
+		require.NoError(t, err)
+
+		resp, err = admin.ListRecords(ctx, &pb.ListRecordsRequest{Invalidated: pb.ListRecordsRequest_ONLY})
+		require.NoError(t, err)
+		require.Len(t, resp.Records, 1)
+		require.Equal(t, keys[2].Bytes(), resp.Records[0].Key)
+		require.Equal(t, "test", resp.Records[0].Record.InvalidationReason)
+
+		listed := make(map[authdb.KeyHash]struct{})
+		req := &pb.ListRecordsRequest{Invalidated: pb.ListRecordsRequest_EXCLUDE, Limit: 1}
+		for {
+			resp, err = admin.ListRecords(ctx, req)
+			require.NoError(t, err)
+			require.Len(t, resp.Records, 1)
+
+			var keyHash authdb.KeyHash
+			require.NoError(t, keyHash.SetBytes(resp.Records[0].Key))
+			listed[keyHash] = struct{}{}
+
+			if !resp.More {
+				break
+			}
+			req.CursorCreatedAtUnix = resp.Records[0].Record.CreatedAtUnix
+			req.CursorKey = resp.Records[0].Key
+		}
+		require.Equal(t, map[authdb.KeyHash]struct{}{keys[0]: {}, keys[1]: {}}, listed)
+	})
+}
@@ -0,0 +1,77 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauthmigration
+
+import (
+	"context"
+	"encoding/json"
+
+	badger "github.com/outcaste-io/badger/v3"
+	"github.com/zeebo/errs"
+)
+
+const (
+	// checkpointKey is where the migration checkpoint is stored in
+	// badgerauth.
+	checkpointKey = "badgerauthmigration/checkpoint"
+	// reverseCheckpointKey is where the reverse migration checkpoint is
+	// stored in badgerauth.
+	reverseCheckpointKey = "badgerauthmigration/reverse-checkpoint"
+)
+
+// Progress is the state of the migration persisted in badgerauth.
+type Progress struct {
+	// LastKeyHash is the key hash of the last migrated record. The migration
+	// resumes after it.
+	LastKeyHash []byte `json:"last_key_hash"`
+	// Migrated is the number of records migrated so far.
+	Migrated int64 `json:"migrated"`
+	// Remaining is the estimated number of records left to migrate.
+	Remaining int64 `json:"remaining"`
+	// Done is whether all records have been migrated.
+	Done bool `json:"done"`
+
+	// Verified is the number of records compared by the verification pass.
+	Verified int64 `json:"verified"`
+	// Mismatches is the number of compared records that differ or are
+	// missing in badgerauth.
+	Mismatches int64 `json:"mismatches"`
+}
+
+// Complete returns whether the migration has finished and the verification
+// pass has found no mismatches.
+func (p Progress) Complete() bool {
+	return p.Done && p.Mismatches == 0
+}
+
+func readCheckpoint(txn *badger.Txn, key string) (p Progress, err error) {
+	item, err := txn.Get([]byte(key))
+	if err != nil {
+		if errs.Is(err, badger.ErrKeyNotFound) {
+			return p, nil
+		}
+		return p, err
+	}
+	return p, item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &p)
+	})
+}
+
+func writeCheckpoint(txn *badger.Txn, key string, p Progress) error {
+	val, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return txn.Set([]byte(key), val)
+}
+
+// Progress returns the state of the migration.
+func (kv *KV) Progress(ctx context.Context) (p Progress, err error) {
+	defer kv.mon.Task()(&ctx)(&err)
+
+	return p, Error.Wrap(kv.dst.UnderlyingDB().UnderlyingDB().View(func(txn *badger.Txn) error {
+		p, err = readCheckpoint(txn, checkpointKey)
+		return err
+	}))
+}
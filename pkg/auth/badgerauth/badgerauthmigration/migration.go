@@ -5,7 +5,9 @@
 package badgerauthmigration
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
 	"time"
 
 	badger "github.com/outcaste-io/badger/v3"
@@ -24,10 +26,12 @@ import (
 // Error is the default error class for the badgerauthmigration package.
 var Error = errs.Class("badgerauthmigration")
 
-// Config represents config for KV.
+// Config represents config for KV and ReverseKV.
 type Config struct {
-	MigrationSelectSize    int    `user:"true" help:"page size while performing migration"                 default:"1000"`
-	SourceSQLAuthKVBackend string `user:"true" help:"source key/value store backend (must be sqlauth) url" default:""`
+	MigrationSelectSize         int    `user:"true" help:"page size while performing migration"                                                            default:"1000"`
+	SourceSQLAuthKVBackend      string `user:"true" help:"source key/value store backend (must be sqlauth) url"                                            default:""`
+	DestinationSQLAuthKVBackend string `user:"true" help:"destination key/value store backend (must be sqlauth) url for reverse migration from badgerauth" default:""`
+	VerificationSampleSize      int    `user:"true" help:"number of random records to verify after migration (0 disables)"                                 default:"1000"`
 }
 
 // KV is an implementation of the KV interface that helps move from sqlauth to
@@ -126,6 +130,13 @@ func (kv *KV) PingDB(ctx context.Context) (err error) {
 	return Error.Wrap(errs.Combine(kv.dst.PingDB(ctx), kv.src.PingDB(ctx)))
 }
 
+// SetInvalidationFeed makes the destination node publish records invalidated,
+// unpublished or deleted through its admin service, and records replicated
+// invalidated, to feed.
+func (kv *KV) SetInvalidationFeed(feed *authdb.InvalidationFeed) {
+	kv.dst.SetInvalidationFeed(feed)
+}
+
 // Run runs the server and the associated servers.
 func (kv *KV) Run(ctx context.Context) error {
 	group, groupCtx := errgroup.WithContext(ctx)
@@ -140,7 +151,14 @@ func (kv *KV) Run(ctx context.Context) error {
 
 // MigrateToLatest migrates all existing records at passed sqlauth to the new
 // badgerauth backend.
-func (kv *KV) MigrateToLatest(ctx context.Context) error {
+//
+// Progress is checkpointed in badgerauth with each batch of records, so an
+// interrupted migration resumes where it stopped. Once all records are
+// migrated, a sample of them is verified (see Verify); a later call starts a
+// new pass.
+func (kv *KV) MigrateToLatest(ctx context.Context) (err error) {
+	defer kv.mon.Task()(&ctx)(&err)
+
 	srcDB := kv.src.UnderlyingDB()
 	dstDB := kv.dst.UnderlyingDB().UnderlyingDB()
 
@@ -149,17 +167,30 @@ func (kv *KV) MigrateToLatest(ctx context.Context) error {
 		return Error.Wrap(err)
 	}
 
+	progress, err := kv.Progress(ctx)
+	if err != nil {
+		return err
+	}
+
+	if progress.Done {
+		progress = Progress{}
+	} else if progress.Migrated > 0 {
+		kv.log.Info("resuming records migration",
+			zap.Int64("migrated", progress.Migrated),
+			zap.String("lastKeyHash", hex.EncodeToString(progress.LastKeyHash)))
+	}
+
 	kv.log.Info("starting records migration", zap.Int64("cutoff", recordsCount))
 
-	var (
-		count      int64
-		nextMarker *dbx.Paged_Record_Continuation
-	)
 	for {
-		rows, next, err := srcDB.Paged_Record(ctx, kv.config.MigrationSelectSize, nextMarker)
+		rows, err := kv.src.RecordsAfter(ctx, progress.LastKeyHash, kv.config.MigrationSelectSize)
 		if err != nil {
 			return Error.Wrap(err)
 		}
+
+		next := progress
+		next.Done = len(rows) < kv.config.MigrationSelectSize
+
 		if err = dstDB.Update(func(txn *badger.Txn) error {
 			for _, r := range rows {
 				var keyHash authdb.KeyHash
@@ -167,28 +198,128 @@ func (kv *KV) MigrateToLatest(ctx context.Context) error {
 					return err
 				}
 				if err = badgerauth.InsertRecord(kv.log, txn, kv.dst.ID(), keyHash, convertRecord(r)); err != nil {
+					if errs.Is(err, badgerauth.ErrKeyAlreadyExistsRecordsNotEqual) {
+						kv.mon.Counter("as_badgerauthmigration_mismatches").Inc(1)
+					}
 					return err
 				}
-				count++
+				next.LastKeyHash = r.EncryptionKeyHash
+				next.Migrated++
+			}
+
+			next.Remaining = recordsCount - next.Migrated
+			if next.Remaining < 0 || next.Done {
+				next.Remaining = 0
 			}
-			return nil
+
+			return writeCheckpoint(txn, checkpointKey, next)
 		}); err != nil {
 			return Error.Wrap(err)
 		}
 
-		kv.log.Info("migrated another batch of records", zap.Int64("count", count))
+		progress = next
 
-		nextMarker = next
+		kv.mon.IntVal("as_badgerauthmigration_migrated").Observe(progress.Migrated)
+		kv.mon.IntVal("as_badgerauthmigration_remaining").Observe(progress.Remaining)
 
-		if nextMarker == nil {
-			kv.log.Info("finished records migration", zap.Int64("count", count), zap.Int64("cutoff", recordsCount))
+		kv.log.Info("migrated another batch of records", zap.Int64("count", progress.Migrated), zap.Int64("remaining", progress.Remaining))
+
+		if progress.Done {
+			kv.log.Info("finished records migration", zap.Int64("count", progress.Migrated), zap.Int64("cutoff", recordsCount))
 			break
 		}
 	}
 
+	if kv.config.VerificationSampleSize > 0 {
+		return kv.Verify(ctx, kv.config.VerificationSampleSize)
+	}
+
+	return nil
+}
+
+// Verify compares a random sample of n records in sqlauth with their
+// counterparts in badgerauth and records the result in the migration progress.
+// It returns an error if any of them is missing in badgerauth or differs.
+func (kv *KV) Verify(ctx context.Context, n int) (err error) {
+	defer kv.mon.Task()(&ctx)(&err)
+
+	sample, err := kv.src.SampleRecords(ctx, n)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	now := time.Now()
+
+	var verified, mismatches int64
+	if err = kv.dst.UnderlyingDB().UnderlyingDB().View(func(txn *badger.Txn) error {
+		for _, r := range sample {
+			if r.ExpiresAt != nil && r.ExpiresAt.Before(now) {
+				continue // it might have already expired in badgerauth
+			}
+			verified++
+
+			item, err := txn.Get(r.EncryptionKeyHash)
+			if err != nil {
+				if !errs.Is(err, badger.ErrKeyNotFound) {
+					return err
+				}
+				kv.log.Warn("verification: record is missing", zap.String("keyHash", hex.EncodeToString(r.EncryptionKeyHash)))
+				mismatches++
+				continue
+			}
+
+			var migrated pb.Record
+			if err = item.Value(func(val []byte) error {
+				return pb.Unmarshal(val, &migrated)
+			}); err != nil {
+				return err
+			}
+
+			if !verifiedEqual(convertRecord(r), &migrated) {
+				kv.log.Warn("verification: records differ", zap.String("keyHash", hex.EncodeToString(r.EncryptionKeyHash)))
+				mismatches++
+			}
+		}
+		return nil
+	}); err != nil {
+		return Error.Wrap(err)
+	}
+
+	kv.mon.IntVal("as_badgerauthmigration_verified").Observe(verified)
+	kv.mon.IntVal("as_badgerauthmigration_verification_mismatches").Observe(mismatches)
+
+	if err = kv.dst.UnderlyingDB().UnderlyingDB().Update(func(txn *badger.Txn) error {
+		progress, err := readCheckpoint(txn, checkpointKey)
+		if err != nil {
+			return err
+		}
+		progress.Verified, progress.Mismatches = verified, mismatches
+		return writeCheckpoint(txn, checkpointKey, progress)
+	}); err != nil {
+		return Error.Wrap(err)
+	}
+
+	kv.log.Info("verified migrated records", zap.Int64("verified", verified), zap.Int64("mismatches", mismatches))
+
+	if mismatches > 0 {
+		return Error.New("verification found %d mismatches in %d records", mismatches, verified)
+	}
+
 	return nil
 }
 
+// verifiedEqual compares the record's data (creation times may differ for
+// records also put directly into badgerauth).
+func verifiedEqual(a, b *pb.Record) bool {
+	return a.Public == b.Public &&
+		a.SatelliteAddress == b.SatelliteAddress &&
+		bytes.Equal(a.MacaroonHead, b.MacaroonHead) &&
+		a.ExpiresAtUnix == b.ExpiresAtUnix &&
+		bytes.Equal(a.EncryptedSecretKey, b.EncryptedSecretKey) &&
+		bytes.Equal(a.EncryptedAccessGrant, b.EncryptedAccessGrant) &&
+		a.InvalidationReason == b.InvalidationReason
+}
+
 func convertRecord(r *dbx.Record) *pb.Record {
 	converted := &pb.Record{
 		CreatedAtUnix:        r.CreatedAt.Unix(),
@@ -4,20 +4,25 @@
 package badgerauthmigration
 
 import (
+	"context"
 	"strconv"
 	"testing"
 	"time"
 
+	badger "github.com/outcaste-io/badger/v3"
 	"github.com/spacemonkeygo/monkit/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
 
 	"storj.io/common/sync2"
 	"storj.io/common/testcontext"
 	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/authdb/kvtest"
 	"storj.io/gateway-mt/pkg/auth/badgerauth"
 	"storj.io/gateway-mt/pkg/auth/badgerauth/badgerauthtest"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/pb"
 	"storj.io/gateway-mt/pkg/auth/sqlauth"
 	"storj.io/private/dbutil/pgtest"
 )
@@ -225,6 +230,78 @@ func testMigrateToLatest(t *testing.T, srcConnstr string) {
 	})
 }
 
+func TestMigrateToLatest_Resume_Postgres(t *testing.T) {
+	testMigrateToLatestResume(t, pgtest.PickPostgres(t))
+}
+
+func TestMigrateToLatest_Resume_Cockroach(t *testing.T) {
+	testMigrateToLatestResume(t, pgtest.PickCockroachAlt(t))
+}
+
+func testMigrateToLatestResume(t *testing.T, srcConnstr string) {
+	badgerauthtest.RunSingleNode(t, badgerauth.Config{}, func(ctx *testcontext.Context, t *testing.T, log *zap.Logger, node *badgerauth.Node) {
+		src, err := sqlauth.OpenTest(ctx, log, t.Name(), srcConnstr)
+		require.NoError(t, err)
+		defer ctx.Check(src.Close)
+
+		kv := New(log, src, node, Config{MigrationSelectSize: 7})
+
+		require.NoError(t, kv.PingDB(ctx))
+		require.NoError(t, src.MigrateToLatest(ctx))
+
+		for i := 0; i < 30; i++ {
+			require.NoError(t, src.Put(ctx, authdb.KeyHash{byte(i)}, &authdb.Record{
+				SatelliteAddress:     "resume",
+				MacaroonHead:         []byte{'r', byte(i)},
+				EncryptedSecretKey:   []byte{'e', byte(i)},
+				EncryptedAccessGrant: []byte{'s', byte(i)},
+				Public:               true,
+			}))
+		}
+
+		// Pretend an earlier migration stopped after the first 10 records.
+		lastKeyHash := authdb.KeyHash{9}
+		require.NoError(t, node.UnderlyingDB().UnderlyingDB().Update(func(txn *badger.Txn) error {
+			return writeCheckpoint(txn, checkpointKey, Progress{LastKeyHash: lastKeyHash.Bytes(), Migrated: 10, Remaining: 20})
+		}))
+
+		require.NoError(t, kv.MigrateToLatest(ctx))
+
+		progress, err := kv.Progress(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Progress{
+			LastKeyHash: authdb.KeyHash{29}.Bytes(),
+			Migrated:    30,
+			Done:        true,
+		}, progress)
+
+		for i := 0; i < 30; i++ {
+			record, err := node.UnderlyingDB().Get(ctx, authdb.KeyHash{byte(i)})
+			require.NoError(t, err)
+			assert.Equal(t, i >= 10, record != nil, i)
+		}
+
+		// The skipped records are reported by verification.
+		require.Error(t, kv.Verify(ctx, 30))
+
+		progress, err = kv.Progress(ctx)
+		require.NoError(t, err)
+		assert.EqualValues(t, 30, progress.Verified)
+		assert.EqualValues(t, 10, progress.Mismatches)
+		assert.False(t, progress.Complete())
+
+		// A finished migration starts over on the next call.
+		kv.config.VerificationSampleSize = 30
+		require.NoError(t, kv.MigrateToLatest(ctx))
+
+		progress, err = kv.Progress(ctx)
+		require.NoError(t, err)
+		assert.EqualValues(t, 30, progress.Migrated)
+		assert.EqualValues(t, 30, progress.Verified)
+		assert.True(t, progress.Complete())
+	})
+}
+
 func TestPutWithSkewedTimes_Postgres(t *testing.T) {
 	testPutWithSkewedTimes(t, pgtest.PickPostgres(t))
 }
@@ -322,3 +399,32 @@ func testGetExpired(t *testing.T, srcConnstr string) {
 		require.Nil(t, record)
 	})
 }
+
+func TestKV_Conformance_Postgres(t *testing.T) {
+	testKVConformance(t, pgtest.PickPostgres(t))
+}
+
+func TestKV_Conformance_Cockroach(t *testing.T) {
+	testKVConformance(t, pgtest.PickCockroachAlt(t))
+}
+
+func testKVConformance(t *testing.T, srcConnstr string) {
+	kvtest.Run(t, func(ctx *testcontext.Context, t *testing.T) authdb.KV {
+		log := zaptest.NewLogger(t)
+
+		src, err := sqlauth.OpenTest(ctx, log, t.Name(), srcConnstr)
+		require.NoError(t, err)
+		require.NoError(t, src.MigrateToLatest(ctx))
+
+		return New(log, src, badgerauthtest.NewNode(t, log, badgerauth.Config{ID: badgerauth.NodeID{'k', 'v'}}), Config{})
+	}, kvtest.Options{
+		Invalidate: func(ctx context.Context, kv authdb.KV, keyHash authdb.KeyHash, reason string) error {
+			_, err := kv.(*KV).dst.Admin().InvalidateRecord(ctx, &pb.InvalidateRecordRequest{
+				Key:    keyHash.Bytes(),
+				Reason: reason,
+			})
+			return err
+		},
+		DeleteUnusedUnsupported: true,
+	})
+}
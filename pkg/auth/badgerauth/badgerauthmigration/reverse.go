@@ -0,0 +1,536 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauthmigration
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"time"
+
+	badger "github.com/outcaste-io/badger/v3"
+	"github.com/spacemonkeygo/monkit/v3"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"storj.io/common/sync2"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/badgerauth"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/pb"
+	"storj.io/gateway-mt/pkg/auth/sqlauth"
+	"storj.io/gateway-mt/pkg/auth/sqlauth/dbx"
+)
+
+// reverseFeedSize is how many invalidations ReverseKV keeps while it applies
+// them to sqlauth.
+const reverseFeedSize = 10000
+
+// ReverseKV is an implementation of the KV interface that helps move back from
+// badgerauth to sqlauth backend. It's the mirror image of KV: badgerauth is the
+// source, sqlauth is the destination, and both are written to on Put.
+//
+// Records invalidated, unpublished and deleted through badgerauth's admin API
+// are followed through the node's invalidations and changed in sqlauth too.
+type ReverseKV struct {
+	mon *monkit.Scope
+	log *zap.Logger
+	src *badgerauth.Node
+	dst *sqlauth.KV
+
+	// invalidations is where src publishes invalidations to apply to dst,
+	// and published, if set, is where they're published after that.
+	invalidations *authdb.InvalidationFeed
+	published     *authdb.InvalidationFeed
+
+	config Config
+}
+
+// Below is a compile-time check ensuring ReverseKV implements the KV interface.
+var (
+	_ authdb.KV                                           = (*ReverseKV)(nil)
+	_ interface{ MigrateToLatest(context.Context) error } = (*ReverseKV)(nil)
+)
+
+// NewReverse constructs new ReverseKV.
+func NewReverse(log *zap.Logger, src *badgerauth.Node, dst *sqlauth.KV, config Config) *ReverseKV {
+	invalidations := authdb.NewInvalidationFeed(reverseFeedSize)
+	src.SetInvalidationFeed(invalidations)
+
+	return &ReverseKV{
+		mon:           monkit.Package(),
+		log:           log,
+		src:           src,
+		dst:           dst,
+		invalidations: invalidations,
+		config:        config,
+	}
+}
+
+// SetInvalidationFeed makes ReverseKV publish records invalidated,
+// unpublished or deleted through badgerauth's admin API to feed once they're
+// changed in sqlauth. It must be called before Run.
+func (kv *ReverseKV) SetInvalidationFeed(feed *authdb.InvalidationFeed) {
+	kv.published = feed
+}
+
+// Put stores the record in both stores with the same creation time.
+// It is an error if the key already exists.
+func (kv *ReverseKV) Put(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record) (err error) {
+	defer kv.mon.Task()(&ctx)(&err)
+
+	// See (*KV).Put for why createdAt is truncated.
+	createdAt := time.Unix(time.Now().Unix(), 0)
+
+	if err := kv.src.PutAtTime(ctx, keyHash, record, createdAt); err != nil {
+		return Error.New("failed to write to badgerauth: %w", err)
+	}
+	kv.log.Debug("Wrote to badgerauth", zap.Binary("keyHash", keyHash.Bytes()))
+	if err := kv.dst.PutAtTime(ctx, keyHash, record, createdAt); err != nil {
+		kv.mon.Event("as_badgerauthmigration_reverse_destination_put_err")
+		return Error.New("failed to write to sqlauth: %w", err)
+	}
+	kv.log.Debug("Wrote to sqlauth", zap.Binary("keyHash", keyHash.Bytes()))
+	return nil
+}
+
+// Get retrieves the record from the key/value store. It returns nil if the key
+// does not exist. If the record is invalid, the error contains why.
+//
+// Unlike KV, the source store is authoritative: records are invalidated,
+// unpublished and deleted through badgerauth's admin API during the migration,
+// and these changes reach sqlauth only after they're followed (see Run). The
+// destination store is only read to measure how many records it misses.
+func (kv *ReverseKV) Get(ctx context.Context, keyHash authdb.KeyHash) (record *authdb.Record, err error) {
+	defer kv.mon.Task()(&ctx)(&err)
+
+	record, err = kv.src.Get(ctx, keyHash)
+	if record == nil || err != nil {
+		return record, Error.Wrap(err)
+	}
+
+	switch dstRecord, dstErr := kv.dst.Get(ctx, keyHash); {
+	case dstErr != nil && !authdb.Invalid.Has(dstErr):
+		kv.log.Warn("unexpected destination store error @ Get", zap.Error(dstErr))
+	case dstRecord == nil && dstErr == nil:
+		kv.log.Warn(
+			"destination miss",
+			zap.String("keyHash (hex)", keyHash.ToHex()),
+			zap.String("SatelliteAddress", record.SatelliteAddress),
+			zap.Binary("MacaroonHead", record.MacaroonHead),
+			zap.Timep("ExpiresAt", record.ExpiresAt),
+		)
+		kv.mon.Event("as_badgerauthmigration_reverse_destination_miss")
+	default:
+		kv.mon.Event("as_badgerauthmigration_reverse_destination_hit")
+	}
+
+	return record, nil
+}
+
+// DeleteUnused is not implemented.
+func (*ReverseKV) DeleteUnused(context.Context, time.Duration, int, int) (int64, int64, map[string]int64, error) {
+	return 0, 0, nil, Error.New("not implemented")
+}
+
+// PingDB attempts to do a database roundtrip and returns an error if it can't.
+func (kv *ReverseKV) PingDB(ctx context.Context) (err error) {
+	defer kv.mon.Task()(&ctx)(&err)
+
+	return Error.Wrap(errs.Combine(kv.src.PingDB(ctx), kv.dst.PingDB(ctx)))
+}
+
+// Run runs the server and the associated servers, and applies records
+// invalidated, unpublished or deleted through badgerauth's admin API to
+// sqlauth.
+func (kv *ReverseKV) Run(ctx context.Context) error {
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		return kv.src.Run(groupCtx)
+	})
+	group.Go(func() error {
+		return kv.dst.Run(groupCtx)
+	})
+	group.Go(func() error {
+		kv.followInvalidations(groupCtx)
+		return nil
+	})
+	return Error.Wrap(group.Wait())
+}
+
+// followInvalidations applies invalidations published by badgerauth to
+// sqlauth until ctx is canceled. If invalidations were missed, only
+// MigrateToLatest reconciles them.
+func (kv *ReverseKV) followInvalidations(ctx context.Context) {
+	var since uint64
+	for {
+		invalidations, next, reset := kv.invalidations.Wait(ctx, kv.invalidations.ID(), since, time.Minute)
+		if ctx.Err() != nil {
+			return
+		}
+		if reset {
+			kv.log.Warn("missed invalidations to apply to sqlauth; run the migration again to reconcile them")
+			kv.mon.Event("as_badgerauthmigration_reverse_invalidations_missed")
+		}
+		since = next
+
+		for _, invalidation := range invalidations {
+			if err := kv.applyInvalidation(ctx, invalidation); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				kv.log.Error("failed to apply invalidation to sqlauth",
+					zap.String("keyHash (hex)", invalidation.KeyHash.ToHex()),
+					zap.String("reason", string(invalidation.Reason)),
+					zap.Error(err))
+				kv.mon.Event("as_badgerauthmigration_reverse_invalidation_err")
+			}
+			if kv.published != nil {
+				kv.published.Publish(invalidation.KeyHash, invalidation.Reason)
+			}
+		}
+	}
+}
+
+// applyInvalidation changes the record in sqlauth the way it was changed in
+// badgerauth, retrying until it succeeds or ctx is canceled.
+func (kv *ReverseKV) applyInvalidation(ctx context.Context, invalidation authdb.Invalidation) (err error) {
+	defer kv.mon.Task()(&ctx)(&err)
+
+	delay := time.Second
+	for {
+		if err = kv.reconcile(ctx, invalidation.KeyHash); err == nil {
+			return nil
+		}
+		kv.log.Warn("failed to apply invalidation to sqlauth; retrying",
+			zap.String("keyHash (hex)", invalidation.KeyHash.ToHex()), zap.Error(err))
+		if !sync2.Sleep(ctx, delay) {
+			return Error.Wrap(ctx.Err())
+		}
+		if delay < time.Minute {
+			delay *= 2
+		}
+	}
+}
+
+// reconcile makes the record in sqlauth match its current state in
+// badgerauth: deleted, unpublished or invalidated.
+func (kv *ReverseKV) reconcile(ctx context.Context, keyHash authdb.KeyHash) error {
+	var r *pb.Record
+	if err := kv.src.UnderlyingDB().UnderlyingDB().View(func(txn *badger.Txn) (err error) {
+		r, err = lookupRecord(txn, keyHash)
+		return err
+	}); err != nil {
+		return Error.Wrap(err)
+	}
+	if r == nil {
+		return Error.Wrap(kv.dst.Delete(ctx, keyHash))
+	}
+
+	if !r.Public {
+		if err := kv.dst.Unpublish(ctx, keyHash); err != nil {
+			return Error.Wrap(err)
+		}
+	}
+	if r.InvalidationReason != "" {
+		return Error.Wrap(kv.dst.InvalidateAtTime(ctx, keyHash, r.InvalidationReason, time.Unix(r.InvalidatedAtUnix, 0)))
+	}
+	return nil
+}
+
+// MigrateToLatest migrates sqlauth's schema and copies all existing records
+// from badgerauth to sqlauth, preserving their creation times, invalidation
+// and public flags.
+//
+// Records that already exist in sqlauth are left alone if they're equal, and
+// get unpublished or the invalidation of the badgerauth record if only that's
+// missing. Any other difference cancels the migration. Once all records are
+// copied, records missing from badgerauth (e.g., deleted through its admin
+// API) are deleted from sqlauth. Like with KV, progress is checkpointed in
+// badgerauth, so an interrupted migration resumes where it stopped, and a
+// later call after it has finished starts a new pass.
+func (kv *ReverseKV) MigrateToLatest(ctx context.Context) (err error) {
+	defer kv.mon.Task()(&ctx)(&err)
+
+	if err = kv.dst.MigrateToLatest(ctx); err != nil {
+		return Error.Wrap(err)
+	}
+
+	srcDB := kv.src.UnderlyingDB().UnderlyingDB()
+
+	recordsCount, err := countRecords(srcDB)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+
+	progress, err := kv.Progress(ctx)
+	if err != nil {
+		return err
+	}
+
+	if progress.Done {
+		progress = Progress{}
+	} else if progress.Migrated > 0 {
+		kv.log.Info("resuming reverse records migration",
+			zap.Int64("migrated", progress.Migrated),
+			zap.String("lastKeyHash", hex.EncodeToString(progress.LastKeyHash)))
+	}
+
+	kv.log.Info("starting reverse records migration", zap.Int64("cutoff", recordsCount))
+
+	for {
+		var keyHashes []authdb.KeyHash
+		var records []*pb.Record
+		if err = srcDB.View(func(txn *badger.Txn) (err error) {
+			keyHashes, records, err = recordsAfter(txn, progress.LastKeyHash, kv.config.MigrationSelectSize)
+			return err
+		}); err != nil {
+			return Error.Wrap(err)
+		}
+
+		next := progress
+		next.Done = len(records) < kv.config.MigrationSelectSize
+
+		for i, r := range records {
+			if err = kv.insert(ctx, keyHashes[i], r); err != nil {
+				return err
+			}
+			next.LastKeyHash = keyHashes[i].Bytes()
+			next.Migrated++
+		}
+
+		next.Remaining = recordsCount - next.Migrated
+		if next.Remaining < 0 || next.Done {
+			next.Remaining = 0
+		}
+
+		if err = srcDB.Update(func(txn *badger.Txn) error {
+			return writeCheckpoint(txn, reverseCheckpointKey, next)
+		}); err != nil {
+			return Error.Wrap(err)
+		}
+
+		progress = next
+
+		kv.mon.IntVal("as_badgerauthmigration_reverse_migrated").Observe(progress.Migrated)
+		kv.mon.IntVal("as_badgerauthmigration_reverse_remaining").Observe(progress.Remaining)
+
+		kv.log.Info("migrated another batch of records", zap.Int64("count", progress.Migrated), zap.Int64("remaining", progress.Remaining))
+
+		if progress.Done {
+			kv.log.Info("finished reverse records migration", zap.Int64("count", progress.Migrated), zap.Int64("cutoff", recordsCount))
+			return kv.deleteMissing(ctx)
+		}
+	}
+}
+
+// deleteMissing deletes records from sqlauth that don't exist in badgerauth.
+func (kv *ReverseKV) deleteMissing(ctx context.Context) (err error) {
+	defer kv.mon.Task()(&ctx)(&err)
+
+	srcDB := kv.src.UnderlyingDB().UnderlyingDB()
+
+	var after []byte
+	for {
+		records, err := kv.dst.RecordsAfter(ctx, after, kv.config.MigrationSelectSize)
+		if err != nil {
+			return Error.Wrap(err)
+		}
+
+		for _, r := range records {
+			var keyHash authdb.KeyHash
+			if err = keyHash.SetBytes(r.EncryptionKeyHash); err != nil {
+				return Error.Wrap(err)
+			}
+
+			var found *pb.Record
+			if err = srcDB.View(func(txn *badger.Txn) (err error) {
+				found, err = lookupRecord(txn, keyHash)
+				return err
+			}); err != nil {
+				return Error.Wrap(err)
+			}
+			if found != nil {
+				continue
+			}
+
+			if err = kv.dst.Delete(ctx, keyHash); err != nil {
+				return Error.Wrap(err)
+			}
+			kv.mon.Counter("as_badgerauthmigration_reverse_deleted").Inc(1)
+			kv.log.Debug("deleted record missing from badgerauth", zap.String("keyHash (hex)", keyHash.ToHex()))
+		}
+
+		if len(records) < kv.config.MigrationSelectSize {
+			return nil
+		}
+		after = records[len(records)-1].EncryptionKeyHash
+	}
+}
+
+// insert copies r to sqlauth, reconciling it with the record that might
+// already exist there.
+func (kv *ReverseKV) insert(ctx context.Context, keyHash authdb.KeyHash, r *pb.Record) error {
+	converted := convertToSQLRecord(keyHash, r)
+
+	inserted, err := kv.dst.InsertRecord(ctx, converted)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	if inserted {
+		return nil
+	}
+
+	existing, err := kv.dst.FindRecord(ctx, keyHash)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	if existing == nil {
+		return Error.New("record %x disappeared from sqlauth", keyHash)
+	}
+
+	if sqlRecordsEqual(existing, converted) {
+		return nil
+	}
+
+	// badgerauth's admin API might have unpublished or invalidated the record
+	// since it was written to sqlauth.
+	changed := *existing
+	if existing.Public && !converted.Public {
+		changed.Public = false
+	}
+	if existing.InvalidReason == nil && converted.InvalidReason != nil {
+		changed.InvalidReason, changed.InvalidAt = converted.InvalidReason, converted.InvalidAt
+	}
+	if !sqlRecordsEqual(&changed, converted) {
+		kv.mon.Counter("as_badgerauthmigration_reverse_mismatches").Inc(1)
+		return Error.New("record %x already exists in sqlauth and differs", keyHash)
+	}
+
+	kv.mon.Counter("as_badgerauthmigration_reverse_reconciled").Inc(1)
+	return kv.reconcile(ctx, keyHash)
+}
+
+// Progress returns the state of the reverse migration.
+func (kv *ReverseKV) Progress(ctx context.Context) (p Progress, err error) {
+	defer kv.mon.Task()(&ctx)(&err)
+
+	return p, Error.Wrap(kv.src.UnderlyingDB().UnderlyingDB().View(func(txn *badger.Txn) error {
+		p, err = readCheckpoint(txn, reverseCheckpointKey)
+		return err
+	}))
+}
+
+// Close closes the database.
+func (kv *ReverseKV) Close() (err error) {
+	return Error.Wrap(errs.Combine(kv.src.Close(), kv.dst.Close()))
+}
+
+// isRecordKey returns whether key is a record's key. Records are stored under
+// their key hash, while other keys (replication log, clocks, etc.) are longer
+// or shorter.
+func isRecordKey(key []byte) bool {
+	return len(key) == len(authdb.KeyHash{})
+}
+
+func countRecords(db *badger.DB) (count int64, err error) {
+	return count, db.View(func(txn *badger.Txn) error {
+		opt := badger.DefaultIteratorOptions
+		opt.PrefetchValues = false
+
+		it := txn.NewIterator(opt)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			if isRecordKey(it.Item().Key()) {
+				count++
+			}
+		}
+		return nil
+	})
+}
+
+// lookupRecord returns the record with keyHash, or nil if it doesn't exist.
+func lookupRecord(txn *badger.Txn, keyHash authdb.KeyHash) (*pb.Record, error) {
+	item, err := txn.Get(keyHash.Bytes())
+	if err != nil {
+		if errs.Is(err, badger.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var r pb.Record
+	if err = item.Value(func(val []byte) error {
+		return pb.Unmarshal(val, &r)
+	}); err != nil {
+		return nil, errs.New("record %x: %w", keyHash, err)
+	}
+	return &r, nil
+}
+
+// recordsAfter returns up to limit records with key hashes greater than after
+// (all if after is empty), ordered by key hash.
+func recordsAfter(txn *badger.Txn, after []byte, limit int) (keyHashes []authdb.KeyHash, records []*pb.Record, err error) {
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+
+	for it.Seek(after); it.Valid() && len(records) < limit; it.Next() {
+		item := it.Item()
+		key := item.Key()
+
+		if !isRecordKey(key) || bytes.Equal(key, after) {
+			continue
+		}
+
+		var keyHash authdb.KeyHash
+		if err = keyHash.SetBytes(key); err != nil {
+			return nil, nil, err
+		}
+
+		var r pb.Record
+		if err = item.Value(func(val []byte) error {
+			return pb.Unmarshal(val, &r)
+		}); err != nil {
+			return nil, nil, errs.New("record %x: %w", key, err)
+		}
+
+		keyHashes = append(keyHashes, keyHash)
+		records = append(records, &r)
+	}
+
+	return keyHashes, records, nil
+}
+
+func convertToSQLRecord(keyHash authdb.KeyHash, r *pb.Record) *dbx.Record {
+	converted := &dbx.Record{
+		EncryptionKeyHash:    keyHash.Bytes(),
+		CreatedAt:            time.Unix(r.CreatedAtUnix, 0).UTC(),
+		Public:               r.Public,
+		SatelliteAddress:     r.SatelliteAddress,
+		MacaroonHead:         r.MacaroonHead,
+		EncryptedSecretKey:   r.EncryptedSecretKey,
+		EncryptedAccessGrant: r.EncryptedAccessGrant,
+	}
+	if r.ExpiresAtUnix != 0 {
+		expiresAt := time.Unix(r.ExpiresAtUnix, 0).UTC()
+		converted.ExpiresAt = &expiresAt
+	}
+	if r.InvalidationReason != "" {
+		reason := r.InvalidationReason
+		converted.InvalidReason = &reason
+	}
+	if r.InvalidatedAtUnix != 0 {
+		invalidAt := time.Unix(r.InvalidatedAtUnix, 0).UTC()
+		converted.InvalidAt = &invalidAt
+	}
+
+	return converted
+}
+
+// sqlRecordsEqual compares records the way badgerauth does, i.e., with times
+// truncated to seconds.
+func sqlRecordsEqual(a, b *dbx.Record) bool {
+	return pb.Equal(convertRecord(a), convertRecord(b))
+}
@@ -0,0 +1,261 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauthmigration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	badger "github.com/outcaste-io/badger/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/common/testcontext"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/authdb/kvtest"
+	"storj.io/gateway-mt/pkg/auth/badgerauth"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/badgerauthtest"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/pb"
+	"storj.io/gateway-mt/pkg/auth/sqlauth"
+	"storj.io/private/dbutil/pgtest"
+)
+
+func TestRecordsAfter(t *testing.T) {
+	badgerauthtest.RunSingleNode(t, badgerauth.Config{}, func(ctx *testcontext.Context, t *testing.T, log *zap.Logger, node *badgerauth.Node) {
+		for i := 0; i < 5; i++ {
+			require.NoError(t, node.Put(ctx, authdb.KeyHash{byte(i)}, &authdb.Record{
+				SatelliteAddress:     "reverse",
+				MacaroonHead:         []byte{byte(i)},
+				EncryptedSecretKey:   []byte{byte(i)},
+				EncryptedAccessGrant: []byte{byte(i)},
+			}))
+		}
+
+		db := node.UnderlyingDB().UnderlyingDB()
+
+		count, err := countRecords(db)
+		require.NoError(t, err)
+		assert.EqualValues(t, 5, count)
+
+		var (
+			after []byte
+			all   []authdb.KeyHash
+		)
+		for {
+			var keyHashes []authdb.KeyHash
+			var records []*pb.Record
+			require.NoError(t, db.View(func(txn *badger.Txn) (err error) {
+				keyHashes, records, err = recordsAfter(txn, after, 2)
+				return err
+			}))
+			require.Len(t, records, len(keyHashes))
+			for i, r := range records {
+				assert.Equal(t, []byte{keyHashes[i][0]}, r.MacaroonHead)
+			}
+			all = append(all, keyHashes...)
+			if len(keyHashes) < 2 {
+				break
+			}
+			after = keyHashes[len(keyHashes)-1].Bytes()
+		}
+
+		assert.Equal(t, []authdb.KeyHash{{0}, {1}, {2}, {3}, {4}}, all)
+	})
+}
+
+func TestReverseKV_Postgres(t *testing.T) {
+	testReverseKV(t, pgtest.PickPostgres(t))
+}
+
+func TestReverseKV_Cockroach(t *testing.T) {
+	testReverseKV(t, pgtest.PickCockroachAlt(t))
+}
+
+func testReverseKV(t *testing.T, dstConnstr string) {
+	badgerauthtest.RunSingleNode(t, badgerauth.Config{}, func(ctx *testcontext.Context, t *testing.T, log *zap.Logger, node *badgerauth.Node) {
+		dst, err := sqlauth.OpenTest(ctx, log, t.Name(), dstConnstr)
+		require.NoError(t, err)
+		defer ctx.Check(dst.Close)
+
+		kv := NewReverse(log, node, dst, Config{MigrationSelectSize: 3})
+
+		require.NoError(t, kv.PingDB(ctx))
+		require.NoError(t, dst.MigrateToLatest(ctx))
+
+		createdAt := time.Unix(time.Now().Add(-time.Hour).Unix(), 0)
+		expiresAt := time.Unix(time.Now().Add(time.Hour).Unix(), 0)
+
+		record := func(i int) *authdb.Record {
+			r := &authdb.Record{
+				SatelliteAddress:     "reverse",
+				MacaroonHead:         []byte{'r', byte(i)},
+				EncryptedSecretKey:   []byte{'e', byte(i)},
+				EncryptedAccessGrant: []byte{'v', byte(i)},
+				Public:               i%2 == 0,
+			}
+			if i%3 == 0 {
+				r.ExpiresAt = &expiresAt
+			}
+			return r
+		}
+
+		for i := 0; i < 20; i++ {
+			require.NoError(t, node.UnderlyingDB().PutAtTime(ctx, authdb.KeyHash{byte(i)}, record(i), createdAt))
+		}
+
+		// Record 5 was dual-written before it got invalidated in badgerauth.
+		require.NoError(t, dst.PutAtTime(ctx, authdb.KeyHash{5}, record(5), createdAt))
+
+		for _, i := range []byte{4, 5} {
+			_, err = node.Admin().InvalidateRecord(ctx, &pb.InvalidateRecordRequest{Key: []byte{i}, Reason: "reverse"})
+			require.NoError(t, err)
+		}
+
+		// Record 12 was dual-written before it got unpublished in badgerauth.
+		require.NoError(t, dst.PutAtTime(ctx, authdb.KeyHash{12}, record(12), createdAt))
+		_, err = node.Admin().UnpublishRecord(ctx, &pb.UnpublishRecordRequest{Key: []byte{12}})
+		require.NoError(t, err)
+
+		// Record 50 was dual-written before it got deleted in badgerauth.
+		require.NoError(t, node.UnderlyingDB().PutAtTime(ctx, authdb.KeyHash{50}, record(50), createdAt))
+		require.NoError(t, dst.PutAtTime(ctx, authdb.KeyHash{50}, record(50), createdAt))
+		_, err = node.Admin().DeleteRecord(ctx, &pb.DeleteRecordRequest{Key: []byte{50}})
+		require.NoError(t, err)
+
+		require.NoError(t, kv.MigrateToLatest(ctx))
+
+		progress, err := kv.Progress(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Progress{LastKeyHash: authdb.KeyHash{19}.Bytes(), Migrated: 20, Done: true}, progress)
+
+		for i := 0; i < 20; i++ {
+			migrated, err := dst.FindRecord(ctx, authdb.KeyHash{byte(i)})
+			require.NoError(t, err)
+			require.NotNil(t, migrated, i)
+
+			assert.True(t, createdAt.Equal(migrated.CreatedAt), i)
+			assert.Equal(t, i%2 == 0 && i != 12, migrated.Public, i)
+			if i == 4 || i == 5 {
+				require.NotNil(t, migrated.InvalidReason, i)
+				assert.Equal(t, "reverse", *migrated.InvalidReason)
+				assert.NotNil(t, migrated.InvalidAt, i)
+			} else {
+				assert.Nil(t, migrated.InvalidReason, i)
+			}
+
+			_, err = kv.Get(ctx, authdb.KeyHash{byte(i)})
+			if i == 4 || i == 5 {
+				require.True(t, authdb.Invalid.Has(err), i)
+			} else {
+				require.NoError(t, err, i)
+			}
+		}
+
+		deleted, err := dst.FindRecord(ctx, authdb.KeyHash{50})
+		require.NoError(t, err)
+		assert.Nil(t, deleted, "records deleted in badgerauth must be deleted")
+
+		// Changes made through badgerauth's admin API after the migration
+		// are followed to sqlauth, and published once they're there.
+		published := authdb.NewInvalidationFeed(10)
+		kv.SetInvalidationFeed(published)
+
+		followCtx, cancel := context.WithCancel(ctx)
+		followed := make(chan struct{})
+		go func() {
+			defer close(followed)
+			kv.followInvalidations(followCtx)
+		}()
+
+		_, err = node.Admin().InvalidateRecord(ctx, &pb.InvalidateRecordRequest{Key: []byte{6}, Reason: "admin"})
+		require.NoError(t, err)
+		_, err = node.Admin().UnpublishRecord(ctx, &pb.UnpublishRecordRequest{Key: []byte{8}})
+		require.NoError(t, err)
+		_, err = node.Admin().DeleteRecord(ctx, &pb.DeleteRecordRequest{Key: []byte{10}})
+		require.NoError(t, err)
+
+		// The changes made before the migration are applied again too.
+		var invalidations []authdb.Invalidation
+		require.Eventually(t, func() bool {
+			events, _, _ := published.Wait(ctx, published.ID(), uint64(len(invalidations)), 0)
+			invalidations = append(invalidations, events...)
+			return len(invalidations) == 7
+		}, 10*time.Second, 10*time.Millisecond)
+		cancel()
+		<-followed
+		assert.Equal(t, authdb.Invalidation{Seq: 7, KeyHash: authdb.KeyHash{10}, Reason: authdb.InvalidationDeleted}, invalidations[6])
+
+		invalidated, err := dst.FindRecord(ctx, authdb.KeyHash{6})
+		require.NoError(t, err)
+		require.NotNil(t, invalidated.InvalidReason)
+		assert.Equal(t, "admin", *invalidated.InvalidReason)
+		unpublishedInDst, err := dst.FindRecord(ctx, authdb.KeyHash{8})
+		require.NoError(t, err)
+		assert.False(t, unpublishedInDst.Public)
+		deleted, err = dst.FindRecord(ctx, authdb.KeyHash{10})
+		require.NoError(t, err)
+		assert.Nil(t, deleted)
+
+		_, err = kv.Get(ctx, authdb.KeyHash{6})
+		require.True(t, authdb.Invalid.Has(err), err)
+		unpublished, err := kv.Get(ctx, authdb.KeyHash{8})
+		require.NoError(t, err)
+		assert.False(t, unpublished.Public)
+		gone, err := kv.Get(ctx, authdb.KeyHash{10})
+		require.NoError(t, err)
+		assert.Nil(t, gone)
+
+		// Migrating again is a no-op.
+		require.NoError(t, kv.MigrateToLatest(ctx))
+
+		// New records go to both stores.
+		require.NoError(t, kv.Put(ctx, authdb.KeyHash{100}, record(100)))
+		for _, store := range []authdb.KV{node, dst} {
+			actual, err := store.Get(ctx, authdb.KeyHash{100})
+			require.NoError(t, err)
+			assert.Equal(t, record(100), actual)
+		}
+
+		// A record that differs cancels the migration.
+		different := record(200)
+		different.SatelliteAddress = "different"
+		require.NoError(t, node.Put(ctx, authdb.KeyHash{200}, record(200)))
+		require.NoError(t, dst.Put(ctx, authdb.KeyHash{200}, different))
+		require.Error(t, kv.MigrateToLatest(ctx))
+	})
+}
+
+func TestReverseKV_Conformance_Postgres(t *testing.T) {
+	testReverseKVConformance(t, pgtest.PickPostgres(t))
+}
+
+func TestReverseKV_Conformance_Cockroach(t *testing.T) {
+	testReverseKVConformance(t, pgtest.PickCockroachAlt(t))
+}
+
+func testReverseKVConformance(t *testing.T, dstConnstr string) {
+	kvtest.Run(t, func(ctx *testcontext.Context, t *testing.T) authdb.KV {
+		log := zaptest.NewLogger(t)
+
+		dst, err := sqlauth.OpenTest(ctx, log, t.Name(), dstConnstr)
+		require.NoError(t, err)
+		require.NoError(t, dst.MigrateToLatest(ctx))
+
+		return NewReverse(log, badgerauthtest.NewNode(t, log, badgerauth.Config{ID: badgerauth.NodeID{'k', 'v'}}), dst, Config{})
+	}, kvtest.Options{
+		// Records are only invalidated through badgerauth's admin API during
+		// the migration, so sqlauth keeps them valid.
+		Invalidate: func(ctx context.Context, kv authdb.KV, keyHash authdb.KeyHash, reason string) error {
+			_, err := kv.(*ReverseKV).src.Admin().InvalidateRecord(ctx, &pb.InvalidateRecordRequest{
+				Key:    keyHash.Bytes(),
+				Reason: reason,
+			})
+			return err
+		},
+		DeleteUnusedUnsupported: true,
+	})
+}
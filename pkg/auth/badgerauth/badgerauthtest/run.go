@@ -15,6 +15,7 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"storj.io/common/errs2"
+	"storj.io/common/memory"
 	"storj.io/common/testcontext"
 	"storj.io/gateway-mt/pkg/auth/badgerauth"
 )
@@ -48,6 +49,17 @@ func RunSingleNode(t *testing.T, config badgerauth.Config, fn func(ctx *testcont
 	fn(ctx, t, log, node)
 }
 
+// NewNode creates a node of a single node cluster with the test defaults
+// applied to config. The caller is responsible for running and closing it.
+func NewNode(t *testing.T, log *zap.Logger, config badgerauth.Config) *badgerauth.Node {
+	setConfigDefaults(&config)
+
+	node, err := badgerauth.New(log, config)
+	require.NoError(t, err)
+
+	return node
+}
+
 // Cluster represents a collection of badgerauth nodes.
 type Cluster struct {
 	Nodes []*badgerauth.Node
@@ -152,6 +164,27 @@ func setConfigDefaults(config *badgerauth.Config) {
 	if config.ReplicationLimit == 0 {
 		config.ReplicationLimit = 1000
 	}
+	if config.JoinRefreshInterval == 0 {
+		config.JoinRefreshInterval = time.Minute
+	}
+	if config.WriteQuorumTimeout == 0 {
+		config.WriteQuorumTimeout = 2 * time.Second
+	}
+	if config.WriteQuorumFallback == "" {
+		config.WriteQuorumFallback = badgerauth.WriteQuorumFallbackDegrade
+	}
+
+	// NegativeCacheTTL is left disabled as tests commonly look up records
+	// that appear on peers shortly after.
+	if config.NegativeCacheCapacity == 0 {
+		config.NegativeCacheCapacity = 100000
+	}
+	if config.LookupHedgeDelay == 0 {
+		config.LookupHedgeDelay = 50 * time.Millisecond
+	}
+	if config.MaxConcurrentLookups == 0 {
+		config.MaxConcurrentLookups = 100
+	}
 
 	if config.ConflictBackoff.Max == 0 {
 		config.ConflictBackoff.Max = 5 * time.Minute
@@ -160,6 +193,16 @@ func setConfigDefaults(config *badgerauth.Config) {
 		config.ConflictBackoff.Min = 100 * time.Millisecond
 	}
 
+	if config.EncryptionKeyRotationDuration == 0 {
+		config.EncryptionKeyRotationDuration = 240 * time.Hour
+	}
+	if config.BlockCacheSize == 0 {
+		config.BlockCacheSize = 256 * memory.MiB
+	}
+	if config.IndexCacheSize == 0 {
+		config.IndexCacheSize = 64 * memory.MiB
+	}
+
 	if config.Backup.Interval == 0 {
 		config.Backup.Interval = time.Hour
 	}
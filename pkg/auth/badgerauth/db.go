@@ -25,13 +25,16 @@ var (
 	ProtoError = errs.Class("proto")
 
 	// ErrKeyAlreadyExists is an error returned when putting a key that exists.
-	ErrKeyAlreadyExists = Error.New("key already exists")
+	ErrKeyAlreadyExists = Error.Wrap(authdb.ErrKeyAlreadyExists)
 
 	// ErrDBStartedWithDifferentNodeID is returned when a database is started with a different node id.
 	ErrDBStartedWithDifferentNodeID = errs.Class("wrong node id")
 
-	errOperationNotSupported           = Error.New("operation not supported")
-	errKeyAlreadyExistsRecordsNotEqual = Error.New("key already exists and records aren't equal")
+	// ErrKeyAlreadyExistsRecordsNotEqual is an error returned when inserting a
+	// record with a key that exists, and the records differ.
+	ErrKeyAlreadyExistsRecordsNotEqual = Error.New("key already exists and records aren't equal")
+
+	errOperationNotSupported = Error.New("operation not supported")
 )
 
 // DB represents authentication storage based on BadgerDB.
@@ -41,6 +44,11 @@ type DB struct {
 	db  *badger.DB
 
 	config Config
+
+	// invalidations, if set, is where records invalidated, unpublished or
+	// deleted through Admin, and records replicated invalidated, are
+	// published.
+	invalidations *authdb.InvalidationFeed
 }
 
 // OpenDB opens the underlying storage engine for badgerauth node.
@@ -54,27 +62,20 @@ func OpenDB(log *zap.Logger, config Config) (*DB, error) {
 		config: config,
 	}
 
-	opt := badger.DefaultOptions(config.Path)
-
-	if inMemory := config.Path == ""; inMemory {
+	if config.Path == "" {
 		log.Warn("in-memory mode enabled. All data will be lost on shutdown!")
-		opt = opt.WithInMemory(inMemory)
 	}
 
-	// We want to fsync after each write to ensure we don't lose data:
-	opt = opt.WithSyncWrites(true)
-	opt = opt.WithCompactL0OnClose(true)
-	// Currently, we don't want to compress because authservice is mostly
-	// deployed in environments where filesystem-level compression is on:
-	opt = opt.WithCompression(options.None)
-	// If compression and encryption are disabled, adding a cache will lead to
-	// unnecessary overhead affecting read performance. Let's disable it then:
-	opt = opt.WithBlockCacheSize(0)
-	opt = opt.WithLogger(badgerLogger{log.Sugar().Named("storage")})
+	opt, err := badgerOptions(log, config)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
 
-	var err error
 	db.db, err = badger.Open(opt)
 	if err != nil {
+		if errs.Is(err, badger.ErrEncryptionKeyMismatch) && config.EncryptionKeyFile != "" {
+			return nil, Error.New("open: %w (if the storage is unencrypted, migrate it first)", err)
+		}
 		return nil, Error.New("open: %w", err)
 	}
 	if err := db.checkFirstStart(); err != nil {
@@ -85,9 +86,52 @@ func OpenDB(log *zap.Logger, config Config) (*DB, error) {
 		_ = db.db.Close()
 		return nil, Error.New("prepare: %w", err)
 	}
+	if err := db.buildIndex(); err != nil {
+		_ = db.db.Close()
+		return nil, Error.New("buildIndex: %w", err)
+	}
 	return db, nil
 }
 
+// badgerOptions returns options for opening the underlying storage engine
+// configured by config.
+func badgerOptions(log *zap.Logger, config Config) (badger.Options, error) {
+	opt := badger.DefaultOptions(config.Path)
+
+	if inMemory := config.Path == ""; inMemory {
+		opt = opt.WithInMemory(inMemory)
+	}
+
+	// We want to fsync after each write to ensure we don't lose data:
+	opt = opt.WithSyncWrites(true)
+	opt = opt.WithCompactL0OnClose(true)
+	// Currently, we don't want to compress because authservice is mostly
+	// deployed in environments where filesystem-level compression is on:
+	opt = opt.WithCompression(options.None)
+
+	if config.EncryptionKeyFile != "" {
+		key, err := LoadEncryptionKey(config.EncryptionKeyFile)
+		if err != nil {
+			return opt, err
+		}
+		opt = opt.WithEncryptionKey(key)
+		opt = opt.WithEncryptionKeyRotationDuration(config.EncryptionKeyRotationDuration)
+		// Encryption requires caches so that blocks and indices aren't
+		// decrypted on every read:
+		opt = opt.WithBlockCacheSize(config.BlockCacheSize.Int64())
+		opt = opt.WithIndexCacheSize(config.IndexCacheSize.Int64())
+	} else {
+		// If compression and encryption are disabled, adding a cache will lead
+		// to unnecessary overhead affecting read performance. Let's disable it
+		// then:
+		opt = opt.WithBlockCacheSize(0)
+	}
+
+	opt = opt.WithLogger(badgerLogger{log.Sugar().Named("storage")})
+
+	return opt, nil
+}
+
 // gcValueLog garbage collects value log. It always returns a nil error.
 func (db *DB) gcValueLog(ctx context.Context) (err error) {
 	defer mon.Task()(&ctx)(nil)
@@ -229,6 +273,54 @@ func (db *DB) Get(ctx context.Context, keyHash authdb.KeyHash) (record *authdb.R
 	}))
 }
 
+// Range calls fn for each record in the key/value store, skipping invalid ones.
+// Records are visited in the order of the replication log. It stops and returns
+// the error if fn returns one.
+func (db *DB) Range(ctx context.Context, fn func(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record) error) (err error) {
+	defer mon.Task(db.eventTags()...)(&ctx)(&err)
+
+	return db.db.View(func(txn *badger.Txn) error {
+		opt := badger.DefaultIteratorOptions
+		opt.PrefetchValues = false
+		opt.Prefix = []byte(replicationLogPrefix)
+
+		it := txn.NewIterator(opt)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			var entry ReplicationLogEntry
+			if err := entry.SetBytes(it.Item().Key()); err != nil {
+				return Error.Wrap(err)
+			}
+
+			r, err := lookupRecordWithTxn(txn, entry.KeyHash)
+			if err != nil {
+				if errs.Is(err, badger.ErrKeyNotFound) {
+					continue // the record has just expired
+				}
+				return Error.Wrap(err)
+			}
+
+			if r.InvalidationReason != "" {
+				continue
+			}
+
+			if err = fn(ctx, entry.KeyHash, &authdb.Record{
+				SatelliteAddress:     r.SatelliteAddress,
+				MacaroonHead:         r.MacaroonHead,
+				EncryptedSecretKey:   r.EncryptedSecretKey,
+				EncryptedAccessGrant: r.EncryptedAccessGrant,
+				ExpiresAt:            timestampToTime(r.ExpiresAtUnix),
+				Public:               r.Public,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
 // DeleteUnused always returns an error because expiring records are deleted by
 // default.
 func (db *DB) DeleteUnused(context.Context, time.Duration, int, int) (int64, int64, map[string]int64, error) {
@@ -315,6 +407,7 @@ func (db *DB) findResponseEntries(nodeID NodeID, clock Clock) ([]*pb.Replication
 				NodeId:            entry.ID.Bytes(),
 				EncryptionKeyHash: entry.KeyHash.Bytes(),
 				Record:            r,
+				Clock:             uint64(entry.Clock),
 			})
 			count++
 		}
@@ -343,8 +436,17 @@ func (db *DB) buildRequestEntries() ([]*pb.ReplicationRequestEntry, error) {
 			return err
 		}
 
+		decommissioned, err := readDecommissioned(txn)
+		if err != nil {
+			return err
+		}
+
 		// We don't need the local node ID in the replication request.
 		delete(availableClocks, db.config.ID)
+		// Nor do we need clocks of decommissioned nodes.
+		for id := range decommissioned {
+			delete(availableClocks, id)
+		}
 
 		for id, clock := range availableClocks {
 			request = append(request, &pb.ReplicationRequestEntry{
@@ -357,29 +459,101 @@ func (db *DB) buildRequestEntries() ([]*pb.ReplicationRequestEntry, error) {
 	}))
 }
 
+// readClock reads the current clock value for id. It returns 0 if the clock
+// doesn't exist yet.
+func (db *DB) readClock(id NodeID) (clock Clock, err error) {
+	return clock, Error.Wrap(db.db.View(func(txn *badger.Txn) error {
+		clock, err = ReadClock(txn, id)
+		if errs.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		return err
+	}))
+}
+
+// readClusterClocks reads all available clocks and decommissioned node IDs.
+func (db *DB) readClusterClocks() (clocks map[NodeID]Clock, decommissioned map[NodeID]struct{}, err error) {
+	return clocks, decommissioned, Error.Wrap(db.db.View(func(txn *badger.Txn) error {
+		if clocks, err = readAvailableClocks(txn); err != nil {
+			return err
+		}
+		decommissioned, err = readDecommissioned(txn)
+		return err
+	}))
+}
+
 func (db *DB) insertResponseEntries(ctx context.Context, response *pb.ReplicationResponse) (err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	return Error.Wrap(db.txnWithBackoff(ctx, func(txn *badger.Txn) error {
+	var inserted []*pb.ReplicationResponseEntry
+	if err = db.txnWithBackoff(ctx, func(txn *badger.Txn) error {
+		inserted = inserted[:0]
 		for i, entry := range response.Entries {
-			var (
-				id      NodeID
-				keyHash authdb.KeyHash
-			)
-
-			if err = id.SetBytes(entry.NodeId); err != nil {
-				return err
+			ok, err := insertResponseEntry(db.log.Named("insertResponseEntries"), txn, entry)
+			if err != nil {
+				return errs.New("failed to insert entry no. %d: %w", i, err)
 			}
-			if err = keyHash.SetBytes(entry.EncryptionKeyHash); err != nil {
-				return err
+			if ok {
+				inserted = append(inserted, entry)
 			}
+		}
+		return nil
+	}); err != nil {
+		return Error.Wrap(err)
+	}
+
+	db.publishReplicatedInvalidations(inserted)
+
+	return nil
+}
 
-			if err = InsertRecord(db.log.Named("insertResponseEntries"), txn, id, keyHash, entry.Record); err != nil {
-				return errs.New("failed to insert entry no. %d (%x) from %s: %w", i, keyHash, id, err)
+// insertPushedEntries inserts entries pushed by the node with id. Entries must
+// contain all the node's entries later than since. If the local clock for the
+// node is behind since, nothing is inserted. It returns the local clock for the
+// node after the insertion.
+func (db *DB) insertPushedEntries(ctx context.Context, id NodeID, since Clock, entries []*pb.ReplicationResponseEntry) (clock Clock, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var inserted []*pb.ReplicationResponseEntry
+	if err = db.txnWithBackoff(ctx, func(txn *badger.Txn) error {
+		inserted = inserted[:0]
+
+		if clock, err = ReadClock(txn, id); err != nil && !errs.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+
+		if clock < since {
+			return nil // there would be a gap; the pushing node needs to resend
+		}
+
+		for i, entry := range entries {
+			if !bytes.Equal(entry.NodeId, id.Bytes()) {
+				return ProtoError.New("entry no. %d is from %x, not %s", i, entry.NodeId, id)
+			}
+			if entry.Clock == 0 {
+				return ProtoError.New("entry no. %d doesn't have a clock", i)
+			}
+			ok, err := insertResponseEntry(db.log.Named("insertPushedEntries"), txn, entry)
+			if err != nil {
+				return errs.New("failed to insert entry no. %d: %w", i, err)
+			}
+			if ok {
+				inserted = append(inserted, entry)
 			}
 		}
-		return nil
-	}))
+
+		clock, err = ReadClock(txn, id)
+		if errs.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		return err
+	}); err != nil {
+		return clock, Error.Wrap(err)
+	}
+
+	db.publishReplicatedInvalidations(inserted)
+
+	return clock, nil
 }
 
 func (db *DB) lookupRecord(keyHash authdb.KeyHash) (record *pb.Record, err error) {
@@ -412,16 +586,43 @@ func (db *DB) updateRecord(ctx context.Context, keyHash authdb.KeyHash, fn func(
 
 func (db *DB) deleteRecord(ctx context.Context, keyHash authdb.KeyHash) error {
 	return Error.Wrap(db.txnWithBackoff(ctx, func(txn *badger.Txn) error {
-		if _, err := txn.Get(keyHash.Bytes()); err != nil {
+		record, err := lookupRecordWithTxn(txn, keyHash)
+		if err != nil {
 			return err
 		}
 		return errs.Combine(
 			txn.Delete(keyHash.Bytes()),
 			deleteReplicationLogEntries(txn, keyHash),
+			deleteIndexEntries(txn, keyHash, record),
 		)
 	}))
 }
 
+// publishInvalidation publishes keyHash to the invalidation feed if there is
+// one.
+func (db *DB) publishInvalidation(keyHash authdb.KeyHash, reason authdb.InvalidationReason) {
+	if db.invalidations != nil {
+		db.invalidations.Publish(keyHash, reason)
+	}
+}
+
+// publishReplicatedInvalidations publishes records among inserted replicated
+// entries that were invalidated before they were replicated to the
+// invalidation feed if there is one. Such records might have been resolved
+// through another node and cached already.
+func (db *DB) publishReplicatedInvalidations(inserted []*pb.ReplicationResponseEntry) {
+	for _, entry := range inserted {
+		if entry.Record == nil || entry.Record.InvalidatedAtUnix == 0 {
+			continue
+		}
+		var keyHash authdb.KeyHash
+		if err := keyHash.SetBytes(entry.EncryptionKeyHash); err != nil {
+			continue // it wouldn't have been inserted
+		}
+		db.publishInvalidation(keyHash, authdb.InvalidationInvalidated)
+	}
+}
+
 func (db *DB) eventTags() []monkit.SeriesTag {
 	return []monkit.SeriesTag{
 		monkit.NewSeriesTag("node_id", db.config.ID.String()),
@@ -429,7 +630,7 @@ func (db *DB) eventTags() []monkit.SeriesTag {
 }
 
 // InsertRecord inserts a record, adding a corresponding replication log entry
-// consistent with the record's state.
+// consistent with the record's state and index entries.
 //
 // InsertRecord can be used to insert on any node for any node.
 func InsertRecord(log *zap.Logger, txn *badger.Txn, nodeID NodeID, keyHash authdb.KeyHash, record *pb.Record) error {
@@ -453,7 +654,7 @@ func InsertRecord(log *zap.Logger, txn *badger.Txn, nodeID NodeID, keyHash authd
 		if !recordsEqual(record, &loaded) {
 			log.Warn("encountered duplicate key, but values aren't equal", nodeIDField, keyHashField)
 			mon.Event("as_badgerauth_duplicate_key", monkit.NewSeriesTag("values_equal", "false"))
-			return errKeyAlreadyExistsRecordsNotEqual
+			return ErrKeyAlreadyExistsRecordsNotEqual
 		}
 		log.Info("encountered duplicate key. See https://github.com/storj/gateway-mt/issues/210", nodeIDField, keyHashField)
 		mon.Event("as_badgerauth_duplicate_key", monkit.NewSeriesTag("values_equal", "true"))
@@ -492,7 +693,50 @@ func InsertRecord(log *zap.Logger, txn *badger.Txn, nodeID NodeID, keyHash authd
 		mon.Event("as_badgerauth_insert")
 	}
 
-	return Error.Wrap(errs.Combine(txn.SetEntry(mainEntry), txn.SetEntry(rlogEntry)))
+	return Error.Wrap(errs.Combine(
+		txn.SetEntry(mainEntry),
+		txn.SetEntry(rlogEntry),
+		setIndexEntries(txn, keyHash, record),
+	))
+}
+
+// insertResponseEntry inserts a replicated entry. If the entry carries the
+// origin node's clock, entries that are already known are skipped, and the
+// local clock for the origin node is set to the entry's clock; otherwise, the
+// local clock is just advanced. It returns whether the entry was inserted.
+func insertResponseEntry(log *zap.Logger, txn *badger.Txn, entry *pb.ReplicationResponseEntry) (inserted bool, err error) {
+	var (
+		id      NodeID
+		keyHash authdb.KeyHash
+	)
+
+	if err := id.SetBytes(entry.NodeId); err != nil {
+		return false, err
+	}
+	if err := keyHash.SetBytes(entry.EncryptionKeyHash); err != nil {
+		return false, err
+	}
+
+	if entry.Clock == 0 {
+		if err := InsertRecord(log, txn, id, keyHash, entry.Record); err != nil {
+			return false, errs.New("%x from %s: %w", keyHash, id, err)
+		}
+		return true, nil
+	}
+
+	current, err := ReadClock(txn, id)
+	if err != nil && !errs.Is(err, badger.ErrKeyNotFound) {
+		return false, err
+	}
+	if Clock(entry.Clock) <= current {
+		return false, nil // already known (e.g., pushed and replicated concurrently)
+	}
+
+	if err = InsertRecord(log, txn, id, keyHash, entry.Record); err != nil {
+		return false, errs.New("%x from %s: %w", keyHash, id, err)
+	}
+
+	return true, ClockError.Wrap(txn.Set(makeClockKey(id), Clock(entry.Clock).Bytes()))
 }
 
 func lookupRecordWithTxn(txn *badger.Txn, keyHash authdb.KeyHash) (*pb.Record, error) {
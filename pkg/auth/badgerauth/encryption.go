@@ -0,0 +1,159 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+
+	badger "github.com/outcaste-io/badger/v3"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// EncryptionError is a class of encryption at rest errors.
+var EncryptionError = errs.Class("encryption")
+
+// LoadEncryptionKey loads the key for encrypting data at rest from path. The
+// file must contain a 16, 24 or 32-byte key (AES-128, AES-192 or AES-256,
+// respectively), either raw or hex-encoded. Contents that are valid hex are
+// always decoded, so e.g. 32 hex characters are a 16-byte key, not a raw
+// 32-byte one.
+func LoadEncryptionKey(path string) ([]byte, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, EncryptionError.New("failed to read key file %q: %w", path, err)
+	}
+
+	if key, err := hex.DecodeString(string(bytes.TrimSpace(contents))); err == nil {
+		if !isValidEncryptionKeyLength(len(key)) {
+			return nil, EncryptionError.New("key file %q must contain a 16, 24 or 32-byte key, but it contains a hex-encoded %d-byte key", path, len(key))
+		}
+		return key, nil
+	}
+
+	if !isValidEncryptionKeyLength(len(contents)) {
+		return nil, EncryptionError.New("key file %q must contain a 16, 24 or 32-byte key (raw or hex-encoded)", path)
+	}
+
+	return contents, nil
+}
+
+func isValidEncryptionKeyLength(n int) bool {
+	return n == 16 || n == 24 || n == 32
+}
+
+// MigrateToEncrypted copies all data from the unencrypted storage at srcPath to
+// config.Path, encrypting it with the key from config.EncryptionKeyFile. The
+// destination storage must be empty, and neither storage may be in use.
+func MigrateToEncrypted(ctx context.Context, log *zap.Logger, srcPath string, config Config) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	switch {
+	case config.EncryptionKeyFile == "":
+		return EncryptionError.New("encryption key file is required")
+	case srcPath == "" || config.Path == "":
+		return EncryptionError.New("source and destination paths are required")
+	case filepath.Clean(srcPath) == filepath.Clean(config.Path):
+		return EncryptionError.New("source and destination paths must differ")
+	}
+
+	srcOpt := badger.DefaultOptions(srcPath).
+		WithReadOnly(true).
+		WithLogger(badgerLogger{log.Sugar().Named("source")})
+
+	src, err := badger.Open(srcOpt)
+	if err != nil {
+		return EncryptionError.New("failed to open source: %w", err)
+	}
+	defer func() { err = errs.Combine(err, src.Close()) }()
+
+	dstOpt, err := badgerOptions(log.Named("destination"), config)
+	if err != nil {
+		return EncryptionError.Wrap(err)
+	}
+
+	dst, err := badger.Open(dstOpt)
+	if err != nil {
+		return EncryptionError.New("failed to open destination: %w", err)
+	}
+	defer func() { err = errs.Combine(err, dst.Close()) }()
+
+	empty, err := isEmpty(dst)
+	if err != nil {
+		return EncryptionError.Wrap(err)
+	}
+	if !empty {
+		return EncryptionError.New("destination %q is not empty", config.Path)
+	}
+
+	pr, pw := io.Pipe()
+
+	var g errgroup.Group
+	g.Go(func() error {
+		_, err := src.Backup(pw, 0)
+		return pw.CloseWithError(err)
+	})
+
+	loadErr := dst.Load(pr, 256)
+	_ = pr.CloseWithError(loadErr)
+
+	if err = errs.Combine(g.Wait(), loadErr); err != nil {
+		return EncryptionError.New("failed to copy data: %w", err)
+	}
+
+	log.Info("migrated storage to encrypted storage", zap.String("source", srcPath), zap.String("destination", config.Path))
+
+	return nil
+}
+
+// RotateEncryptionKey re-encrypts data keys of the storage at config.Path,
+// currently encrypted with the key from oldKeyFile, with the key from
+// config.EncryptionKeyFile. The storage may not be in use.
+func RotateEncryptionKey(config Config, oldKeyFile string) error {
+	oldKey, err := LoadEncryptionKey(oldKeyFile)
+	if err != nil {
+		return err
+	}
+	newKey, err := LoadEncryptionKey(config.EncryptionKeyFile)
+	if err != nil {
+		return err
+	}
+
+	opt := badger.KeyRegistryOptions{
+		Dir:                           config.Path,
+		ReadOnly:                      true,
+		EncryptionKey:                 oldKey,
+		EncryptionKeyRotationDuration: config.EncryptionKeyRotationDuration,
+	}
+
+	registry, err := badger.OpenKeyRegistry(opt)
+	if err != nil {
+		return EncryptionError.New("failed to open key registry: %w", err)
+	}
+
+	opt.EncryptionKey = newKey
+
+	return EncryptionError.Wrap(errs.Combine(badger.WriteKeyRegistry(registry, opt), registry.Close()))
+}
+
+func isEmpty(db *badger.DB) (empty bool, err error) {
+	return empty, db.View(func(txn *badger.Txn) error {
+		opt := badger.DefaultIteratorOptions
+		opt.PrefetchValues = false
+
+		it := txn.NewIterator(opt)
+		defer it.Close()
+
+		it.Rewind()
+		empty = !it.Valid()
+
+		return nil
+	})
+}
@@ -0,0 +1,227 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauth_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/common/memory"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/badgerauth"
+)
+
+func TestLoadEncryptionKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	key := testrand.BytesInt(32)
+
+	raw := ctx.File("raw.key")
+	require.NoError(t, os.WriteFile(raw, key, 0600))
+	loaded, err := badgerauth.LoadEncryptionKey(raw)
+	require.NoError(t, err)
+	assert.Equal(t, key, loaded)
+
+	encoded := ctx.File("hex.key")
+	require.NoError(t, os.WriteFile(encoded, []byte(hex.EncodeToString(key[:16])+"\n"), 0600))
+	loaded, err = badgerauth.LoadEncryptionKey(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, key[:16], loaded)
+
+	// 32 hex characters without a trailing newline are a hex-encoded 16-byte
+	// key, not a raw 32-byte one.
+	unterminated := ctx.File("unterminated.key")
+	require.NoError(t, os.WriteFile(unterminated, []byte(hex.EncodeToString(key[:16])), 0600))
+	loaded, err = badgerauth.LoadEncryptionKey(unterminated)
+	require.NoError(t, err)
+	assert.Equal(t, key[:16], loaded)
+
+	tooShort := ctx.File("too-short.key")
+	require.NoError(t, os.WriteFile(tooShort, []byte(hex.EncodeToString(key[:8])), 0600))
+	_, err = badgerauth.LoadEncryptionKey(tooShort)
+	require.Error(t, err)
+	assert.True(t, badgerauth.EncryptionError.Has(err))
+
+	invalid := ctx.File("invalid.key")
+	require.NoError(t, os.WriteFile(invalid, key[:10], 0600))
+	_, err = badgerauth.LoadEncryptionKey(invalid)
+	require.Error(t, err)
+	assert.True(t, badgerauth.EncryptionError.Has(err))
+
+	_, err = badgerauth.LoadEncryptionKey(ctx.File("missing.key"))
+	require.Error(t, err)
+}
+
+func TestEncryptionAtRest(t *testing.T) {
+	t.Parallel()
+
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+	defer ctx.Check(log.Sync)
+
+	config := encryptedConfig(ctx, t, "encrypted", "node.key")
+
+	marker := putMarker(ctx, t, config)
+
+	assert.False(t, containsInFiles(t, config.Path, marker), "storage contains plaintext")
+
+	// reopening with the same key works
+	db, err := badgerauth.OpenDB(log, config)
+	require.NoError(t, err)
+	record, err := db.Get(ctx, authdb.KeyHash{'m'})
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, marker, record.MacaroonHead)
+	require.NoError(t, db.Close())
+
+	// reopening with a different key or without a key doesn't
+	wrongKey := encryptedConfig(ctx, t, "encrypted", "wrong.key")
+	_, err = badgerauth.OpenDB(log, wrongKey)
+	require.Error(t, err)
+
+	noKey := config
+	noKey.EncryptionKeyFile = ""
+	_, err = badgerauth.OpenDB(log, noKey)
+	require.Error(t, err)
+}
+
+func TestMigrateToEncrypted(t *testing.T) {
+	t.Parallel()
+
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+	defer ctx.Check(log.Sync)
+
+	src := encryptedConfig(ctx, t, "plain", "")
+	src.EncryptionKeyFile = ""
+
+	marker := putMarker(ctx, t, src)
+	require.True(t, containsInFiles(t, src.Path, marker))
+
+	dst := encryptedConfig(ctx, t, "migrated", "node.key")
+
+	require.Error(t, badgerauth.MigrateToEncrypted(ctx, log, src.Path, src))
+	require.Error(t, badgerauth.MigrateToEncrypted(ctx, log, dst.Path, dst))
+	require.NoError(t, badgerauth.MigrateToEncrypted(ctx, log, src.Path, dst))
+	// the destination isn't empty anymore
+	require.Error(t, badgerauth.MigrateToEncrypted(ctx, log, src.Path, dst))
+
+	assert.False(t, containsInFiles(t, dst.Path, marker), "storage contains plaintext")
+
+	dst.FirstStart = false
+
+	db, err := badgerauth.OpenDB(log, dst)
+	require.NoError(t, err)
+	defer ctx.Check(db.Close)
+
+	record, err := db.Get(ctx, authdb.KeyHash{'m'})
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, marker, record.MacaroonHead)
+}
+
+func TestRotateEncryptionKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+	defer ctx.Check(log.Sync)
+
+	old := encryptedConfig(ctx, t, "rotated", "old.key")
+	marker := putMarker(ctx, t, old)
+
+	rotated := encryptedConfig(ctx, t, "rotated", "new.key")
+	require.Error(t, badgerauth.RotateEncryptionKey(rotated, ctx.File("missing.key")))
+	require.NoError(t, badgerauth.RotateEncryptionKey(rotated, old.EncryptionKeyFile))
+
+	_, err := badgerauth.OpenDB(log, old)
+	require.Error(t, err)
+
+	db, err := badgerauth.OpenDB(log, rotated)
+	require.NoError(t, err)
+	defer ctx.Check(db.Close)
+
+	record, err := db.Get(ctx, authdb.KeyHash{'m'})
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, marker, record.MacaroonHead)
+}
+
+// encryptedConfig returns configuration for storage at dir encrypted with a key
+// from keyFile. The key is created if it doesn't exist.
+func encryptedConfig(ctx *testcontext.Context, t *testing.T, dir, keyFile string) badgerauth.Config {
+	config := badgerauth.Config{
+		ID:                            badgerauth.NodeID{'e', 'n', 'c'},
+		FirstStart:                    true,
+		Path:                          ctx.Dir(dir),
+		EncryptionKeyRotationDuration: time.Hour,
+		BlockCacheSize:                memory.MiB,
+		IndexCacheSize:                memory.MiB,
+	}
+	config.ConflictBackoff.Max = time.Minute
+
+	if keyFile != "" {
+		config.EncryptionKeyFile = ctx.File(keyFile)
+		if _, err := os.Stat(config.EncryptionKeyFile); os.IsNotExist(err) {
+			require.NoError(t, os.WriteFile(config.EncryptionKeyFile, testrand.BytesInt(32), 0600))
+		}
+	}
+
+	return config
+}
+
+// putMarker puts a record containing a random marker into the storage
+// configured by config and closes it.
+func putMarker(ctx *testcontext.Context, t *testing.T, config badgerauth.Config) []byte {
+	db, err := badgerauth.OpenDB(zaptest.NewLogger(t), config)
+	require.NoError(t, err)
+
+	marker := testrand.RandAlphaNumeric(64)
+	require.NoError(t, db.Put(ctx, authdb.KeyHash{'m'}, &authdb.Record{
+		SatelliteAddress:     "satellite",
+		MacaroonHead:         marker,
+		EncryptedSecretKey:   []byte{'s'},
+		EncryptedAccessGrant: []byte{'g'},
+	}))
+	require.NoError(t, db.Close())
+
+	return marker
+}
+
+func containsInFiles(t *testing.T, dir string, needle []byte) (found bool) {
+	require.NoError(t, filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if bytes.Contains(contents, needle) {
+			found = true
+		}
+		return nil
+	}))
+	return found
+}
@@ -52,7 +52,7 @@ func errorName(err error) (name string, ok bool) {
 		name = "KeyAlreadyExists"
 	case errs.Is(err, errOperationNotSupported):
 		name = "OperationNotSupported"
-	case errs.Is(err, errKeyAlreadyExistsRecordsNotEqual):
+	case errs.Is(err, ErrKeyAlreadyExistsRecordsNotEqual):
 		name = "KeyAlreadyExistsRecordsNotEqual"
 	case errs.Is(err, badger.ErrKeyNotFound):
 		name = "KeyNotFound"
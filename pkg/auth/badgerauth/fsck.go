@@ -0,0 +1,368 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	badger "github.com/outcaste-io/badger/v3"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/pb"
+)
+
+// FsckError is a class of fsck errors.
+var FsckError = errs.Class("fsck")
+
+// FsckProblemKind is a kind of inconsistency found by Fsck.
+type FsckProblemKind string
+
+const (
+	// FsckNodeIDMismatch means the storage belongs to a different node ID
+	// than configured (or it has none).
+	FsckNodeIDMismatch FsckProblemKind = "node-id-mismatch"
+	// FsckMalformedRecord means a record doesn't unmarshal as pb.Record.
+	FsckMalformedRecord FsckProblemKind = "malformed-record"
+	// FsckMalformedLogEntry means a replication log entry key can't be
+	// parsed.
+	FsckMalformedLogEntry FsckProblemKind = "malformed-log-entry"
+	// FsckMissingLogEntry means a record has no replication log entry.
+	FsckMissingLogEntry FsckProblemKind = "missing-log-entry"
+	// FsckOrphanedLogEntry means a replication log entry has no record.
+	FsckOrphanedLogEntry FsckProblemKind = "orphaned-log-entry"
+	// FsckClockBehind means a clock is missing or lower than the highest
+	// replication log entry of its node ID.
+	FsckClockBehind FsckProblemKind = "clock-behind"
+	// FsckMissingIndexEntry means a record is missing from the record index.
+	FsckMissingIndexEntry FsckProblemKind = "missing-index-entry"
+	// FsckOrphanedIndexEntry means a record index entry has no record.
+	FsckOrphanedIndexEntry FsckProblemKind = "orphaned-index-entry"
+)
+
+// FsckProblem is an inconsistency found by Fsck.
+type FsckProblem struct {
+	Kind        FsckProblemKind
+	Description string
+	Repaired    bool
+}
+
+// FsckReport is the result of Fsck.
+type FsckReport struct {
+	NodeID     NodeID
+	Records    int
+	LogEntries int
+	Clocks     int
+	Problems   []FsckProblem
+}
+
+// OK returns whether there are no unrepaired problems.
+func (report *FsckReport) OK() bool {
+	for _, p := range report.Problems {
+		if !p.Repaired {
+			return false
+		}
+	}
+	return true
+}
+
+func (report *FsckReport) add(kind FsckProblemKind, format string, args ...interface{}) *FsckProblem {
+	report.Problems = append(report.Problems, FsckProblem{
+		Kind:        kind,
+		Description: fmt.Sprintf(format, args...),
+	})
+	return &report.Problems[len(report.Problems)-1]
+}
+
+// fsckState is what Fsck collects while scanning the storage. Records are
+// checked as they're scanned, so only key hashes are kept in memory.
+type fsckState struct {
+	logged   map[authdb.KeyHash]struct{}
+	orphans  [][]byte
+	unlogged []authdb.KeyHash
+	maxLog   map[NodeID]Clock
+	clocks   map[NodeID]Clock
+
+	// indexed is whether the record index has been built; it's not checked
+	// otherwise, as it's built when the node starts.
+	indexed      bool
+	unindexed    []authdb.KeyHash
+	indexOrphans [][]byte
+}
+
+// Fsck checks the consistency of the storage at config.Path, which may not be
+// in use. It checks that:
+//
+//   - the storage belongs to config.ID (if set);
+//   - records unmarshal as pb.Record;
+//   - every record has a replication log entry, and vice versa;
+//   - clocks are at least the highest replication log entry per node ID;
+//   - every record is in the record index, and vice versa.
+//
+// The storage is opened read-only unless repair is true, in which case
+// orphaned replication log and index entries are deleted, clocks are raised to
+// the highest replication log entry, records without replication log entries
+// get fresh ones of the storage's node ID, and missing index entries are
+// added. Problems that can't be repaired are only reported.
+func Fsck(ctx context.Context, log *zap.Logger, config Config, repair bool) (_ *FsckReport, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if config.Path == "" {
+		return nil, FsckError.New("path must be set")
+	}
+
+	opt, err := badgerOptions(log, config)
+	if err != nil {
+		return nil, FsckError.Wrap(err)
+	}
+	opt = opt.WithReadOnly(!repair)
+
+	db, err := badger.Open(opt)
+	if err != nil {
+		return nil, FsckError.New("open: %w", err)
+	}
+	defer func() { err = errs.Combine(err, FsckError.Wrap(db.Close())) }()
+
+	report := &FsckReport{}
+	state := fsckState{
+		logged: make(map[authdb.KeyHash]struct{}),
+		maxLog: make(map[NodeID]Clock),
+	}
+
+	if err = db.View(func(txn *badger.Txn) error {
+		return fsckScan(txn, config, report, &state)
+	}); err != nil {
+		return nil, FsckError.Wrap(err)
+	}
+
+	report.Clocks = len(state.clocks)
+
+	// Orphaned log entries break replication of their node ID, as records
+	// for them can't be found.
+	for _, key := range state.orphans {
+		var entry ReplicationLogEntry
+		_ = entry.SetBytes(key) // already parsed while scanning
+		p := report.add(FsckOrphanedLogEntry, "replication log entry %s/%d has no record %x", entry.ID, entry.Clock, entry.KeyHash)
+		if repair {
+			if err = db.Update(func(txn *badger.Txn) error {
+				return txn.Delete(key)
+			}); err != nil {
+				return report, FsckError.Wrap(err)
+			}
+			p.Repaired = true
+		}
+	}
+
+	for id, max := range state.maxLog {
+		clock, ok := state.clocks[id]
+		if ok && clock >= max {
+			continue
+		}
+		var p *FsckProblem
+		if !ok {
+			p = report.add(FsckClockBehind, "clock of %s is missing (highest replication log entry: %d)", id, max)
+		} else {
+			p = report.add(FsckClockBehind, "clock of %s is %d (highest replication log entry: %d)", id, clock, max)
+		}
+		if repair {
+			if err = db.Update(func(txn *badger.Txn) error {
+				return txn.Set(makeClockKey(id), max.Bytes())
+			}); err != nil {
+				return report, FsckError.Wrap(err)
+			}
+			p.Repaired = true
+		}
+	}
+
+	for _, keyHash := range state.unlogged {
+		p := report.add(FsckMissingLogEntry, "record %x has no replication log entry", keyHash)
+		if repair && report.NodeID != (NodeID{}) {
+			// The origin is unknown, so the record gets a fresh entry as if
+			// it was created on this node. Peers that already have it ignore
+			// it as a duplicate.
+			keyHash := keyHash
+			if err = db.Update(func(txn *badger.Txn) error {
+				record, err := lookupRecordWithTxn(txn, keyHash)
+				if err != nil {
+					return err
+				}
+				return InsertRecord(log, txn, report.NodeID, keyHash, record)
+			}); err != nil {
+				return report, FsckError.Wrap(err)
+			}
+			p.Repaired = true
+		}
+	}
+
+	for _, key := range state.indexOrphans {
+		p := report.add(FsckOrphanedIndexEntry, "index entry %x has no record", key)
+		if repair {
+			if err = db.Update(func(txn *badger.Txn) error {
+				return txn.Delete(key)
+			}); err != nil {
+				return report, FsckError.Wrap(err)
+			}
+			p.Repaired = true
+		}
+	}
+
+	for _, keyHash := range state.unindexed {
+		p := report.add(FsckMissingIndexEntry, "record %x is missing from the record index", keyHash)
+		if repair {
+			keyHash := keyHash
+			if err = db.Update(func(txn *badger.Txn) error {
+				record, err := lookupRecordWithTxn(txn, keyHash)
+				if err != nil {
+					return err
+				}
+				return setIndexEntries(txn, keyHash, record)
+			}); err != nil {
+				return report, FsckError.Wrap(err)
+			}
+			p.Repaired = true
+		}
+	}
+
+	return report, nil
+}
+
+func fsckScan(txn *badger.Txn, config Config, report *FsckReport, state *fsckState) error {
+	item, err := txn.Get([]byte(nodeIDKey))
+	switch {
+	case errs.Is(err, badger.ErrKeyNotFound):
+		report.add(FsckNodeIDMismatch, "storage has no node ID")
+	case err != nil:
+		return err
+	default:
+		if err = item.Value(func(val []byte) error {
+			return report.NodeID.SetBytes(val)
+		}); err != nil {
+			return err
+		}
+		if config.ID != (NodeID{}) && report.NodeID != config.ID {
+			report.add(FsckNodeIDMismatch, "storage belongs to %s, not %s", report.NodeID, config.ID)
+		}
+	}
+
+	if state.clocks, err = readAvailableClocks(txn); err != nil {
+		return err
+	}
+
+	switch _, err = txn.Get([]byte(recordIndexVersionKey)); {
+	case err == nil:
+		state.indexed = true
+	case !errs.Is(err, badger.ErrKeyNotFound):
+		return err
+	}
+
+	if err = fsckScanLog(txn, report, state); err != nil {
+		return err
+	}
+
+	opt := badger.DefaultIteratorOptions
+	opt.PrefetchValues = false
+
+	it := txn.NewIterator(opt)
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		item := it.Item()
+		key := item.Key()
+
+		switch {
+		case bytes.HasPrefix(key, []byte(recordIndexPrefix)):
+			if !state.indexed {
+				continue
+			}
+
+			_, keyHash, err := parseIndexSuffix(key)
+			if err == nil {
+				_, err = txn.Get(keyHash.Bytes())
+			}
+			if err != nil {
+				if !errs.Is(err, badger.ErrKeyNotFound) && !IndexError.Has(err) {
+					return err
+				}
+				state.indexOrphans = append(state.indexOrphans, item.KeyCopy(nil))
+			}
+		case len(key) == len(authdb.KeyHash{}):
+			var keyHash authdb.KeyHash
+			if err := keyHash.SetBytes(key); err != nil {
+				return err
+			}
+
+			var record pb.Record
+			if err := item.Value(func(val []byte) error {
+				return pb.Unmarshal(val, &record)
+			}); err != nil {
+				// No log entry is created for it, so it's not checked
+				// further.
+				report.add(FsckMalformedRecord, "record %x: %v", keyHash, err)
+				continue
+			}
+
+			report.Records++
+
+			if _, ok := state.logged[keyHash]; !ok {
+				state.unlogged = append(state.unlogged, keyHash)
+			}
+
+			if !state.indexed {
+				continue
+			}
+			for _, key := range indexKeys(keyHash, &record) {
+				if _, err := txn.Get(key); err != nil {
+					if !errs.Is(err, badger.ErrKeyNotFound) {
+						return err
+					}
+					state.unindexed = append(state.unindexed, keyHash)
+					break
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// fsckScanLog scans the replication log, so records can be checked against
+// it as they're scanned.
+func fsckScanLog(txn *badger.Txn, report *FsckReport, state *fsckState) error {
+	opt := badger.DefaultIteratorOptions
+	opt.PrefetchValues = false
+	opt.Prefix = []byte(replicationLogPrefix)
+
+	it := txn.NewIterator(opt)
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		item := it.Item()
+		key := item.Key()
+
+		report.LogEntries++
+
+		var entry ReplicationLogEntry
+		if err := entry.SetBytes(key); err != nil {
+			report.add(FsckMalformedLogEntry, "%x: %v", key, err)
+			continue
+		}
+
+		if entry.Clock > state.maxLog[entry.ID] {
+			state.maxLog[entry.ID] = entry.Clock
+		}
+		state.logged[entry.KeyHash] = struct{}{}
+
+		if _, err := txn.Get(entry.KeyHash.Bytes()); err != nil {
+			if !errs.Is(err, badger.ErrKeyNotFound) {
+				return err
+			}
+			state.orphans = append(state.orphans, item.KeyCopy(nil))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauth_test
+
+import (
+	"testing"
+
+	badger "github.com/outcaste-io/badger/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/common/testcontext"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/badgerauth"
+)
+
+func TestFsck(t *testing.T) {
+	t.Parallel()
+
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	config := badgerauth.Config{
+		FirstStart: true,
+		Path:       ctx.Dir("storage"),
+	}
+	require.NoError(t, config.ID.Set("fsck"))
+
+	db, err := badgerauth.OpenDB(log, config)
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.Put(ctx, authdb.KeyHash{byte(i)}, &authdb.Record{
+			SatelliteAddress:     "t",
+			MacaroonHead:         []byte{'h'},
+			EncryptedSecretKey:   []byte{'s'},
+			EncryptedAccessGrant: []byte{'g'},
+		}))
+	}
+
+	var other badgerauth.NodeID
+	require.NoError(t, other.Set("other"))
+
+	require.NoError(t, db.UnderlyingDB().Update(func(txn *badger.Txn) error {
+		// Record 0 loses its log entry.
+		if err := txn.Delete(badgerauth.ReplicationLogEntry{ID: config.ID, Clock: 1, KeyHash: authdb.KeyHash{0}}.Bytes()); err != nil {
+			return err
+		}
+		// Record 1 is gone, but its log entry (and index entries) aren't.
+		if err := txn.Delete(authdb.KeyHash{1}.Bytes()); err != nil {
+			return err
+		}
+		// Record 2 gets a log entry of another node without a clock.
+		if err := txn.SetEntry(badgerauth.ReplicationLogEntry{ID: other, Clock: 7, KeyHash: authdb.KeyHash{2}}.ToBadgerEntry()); err != nil {
+			return err
+		}
+		// Record 9 doesn't unmarshal.
+		return txn.Set(authdb.KeyHash{9}.Bytes(), []byte{0xff, 0xff})
+	}))
+	// Records lose their creation time index entries.
+	require.NoError(t, db.UnderlyingDB().DropPrefix([]byte("record_index/created/")))
+	require.NoError(t, db.Close())
+
+	kinds := func(report *badgerauth.FsckReport) map[badgerauth.FsckProblemKind]bool {
+		kinds := make(map[badgerauth.FsckProblemKind]bool)
+		for _, p := range report.Problems {
+			kinds[p.Kind] = p.Repaired
+		}
+		return kinds
+	}
+
+	report, err := badgerauth.Fsck(ctx, log, config, false)
+	require.NoError(t, err)
+	assert.Equal(t, config.ID, report.NodeID)
+	assert.Equal(t, 4, report.Records)
+	assert.Equal(t, 5, report.LogEntries)
+	assert.False(t, report.OK())
+	assert.Equal(t, map[badgerauth.FsckProblemKind]bool{
+		badgerauth.FsckMissingLogEntry:    false,
+		badgerauth.FsckOrphanedLogEntry:   false,
+		badgerauth.FsckClockBehind:        false,
+		badgerauth.FsckMalformedRecord:    false,
+		badgerauth.FsckMissingIndexEntry:  false,
+		badgerauth.FsckOrphanedIndexEntry: false,
+	}, kinds(report))
+
+	mismatched := config
+	require.NoError(t, mismatched.ID.Set("mismatched"))
+	report, err = badgerauth.Fsck(ctx, log, mismatched, false)
+	require.NoError(t, err)
+	assert.Contains(t, kinds(report), badgerauth.FsckNodeIDMismatch)
+
+	report, err = badgerauth.Fsck(ctx, log, config, true)
+	require.NoError(t, err)
+	assert.Equal(t, map[badgerauth.FsckProblemKind]bool{
+		badgerauth.FsckMissingLogEntry:    true,
+		badgerauth.FsckOrphanedLogEntry:   true,
+		badgerauth.FsckClockBehind:        true,
+		badgerauth.FsckMalformedRecord:    false,
+		badgerauth.FsckMissingIndexEntry:  true,
+		badgerauth.FsckOrphanedIndexEntry: true,
+	}, kinds(report))
+
+	// Only the malformed record remains.
+	report, err = badgerauth.Fsck(ctx, log, config, false)
+	require.NoError(t, err)
+	assert.Equal(t, map[badgerauth.FsckProblemKind]bool{
+		badgerauth.FsckMalformedRecord: false,
+	}, kinds(report))
+
+	config.FirstStart = false
+	db, err = badgerauth.OpenDB(log, config)
+	require.NoError(t, err)
+	defer ctx.Check(db.Close)
+
+	records, _, err := db.List(ctx, authdb.ListFilter{}, authdb.ListCursor{}, 10)
+	require.NoError(t, err)
+	assert.Len(t, records, 4)
+
+	require.NoError(t, db.UnderlyingDB().View(func(txn *badger.Txn) error {
+		clock, err := badgerauth.ReadClock(txn, other)
+		require.NoError(t, err)
+		assert.EqualValues(t, 7, clock)
+
+		// Record 0 got a fresh log entry.
+		clock, err = badgerauth.ReadClock(txn, config.ID)
+		require.NoError(t, err)
+		assert.EqualValues(t, 6, clock)
+		_, err = txn.Get(badgerauth.ReplicationLogEntry{ID: config.ID, Clock: 6, KeyHash: authdb.KeyHash{0}}.Bytes())
+		return err
+	}))
+}
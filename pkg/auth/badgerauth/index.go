@@ -0,0 +1,276 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"time"
+
+	badger "github.com/outcaste-io/badger/v3"
+	"github.com/zeebo/errs"
+
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/pb"
+)
+
+// Records are indexed by creation time, and by macaroon head and creation
+// time, so they can be listed without scanning the whole database. Index
+// entries have empty values and expire with their records. Their keys are:
+//
+//	record_index/created/<created at (8 bytes)><key hash>
+//	record_index/macaroon_head/<head length (uvarint)><head><created at (8 bytes)><key hash>
+const (
+	recordIndexPrefix       = "record_index/"
+	createdIndexPrefix      = recordIndexPrefix + "created/"
+	macaroonHeadIndexPrefix = recordIndexPrefix + "macaroon_head/"
+
+	// recordIndexVersionKey stores recordIndexVersion once records stored
+	// before the index existed have been indexed.
+	recordIndexVersionKey = "record_index_version"
+	recordIndexVersion    = 1
+)
+
+// IndexError is a class of record index errors.
+var IndexError = errs.Class("record index")
+
+func makeMacaroonHeadIndexPrefix(head []byte) []byte {
+	var length [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(length[:], uint64(len(head)))
+
+	p := make([]byte, 0, len(macaroonHeadIndexPrefix)+n+len(head))
+	p = append(p, macaroonHeadIndexPrefix...)
+	p = append(p, length[:n]...)
+	return append(p, head...)
+}
+
+// appendIndexSuffix appends creation time and key hash, the part of index keys
+// that orders records.
+func appendIndexSuffix(p []byte, createdAtUnix int64, keyHash authdb.KeyHash) []byte {
+	var createdAt [8]byte
+	binary.BigEndian.PutUint64(createdAt[:], uint64(createdAtUnix))
+	p = append(p, createdAt[:]...)
+	return append(p, keyHash.Bytes()...)
+}
+
+// parseIndexSuffix parses what appendIndexSuffix appended to key.
+func parseIndexSuffix(key []byte) (createdAtUnix int64, keyHash authdb.KeyHash, err error) {
+	if len(key) < 8+lenKeyHash {
+		return 0, keyHash, IndexError.New("index key %x is too short", key)
+	}
+	suffix := key[len(key)-8-lenKeyHash:]
+	copy(keyHash[:], suffix[8:])
+	return int64(binary.BigEndian.Uint64(suffix[:8])), keyHash, nil
+}
+
+// indexKeys returns keys of the index entries of record.
+func indexKeys(keyHash authdb.KeyHash, record *pb.Record) [][]byte {
+	return [][]byte{
+		appendIndexSuffix([]byte(createdIndexPrefix), record.CreatedAtUnix, keyHash),
+		appendIndexSuffix(makeMacaroonHeadIndexPrefix(record.MacaroonHead), record.CreatedAtUnix, keyHash),
+	}
+}
+
+func indexEntries(keyHash authdb.KeyHash, record *pb.Record, expiresAt uint64) []*badger.Entry {
+	var entries []*badger.Entry
+	for _, key := range indexKeys(keyHash, record) {
+		e := badger.NewEntry(key, nil)
+		e.ExpiresAt = expiresAt
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func setIndexEntries(txn *badger.Txn, keyHash authdb.KeyHash, record *pb.Record) error {
+	var group errs.Group
+	for _, e := range indexEntries(keyHash, record, uint64(record.ExpiresAtUnix)) {
+		group.Add(txn.SetEntry(e))
+	}
+	return group.Err()
+}
+
+func deleteIndexEntries(txn *badger.Txn, keyHash authdb.KeyHash, record *pb.Record) error {
+	var group errs.Group
+	for _, key := range indexKeys(keyHash, record) {
+		group.Add(txn.Delete(key))
+	}
+	return group.Err()
+}
+
+// buildIndex indexes records stored before the index existed. It does nothing
+// if they have been indexed already.
+func (db *DB) buildIndex() (err error) {
+	defer mon.Task(db.eventTags()...)(nil)(&err)
+
+	var version [8]byte
+	binary.BigEndian.PutUint64(version[:], recordIndexVersion)
+
+	built := false
+	if err = db.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(recordIndexVersionKey))
+		if err != nil {
+			if errs.Is(err, badger.ErrKeyNotFound) {
+				return nil
+			}
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			built = bytes.Equal(val, version[:])
+			return nil
+		})
+	}); err != nil || built {
+		return IndexError.Wrap(err)
+	}
+
+	wb := db.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	var count int
+	if err = db.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			if len(item.Key()) != lenKeyHash {
+				continue
+			}
+
+			var keyHash authdb.KeyHash
+			copy(keyHash[:], item.Key())
+
+			var record pb.Record
+			if err := item.Value(func(val []byte) error {
+				return pb.Unmarshal(val, &record)
+			}); err != nil {
+				// Malformed records are reported by Fsck.
+				db.log.Sugar().Warnf("skipped indexing malformed record %x: %v", keyHash, err)
+				continue
+			}
+
+			for _, e := range indexEntries(keyHash, &record, item.ExpiresAt()) {
+				if err := wb.SetEntry(e); err != nil {
+					return err
+				}
+			}
+			count++
+		}
+
+		return nil
+	}); err != nil {
+		return IndexError.Wrap(err)
+	}
+
+	if err = wb.Set([]byte(recordIndexVersionKey), version[:]); err != nil {
+		return IndexError.Wrap(err)
+	}
+	if err = wb.Flush(); err != nil {
+		return IndexError.Wrap(err)
+	}
+
+	db.log.Sugar().Infof("indexed %d records", count)
+
+	return nil
+}
+
+// List returns up to limit records matching filter, ordered by creation time
+// and key hash, after cursor (from the start if it's zero). next is the cursor
+// to continue listing from if more records might match, and zero otherwise.
+//
+// List scans the macaroon head index if filter has a macaroon head and the
+// creation time index otherwise; the remaining filters are applied to records
+// the index yields. It scans up to Config.ListScanLimit index entries, so if
+// few records match, it might return fewer than limit records (even none),
+// with next at the last entry scanned.
+func (db *DB) List(ctx context.Context, filter authdb.ListFilter, cursor authdb.ListCursor, limit int) (records []authdb.ListedRecord, next authdb.ListCursor, err error) {
+	defer mon.Task(db.eventTags()...)(&ctx)(&err)
+
+	if limit <= 0 {
+		return nil, next, Error.New("limit must be positive")
+	}
+
+	prefix := []byte(createdIndexPrefix)
+	if len(filter.MacaroonHead) > 0 {
+		prefix = makeMacaroonHeadIndexPrefix(filter.MacaroonHead)
+	}
+
+	seek := appendIndexSuffix(append([]byte{}, prefix...), filter.CreatedAfter.Unix(), authdb.KeyHash{})
+	if filter.CreatedAfter.IsZero() {
+		seek = prefix
+	}
+	if !cursor.IsZero() && !cursor.CreatedAt.Before(filter.CreatedAfter) {
+		seek = appendIndexSuffix(append([]byte{}, prefix...), cursor.CreatedAt.Unix(), cursor.KeyHash)
+	}
+
+	return records, next, Error.Wrap(db.db.View(func(txn *badger.Txn) error {
+		opt := badger.DefaultIteratorOptions
+		opt.PrefetchValues = false
+		opt.Prefix = prefix
+
+		it := txn.NewIterator(opt)
+		defer it.Close()
+
+		var (
+			scanned int
+			last    authdb.ListCursor
+		)
+		for it.Seek(seek); it.Valid(); it.Next() {
+			createdAtUnix, keyHash, err := parseIndexSuffix(it.Item().Key())
+			if err != nil {
+				return err
+			}
+
+			position := authdb.ListCursor{CreatedAt: time.Unix(createdAtUnix, 0), KeyHash: keyHash}
+			if !cursor.IsZero() && !cursor.Less(position) {
+				continue
+			}
+			if !filter.CreatedBefore.IsZero() && !position.CreatedAt.Before(filter.CreatedBefore) {
+				break
+			}
+			if db.config.ListScanLimit > 0 && scanned == db.config.ListScanLimit {
+				// entries up to the last one scanned don't match, except
+				// for records returned.
+				next = last
+				break
+			}
+			scanned++
+			last = position
+
+			r, err := lookupRecordWithTxn(txn, keyHash)
+			if err != nil {
+				if errs.Is(err, badger.ErrKeyNotFound) {
+					continue // the record has just expired
+				}
+				return err
+			}
+
+			listed := toListedRecord(keyHash, r)
+			if !listed.Matches(filter) {
+				continue
+			}
+
+			if len(records) == limit {
+				next = records[len(records)-1].Cursor()
+				break
+			}
+			records = append(records, listed)
+		}
+
+		return nil
+	}))
+}
+
+func toListedRecord(keyHash authdb.KeyHash, r *pb.Record) authdb.ListedRecord {
+	return authdb.ListedRecord{
+		KeyHash:            keyHash,
+		CreatedAt:          time.Unix(r.CreatedAtUnix, 0),
+		SatelliteAddress:   r.SatelliteAddress,
+		MacaroonHead:       r.MacaroonHead,
+		ExpiresAt:          timestampToTime(r.ExpiresAtUnix),
+		Public:             r.Public,
+		InvalidationReason: r.InvalidationReason,
+		InvalidatedAt:      timestampToTime(r.InvalidatedAtUnix),
+	}
+}
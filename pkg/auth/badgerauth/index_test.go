@@ -0,0 +1,193 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/common/testcontext"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/badgerauth"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/pb"
+)
+
+func TestDB_List(t *testing.T) {
+	t.Parallel()
+
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	config := badgerauth.Config{
+		FirstStart: true,
+		Path:       ctx.Dir("storage"),
+	}
+	require.NoError(t, config.ID.Set("list"))
+
+	db, err := badgerauth.OpenDB(log, config)
+	require.NoError(t, err)
+
+	start := time.Unix(time.Now().Unix(), 0)
+	expiresAt := start.Add(time.Hour)
+
+	// Records 0-5 are created a second apart; even ones have head a, odd
+	// ones head b, and records 4 and 5 are on another satellite. Record 3
+	// has an expiration time.
+	for i := 0; i < 6; i++ {
+		record := &authdb.Record{
+			SatelliteAddress:     "sat1",
+			MacaroonHead:         []byte{'a'},
+			EncryptedSecretKey:   []byte{'s'},
+			EncryptedAccessGrant: []byte{'g'},
+			Public:               i%3 == 0,
+		}
+		if i%2 == 1 {
+			record.MacaroonHead = []byte{'b'}
+		}
+		if i >= 4 {
+			record.SatelliteAddress = "sat2"
+		}
+		if i == 3 {
+			record.ExpiresAt = &expiresAt
+		}
+		require.NoError(t, db.PutAtTime(ctx, authdb.KeyHash{byte(i)}, record, start.Add(time.Duration(i)*time.Second)))
+	}
+
+	_, err = badgerauth.NewAdmin(db).InvalidateRecord(ctx, &pb.InvalidateRecordRequest{Key: authdb.KeyHash{2}.Bytes(), Reason: "test"})
+	require.NoError(t, err)
+
+	keys := func(records []authdb.ListedRecord) (keys []byte) {
+		for _, r := range records {
+			keys = append(keys, r.KeyHash[0])
+		}
+		return keys
+	}
+	list := func(filter authdb.ListFilter) []byte {
+		records, next, err := db.List(ctx, filter, authdb.ListCursor{}, 10)
+		require.NoError(t, err)
+		assert.True(t, next.IsZero())
+		return keys(records)
+	}
+	yes, no := true, false
+
+	assert.Equal(t, []byte{0, 1, 2, 3, 4, 5}, list(authdb.ListFilter{}))
+	assert.Equal(t, []byte{0, 2, 4}, list(authdb.ListFilter{MacaroonHead: []byte{'a'}}))
+	assert.Equal(t, []byte{5}, list(authdb.ListFilter{MacaroonHead: []byte{'b'}, SatelliteAddress: "sat2"}))
+	assert.Empty(t, list(authdb.ListFilter{MacaroonHead: []byte{'a', 'b'}}))
+	assert.Equal(t, []byte{4, 5}, list(authdb.ListFilter{SatelliteAddress: "sat2"}))
+	assert.Equal(t, []byte{1, 2}, list(authdb.ListFilter{CreatedAfter: start.Add(time.Second), CreatedBefore: start.Add(3 * time.Second)}))
+	assert.Equal(t, []byte{2, 4}, list(authdb.ListFilter{MacaroonHead: []byte{'a'}, CreatedAfter: start.Add(500 * time.Millisecond)}))
+	assert.Equal(t, []byte{0, 3}, list(authdb.ListFilter{Public: &yes}))
+	assert.Equal(t, []byte{2}, list(authdb.ListFilter{Invalidated: &yes}))
+	assert.Equal(t, []byte{1, 5}, list(authdb.ListFilter{Public: &no, Invalidated: &no, MacaroonHead: []byte{'b'}}))
+
+	records, _, err := db.List(ctx, authdb.ListFilter{Invalidated: &yes}, authdb.ListCursor{}, 1)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "test", records[0].InvalidationReason)
+	assert.NotNil(t, records[0].InvalidatedAt)
+	assert.Equal(t, start.Add(2*time.Second), records[0].CreatedAt)
+
+	t.Run("pagination", func(t *testing.T) {
+		var (
+			listed []byte
+			cursor authdb.ListCursor
+		)
+		for {
+			records, next, err := db.List(ctx, authdb.ListFilter{CreatedAfter: start.Add(time.Second)}, cursor, 2)
+			require.NoError(t, err)
+			listed = append(listed, keys(records)...)
+			if next.IsZero() {
+				break
+			}
+			assert.Equal(t, records[len(records)-1].Cursor(), next)
+			cursor = next
+		}
+		assert.Equal(t, []byte{1, 2, 3, 4, 5}, listed)
+	})
+
+	_, _, err = db.List(ctx, authdb.ListFilter{}, authdb.ListCursor{}, 0)
+	require.Error(t, err)
+
+	_, err = badgerauth.NewAdmin(db).DeleteRecord(ctx, &pb.DeleteRecordRequest{Key: authdb.KeyHash{0}.Bytes()})
+	require.NoError(t, err)
+	assert.Equal(t, []byte{2, 4}, list(authdb.ListFilter{MacaroonHead: []byte{'a'}}))
+
+	t.Run("index is built for existing records", func(t *testing.T) {
+		require.NoError(t, db.UnderlyingDB().DropPrefix([]byte("record_index")))
+		assert.Empty(t, list(authdb.ListFilter{}))
+		require.NoError(t, db.Close())
+
+		config.FirstStart = false
+		db, err = badgerauth.OpenDB(log, config)
+		require.NoError(t, err)
+
+		assert.Equal(t, []byte{1, 2, 3, 4, 5}, list(authdb.ListFilter{}))
+		assert.Equal(t, []byte{1, 3, 5}, list(authdb.ListFilter{MacaroonHead: []byte{'b'}}))
+	})
+
+	require.NoError(t, db.Close())
+}
+
+func TestDB_ListScanLimit(t *testing.T) {
+	t.Parallel()
+
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	config := badgerauth.Config{
+		FirstStart:    true,
+		ListScanLimit: 2,
+	}
+	require.NoError(t, config.ID.Set("list"))
+
+	db, err := badgerauth.OpenDB(zaptest.NewLogger(t), config)
+	require.NoError(t, err)
+	defer ctx.Check(db.Close)
+
+	start := time.Unix(time.Now().Unix(), 0)
+
+	// Only records 0 and 5 are on sat2, so listing them scans entries that
+	// don't match in between.
+	for i := 0; i < 6; i++ {
+		record := &authdb.Record{
+			SatelliteAddress:     "sat1",
+			MacaroonHead:         []byte{'a'},
+			EncryptedSecretKey:   []byte{'s'},
+			EncryptedAccessGrant: []byte{'g'},
+		}
+		if i == 0 || i == 5 {
+			record.SatelliteAddress = "sat2"
+		}
+		require.NoError(t, db.PutAtTime(ctx, authdb.KeyHash{byte(i)}, record, start.Add(time.Duration(i)*time.Second)))
+	}
+
+	var (
+		listed []byte
+		pages  int
+		cursor authdb.ListCursor
+	)
+	for {
+		records, next, err := db.List(ctx, authdb.ListFilter{SatelliteAddress: "sat2"}, cursor, 10)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(records), 2)
+		for _, r := range records {
+			listed = append(listed, r.KeyHash[0])
+		}
+		pages++
+		if next.IsZero() {
+			break
+		}
+		require.True(t, cursor.Less(next))
+		cursor = next
+	}
+	assert.Equal(t, []byte{0, 5}, listed)
+	assert.Equal(t, 3, pages)
+}
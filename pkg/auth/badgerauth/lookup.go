@@ -0,0 +1,225 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauth
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"storj.io/common/errs2"
+	"storj.io/common/lrucache"
+	"storj.io/common/rpc/rpcstatus"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/pb"
+)
+
+// ErrTooManyLookups is returned when a record isn't available locally, and
+// there are already MaxConcurrentLookups lookups on peers in progress.
+var ErrTooManyLookups = Error.New("too many concurrent lookups on peers")
+
+// lookupTimeout limits how long a lookup on peers can take.
+const lookupTimeout = time.Second
+
+// newNegativeCache returns a cache of key hashes confirmed missing on all
+// peers or nil if negative caching is disabled.
+func newNegativeCache(config Config) *lrucache.ExpiringLRU {
+	if config.NegativeCacheTTL <= 0 || config.NegativeCacheCapacity <= 0 {
+		return nil
+	}
+	return lrucache.New(lrucache.Options{
+		Expiration: config.NegativeCacheTTL,
+		Capacity:   config.NegativeCacheCapacity,
+	})
+}
+
+// newLookupLimiter returns a semaphore limiting concurrent lookups on peers or
+// nil if they are unlimited.
+func newLookupLimiter(config Config) chan struct{} {
+	if config.MaxConcurrentLookups <= 0 {
+		return nil
+	}
+	return make(chan struct{}, config.MaxConcurrentLookups)
+}
+
+// lookupPeers looks up the record on peers. Peers are asked one by one, in the
+// order of their latency, and the next peer is asked when the previous one
+// either doesn't have the record or doesn't answer within LookupHedgeDelay. The
+// first record found ends the lookup.
+//
+// Key hashes confirmed missing on all peers are remembered for
+// NegativeCacheTTL.
+func (node *Node) lookupPeers(ctx context.Context, keyHash authdb.KeyHash) (record *authdb.Record, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	peers := node.Peers()
+	if len(peers) == 0 {
+		// We have no peers, so we end here.
+		return nil, nil
+	}
+
+	cacheKey := string(keyHash.Bytes())
+
+	if node.negativeCache != nil {
+		if _, ok := node.negativeCache.GetCached(cacheKey); ok {
+			mon.Event("as_badgerauth_negative_cache_hit")
+			return nil, nil
+		}
+	}
+
+	if node.lookups != nil {
+		select {
+		case node.lookups <- struct{}{}:
+			defer func() { <-node.lookups }()
+		default:
+			mon.Event("as_badgerauth_lookup_limit_reached")
+			return nil, ErrTooManyLookups
+		}
+	}
+
+	sortPeersByLatency(peers)
+
+	type result struct {
+		peer   *Peer
+		record *pb.Record
+		err    error
+	}
+
+	results := make(chan result, len(peers))
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	var next, pending int
+	askNext := func() {
+		peer := peers[next]
+		next++
+		pending++
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := peer.Peek(ctx, keyHash)
+			results <- result{peer: peer, record: r, err: err}
+		}()
+	}
+
+	hedgeDelay := node.config.LookupHedgeDelay
+	if hedgeDelay <= 0 {
+		for next < len(peers) {
+			askNext()
+		}
+	} else {
+		askNext()
+	}
+
+	hedge := time.NewTimer(hedgeDelay)
+	defer hedge.Stop()
+
+	var (
+		allErrs     []error
+		allNotFound = true
+	)
+
+	for pending > 0 {
+		select {
+		case res := <-results:
+			pending--
+
+			if res.err == nil {
+				r := res.record
+				return &authdb.Record{
+					SatelliteAddress:     r.SatelliteAddress,
+					MacaroonHead:         r.MacaroonHead,
+					EncryptedSecretKey:   r.EncryptedSecretKey,
+					EncryptedAccessGrant: r.EncryptedAccessGrant,
+					ExpiresAt:            timestampToTime(r.ExpiresAtUnix),
+					Public:               r.Public,
+				}, nil
+			}
+
+			allErrs = append(allErrs, errs.New("%s: %w", res.peer.address, res.err))
+			if !errs2.IsRPC(res.err, rpcstatus.NotFound) {
+				allNotFound = false
+			}
+
+			// There's no point in waiting for the hedge if the peer has
+			// already answered.
+			if next < len(peers) && ctx.Err() == nil {
+				askNext()
+				resetTimer(hedge, hedgeDelay)
+			}
+		case <-hedge.C:
+			if next < len(peers) && ctx.Err() == nil {
+				mon.Event("as_badgerauth_lookup_hedged")
+				askNext()
+				hedge.Reset(hedgeDelay)
+			}
+		}
+	}
+
+	// The record is only missing if every peer has been asked; peers may be
+	// left unasked if ctx was canceled after the first NotFound.
+	if allNotFound && next == len(peers) && node.negativeCache != nil {
+		node.negativeCache.Add(cacheKey, struct{}{})
+	}
+
+	// TODO(artur): should we even care about errors from other nodes?
+	var errGroup errs.Group
+	for _, e := range allErrs {
+		if !(errs2.IsRPC(e, rpcstatus.NotFound) || errs2.IsCanceled(e)) {
+			errGroup.Add(e)
+		}
+	}
+
+	if errGroup.Err() == nil {
+		node.log.Debug("broadcasted Get finishes with NotFound/Canceled errors only", zap.Errors("allErrs", allErrs))
+	}
+
+	return nil, Error.Wrap(errGroup.Err())
+}
+
+// sortPeersByLatency sorts peers from the lowest latency. Peers with unknown
+// latency go last.
+func sortPeersByLatency(peers []*Peer) {
+	latencies := make(map[*Peer]time.Duration, len(peers))
+	for _, p := range peers {
+		latencies[p] = p.Status().Latency
+	}
+	sort.SliceStable(peers, func(i, j int) bool {
+		li, lj := latencies[peers[i]], latencies[peers[j]]
+		if li == 0 || lj == 0 {
+			return lj == 0 && li != 0
+		}
+		return li < lj
+	})
+}
+
+// observeLatency updates the peer's smoothed round-trip time with d.
+func (peer *Peer) observeLatency(d time.Duration) {
+	peer.changeStatus(func(status *PeerStatus) {
+		if status.Latency == 0 {
+			status.Latency = d
+			return
+		}
+		// exponentially weighted moving average with α = 1/8 (like TCP's SRTT)
+		status.Latency += (d - status.Latency) / 8
+	})
+}
+
+func resetTimer(t *time.Timer, d time.Duration) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+	t.Reset(d)
+}
@@ -0,0 +1,152 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauth_test
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"storj.io/common/testcontext"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/badgerauth"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/badgerauthtest"
+)
+
+func TestNegativeCache(t *testing.T) {
+	badgerauthtest.RunCluster(t, badgerauthtest.ClusterConfig{
+		NodeCount: 3,
+		Defaults: badgerauth.Config{
+			ReplicationInterval: time.Hour,
+			NegativeCacheTTL:    time.Hour,
+		},
+	}, func(ctx *testcontext.Context, t *testing.T, cluster *badgerauthtest.Cluster) {
+		record := &authdb.Record{
+			SatelliteAddress:     "t",
+			MacaroonHead:         []byte{'n'},
+			EncryptedSecretKey:   []byte{'s'},
+			EncryptedAccessGrant: []byte{'g'},
+		}
+
+		missing, present := authdb.KeyHash{'m'}, authdb.KeyHash{'p'}
+
+		require.NoError(t, cluster.Nodes[1].UnderlyingDB().Put(ctx, present, record))
+
+		// A record available on one of the peers is found and not cached as
+		// missing.
+		badgerauthtest.Get{KeyHash: present, Result: record}.Check(ctx, t, cluster.Nodes[0])
+
+		// A record missing on all peers is cached as missing...
+		badgerauthtest.Get{KeyHash: missing}.Check(ctx, t, cluster.Nodes[0])
+
+		require.NoError(t, cluster.Nodes[2].UnderlyingDB().Put(ctx, missing, record))
+
+		// ...so it's still missing for the node that cached it.
+		badgerauthtest.Get{KeyHash: missing}.Check(ctx, t, cluster.Nodes[0])
+		// Other nodes haven't cached it.
+		badgerauthtest.Get{KeyHash: missing, Result: record}.Check(ctx, t, cluster.Nodes[1])
+	})
+}
+
+func TestHedgedLookup(t *testing.T) {
+	badgerauthtest.RunCluster(t, badgerauthtest.ClusterConfig{
+		NodeCount: 4,
+		Defaults: badgerauth.Config{
+			ReplicationInterval: time.Hour,
+			// The delay is longer than the lookup timeout, so the next peer is
+			// only asked if the previous one doesn't have the record.
+			LookupHedgeDelay: time.Hour,
+		},
+	}, func(ctx *testcontext.Context, t *testing.T, cluster *badgerauthtest.Cluster) {
+		for _, node := range cluster.Nodes {
+			node.SyncCycle.TriggerWait()
+		}
+
+		for _, peer := range cluster.Nodes[0].TestingPeers(ctx) {
+			assert.Positive(t, peer.Status().Latency, peer.Status().Address)
+		}
+
+		record := &authdb.Record{
+			SatelliteAddress:     "t",
+			MacaroonHead:         []byte{'h'},
+			EncryptedSecretKey:   []byte{'s'},
+			EncryptedAccessGrant: []byte{'g'},
+		}
+
+		// Whatever the order of peers is, the record is found on the last one
+		// asked.
+		for i, node := range cluster.Nodes[1:] {
+			kh := authdb.KeyHash{'h', byte(i)}
+			require.NoError(t, node.UnderlyingDB().Put(ctx, kh, record))
+			badgerauthtest.Get{KeyHash: kh, Result: record}.Check(ctx, t, cluster.Nodes[0])
+		}
+	})
+}
+
+func TestMaxConcurrentLookups(t *testing.T) {
+	// The peer accepts connections but never answers, so lookups on it last
+	// until they time out.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+
+	var (
+		mu    sync.Mutex
+		conns []net.Conn
+	)
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, c := range conns {
+			_ = c.Close()
+		}
+	}()
+	go func() {
+		for {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			conns = append(conns, c)
+			mu.Unlock()
+		}
+	}()
+
+	badgerauthtest.RunSingleNode(t, badgerauth.Config{
+		Join:                 []string{listener.Addr().String()},
+		ReplicationInterval:  time.Hour,
+		MaxConcurrentLookups: 1,
+	}, func(ctx *testcontext.Context, t *testing.T, _ *zap.Logger, node *badgerauth.Node) {
+		const lookups = 5
+
+		var wg sync.WaitGroup
+		results := make(chan error, lookups)
+		for i := 0; i < lookups; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := node.Get(ctx, authdb.KeyHash{'l'})
+				results <- err
+			}()
+		}
+		wg.Wait()
+		close(results)
+
+		var limited int
+		for err := range results {
+			if errors.Is(err, badgerauth.ErrTooManyLookups) {
+				limited++
+			}
+		}
+		assert.Positive(t, limited)
+		assert.Less(t, limited, lookups)
+	})
+}
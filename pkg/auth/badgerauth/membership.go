@@ -0,0 +1,335 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	badger "github.com/outcaste-io/badger/v3"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+)
+
+const decommissionedPrefix = "decommissioned/"
+
+var (
+	// MembershipError is a class of cluster membership errors.
+	MembershipError = errs.Class("membership")
+
+	// ErrUnknownPeer is returned when removing a peer that isn't a member of
+	// the cluster.
+	ErrUnknownPeer = MembershipError.New("unknown peer")
+
+	// lookupSRV is replaceable in tests.
+	lookupSRV = net.DefaultResolver.LookupSRV
+)
+
+// membership keeps track of the node's peers.
+//
+// The effective list of peers is built from the static join list, addresses
+// discovered through the DNS SRV record and the membership file (both re-read
+// periodically), and addresses added at runtime through the admin API. Peers
+// removed at runtime are excluded until they are added back.
+type membership struct {
+	mu sync.Mutex
+
+	discoveredSRV  []string
+	discoveredFile []string
+
+	added   map[string]struct{}
+	removed map[string]struct{}
+	// self contains discovered addresses that turned out to be this node.
+	self map[string]struct{}
+
+	peers []*Peer
+}
+
+func newMembership() *membership {
+	return &membership{
+		added:   make(map[string]struct{}),
+		removed: make(map[string]struct{}),
+		self:    make(map[string]struct{}),
+	}
+}
+
+// Peers returns a snapshot of the current peers.
+func (node *Node) Peers() []*Peer {
+	node.membership.mu.Lock()
+	defer node.membership.mu.Unlock()
+
+	return append([]*Peer(nil), node.membership.peers...)
+}
+
+// AddPeer adds a peer at address to the cluster membership. Peers added this
+// way are kept until removed or the node restarts.
+func (node *Node) AddPeer(address string) error {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return MembershipError.New("invalid address %q: %w", address, err)
+	}
+
+	m := node.membership
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.removed, address)
+	m.added[address] = struct{}{}
+
+	node.updatePeersLocked()
+
+	return nil
+}
+
+// RemovePeer removes a peer at address from the cluster membership. The peer
+// won't be re-added by discovery until it's added back with AddPeer.
+func (node *Node) RemovePeer(address string) error {
+	m := node.membership
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var found bool
+	for _, peer := range m.peers {
+		if peer.address == address {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ErrUnknownPeer
+	}
+
+	delete(m.added, address)
+	m.removed[address] = struct{}{}
+
+	node.updatePeersLocked()
+
+	return nil
+}
+
+// Decommission permanently marks a node ID as decommissioned, so its clock
+// entries are no longer requested during replication.
+func (node *Node) Decommission(ctx context.Context, id NodeID) error {
+	if id == node.ID() {
+		return MembershipError.New("cannot decommission self (%s)", id)
+	}
+	return node.db.decommission(ctx, id, time.Now())
+}
+
+// refreshMembership re-reads discovery sources and updates peers. It always
+// returns a nil error, so failing discovery doesn't stop the node; addresses
+// from a failing source are kept from its last successful read.
+func (node *Node) refreshMembership(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(nil)
+
+	m := node.membership
+
+	var srv, file []string
+	var srvErr, fileErr error
+
+	if node.config.JoinSRV != "" {
+		if srv, srvErr = discoverSRV(ctx, node.config.JoinSRV); srvErr != nil {
+			node.log.Warn("failed to discover peers from SRV record", zap.String("name", node.config.JoinSRV), zap.Error(srvErr))
+		}
+	}
+	if node.config.JoinFile != "" {
+		if file, fileErr = discoverFile(node.config.JoinFile); fileErr != nil {
+			node.log.Warn("failed to discover peers from membership file", zap.String("path", node.config.JoinFile), zap.Error(fileErr))
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if srvErr == nil {
+		m.discoveredSRV = srv
+	}
+	if fileErr == nil {
+		m.discoveredFile = file
+	}
+
+	node.updatePeersLocked()
+
+	return nil
+}
+
+// excludeSelf excludes address from peers if it was only discovered (and not
+// explicitly configured), reporting whether it has been excluded.
+func (node *Node) excludeSelf(address string) bool {
+	m := node.membership
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.added[address]; ok {
+		return false
+	}
+	for _, a := range node.config.Join {
+		if a == address {
+			return false
+		}
+	}
+
+	m.self[address] = struct{}{}
+	node.updatePeersLocked()
+
+	return true
+}
+
+// updatePeersLocked rebuilds the list of peers, keeping existing peers (and
+// their status) intact. It must be called with membership's mutex held.
+func (node *Node) updatePeersLocked() {
+	m := node.membership
+
+	var added []string
+	for a := range m.added {
+		added = append(added, a)
+	}
+	sort.Strings(added)
+
+	var addresses []string
+	seen := make(map[string]struct{})
+	for _, list := range [][]string{node.config.Join, m.discoveredSRV, m.discoveredFile, added} {
+		for _, a := range list {
+			if _, ok := seen[a]; ok {
+				continue
+			}
+			seen[a] = struct{}{}
+			if _, ok := m.removed[a]; ok {
+				continue
+			}
+			if _, ok := m.self[a]; ok {
+				continue
+			}
+			addresses = append(addresses, a)
+		}
+	}
+
+	existing := make(map[string]*Peer, len(m.peers))
+	for _, peer := range m.peers {
+		existing[peer.address] = peer
+	}
+
+	peers := make([]*Peer, 0, len(addresses))
+	for _, a := range addresses {
+		if peer, ok := existing[a]; ok {
+			peers = append(peers, peer)
+			delete(existing, a)
+			continue
+		}
+		node.log.Info("peer joined", zap.String("address", a))
+		peers = append(peers, NewPeer(node, a))
+	}
+	for a := range existing {
+		node.log.Info("peer left", zap.String("address", a))
+	}
+
+	m.peers = peers
+
+	mon.IntVal("as_badgerauth_peers").Observe(int64(len(peers)))
+}
+
+// discoverSRV looks up peers' addresses in the DNS SRV record.
+func discoverSRV(ctx context.Context, name string) ([]string, error) {
+	_, records, err := lookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, MembershipError.Wrap(err)
+	}
+	return addressesFromSRV(records), nil
+}
+
+func addressesFromSRV(records []*net.SRV) []string {
+	addresses := make([]string, 0, len(records))
+	for _, r := range records {
+		host := strings.TrimSuffix(r.Target, ".")
+		addresses = append(addresses, net.JoinHostPort(host, strconv.Itoa(int(r.Port))))
+	}
+	return addresses
+}
+
+// discoverFile reads peers' addresses from the membership file. The file
+// contains one address per line; empty lines and lines starting with # are
+// ignored.
+func discoverFile(path string) (addresses []string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, MembershipError.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, f.Close()) }()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, _, err = net.SplitHostPort(line); err != nil {
+			return nil, MembershipError.New("invalid address %q: %w", line, err)
+		}
+		addresses = append(addresses, line)
+	}
+
+	return addresses, MembershipError.Wrap(s.Err())
+}
+
+func makeDecommissionedKey(id NodeID) []byte {
+	return append([]byte(decommissionedPrefix), id.Bytes()...)
+}
+
+// decommission marks id as decommissioned at now.
+func (db *DB) decommission(ctx context.Context, id NodeID, now time.Time) (err error) {
+	defer mon.Task(db.eventTags()...)(&ctx)(&err)
+
+	var value [8]byte
+	binary.BigEndian.PutUint64(value[:], uint64(now.Unix()))
+
+	return Error.Wrap(db.txnWithBackoff(ctx, func(txn *badger.Txn) error {
+		return txn.Set(makeDecommissionedKey(id), value[:])
+	}))
+}
+
+// isDecommissioned returns whether id has been decommissioned.
+func (db *DB) isDecommissioned(id NodeID) (decommissioned bool, err error) {
+	return decommissioned, Error.Wrap(db.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(makeDecommissionedKey(id))
+		if errs.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		decommissioned = true
+		return nil
+	}))
+}
+
+func readDecommissioned(txn *badger.Txn) (map[NodeID]struct{}, error) {
+	ids := make(map[NodeID]struct{})
+
+	opt := badger.DefaultIteratorOptions
+	opt.PrefetchValues = false
+	opt.Prefix = []byte(decommissionedPrefix)
+
+	it := txn.NewIterator(opt)
+	defer it.Close()
+
+	for it.Rewind(); it.Valid(); it.Next() {
+		var id NodeID
+		if err := id.SetBytes(bytes.TrimPrefix(it.Item().Key(), opt.Prefix)); err != nil {
+			return nil, MembershipError.Wrap(err)
+		}
+		ids[id] = struct{}{}
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauth_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"storj.io/common/rpc/rpcstatus"
+	"storj.io/common/testcontext"
+	"storj.io/gateway-mt/pkg/auth/badgerauth"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/badgerauthtest"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/pb"
+)
+
+func TestMembership_JoinFile(t *testing.T) {
+	filectx := testcontext.New(t)
+	path := filectx.File("membership")
+
+	require.NoError(t, os.WriteFile(path, []byte("# peers\n127.0.0.1:1\n\n127.0.0.1:2\n"), 0644))
+
+	badgerauthtest.RunSingleNode(t, badgerauth.Config{
+		ID:       badgerauth.NodeID{'j', 'f'},
+		JoinFile: path,
+	}, func(ctx *testcontext.Context, t *testing.T, _ *zap.Logger, node *badgerauth.Node) {
+		node.MembershipCycle.TriggerWait()
+		require.Equal(t, []string{"127.0.0.1:1", "127.0.0.1:2"}, peerAddresses(node))
+
+		// invalid contents keep the last known peers
+		require.NoError(t, os.WriteFile(path, []byte("not an address\n"), 0644))
+		node.MembershipCycle.TriggerWait()
+		require.Equal(t, []string{"127.0.0.1:1", "127.0.0.1:2"}, peerAddresses(node))
+
+		// the node excludes itself if it finds its address
+		require.NoError(t, os.WriteFile(path, []byte("127.0.0.1:2\n"+node.Address()+"\n"), 0644))
+		node.MembershipCycle.TriggerWait()
+		require.Equal(t, []string{"127.0.0.1:2", node.Address()}, peerAddresses(node))
+		node.SyncCycle.TriggerWait()
+		require.Equal(t, []string{"127.0.0.1:2"}, peerAddresses(node))
+	})
+}
+
+func TestMembership_AddRemovePeer(t *testing.T) {
+	badgerauthtest.RunCluster(t, badgerauthtest.ClusterConfig{
+		NodeCount: 3,
+	}, func(ctx *testcontext.Context, t *testing.T, cluster *badgerauthtest.Cluster) {
+		node := cluster.Nodes[0]
+		admin := node.Admin()
+
+		require.Len(t, node.Peers(), 2)
+
+		_, err := admin.RemovePeer(ctx, &pb.RemovePeerRequest{Address: cluster.Nodes[1].Address()})
+		require.NoError(t, err)
+		require.Equal(t, []string{cluster.Nodes[2].Address()}, peerAddresses(node))
+
+		_, err = admin.RemovePeer(ctx, &pb.RemovePeerRequest{Address: cluster.Nodes[1].Address()})
+		require.Equal(t, rpcstatus.NotFound, rpcstatus.Code(err))
+
+		_, err = admin.AddPeer(ctx, &pb.AddPeerRequest{Address: "not an address"})
+		require.Equal(t, rpcstatus.InvalidArgument, rpcstatus.Code(err))
+
+		_, err = admin.AddPeer(ctx, &pb.AddPeerRequest{Address: cluster.Nodes[1].Address()})
+		require.NoError(t, err)
+		require.ElementsMatch(t, cluster.Addresses()[1:], peerAddresses(node))
+
+		testPing(ctx, t, cluster)
+	})
+}
+
+func TestMembership_DecommissionNode(t *testing.T) {
+	badgerauthtest.RunCluster(t, badgerauthtest.ClusterConfig{
+		NodeCount: 2,
+	}, func(ctx *testcontext.Context, t *testing.T, cluster *badgerauthtest.Cluster) {
+		admin := cluster.Nodes[0].Admin()
+
+		_, err := admin.DecommissionNode(ctx, &pb.DecommissionNodeRequest{NodeId: cluster.Nodes[0].ID().Bytes()})
+		require.Equal(t, rpcstatus.InvalidArgument, rpcstatus.Code(err))
+
+		_, err = admin.DecommissionNode(ctx, &pb.DecommissionNodeRequest{})
+		require.Equal(t, rpcstatus.InvalidArgument, rpcstatus.Code(err))
+
+		_, err = admin.DecommissionNode(ctx, &pb.DecommissionNodeRequest{NodeId: cluster.Nodes[1].ID().Bytes()})
+		require.NoError(t, err)
+
+		_, keys, _ := badgerauthtest.CreateFullRecords(ctx, t, cluster.Nodes[1], 1)
+
+		cluster.Nodes[0].SyncCycle.TriggerWait()
+
+		record, err := cluster.Nodes[0].UnderlyingDB().Get(ctx, keys[0])
+		require.NoError(t, err)
+		require.Nil(t, record)
+
+		status := cluster.Nodes[0].Peers()[0].Status()
+		require.False(t, status.LastWasUp)
+		require.Error(t, status.LastError)
+	})
+}
+
+func TestAdmin_MembershipUnavailable(t *testing.T) {
+	badgerauthtest.RunSingleNode(t, badgerauth.Config{}, func(ctx *testcontext.Context, t *testing.T, _ *zap.Logger, node *badgerauth.Node) {
+		admin := badgerauth.NewAdmin(node.UnderlyingDB())
+
+		_, err := admin.AddPeer(ctx, &pb.AddPeerRequest{Address: "127.0.0.1:1"})
+		require.Equal(t, rpcstatus.FailedPrecondition, rpcstatus.Code(err))
+	})
+}
+
+func peerAddresses(node *badgerauth.Node) (addresses []string) {
+	for _, peer := range node.Peers() {
+		addresses = append(addresses, peer.Status().Address)
+	}
+	return addresses
+}
@@ -20,6 +20,8 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"storj.io/common/errs2"
+	"storj.io/common/lrucache"
+	"storj.io/common/memory"
 	"storj.io/common/rpc"
 	"storj.io/common/rpc/rpcpool"
 	"storj.io/common/rpc/rpcstatus"
@@ -40,6 +42,9 @@ var (
 
 	// DialError is an error class for dial failures.
 	DialError = errs.Class("dial")
+
+	// ErrReadOnly is returned when putting a record into a read-only node.
+	ErrReadOnly = Error.New("node is read-only")
 )
 
 // Config provides options for creating a Node.
@@ -58,21 +63,59 @@ type Config struct {
 	FirstStart bool `user:"true" help:"allow start with empty storage" devDefault:"true" releaseDefault:"false"`
 	// Path is where to store data. Empty means in memory.
 	Path string `user:"true" help:"path where to store data" default:""`
+	// ReadOnly makes the node a replica that only replicates records from
+	// its peers and serves them. Other nodes don't replicate from it.
+	ReadOnly bool `user:"true" help:"reject new records and only replicate from peers" default:"false"`
 
 	Address  string   `user:"true" help:"address that the node listens on" default:":20004"`
 	Join     []string `user:"true" help:"comma delimited list of cluster peers" default:""`
 	CertsDir string   `user:"true" help:"directory for certificates for mutual authentication"`
 
+	// JoinSRV and JoinFile are sources of cluster peers that are re-read
+	// every JoinRefreshInterval in addition to the static Join list.
+	JoinSRV             string        `user:"true" help:"DNS SRV record to discover cluster peers from (e.g. _badgerauth._tcp.example.com)" default:""`
+	JoinFile            string        `user:"true" help:"path to a file with cluster peers (one per line)" default:""`
+	JoinRefreshInterval time.Duration `user:"true" help:"how often to re-read cluster peers from join-srv and join-file" default:"1m"`
+
 	// ReplicationInterval defines how often to connect and request status from
 	// other nodes.
 	ReplicationInterval time.Duration `user:"true" help:"how often to replicate" default:"30s" devDefault:"5s"`
 	// ReplicationLimit is per node ID limit of replication response entries to
 	// return.
 	ReplicationLimit int `user:"true" help:"maximum entries returned in replication response" default:"1000"`
+	// ListScanLimit bounds the index entries a single record listing scans,
+	// so that selective filters the index can't narrow down (e.g., by
+	// satellite) don't scan the whole index at once.
+	ListScanLimit int `user:"true" help:"maximum index entries scanned by a single record listing (0 means no limit)" default:"10000"`
 	// ConflictBackoff configures retries for conflicting transactions that may
 	// occur when Node's underlying storage engine is under heavy load.
 	ConflictBackoff backoff.ExponentialBackoff
 
+	// WriteQuorum is the number of peers that must acknowledge a new record
+	// before Put returns. If it isn't reached within WriteQuorumTimeout,
+	// WriteQuorumFallback decides whether Put fails or succeeds with the
+	// record stored locally only.
+	WriteQuorum         int           `user:"true" help:"number of peers that must acknowledge a new record before it's considered written (0 disables)" default:"0"`
+	WriteQuorumTimeout  time.Duration `user:"true" help:"how long to wait for write-quorum acknowledgements" default:"2s"`
+	WriteQuorumFallback string        `user:"true" help:"what to do if write-quorum isn't reached in time: fail or degrade (to local-only write)" default:"degrade"`
+
+	// NegativeCacheTTL is how long key hashes confirmed missing on all peers
+	// are answered as missing without asking peers again.
+	NegativeCacheTTL      time.Duration `user:"true" help:"how long to remember key hashes missing on all peers (0 disables)" default:"10s"`
+	NegativeCacheCapacity int           `user:"true" help:"maximum number of key hashes remembered as missing" default:"100000"`
+	// LookupHedgeDelay is how long to wait for a peer's answer before also
+	// asking the next closest peer for a record that isn't available locally.
+	LookupHedgeDelay     time.Duration `user:"true" help:"how long to wait for a peer before also asking the next one for a record (0 asks all peers at once)" default:"50ms"`
+	MaxConcurrentLookups int           `user:"true" help:"maximum number of concurrent lookups on peers (0 means unlimited)" default:"100"`
+
+	// EncryptionKeyFile enables encryption at rest if set.
+	EncryptionKeyFile             string        `user:"true" help:"path to a file with a 16, 24 or 32-byte key (raw or hex-encoded) to encrypt stored data with" default:""`
+	EncryptionKeyRotationDuration time.Duration `user:"true" help:"how often to rotate data keys encrypted with the key from encryption-key-file" default:"240h"`
+	// BlockCacheSize and IndexCacheSize are only used when encryption is
+	// enabled.
+	BlockCacheSize memory.Size `user:"true" help:"size of the block cache used when encryption is enabled" default:"256MiB"`
+	IndexCacheSize memory.Size `user:"true" help:"size of the index cache used when encryption is enabled" default:"64MiB"`
+
 	// InsecureDisableTLS allows disabling tls for testing.
 	InsecureDisableTLS bool `internal:"true"`
 
@@ -94,10 +137,14 @@ type Node struct {
 	mux          *drpcmux.Mux
 	server       *drpcserver.Server
 	admin        *Admin
-	peers        []*Peer
+	membership   *membership
 
-	gc        sync2.Cycle
-	SyncCycle sync2.Cycle
+	negativeCache *lrucache.ExpiringLRU
+	lookups       chan struct{}
+
+	gc              sync2.Cycle
+	SyncCycle       sync2.Cycle
+	MembershipCycle sync2.Cycle
 }
 
 // Below is a compile-time check ensuring Node implements the
@@ -111,9 +158,13 @@ func New(log *zap.Logger, config Config) (_ *Node, err error) {
 	}
 
 	node := &Node{
-		log:    log,
-		config: config,
-		mux:    drpcmux.New(),
+		log:        log,
+		config:     config,
+		mux:        drpcmux.New(),
+		membership: newMembership(),
+
+		negativeCache: newNegativeCache(config),
+		lookups:       newLookupLimiter(config),
 	}
 
 	defer func() {
@@ -122,6 +173,10 @@ func New(log *zap.Logger, config Config) (_ *Node, err error) {
 		}
 	}()
 
+	if err = config.validateWriteQuorum(); err != nil {
+		return nil, err
+	}
+
 	node.db, err = OpenDB(log, config)
 	if err != nil {
 		return nil, Error.Wrap(err)
@@ -167,7 +222,7 @@ func New(log *zap.Logger, config Config) (_ *Node, err error) {
 		return nil, Error.New("failed to register server: %w", err)
 	}
 
-	node.admin = NewAdmin(node.db)
+	node.admin = &Admin{db: node.db, node: node}
 	if err = pb.DRPCRegisterAdminService(node.mux, node.admin); err != nil {
 		return nil, Error.New("failed to register server: %w", err)
 	}
@@ -191,6 +246,7 @@ func New(log *zap.Logger, config Config) (_ *Node, err error) {
 
 	node.gc.SetInterval(5 * time.Minute)
 	node.SyncCycle.SetInterval(config.ReplicationInterval)
+	node.MembershipCycle.SetInterval(config.JoinRefreshInterval)
 
 	return node, nil
 }
@@ -198,19 +254,52 @@ func New(log *zap.Logger, config Config) (_ *Node, err error) {
 // ID returns the configured node id.
 func (node *Node) ID() NodeID { return node.config.ID }
 
+// Admin returns the admin service of the node.
+func (node *Node) Admin() *Admin { return node.admin }
+
+// SetInvalidationFeed makes the node publish records invalidated, unpublished
+// or deleted through its admin service, and records replicated invalidated, to
+// feed. It must be called before Run.
+func (node *Node) SetInvalidationFeed(feed *authdb.InvalidationFeed) {
+	node.db.invalidations = feed
+}
+
 // Address returns the server address.
 func (node *Node) Address() string {
 	return node.listener.Addr().String()
 }
 
-// Put proxies DB's Put.
+// Put is like PutAtTime, but it uses current time to store the record.
 func (node *Node) Put(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record) error {
-	return node.db.Put(ctx, keyHash, record)
+	return node.PutAtTime(ctx, keyHash, record, time.Now())
 }
 
-// PutAtTime proxies DB's PutAtTime.
-func (node *Node) PutAtTime(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record, now time.Time) error {
-	return node.db.PutAtTime(ctx, keyHash, record, now)
+// PutAtTime proxies DB's PutAtTime. If write quorum is configured, it also
+// pushes the record to peers and waits for their acknowledgements. It returns
+// ErrReadOnly if the node is read-only.
+func (node *Node) PutAtTime(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record, now time.Time) (err error) {
+	defer mon.Task(node.db.eventTags()...)(&ctx)(&err)
+
+	if node.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	if err = node.db.PutAtTime(ctx, keyHash, record, now); err != nil {
+		return err
+	}
+
+	if node.config.WriteQuorum == 0 {
+		return nil
+	}
+
+	// The current clock might already be later than the record's one if there
+	// were concurrent puts, but waiting for it is only stricter.
+	clock, err := node.db.readClock(node.ID())
+	if err != nil {
+		return err
+	}
+
+	return node.awaitWriteQuorum(ctx, clock)
 }
 
 // Get returns a record from the database. If the record isn't found, we consult
@@ -231,71 +320,17 @@ func (node *Node) Get(ctx context.Context, keyHash authdb.KeyHash) (record *auth
 	}
 
 	// Slow path (we need to contact other nodes):
-	if len(node.peers) == 0 {
-		// We have no peers, so we end here.
-		return nil, nil
-	}
-
-	// The idea behind this is that the first result cancels the rest, and if
-	// multiple succeed at once, subsequent results will be discarded by default
-	// case. When the group is finished, we do a non-blocking read which is
-	// guaranteed to succeed if the channel has value inside.
-
-	result := make(chan *authdb.Record, 1)
-
-	var group errs2.Group
-
-	ctx, cancel := context.WithTimeout(ctx, time.Second)
-	defer cancel()
-
-	for _, peer := range node.peers {
-		peer := peer
-		group.Go(func() error {
-			r, err := peer.Peek(ctx, keyHash)
-			if err != nil {
-				return errs.New("%s: %w", peer.address, err)
-			}
-
-			select {
-			case result <- &authdb.Record{
-				SatelliteAddress:     r.SatelliteAddress,
-				MacaroonHead:         r.MacaroonHead,
-				EncryptedSecretKey:   r.EncryptedSecretKey,
-				EncryptedAccessGrant: r.EncryptedAccessGrant,
-				ExpiresAt:            timestampToTime(r.ExpiresAtUnix),
-				Public:               r.Public,
-			}:
-				cancel()
-			default:
-			}
-
-			return nil
-		})
-	}
-
-	allErrs := group.Wait()
-
-	select {
-	case record = <-result:
-		// If we had at least one success, we drop all errors and just go ahead
-		// and return the first result.
-		return record, nil
-	default:
-	}
-
-	// TODO(artur): should we even care about errors from other nodes?
-	var errGroup errs.Group
-	for _, e := range allErrs {
-		if !(errs2.IsRPC(e, rpcstatus.NotFound) || errs2.IsCanceled(e)) {
-			errGroup.Add(e)
-		}
-	}
+	return node.lookupPeers(ctx, keyHash)
+}
 
-	if errGroup.Err() == nil {
-		node.log.Debug("broadcasted Get finishes with NotFound/Canceled errors only", zap.Errors("allErrs", allErrs))
-	}
+// Range proxies DB's Range. Unlike Get, it doesn't consult peers.
+func (node *Node) Range(ctx context.Context, fn func(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record) error) error {
+	return node.db.Range(ctx, fn)
+}
 
-	return nil, Error.Wrap(errGroup.Err())
+// List proxies DB's List. Like Range, it doesn't consult peers.
+func (node *Node) List(ctx context.Context, filter authdb.ListFilter, cursor authdb.ListCursor, limit int) ([]authdb.ListedRecord, authdb.ListCursor, error) {
+	return node.db.List(ctx, filter, cursor, limit)
 }
 
 // DeleteUnused proxies DB's DeleteUnused.
@@ -318,8 +353,10 @@ func (node *Node) PingDB(ctx context.Context) error {
 
 // Run runs the server and the associated servers.
 func (node *Node) Run(ctx context.Context) error {
-	if len(node.config.Join) == 0 {
-		node.log.Warn("node doesn't know about other nodes in the cluster (no entries for join parameter)")
+	dynamic := node.config.JoinSRV != "" || node.config.JoinFile != ""
+
+	if len(node.config.Join) == 0 && !dynamic {
+		node.log.Warn("node doesn't know about other nodes in the cluster (no entries for join parameters)")
 	}
 
 	group, gCtx := errgroup.WithContext(ctx)
@@ -332,9 +369,12 @@ func (node *Node) Run(ctx context.Context) error {
 		defer node.Backup.SyncCycle.Close()
 	}
 
-	for _, join := range node.config.Join {
-		node.peers = append(node.peers, NewPeer(node, join))
+	_ = node.refreshMembership(ctx)
+	if dynamic {
+		node.MembershipCycle.Start(gCtx, group, node.refreshMembership)
+		defer node.MembershipCycle.Close()
 	}
+
 	node.SyncCycle.Start(gCtx, group, node.syncAll)
 	defer node.SyncCycle.Close()
 
@@ -348,7 +388,7 @@ func (node *Node) Run(ctx context.Context) error {
 
 // syncAll tries to synchronize all nodes.
 func (node *Node) syncAll(ctx context.Context) error {
-	for _, peer := range node.peers {
+	for _, peer := range node.Peers() {
 		if err := IgnoreDialFailures(peer.Sync(ctx)); err != nil {
 			return Error.Wrap(err)
 		}
@@ -382,7 +422,8 @@ func (node *Node) Close() error {
 // Ping allows to fetch information about the node.
 func (node *Node) Ping(ctx context.Context, req *pb.PingRequest) (*pb.PingResponse, error) {
 	return &pb.PingResponse{
-		NodeId: node.config.ID.Bytes(),
+		NodeId:   node.config.ID.Bytes(),
+		ReadOnly: node.config.ReadOnly,
 	}, nil
 }
 
@@ -449,6 +490,37 @@ func (node *Node) Replicate(ctx context.Context, req *pb.ReplicationRequest) (_
 	return &response, nil
 }
 
+// Push allows another node to push its new records to the node. It responds
+// with RPC errors only.
+func (node *Node) Push(ctx context.Context, req *pb.PushRequest) (_ *pb.PushResponse, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var id NodeID
+	if err = id.SetBytes(req.NodeId); err != nil {
+		return nil, rpcstatus.Error(rpcstatus.InvalidArgument, err.Error())
+	}
+
+	if id == node.ID() {
+		return nil, rpcstatus.Errorf(rpcstatus.InvalidArgument, "push from the same node ID (%s)", id)
+	}
+
+	decommissioned, err := node.db.isDecommissioned(id)
+	if err != nil {
+		return nil, rpcstatus.Error(rpcstatus.Internal, err.Error())
+	}
+	if decommissioned {
+		return nil, rpcstatus.Errorf(rpcstatus.FailedPrecondition, "node ID %s is decommissioned", id)
+	}
+
+	clock, err := node.db.insertPushedEntries(ctx, id, Clock(req.Since), req.Entries)
+	if err != nil {
+		node.log.Error("push failed", zap.Stringer("nodeID", id), zap.Error(err))
+		return nil, errToRPCStatusErr(err)
+	}
+
+	return &pb.PushResponse{Clock: uint64(clock)}, nil
+}
+
 // UnderlyingDB returns underlying DB. This method is most useful in tests.
 func (node *Node) UnderlyingDB() *DB {
 	return node.db
@@ -456,12 +528,16 @@ func (node *Node) UnderlyingDB() *DB {
 
 // TestingSetJoin sets peer nodes to join to.
 func (node *Node) TestingSetJoin(addresses []string) {
+	node.membership.mu.Lock()
+	defer node.membership.mu.Unlock()
+
 	node.config.Join = addresses
+	node.updatePeersLocked()
 }
 
 // TestingPeers allows to access the peers for testing.
 func (node *Node) TestingPeers(ctx context.Context) []*Peer {
-	return node.peers
+	return node.Peers()
 }
 
 // Peer represents a node peer replication logic.
@@ -483,7 +559,15 @@ type PeerStatus struct {
 	LastUpdated time.Time
 	LastWasUp   bool
 	LastError   error
-
+	LastSynced  time.Time
+	// ReadOnly is whether the peer is a read-only replica that isn't
+	// replicated from.
+	ReadOnly bool
+	// Latency is the smoothed round-trip time to the peer. Zero means
+	// unknown.
+	Latency time.Duration
+
+	// Clock is the clock of the peer's own records as known locally.
 	Clock Clock
 }
 
@@ -511,7 +595,9 @@ func (peer *Peer) Sync(ctx context.Context) (err error) {
 				return err // already wrapped if needed
 			}
 			if !ok {
-				peer.log.Warn("peer is down or misbehaving, skipping records sync")
+				if !peer.Status().ReadOnly {
+					peer.log.Warn("peer is down or misbehaving, skipping records sync")
+				}
 				return nil
 			}
 
@@ -531,7 +617,11 @@ func (peer *Peer) Peek(ctx context.Context, keyHash authdb.KeyHash) (record *pb.
 		func(ctx context.Context, client pb.DRPCReplicationServiceClient) (err error) {
 			defer mon.Task()(&ctx)(&err)
 
+			start := time.Now()
 			resp, err := client.Peek(ctx, &pb.PeekRequest{EncryptionKeyHash: keyHash.Bytes()})
+			if err == nil || errs2.IsRPC(err, rpcstatus.NotFound) {
+				peer.observeLatency(time.Since(start))
+			}
 			if err != nil {
 				return Error.Wrap(err)
 			}
@@ -544,11 +634,13 @@ func (peer *Peer) Peek(ctx context.Context, keyHash authdb.KeyHash) (record *pb.
 func (peer *Peer) pingClient(ctx context.Context, client pb.DRPCReplicationServiceClient) (ok bool, err error) {
 	defer mon.Task()(&ctx)(&err)
 
+	start := time.Now()
 	resp, err := client.Ping(ctx, &pb.PingRequest{})
 	if err != nil {
 		peer.statusDown(err)
 		return false, nil
 	}
+	peer.observeLatency(time.Since(start))
 
 	var clientID NodeID
 	if err = clientID.SetBytes(resp.NodeId); err != nil {
@@ -556,11 +648,34 @@ func (peer *Peer) pingClient(ctx context.Context, client pb.DRPCReplicationServi
 		return false, nil
 	}
 	peer.statusUp()
+	peer.changeStatus(func(status *PeerStatus) {
+		status.NodeID = clientID
+		status.ReadOnly = resp.ReadOnly
+	})
 
 	if clientID == peer.node.ID() {
+		if peer.node.excludeSelf(peer.address) {
+			peer.log.Info("discovered address belongs to this node; excluding it from peers")
+			return false, nil
+		}
 		return false, Error.New("started with the same node ID (%s) as %s:", clientID, peer.address)
 	}
 
+	decommissioned, err := peer.node.db.isDecommissioned(clientID)
+	if err != nil {
+		return false, Error.New("couldn't check whether %s is decommissioned: %w", clientID, err)
+	}
+	if decommissioned {
+		peer.statusDown(Error.New("node ID %s is decommissioned", clientID))
+		return false, nil
+	}
+
+	// Read-only replicas don't have records of their own, so there's no need
+	// to track their clock or replicate from them.
+	if resp.ReadOnly {
+		return false, nil
+	}
+
 	if !peer.ensuredClock {
 		if err = peer.node.db.ensureClock(ctx, clientID); err != nil {
 			return false, Error.New("couldn't ensure clock for %s: %w", clientID, err)
@@ -601,6 +716,19 @@ func (peer *Peer) syncRecords(ctx context.Context, client pb.DRPCReplicationServ
 
 	peer.log.Debug("inserted new records from this peer", zap.Int("count", len(response.Entries)))
 
+	status := peer.Status()
+
+	clock, err := db.readClock(status.NodeID)
+	if err != nil {
+		peer.log.Error("failed to read clock", zap.Error(err))
+		return nil
+	}
+
+	peer.changeStatus(func(status *PeerStatus) {
+		status.LastSynced = time.Now()
+		status.Clock = clock
+	})
+
 	return nil
 }
 
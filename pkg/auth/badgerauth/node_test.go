@@ -4,6 +4,7 @@
 package badgerauth_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"storj.io/common/rpc/rpcstatus"
 	"storj.io/common/testcontext"
 	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/authdb/kvtest"
 	"storj.io/gateway-mt/pkg/auth/badgerauth"
 	"storj.io/gateway-mt/pkg/auth/badgerauth/badgerauthtest"
 	"storj.io/gateway-mt/pkg/auth/badgerauth/pb"
@@ -147,6 +149,7 @@ func TestNode_Replicate_Basic(t *testing.T) {
 						EncryptedAccessGrant: r.EncryptedAccessGrant,
 						State:                pb.Record_CREATED,
 					},
+					Clock: uint64(i + 1),
 				})
 			}
 		}
@@ -176,6 +179,7 @@ func TestNode_Replicate_Basic(t *testing.T) {
 						NodeId:            id.Bytes(),
 						EncryptionKeyHash: kh.Bytes(),
 						Record:            record,
+						Clock:             uint64(i - 51),
 					})
 				}
 			}
@@ -208,6 +212,7 @@ func TestNode_Replicate_Basic(t *testing.T) {
 				NodeId:            id.Bytes(),
 				EncryptionKeyHash: kh.Bytes(),
 				Record:            record,
+				Clock:             1,
 			})
 
 			return nil
@@ -267,6 +272,52 @@ func TestNode_Replicate_Basic(t *testing.T) {
 	})
 }
 
+func TestNode_PublishesReplicatedInvalidations(t *testing.T) {
+	badgerauthtest.RunSingleNode(t, badgerauth.Config{
+		ID: badgerauth.NodeID{'r', 'e', 'p', 'p', 'u', 'b'},
+	}, func(ctx *testcontext.Context, t *testing.T, _ *zap.Logger, node *badgerauth.Node) {
+		feed := authdb.NewInvalidationFeed(10)
+		node.SetInvalidationFeed(feed)
+
+		now := time.Now()
+		entry := func(keyHash authdb.KeyHash, clock uint64, invalidatedAt int64) *pb.ReplicationResponseEntry {
+			return &pb.ReplicationResponseEntry{
+				NodeId:            badgerauth.NodeID{'o'}.Bytes(),
+				EncryptionKeyHash: keyHash.Bytes(),
+				Record: &pb.Record{
+					CreatedAtUnix:        now.Unix(),
+					SatelliteAddress:     "s",
+					MacaroonHead:         []byte{'m'},
+					EncryptedSecretKey:   []byte{'k'},
+					EncryptedAccessGrant: []byte{'g'},
+					InvalidatedAtUnix:    invalidatedAt,
+					State:                pb.Record_CREATED,
+				},
+				Clock: clock,
+			}
+		}
+
+		// the record was invalidated on the origin node before it was
+		// replicated.
+		entries := []*pb.ReplicationResponseEntry{
+			entry(authdb.KeyHash{1}, 1, 0),
+			entry(authdb.KeyHash{2}, 2, now.Unix()),
+		}
+		for i := 0; i < 2; i++ { // already known entries aren't published again
+			resp, err := node.Push(ctx, &pb.PushRequest{NodeId: badgerauth.NodeID{'o'}.Bytes(), Entries: entries})
+			require.NoError(t, err)
+			require.EqualValues(t, 2, resp.Clock)
+		}
+
+		events, next, reset := feed.Wait(ctx, feed.ID(), 0, 0)
+		require.False(t, reset)
+		require.EqualValues(t, 1, next)
+		require.Equal(t, []authdb.Invalidation{
+			{Seq: 1, KeyHash: authdb.KeyHash{2}, Reason: authdb.InvalidationInvalidated},
+		}, events)
+	})
+}
+
 func TestNode_PeekRecord(t *testing.T) {
 	badgerauthtest.RunSingleNode(t, badgerauth.Config{
 		ID: badgerauth.NodeID{'p', 'e', 'e', 'k'},
@@ -364,3 +415,18 @@ func TestNew_CheckFirstStart(t *testing.T) {
 	require.Nil(t, n)
 	require.Error(t, err)
 }
+
+func TestNode_Conformance(t *testing.T) {
+	kvtest.Run(t, func(ctx *testcontext.Context, t *testing.T) authdb.KV {
+		return badgerauthtest.NewNode(t, zaptest.NewLogger(t), badgerauth.Config{ID: badgerauth.NodeID{'k', 'v'}})
+	}, kvtest.Options{
+		Invalidate: func(ctx context.Context, kv authdb.KV, keyHash authdb.KeyHash, reason string) error {
+			_, err := kv.(*badgerauth.Node).Admin().InvalidateRecord(ctx, &pb.InvalidateRecordRequest{
+				Key:    keyHash.Bytes(),
+				Reason: reason,
+			})
+			return err
+		},
+		DeleteUnusedUnsupported: true,
+	})
+}
@@ -41,6 +41,11 @@ type DRPCAdminServiceClient interface {
 	InvalidateRecord(ctx context.Context, in *InvalidateRecordRequest) (*InvalidateRecordResponse, error)
 	UnpublishRecord(ctx context.Context, in *UnpublishRecordRequest) (*UnpublishRecordResponse, error)
 	DeleteRecord(ctx context.Context, in *DeleteRecordRequest) (*DeleteRecordResponse, error)
+	AddPeer(ctx context.Context, in *AddPeerRequest) (*AddPeerResponse, error)
+	RemovePeer(ctx context.Context, in *RemovePeerRequest) (*RemovePeerResponse, error)
+	DecommissionNode(ctx context.Context, in *DecommissionNodeRequest) (*DecommissionNodeResponse, error)
+	ClusterStatus(ctx context.Context, in *ClusterStatusRequest) (*ClusterStatusResponse, error)
+	ListRecords(ctx context.Context, in *ListRecordsRequest) (*ListRecordsResponse, error)
 }
 
 type drpcAdminServiceClient struct {
@@ -80,10 +85,60 @@ func (c *drpcAdminServiceClient) DeleteRecord(ctx context.Context, in *DeleteRec
 	return out, nil
 }
 
+func (c *drpcAdminServiceClient) AddPeer(ctx context.Context, in *AddPeerRequest) (*AddPeerResponse, error) {
+	out := new(AddPeerResponse)
+	err := c.cc.Invoke(ctx, "/badgerauth.AdminService/AddPeer", drpcEncoding_File_badgerauth_admin_proto{}, in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *drpcAdminServiceClient) RemovePeer(ctx context.Context, in *RemovePeerRequest) (*RemovePeerResponse, error) {
+	out := new(RemovePeerResponse)
+	err := c.cc.Invoke(ctx, "/badgerauth.AdminService/RemovePeer", drpcEncoding_File_badgerauth_admin_proto{}, in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *drpcAdminServiceClient) DecommissionNode(ctx context.Context, in *DecommissionNodeRequest) (*DecommissionNodeResponse, error) {
+	out := new(DecommissionNodeResponse)
+	err := c.cc.Invoke(ctx, "/badgerauth.AdminService/DecommissionNode", drpcEncoding_File_badgerauth_admin_proto{}, in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *drpcAdminServiceClient) ClusterStatus(ctx context.Context, in *ClusterStatusRequest) (*ClusterStatusResponse, error) {
+	out := new(ClusterStatusResponse)
+	err := c.cc.Invoke(ctx, "/badgerauth.AdminService/ClusterStatus", drpcEncoding_File_badgerauth_admin_proto{}, in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *drpcAdminServiceClient) ListRecords(ctx context.Context, in *ListRecordsRequest) (*ListRecordsResponse, error) {
+	out := new(ListRecordsResponse)
+	err := c.cc.Invoke(ctx, "/badgerauth.AdminService/ListRecords", drpcEncoding_File_badgerauth_admin_proto{}, in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 type DRPCAdminServiceServer interface {
 	InvalidateRecord(context.Context, *InvalidateRecordRequest) (*InvalidateRecordResponse, error)
 	UnpublishRecord(context.Context, *UnpublishRecordRequest) (*UnpublishRecordResponse, error)
 	DeleteRecord(context.Context, *DeleteRecordRequest) (*DeleteRecordResponse, error)
+	AddPeer(context.Context, *AddPeerRequest) (*AddPeerResponse, error)
+	RemovePeer(context.Context, *RemovePeerRequest) (*RemovePeerResponse, error)
+	DecommissionNode(context.Context, *DecommissionNodeRequest) (*DecommissionNodeResponse, error)
+	ClusterStatus(context.Context, *ClusterStatusRequest) (*ClusterStatusResponse, error)
+	ListRecords(context.Context, *ListRecordsRequest) (*ListRecordsResponse, error)
 }
 
 type DRPCAdminServiceUnimplementedServer struct{}
@@ -100,9 +155,29 @@ func (s *DRPCAdminServiceUnimplementedServer) DeleteRecord(context.Context, *Del
 	return nil, drpcerr.WithCode(errors.New("Unimplemented"), drpcerr.Unimplemented)
 }
 
+func (s *DRPCAdminServiceUnimplementedServer) AddPeer(context.Context, *AddPeerRequest) (*AddPeerResponse, error) {
+	return nil, drpcerr.WithCode(errors.New("Unimplemented"), drpcerr.Unimplemented)
+}
+
+func (s *DRPCAdminServiceUnimplementedServer) RemovePeer(context.Context, *RemovePeerRequest) (*RemovePeerResponse, error) {
+	return nil, drpcerr.WithCode(errors.New("Unimplemented"), drpcerr.Unimplemented)
+}
+
+func (s *DRPCAdminServiceUnimplementedServer) DecommissionNode(context.Context, *DecommissionNodeRequest) (*DecommissionNodeResponse, error) {
+	return nil, drpcerr.WithCode(errors.New("Unimplemented"), drpcerr.Unimplemented)
+}
+
+func (s *DRPCAdminServiceUnimplementedServer) ClusterStatus(context.Context, *ClusterStatusRequest) (*ClusterStatusResponse, error) {
+	return nil, drpcerr.WithCode(errors.New("Unimplemented"), drpcerr.Unimplemented)
+}
+
+func (s *DRPCAdminServiceUnimplementedServer) ListRecords(context.Context, *ListRecordsRequest) (*ListRecordsResponse, error) {
+	return nil, drpcerr.WithCode(errors.New("Unimplemented"), drpcerr.Unimplemented)
+}
+
 type DRPCAdminServiceDescription struct{}
 
-func (DRPCAdminServiceDescription) NumMethods() int { return 3 }
+func (DRPCAdminServiceDescription) NumMethods() int { return 8 }
 
 func (DRPCAdminServiceDescription) Method(n int) (string, drpc.Encoding, drpc.Receiver, interface{}, bool) {
 	switch n {
@@ -133,6 +208,51 @@ func (DRPCAdminServiceDescription) Method(n int) (string, drpc.Encoding, drpc.Re
 						in1.(*DeleteRecordRequest),
 					)
 			}, DRPCAdminServiceServer.DeleteRecord, true
+	case 3:
+		return "/badgerauth.AdminService/AddPeer", drpcEncoding_File_badgerauth_admin_proto{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return srv.(DRPCAdminServiceServer).
+					AddPeer(
+						ctx,
+						in1.(*AddPeerRequest),
+					)
+			}, DRPCAdminServiceServer.AddPeer, true
+	case 4:
+		return "/badgerauth.AdminService/RemovePeer", drpcEncoding_File_badgerauth_admin_proto{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return srv.(DRPCAdminServiceServer).
+					RemovePeer(
+						ctx,
+						in1.(*RemovePeerRequest),
+					)
+			}, DRPCAdminServiceServer.RemovePeer, true
+	case 5:
+		return "/badgerauth.AdminService/DecommissionNode", drpcEncoding_File_badgerauth_admin_proto{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return srv.(DRPCAdminServiceServer).
+					DecommissionNode(
+						ctx,
+						in1.(*DecommissionNodeRequest),
+					)
+			}, DRPCAdminServiceServer.DecommissionNode, true
+	case 6:
+		return "/badgerauth.AdminService/ClusterStatus", drpcEncoding_File_badgerauth_admin_proto{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return srv.(DRPCAdminServiceServer).
+					ClusterStatus(
+						ctx,
+						in1.(*ClusterStatusRequest),
+					)
+			}, DRPCAdminServiceServer.ClusterStatus, true
+	case 7:
+		return "/badgerauth.AdminService/ListRecords", drpcEncoding_File_badgerauth_admin_proto{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return srv.(DRPCAdminServiceServer).
+					ListRecords(
+						ctx,
+						in1.(*ListRecordsRequest),
+					)
+			}, DRPCAdminServiceServer.ListRecords, true
 	default:
 		return "", nil, nil, nil, false
 	}
@@ -189,3 +309,83 @@ func (x *drpcAdminService_DeleteRecordStream) SendAndClose(m *DeleteRecordRespon
 	}
 	return x.CloseSend()
 }
+
+type DRPCAdminService_AddPeerStream interface {
+	drpc.Stream
+	SendAndClose(*AddPeerResponse) error
+}
+
+type drpcAdminService_AddPeerStream struct {
+	drpc.Stream
+}
+
+func (x *drpcAdminService_AddPeerStream) SendAndClose(m *AddPeerResponse) error {
+	if err := x.MsgSend(m, drpcEncoding_File_badgerauth_admin_proto{}); err != nil {
+		return err
+	}
+	return x.CloseSend()
+}
+
+type DRPCAdminService_RemovePeerStream interface {
+	drpc.Stream
+	SendAndClose(*RemovePeerResponse) error
+}
+
+type drpcAdminService_RemovePeerStream struct {
+	drpc.Stream
+}
+
+func (x *drpcAdminService_RemovePeerStream) SendAndClose(m *RemovePeerResponse) error {
+	if err := x.MsgSend(m, drpcEncoding_File_badgerauth_admin_proto{}); err != nil {
+		return err
+	}
+	return x.CloseSend()
+}
+
+type DRPCAdminService_DecommissionNodeStream interface {
+	drpc.Stream
+	SendAndClose(*DecommissionNodeResponse) error
+}
+
+type drpcAdminService_DecommissionNodeStream struct {
+	drpc.Stream
+}
+
+func (x *drpcAdminService_DecommissionNodeStream) SendAndClose(m *DecommissionNodeResponse) error {
+	if err := x.MsgSend(m, drpcEncoding_File_badgerauth_admin_proto{}); err != nil {
+		return err
+	}
+	return x.CloseSend()
+}
+
+type DRPCAdminService_ClusterStatusStream interface {
+	drpc.Stream
+	SendAndClose(*ClusterStatusResponse) error
+}
+
+type drpcAdminService_ClusterStatusStream struct {
+	drpc.Stream
+}
+
+func (x *drpcAdminService_ClusterStatusStream) SendAndClose(m *ClusterStatusResponse) error {
+	if err := x.MsgSend(m, drpcEncoding_File_badgerauth_admin_proto{}); err != nil {
+		return err
+	}
+	return x.CloseSend()
+}
+
+type DRPCAdminService_ListRecordsStream interface {
+	drpc.Stream
+	SendAndClose(*ListRecordsResponse) error
+}
+
+type drpcAdminService_ListRecordsStream struct {
+	drpc.Stream
+}
+
+func (x *drpcAdminService_ListRecordsStream) SendAndClose(m *ListRecordsResponse) error {
+	if err := x.MsgSend(m, drpcEncoding_File_badgerauth_admin_proto{}); err != nil {
+		return err
+	}
+	return x.CloseSend()
+}
@@ -41,6 +41,7 @@ type DRPCReplicationServiceClient interface {
 	Ping(ctx context.Context, in *PingRequest) (*PingResponse, error)
 	Peek(ctx context.Context, in *PeekRequest) (*PeekResponse, error)
 	Replicate(ctx context.Context, in *ReplicationRequest) (*ReplicationResponse, error)
+	Push(ctx context.Context, in *PushRequest) (*PushResponse, error)
 }
 
 type drpcReplicationServiceClient struct {
@@ -80,10 +81,20 @@ func (c *drpcReplicationServiceClient) Replicate(ctx context.Context, in *Replic
 	return out, nil
 }
 
+func (c *drpcReplicationServiceClient) Push(ctx context.Context, in *PushRequest) (*PushResponse, error) {
+	out := new(PushResponse)
+	err := c.cc.Invoke(ctx, "/badgerauth.ReplicationService/Push", drpcEncoding_File_badgerauth_proto{}, in, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 type DRPCReplicationServiceServer interface {
 	Ping(context.Context, *PingRequest) (*PingResponse, error)
 	Peek(context.Context, *PeekRequest) (*PeekResponse, error)
 	Replicate(context.Context, *ReplicationRequest) (*ReplicationResponse, error)
+	Push(context.Context, *PushRequest) (*PushResponse, error)
 }
 
 type DRPCReplicationServiceUnimplementedServer struct{}
@@ -100,9 +111,13 @@ func (s *DRPCReplicationServiceUnimplementedServer) Replicate(context.Context, *
 	return nil, drpcerr.WithCode(errors.New("Unimplemented"), drpcerr.Unimplemented)
 }
 
+func (s *DRPCReplicationServiceUnimplementedServer) Push(context.Context, *PushRequest) (*PushResponse, error) {
+	return nil, drpcerr.WithCode(errors.New("Unimplemented"), drpcerr.Unimplemented)
+}
+
 type DRPCReplicationServiceDescription struct{}
 
-func (DRPCReplicationServiceDescription) NumMethods() int { return 3 }
+func (DRPCReplicationServiceDescription) NumMethods() int { return 4 }
 
 func (DRPCReplicationServiceDescription) Method(n int) (string, drpc.Encoding, drpc.Receiver, interface{}, bool) {
 	switch n {
@@ -133,6 +148,15 @@ func (DRPCReplicationServiceDescription) Method(n int) (string, drpc.Encoding, d
 						in1.(*ReplicationRequest),
 					)
 			}, DRPCReplicationServiceServer.Replicate, true
+	case 3:
+		return "/badgerauth.ReplicationService/Push", drpcEncoding_File_badgerauth_proto{},
+			func(srv interface{}, ctx context.Context, in1, in2 interface{}) (drpc.Message, error) {
+				return srv.(DRPCReplicationServiceServer).
+					Push(
+						ctx,
+						in1.(*PushRequest),
+					)
+			}, DRPCReplicationServiceServer.Push, true
 	default:
 		return "", nil, nil, nil, false
 	}
@@ -189,3 +213,19 @@ func (x *drpcReplicationService_ReplicateStream) SendAndClose(m *ReplicationResp
 	}
 	return x.CloseSend()
 }
+
+type DRPCReplicationService_PushStream interface {
+	drpc.Stream
+	SendAndClose(*PushResponse) error
+}
+
+type drpcReplicationService_PushStream struct {
+	drpc.Stream
+}
+
+func (x *drpcReplicationService_PushStream) SendAndClose(m *PushResponse) error {
+	if err := x.MsgSend(m, drpcEncoding_File_badgerauth_proto{}); err != nil {
+		return err
+	}
+	return x.CloseSend()
+}
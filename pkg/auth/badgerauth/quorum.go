@@ -0,0 +1,158 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauth
+
+import (
+	"context"
+	"sync"
+
+	"github.com/spacemonkeygo/monkit/v3"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"storj.io/gateway-mt/pkg/auth/badgerauth/pb"
+)
+
+const (
+	// WriteQuorumFallbackFail makes Put fail if write quorum isn't reached.
+	WriteQuorumFallbackFail = "fail"
+	// WriteQuorumFallbackDegrade makes Put succeed with the record stored
+	// locally only if write quorum isn't reached.
+	WriteQuorumFallbackDegrade = "degrade"
+)
+
+// WriteQuorumError is a class of write quorum errors.
+var WriteQuorumError = errs.Class("write quorum")
+
+func (config Config) validateWriteQuorum() error {
+	if config.WriteQuorum < 0 {
+		return WriteQuorumError.New("must not be negative")
+	}
+	if config.WriteQuorum == 0 {
+		return nil
+	}
+	if config.WriteQuorumTimeout <= 0 {
+		return WriteQuorumError.New("timeout must be positive")
+	}
+	switch config.WriteQuorumFallback {
+	case WriteQuorumFallbackFail, WriteQuorumFallbackDegrade:
+		return nil
+	default:
+		return WriteQuorumError.New("unknown fallback %q (want %q or %q)", config.WriteQuorumFallback, WriteQuorumFallbackFail, WriteQuorumFallbackDegrade)
+	}
+}
+
+// awaitWriteQuorum pushes the node's records up to clock to all peers (except
+// known read-only ones) and waits until WriteQuorum of them acknowledge them or
+// WriteQuorumTimeout passes.
+func (node *Node) awaitWriteQuorum(ctx context.Context, clock Clock) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var peers []*Peer
+	for _, peer := range node.Peers() {
+		// Read-only replicas don't count towards the quorum.
+		if !peer.Status().ReadOnly {
+			peers = append(peers, peer)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, node.config.WriteQuorumTimeout)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	results := make(chan error, len(peers))
+	for _, peer := range peers {
+		peer := peer
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- peer.Push(ctx, clock)
+		}()
+	}
+
+	var (
+		acks     int
+		errGroup errs.Group
+	)
+	for range peers {
+		if acks >= node.config.WriteQuorum {
+			break
+		}
+		if err := <-results; err != nil {
+			errGroup.Add(err)
+			continue
+		}
+		acks++
+	}
+
+	mon.IntVal("as_badgerauth_write_quorum_acks").Observe(int64(acks))
+
+	if acks >= node.config.WriteQuorum {
+		mon.Event("as_badgerauth_write_quorum", monkit.NewSeriesTag("result", "reached"))
+		return nil
+	}
+
+	fields := []zap.Field{
+		zap.Int("acks", acks),
+		zap.Int("quorum", node.config.WriteQuorum),
+		zap.Int("peers", len(peers)),
+		zap.Error(errGroup.Err()),
+	}
+
+	if node.config.WriteQuorumFallback == WriteQuorumFallbackDegrade {
+		mon.Event("as_badgerauth_write_quorum", monkit.NewSeriesTag("result", "degraded"))
+		node.log.Warn("write quorum not reached; record is stored locally only", fields...)
+		return nil
+	}
+
+	mon.Event("as_badgerauth_write_quorum", monkit.NewSeriesTag("result", "failed"))
+	node.log.Error("write quorum not reached", fields...)
+
+	return WriteQuorumError.New("%d of %d required acknowledgements: %w", acks, node.config.WriteQuorum, errGroup.Err())
+}
+
+// Push pushes the node's records to the peer until the peer acknowledges
+// having the record at clock.
+func (peer *Peer) Push(ctx context.Context, clock Clock) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	return peer.withClient(ctx,
+		func(ctx context.Context, client pb.DRPCReplicationServiceClient) (err error) {
+			defer mon.Task()(&ctx)(&err)
+
+			id := peer.node.ID()
+
+			// Optimistically, the peer already has everything before clock.
+			since := clock - 1
+			for {
+				entries, err := peer.node.db.findResponseEntries(id, since)
+				if err != nil {
+					return Error.Wrap(err)
+				}
+				if len(entries) == 0 {
+					return Error.New("no records to push after %d", since)
+				}
+
+				resp, err := client.Push(ctx, &pb.PushRequest{
+					NodeId:  id.Bytes(),
+					Since:   uint64(since),
+					Entries: entries,
+				})
+				if err != nil {
+					return Error.Wrap(err)
+				}
+
+				if Clock(resp.Clock) >= clock {
+					return nil
+				}
+
+				// The peer is either behind since, or it has only caught up
+				// to the replication limit. Either way, continue from where
+				// it is.
+				since = Clock(resp.Clock)
+			}
+		}, "push")
+}
@@ -0,0 +1,146 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauth_test
+
+import (
+	"testing"
+	"time"
+
+	badger "github.com/outcaste-io/badger/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/common/testcontext"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/badgerauth"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/badgerauthtest"
+)
+
+func TestWriteQuorum(t *testing.T) {
+	badgerauthtest.RunCluster(t, badgerauthtest.ClusterConfig{
+		NodeCount: 3,
+		Defaults: badgerauth.Config{
+			ReplicationInterval: time.Hour,
+			ReplicationLimit:    2,
+			WriteQuorum:         2,
+		},
+	}, func(ctx *testcontext.Context, t *testing.T, cluster *badgerauthtest.Cluster) {
+		for _, node := range cluster.Nodes {
+			node.SyncCycle.TriggerWait()
+		}
+
+		origin := cluster.Nodes[0]
+
+		// Records put directly into the underlying DB aren't pushed, so peers
+		// are behind by more than the replication limit before the next put.
+		var keys []authdb.KeyHash
+		for i := 0; i < 5; i++ {
+			kh := authdb.KeyHash{'d', byte(i)}
+			require.NoError(t, origin.UnderlyingDB().Put(ctx, kh, &authdb.Record{
+				SatelliteAddress:     "t",
+				MacaroonHead:         []byte{'d'},
+				EncryptedSecretKey:   []byte{'s'},
+				EncryptedAccessGrant: []byte{'g'},
+			}))
+			keys = append(keys, kh)
+		}
+
+		for _, peer := range cluster.Nodes[1:] {
+			assert.EqualValues(t, 0, readClock(t, peer, origin.ID()))
+		}
+
+		badgerauthtest.Put{
+			KeyHash: authdb.KeyHash{'q'},
+			Record: &authdb.Record{
+				SatelliteAddress:     "t",
+				MacaroonHead:         []byte{'q'},
+				EncryptedSecretKey:   []byte{'s'},
+				EncryptedAccessGrant: []byte{'g'},
+			},
+		}.Check(ctx, t, origin)
+		keys = append(keys, authdb.KeyHash{'q'})
+
+		for _, peer := range cluster.Nodes[1:] {
+			for _, kh := range keys {
+				record, err := peer.UnderlyingDB().Get(ctx, kh)
+				require.NoError(t, err)
+				require.NotNil(t, record, "%x", kh)
+			}
+			assert.EqualValues(t, 6, readClock(t, peer, origin.ID()))
+		}
+
+		// Replication after pushing doesn't duplicate entries.
+		for _, node := range cluster.Nodes {
+			node.SyncCycle.TriggerWait()
+		}
+		for _, peer := range cluster.Nodes[1:] {
+			assert.EqualValues(t, 6, readClock(t, peer, origin.ID()))
+		}
+	})
+}
+
+func TestWriteQuorum_Fallback(t *testing.T) {
+	for _, fallback := range []string{badgerauth.WriteQuorumFallbackFail, badgerauth.WriteQuorumFallbackDegrade} {
+		fallback := fallback
+		t.Run(fallback, func(t *testing.T) {
+			badgerauthtest.RunCluster(t, badgerauthtest.ClusterConfig{
+				NodeCount: 2,
+				Defaults: badgerauth.Config{
+					WriteQuorum:         2,
+					WriteQuorumTimeout:  time.Second,
+					WriteQuorumFallback: fallback,
+				},
+			}, func(ctx *testcontext.Context, t *testing.T, cluster *badgerauthtest.Cluster) {
+				node := cluster.Nodes[0]
+				kh := authdb.KeyHash{'f'}
+
+				err := node.Put(ctx, kh, &authdb.Record{
+					SatelliteAddress:     "t",
+					MacaroonHead:         []byte{'f'},
+					EncryptedSecretKey:   []byte{'s'},
+					EncryptedAccessGrant: []byte{'g'},
+				})
+				if fallback == badgerauth.WriteQuorumFallbackFail {
+					require.Error(t, err)
+					assert.True(t, badgerauth.WriteQuorumError.Has(err))
+				} else {
+					require.NoError(t, err)
+				}
+
+				// The record is stored locally and pushed to the only peer
+				// regardless.
+				for _, n := range cluster.Nodes {
+					record, err := n.UnderlyingDB().Get(ctx, kh)
+					require.NoError(t, err)
+					require.NotNil(t, record)
+				}
+			})
+		})
+	}
+}
+
+func TestWriteQuorum_InvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	log := zaptest.NewLogger(t)
+
+	for _, config := range []badgerauth.Config{
+		{WriteQuorum: -1},
+		{WriteQuorum: 1, WriteQuorumFallback: badgerauth.WriteQuorumFallbackFail},
+		{WriteQuorum: 1, WriteQuorumTimeout: time.Second, WriteQuorumFallback: "ignore"},
+	} {
+		_, err := badgerauth.New(log, config)
+		require.Error(t, err)
+		assert.True(t, badgerauth.WriteQuorumError.Has(err))
+	}
+}
+
+func readClock(t *testing.T, node *badgerauth.Node, id badgerauth.NodeID) (clock badgerauth.Clock) {
+	require.NoError(t, node.UnderlyingDB().UnderlyingDB().View(func(txn *badger.Txn) (err error) {
+		clock, err = badgerauth.ReadClock(txn, id)
+		return err
+	}))
+	return clock
+}
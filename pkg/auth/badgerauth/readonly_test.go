@@ -0,0 +1,67 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package badgerauth_test
+
+import (
+	"testing"
+	"time"
+
+	badger "github.com/outcaste-io/badger/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/testcontext"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/badgerauth"
+	"storj.io/gateway-mt/pkg/auth/badgerauth/badgerauthtest"
+)
+
+func TestReadOnly(t *testing.T) {
+	badgerauthtest.RunCluster(t, badgerauthtest.ClusterConfig{
+		NodeCount: 2,
+		Defaults: badgerauth.Config{
+			ReplicationInterval: time.Hour,
+		},
+		ReconfigureNode: func(index int, config *badgerauth.Config) {
+			config.ReadOnly = index == 1
+		},
+	}, func(ctx *testcontext.Context, t *testing.T, cluster *badgerauthtest.Cluster) {
+		writer, replica := cluster.Nodes[0], cluster.Nodes[1]
+
+		records, keys, _ := badgerauthtest.CreateFullRecords(ctx, t, writer, 3)
+
+		badgerauthtest.Put{
+			KeyHash: authdb.KeyHash{'r', 'o'},
+			Record:  records[keys[0]],
+			Error:   badgerauth.ErrReadOnly,
+		}.Check(ctx, t, replica)
+
+		for _, node := range cluster.Nodes {
+			node.SyncCycle.TriggerWait()
+		}
+
+		// the replica replicates from the writer…
+		for _, kh := range keys {
+			badgerauthtest.Get{
+				KeyHash: kh,
+				Result:  records[kh],
+			}.Check(ctx, t, replica)
+		}
+
+		// …but the writer neither tracks the replica's clock nor replicates
+		// from it.
+		require.NoError(t, writer.UnderlyingDB().UnderlyingDB().View(func(txn *badger.Txn) error {
+			_, err := badgerauth.ReadClock(txn, replica.ID())
+			require.ErrorIs(t, err, badger.ErrKeyNotFound)
+			return nil
+		}))
+
+		status := writer.Peers()[0].Status()
+		assert.True(t, status.LastWasUp)
+		assert.True(t, status.ReadOnly)
+		assert.True(t, status.LastSynced.IsZero())
+
+		assert.False(t, replica.Peers()[0].Status().ReadOnly)
+	})
+}
@@ -30,6 +30,14 @@ func timeToTimestamp(t *time.Time) int64 {
 	return 0
 }
 
+// nonZeroTimeToTimestamp converts t to Unix time. It returns 0 if t is zero.
+func nonZeroTimeToTimestamp(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
 func recordsEqual(a, b *pb.Record) bool {
 	return pb.Equal(a, b)
 }
@@ -30,6 +30,14 @@ func TestTimeToTimestamp(t *testing.T) {
 	assert.Equal(t, now.Unix(), timeToTimestamp(&now))
 }
 
+func TestNonZeroTimeToTimestamp(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	assert.EqualValues(t, 0, nonZeroTimeToTimestamp(time.Time{}))
+	assert.Equal(t, now.Unix(), nonZeroTimeToTimestamp(now))
+}
+
 func TestRecordsEqual(t *testing.T) {
 	t.Parallel()
 
@@ -41,8 +41,12 @@ type Server struct {
 	// This is duplicated with package storj.io/gateway-mt/pkg/auth/httpauth/resources
 	// TODO: factor out common functionality
 	db                   *authdb.Database
-	endpoint             *url.URL
+	endpoints            *authdb.Endpoints
 	accessGrantSizeLimit memory.Size
+
+	// writableEndpoint is where clients are told to register access if set
+	// (e.g., because db is read-only).
+	writableEndpoint *url.URL
 }
 
 // NewServer creates a Server that is not running.
@@ -55,11 +59,25 @@ func NewServer(
 	return &Server{
 		log:                  log,
 		db:                   db,
-		endpoint:             endpoint,
+		endpoints:            authdb.NewEndpoints(endpoint, nil),
 		accessGrantSizeLimit: accessGrantSizeLimit,
 	}
 }
 
+// SetEndpoints makes the Server return endpoints for the satellites of
+// registered access grants instead of the single endpoint it was constructed
+// with. It must be called before serving requests.
+func (g *Server) SetEndpoints(endpoints *authdb.Endpoints) {
+	g.endpoints = endpoints
+}
+
+// SetWritableEndpoint makes the Server refuse requests to register access,
+// pointing clients to the authservice at endpoint instead. It must be called
+// before serving requests.
+func (g *Server) SetWritableEndpoint(endpoint *url.URL) {
+	g.writableEndpoint = endpoint
+}
+
 // RegisterAccess implements interface DRPCEdgeAuthServer.
 // Wraps the actual functionality with logging.
 func (g *Server) RegisterAccess(
@@ -70,6 +88,12 @@ func (g *Server) RegisterAccess(
 
 	g.log.Debug("DRPC RegisterAccess request")
 
+	// DRPC has no notion of redirects, so the best we can do is to tell the
+	// client where to go.
+	if g.writableEndpoint != nil {
+		return nil, rpcstatus.Errorf(rpcstatus.FailedPrecondition, "this authservice is read-only; register access at %s", g.writableEndpoint)
+	}
+
 	// NOTE(artur): DRPC's default message limit is 4 MiB, so we will read such
 	// messages anyway, but Auth Service would blow up memory consumption
 	// because it copies this access grant several times later on. Avoiding
@@ -111,7 +135,7 @@ func (g *Server) registerAccessImpl(
 	response := pb.EdgeRegisterAccessResponse{
 		AccessKeyId: accessKey.ToBase32(),
 		SecretKey:   secretKey.ToBase32(),
-		Endpoint:    g.endpoint.String(),
+		Endpoint:    g.endpoints.ForAccessGrant(request.AccessGrant).String(),
 	}
 
 	return &response, nil
@@ -66,7 +66,7 @@ func TestRegisterAccess(t *testing.T) {
 	err = accessKeyID.FromBase32(response.AccessKeyId)
 	require.NoError(t, err)
 
-	storedAccessGrant, storedPublic, storedSecretKey, err := db.Get(
+	storedAccessGrant, storedPublic, storedSecretKey, _, err := db.Get(
 		ctx,
 		accessKeyID,
 	)
@@ -77,6 +77,21 @@ func TestRegisterAccess(t *testing.T) {
 	require.Equal(t, response.SecretKey, storedSecretKey.ToBase32())
 }
 
+func TestRegisterAccessSatelliteEndpoints(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	server, _ := createBackend(t, 4*memory.KiB)
+
+	endpoints, err := authdb.ParseEndpoints("http://gateway.test", []string{minimalAccessSatelliteURL + "=https://gateway.region.test"})
+	require.NoError(t, err)
+	server.SetEndpoints(endpoints)
+
+	response, err := server.RegisterAccess(ctx, &pb.EdgeRegisterAccessRequest{AccessGrant: minimalAccess})
+	require.NoError(t, err)
+	require.Equal(t, "https://gateway.region.test", response.Endpoint)
+}
+
 func TestRegisterAccessTooLarge(t *testing.T) {
 	ctx := testcontext.New(t)
 	defer ctx.Cleanup()
@@ -104,3 +119,26 @@ func TestRegisterAccessTooLarge(t *testing.T) {
 	)
 	require.NoError(t, err)
 }
+
+func TestRegisterAccessWritableEndpoint(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	server, _ := createBackend(t, 4*memory.KiB)
+
+	writableEndpoint, err := url.Parse("https://writable.test")
+	require.NoError(t, err)
+	server.SetWritableEndpoint(writableEndpoint)
+
+	_, err = server.RegisterAccess(
+		ctx,
+		&pb.EdgeRegisterAccessRequest{
+			AccessGrant: minimalAccess,
+			Public:      false,
+		},
+	)
+	require.Error(t, err)
+
+	assert.Equal(t, rpcstatus.FailedPrecondition, rpcstatus.Code(err))
+	assert.Contains(t, err.Error(), "https://writable.test")
+}
@@ -0,0 +1,170 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"storj.io/common/memory"
+	"storj.io/common/sync2"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/badgerauth"
+	"storj.io/gateway-mt/pkg/auth/httpauth"
+	"storj.io/gateway-mt/pkg/auth/memauth"
+	"storj.io/gateway-mt/pkg/auth/satellitelist"
+	"storj.io/private/dbutil"
+)
+
+// EmbeddedConfig holds the configuration of authservice embedded in another
+// service.
+type EmbeddedConfig struct {
+	Enabled           bool          `help:"run authservice in-process, resolving access keys without requests to the auth service" default:"false"`
+	Endpoint          string        `help:"gateway endpoint URL to return to clients registering access grants" releaseDefault:"" devDefault:"http://localhost:20010"`
+	AllowedSatellites []string      `help:"list of satellite NodeURLs allowed for incoming access grants" default:"https://www.storj.io/dcs-satellites"`
+	CacheExpiration   time.Duration `help:"length of time satellite addresses are cached for" default:"10m"`
+	POSTSizeLimit     memory.Size   `help:"maximum size that the incoming POST request body with access grant can be" default:"4KiB"`
+	// KVBackend is limited to backends that make sense for a single process.
+	KVBackend string `help:"key/value store backend url (memory://, sqlite://path or badger://)" default:"memory://"`
+
+	Memory memauth.Config
+	Node   badgerauth.Config
+}
+
+// Embedded is authservice running in the same process as another service,
+// e.g., Gateway-MT. It serves the registration endpoints with Handler and
+// resolves access keys directly with Database.
+type Embedded struct {
+	log *zap.Logger
+	kv  authdb.KV
+	adb *authdb.Database
+	res *httpauth.Resources
+
+	satelliteList       *satellitelist.Loader
+	endpoints           *authdb.Endpoints
+	areSatsDynamic      bool
+	satelliteListReload *sync2.Cycle
+}
+
+// NewEmbedded constructs authservice to embed. The schema of sqlite backends
+// is migrated to the latest version right away. sqlite backends fail to open
+// if the binary was built without cgo.
+func NewEmbedded(ctx context.Context, log *zap.Logger, config EmbeddedConfig) (_ *Embedded, err error) {
+	if config.Endpoint == "" {
+		return nil, errs.New("embedded authservice requires an endpoint")
+	}
+	if len(config.AllowedSatellites) == 0 {
+		return nil, errs.New("embedded authservice requires allowed satellites")
+	}
+
+	driver, _, _, err := dbutil.SplitConnStr(config.KVBackend)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	switch driver {
+	case "memory", "sqlite", "sqlite3", "badger":
+	default:
+		return nil, errs.New("key/value store backend %q can't be embedded", driver)
+	}
+
+	satelliteList := satellitelist.NewLoader(log.Named("satellitelist"), config.AllowedSatellites, "")
+	list, areSatsDynamic, err := satelliteList.Load(ctx)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	endpoints, err := authdb.ParseEndpoints(config.Endpoint, nil)
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	endpoints.SetListed(list.Satellites)
+
+	kv, err := OpenKV(ctx, log.Named("db"), Config{
+		KVBackend: config.KVBackend,
+		Memory:    config.Memory,
+		Node:      config.Node,
+	})
+	if err != nil {
+		return nil, errs.Wrap(err)
+	}
+	defer func() {
+		if err != nil {
+			err = errs.Combine(err, kv.Close())
+		}
+	}()
+
+	if migrator, ok := kv.(interface {
+		MigrateToLatest(ctx context.Context) error
+	}); ok && driver != "badger" {
+		if err = migrator.MigrateToLatest(ctx); err != nil {
+			return nil, errs.Wrap(err)
+		}
+	}
+
+	// nothing needs to resolve access keys over HTTP, so we make sure nothing
+	// can with a token no one knows.
+	var token [32]byte
+	if _, err = rand.Read(token[:]); err != nil {
+		return nil, errs.Wrap(err)
+	}
+
+	adb := authdb.NewDatabase(kv, satellitelist.URLs(list.Satellites))
+	res := httpauth.New(log.Named("resources"), adb, endpoints.Default(), hex.EncodeToString(token[:]), config.POSTSizeLimit)
+	res.SetEndpoints(endpoints)
+	res.SetSatelliteList(satelliteList.Active)
+	res.SetStartupDone()
+
+	return &Embedded{
+		log: log,
+		kv:  kv,
+		adb: adb,
+		res: res,
+
+		satelliteList:       satelliteList,
+		endpoints:           endpoints,
+		areSatsDynamic:      areSatsDynamic,
+		satelliteListReload: sync2.NewCycle(config.CacheExpiration),
+	}, nil
+}
+
+// Database returns the database to resolve access keys with.
+func (e *Embedded) Database() *authdb.Database { return e.adb }
+
+// Handler returns the handler serving authservice's HTTP endpoints. Endpoints
+// requiring the auth token are unauthorized, as access keys are resolved
+// through Database.
+func (e *Embedded) Handler() http.Handler { return e.res }
+
+// Run runs the key/value store and reloads the allowed satellite list until
+// ctx is canceled.
+func (e *Embedded) Run(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	if e.areSatsDynamic {
+		e.satelliteListReload.Start(groupCtx, group, func(ctx context.Context) error {
+			reloadSatelliteList(ctx, e.log, e.adb, e.endpoints, e.satelliteList)
+			return nil
+		})
+		defer e.satelliteListReload.Close()
+	}
+
+	group.Go(func() error {
+		return e.kv.Run(groupCtx)
+	})
+
+	return errs.Wrap(group.Wait())
+}
+
+// Close closes the key/value store.
+func (e *Embedded) Close() error {
+	return errs.Wrap(e.kv.Close())
+}
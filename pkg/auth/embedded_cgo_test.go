@@ -0,0 +1,40 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+//go:build cgo
+// +build cgo
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/common/memory"
+	"storj.io/common/testcontext"
+)
+
+func TestEmbedded_SQLite(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	embedded, err := NewEmbedded(ctx, zaptest.NewLogger(t), EmbeddedConfig{
+		Endpoint:          embeddedEndpoint,
+		AllowedSatellites: []string{embeddedSatellite},
+		POSTSizeLimit:     4 * memory.KiB,
+		KVBackend:         "sqlite://" + ctx.File("auth.db"),
+	})
+	require.NoError(t, err)
+	defer ctx.Check(embedded.Close)
+
+	// the schema is migrated, so access grants can be registered right away.
+	rec := httptest.NewRecorder()
+	embedded.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/access",
+		strings.NewReader(`{"access_grant": "`+minimalAccess+`", "public": true}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
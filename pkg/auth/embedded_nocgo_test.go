@@ -0,0 +1,30 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+//go:build !cgo
+// +build !cgo
+
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/common/testcontext"
+	"storj.io/gateway-mt/pkg/auth/sqliteauth"
+)
+
+func TestEmbedded_SQLiteWithoutCgo(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	_, err := NewEmbedded(ctx, zaptest.NewLogger(t), EmbeddedConfig{
+		Endpoint:          embeddedEndpoint,
+		AllowedSatellites: []string{embeddedSatellite},
+		KVBackend:         "sqlite://" + ctx.File("auth.db"),
+	})
+	require.Error(t, err)
+	require.True(t, sqliteauth.Error.Has(err))
+}
@@ -0,0 +1,104 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/common/memory"
+	"storj.io/common/testcontext"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/authclient"
+	"storj.io/gateway-mt/pkg/errdata"
+)
+
+const (
+	// minimalAccess is an access grant of embeddedSatellite.
+	minimalAccess     = "13J4Upun87ATb3T5T5sDXVeQaCzWFZeF9Ly4ELfxS5hUwTL8APEkwahTEJ1wxZjyErimiDs3kgid33kDLuYPYtwaY7Toy32mCTapfrUB814X13RiA844HPWK3QLKZb9cAoVceTowmNZXWbcUMKNbkMHCURE4hn8ZrdHPE3S86yngjvDxwKmarfGx"
+	embeddedSatellite = "1SYXsAycDPUu4z2ZksJD5fh5nTDcH3vCFHnpcVye5XuL1NrYV@s"
+	embeddedEndpoint  = "http://gateway.invalid"
+)
+
+func TestEmbedded(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	embedded, err := NewEmbedded(ctx, zaptest.NewLogger(t), EmbeddedConfig{
+		Endpoint:          embeddedEndpoint,
+		AllowedSatellites: []string{embeddedSatellite},
+		POSTSizeLimit:     4 * memory.KiB,
+		KVBackend:         "memory://",
+	})
+	require.NoError(t, err)
+	defer ctx.Check(embedded.Close)
+
+	rec := httptest.NewRecorder()
+	embedded.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/access",
+		strings.NewReader(`{"access_grant": "`+minimalAccess+`", "public": true}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var registered map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &registered))
+	accessKeyID := registered["access_key_id"]
+	assert.Equal(t, embeddedEndpoint, registered["endpoint"])
+
+	// access grants can't be resolved over HTTP, not even with an empty
+	// token.
+	for _, authorization := range []string{"", "Bearer "} {
+		rec = httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/access/"+accessKeyID, nil)
+		req.Header.Set("Authorization", authorization)
+		embedded.Handler().ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	client := authclient.New(authclient.Config{})
+	client.SetLocal(embedded.Database())
+
+	resp, err := client.ResolveWithCache(ctx, accessKeyID, "127.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, minimalAccess, resp.AccessGrant)
+	assert.Equal(t, registered["secret_key"], resp.SecretKey)
+	assert.True(t, resp.Public)
+
+	unknown, err := authdb.NewEncryptionKey()
+	require.NoError(t, err)
+	_, err = client.Resolve(ctx, unknown.ToBase32(), "127.0.0.1")
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, errdata.GetStatus(err, http.StatusOK))
+
+	_, err = client.Resolve(ctx, "invalid", "127.0.0.1")
+	require.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, errdata.GetStatus(err, http.StatusOK))
+
+	healthy, err := client.GetHealthLive(ctx)
+	require.NoError(t, err)
+	assert.True(t, healthy)
+}
+
+func TestEmbedded_Config(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	_, err := NewEmbedded(ctx, zaptest.NewLogger(t), EmbeddedConfig{
+		AllowedSatellites: []string{embeddedSatellite},
+		KVBackend:         "memory://",
+	})
+	require.Error(t, err)
+
+	_, err = NewEmbedded(ctx, zaptest.NewLogger(t), EmbeddedConfig{
+		Endpoint:          embeddedEndpoint,
+		AllowedSatellites: []string{embeddedSatellite},
+		KVBackend:         "postgres://localhost/auth",
+	})
+	require.Error(t, err)
+}
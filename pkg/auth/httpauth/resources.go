@@ -4,25 +4,57 @@
 package httpauth
 
 import (
+	"context"
 	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"go.uber.org/zap"
 
 	"storj.io/common/memory"
 	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/satellitelist"
 )
 
+// maxInvalidationsWait is the longest a request for invalidations waits for
+// one to be published.
+const maxInvalidationsWait = time.Minute
+
+// InvalidReasonHeader is the header the reason an invalid record is invalid
+// is returned in when it's requested.
+const InvalidReasonHeader = "X-Invalid-Reason"
+
 // Resources wrap a database and expose methods over HTTP.
 type Resources struct {
 	db        *authdb.Database
-	endpoint  *url.URL
+	endpoints *authdb.Endpoints
 	authToken string
 
+	// writableEndpoint is where requests to register access are redirected
+	// if set (e.g., because db is read-only).
+	writableEndpoint *url.URL
+	// migrationProgress reports the progress of a migration between key/value
+	// store backends if set.
+	migrationProgress func(ctx context.Context) (progress interface{}, complete bool, err error)
+	// satelliteList returns the active allowed satellite list if set.
+	satelliteList func() *satellitelist.List
+	// accessCacheMaxAge is how long clients may cache resolved access grants
+	// if set. It's capped by the record's expiration.
+	accessCacheMaxAge time.Duration
+	// clientCertScopes, if set, additionally requires authorized requests to
+	// come with a client certificate granted the endpoint's scope.
+	clientCertScopes ClientCertScopes
+	// invalidations is the feed of records that clients should evict from
+	// their caches if set.
+	invalidations *authdb.InvalidationFeed
+
 	handler       http.Handler
 	id            *Arg
 	postSizeLimit memory.Size
@@ -44,7 +76,7 @@ func New(
 ) *Resources {
 	res := &Resources{
 		db:        db,
-		endpoint:  endpoint,
+		endpoints: authdb.NewEndpoints(endpoint, nil),
 		authToken: authToken,
 
 		id:            new(Arg),
@@ -65,6 +97,21 @@ func New(
 						"GET": http.HandlerFunc(res.getLive),
 					},
 				},
+				"/migration": Dir{
+					"": Method{
+						"GET": http.HandlerFunc(res.getMigration),
+					},
+				},
+			},
+			"/satellites": Dir{
+				"": Method{
+					"GET": http.HandlerFunc(res.getSatellites),
+				},
+			},
+			"/invalidations": Dir{
+				"": Method{
+					"GET": http.HandlerFunc(res.getInvalidations),
+				},
 			},
 			"/access": Dir{
 				"": Method{
@@ -99,6 +146,49 @@ func (res *Resources) writeError(w http.ResponseWriter, method string, msg strin
 	http.Error(w, msg, status)
 }
 
+// SetWritableEndpoint makes Resources redirect requests to register access to
+// the authservice at endpoint. It must be called before serving requests.
+func (res *Resources) SetWritableEndpoint(endpoint *url.URL) {
+	res.writableEndpoint = endpoint
+}
+
+// SetEndpoints makes Resources return endpoints for the satellites of
+// registered access grants instead of the single endpoint it was constructed
+// with. It must be called before serving requests.
+func (res *Resources) SetEndpoints(endpoints *authdb.Endpoints) {
+	res.endpoints = endpoints
+}
+
+// SetMigrationProgress makes Resources report the progress of a migration
+// between key/value store backends. It must be called before serving requests.
+func (res *Resources) SetMigrationProgress(progress func(ctx context.Context) (progress interface{}, complete bool, err error)) {
+	res.migrationProgress = progress
+}
+
+// SetAccessCacheMaxAge sets how long clients may cache resolved access
+// grants. Responses tell clients via Cache-Control.
+func (res *Resources) SetAccessCacheMaxAge(maxAge time.Duration) {
+	res.accessCacheMaxAge = maxAge
+}
+
+// SetClientCertScopes makes authorized endpoints require a verified client
+// certificate granted their scope in addition to the auth token.
+func (res *Resources) SetClientCertScopes(scopes ClientCertScopes) {
+	res.clientCertScopes = scopes
+}
+
+// SetInvalidationFeed makes Resources serve feed to clients caching access
+// grants. It must be called before serving requests.
+func (res *Resources) SetInvalidationFeed(feed *authdb.InvalidationFeed) {
+	res.invalidations = feed
+}
+
+// SetSatelliteList makes Resources show the active allowed satellite list
+// returned by list. It must be called before serving requests.
+func (res *Resources) SetSatelliteList(list func() *satellitelist.List) {
+	res.satelliteList = list
+}
+
 // SetStartupDone sets the startup status flag to true indicating startup is complete.
 func (res *Resources) SetStartupDone() {
 	res.mu.Lock()
@@ -148,9 +238,189 @@ func (res *Resources) getLive(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// getMigration returns the progress of a migration between key/value store
+// backends as JSON with 200 when it's complete and 503 Service Unavailable
+// otherwise, or 404 if there's no migration.
+func (res *Resources) getMigration(w http.ResponseWriter, req *http.Request) {
+	if res.migrationProgress == nil {
+		res.writeError(w, "getMigration", "no migration configured", http.StatusNotFound)
+		return
+	}
+
+	progress, complete, err := res.migrationProgress(req.Context())
+	if err != nil {
+		res.writeError(w, "getMigration", err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if complete {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	if err = json.NewEncoder(w).Encode(progress); err != nil {
+		res.log.Error("failed to encode migration progress", zap.Error(err))
+	}
+}
+
+// getSatellites writes the active allowed satellite list with its source and
+// age as JSON. It requires authorization.
+func (res *Resources) getSatellites(w http.ResponseWriter, req *http.Request) {
+	if !res.requestAuthorized(req, ScopeSatellites) {
+		res.writeError(w, "getSatellites", "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var list *satellitelist.List
+	if res.satelliteList != nil {
+		list = res.satelliteList()
+	}
+	if list == nil {
+		res.writeError(w, "getSatellites", "no satellite list loaded", http.StatusNotFound)
+		return
+	}
+
+	type satellite struct {
+		URL      string `json:"url"`
+		Region   string `json:"region,omitempty"`
+		Name     string `json:"name,omitempty"`
+		Endpoint string `json:"endpoint,omitempty"`
+	}
+
+	response := struct {
+		Source     satellitelist.Source `json:"source"`
+		LoadedAt   time.Time            `json:"loaded_at"`
+		AgeSeconds int64                `json:"age_seconds"`
+		Satellites []satellite          `json:"satellites"`
+	}{
+		Source:     list.Source,
+		LoadedAt:   list.LoadedAt,
+		AgeSeconds: int64(time.Since(list.LoadedAt).Seconds()),
+		Satellites: make([]satellite, 0, len(list.Satellites)),
+	}
+
+	for _, s := range list.Satellites {
+		response.Satellites = append(response.Satellites, satellite{
+			URL:      s.URL.String(),
+			Region:   s.Region,
+			Name:     s.Name,
+			Endpoint: s.Endpoint,
+		})
+	}
+	sort.Slice(response.Satellites, func(i, j int) bool {
+		return response.Satellites[i].URL < response.Satellites[j].URL
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		res.log.Error("failed to encode satellite list", zap.Error(err))
+	}
+}
+
+// getInvalidations writes invalidations published to the feed after the
+// client's position in it as JSON. If there are none, it waits for one up to
+// the wait parameter (capped by maxInvalidationsWait). It requires
+// authorization.
+//
+// Clients following several feeds (e.g., of auth services behind a load
+// balancer) pass their position in each as a cursor parameter of the form
+// <feed>:<since>, so that any auth service can continue from where they are in
+// its feed. The feed and since parameters are a single position.
+func (res *Resources) getInvalidations(w http.ResponseWriter, req *http.Request) {
+	if !res.requestAuthorized(req, ScopeAccess) {
+		res.writeError(w, "getInvalidations", "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if res.invalidations == nil {
+		res.writeError(w, "getInvalidations", "no invalidation feed configured", http.StatusNotFound)
+		return
+	}
+
+	query := req.URL.Query()
+
+	feed := query.Get("feed")
+
+	var since uint64
+	if v := query.Get("since"); v != "" {
+		var err error
+		if since, err = strconv.ParseUint(v, 10, 64); err != nil {
+			res.writeError(w, "getInvalidations", "invalid since parameter", http.StatusBadRequest)
+			return
+		}
+	}
+
+	for _, cursor := range query["cursor"] {
+		i := strings.LastIndexByte(cursor, ':')
+		if i < 0 {
+			res.writeError(w, "getInvalidations", "invalid cursor parameter", http.StatusBadRequest)
+			return
+		}
+		position, err := strconv.ParseUint(cursor[i+1:], 10, 64)
+		if err != nil {
+			res.writeError(w, "getInvalidations", "invalid cursor parameter", http.StatusBadRequest)
+			return
+		}
+		if cursor[:i] == res.invalidations.ID() {
+			feed, since = cursor[:i], position
+		}
+	}
+
+	var wait time.Duration
+	if v := query.Get("wait"); v != "" {
+		var err error
+		if wait, err = time.ParseDuration(v); err != nil || wait < 0 {
+			res.writeError(w, "getInvalidations", "invalid wait parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	if wait > maxInvalidationsWait {
+		wait = maxInvalidationsWait
+	}
+
+	events, next, reset := res.invalidations.Wait(req.Context(), feed, since, wait)
+
+	type invalidation struct {
+		KeyHash string                    `json:"key_hash"`
+		Reason  authdb.InvalidationReason `json:"reason"`
+	}
+
+	response := struct {
+		Feed          string         `json:"feed"`
+		Next          uint64         `json:"next"`
+		Reset         bool           `json:"reset"`
+		Invalidations []invalidation `json:"invalidations"`
+	}{
+		Feed:          res.invalidations.ID(),
+		Next:          next,
+		Reset:         reset,
+		Invalidations: make([]invalidation, 0, len(events)),
+	}
+
+	for _, e := range events {
+		response.Invalidations = append(response.Invalidations, invalidation{
+			KeyHash: e.KeyHash.ToHex(),
+			Reason:  e.Reason,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		res.log.Error("failed to encode invalidations", zap.Error(err))
+	}
+}
+
 func (res *Resources) newAccess(w http.ResponseWriter, req *http.Request) {
 	res.newAccessCORS(w, req)
 	res.log.Debug("newAccess request", zap.String("remote address", req.RemoteAddr))
+
+	if res.writableEndpoint != nil {
+		// 307 makes clients repeat the request with the same method and body.
+		target := res.writableEndpoint.ResolveReference(&url.URL{Path: "/v1/access"})
+		http.Redirect(w, req, target.String(), http.StatusTemporaryRedirect)
+		return
+	}
 	var request struct {
 		AccessGrant string `json:"access_grant"`
 		Public      bool   `json:"public"`
@@ -191,7 +461,7 @@ func (res *Resources) newAccess(w http.ResponseWriter, req *http.Request) {
 
 	response.AccessKeyID = key.ToBase32()
 	response.SecretKey = secretKey.ToBase32()
-	response.Endpoint = res.endpoint.String()
+	response.Endpoint = res.endpoints.ForAccessGrant(request.AccessGrant).String()
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(response)
@@ -213,14 +483,17 @@ func (res *Resources) newAccessCORS(w http.ResponseWriter, req *http.Request) {
 		"Content-Type, Accept, Accept-Language, Content-Language, Content-Length, Accept-Encoding")
 }
 
-func (res *Resources) requestAuthorized(req *http.Request) bool {
+func (res *Resources) requestAuthorized(req *http.Request, scope Scope) bool {
 	auth := req.Header.Get("Authorization")
-	return subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+res.authToken)) == 1
+	if subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+res.authToken)) != 1 {
+		return false
+	}
+	return res.clientCertScopes == nil || res.clientCertScopes.Allowed(req, scope)
 }
 
 func (res *Resources) getAccess(w http.ResponseWriter, req *http.Request) {
 	res.log.Debug("getAccess request", zap.String("remote address", req.RemoteAddr))
-	if !res.requestAuthorized(req) {
+	if !res.requestAuthorized(req, ScopeAccess) {
 		res.writeError(w, "getAccess", "unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -232,27 +505,53 @@ func (res *Resources) getAccess(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	accessGrant, public, secretKey, err := res.db.Get(req.Context(), key)
+	accessGrant, public, secretKey, expiresAt, err := res.db.Get(req.Context(), key)
 	if err != nil {
 		if authdb.NotFound.Has(err) {
 			res.writeError(w, "getAccess", err.Error(), http.StatusUnauthorized)
 			return
 		}
+		if reason, ok := authdb.InvalidReason(err); ok {
+			w.Header().Set(InvalidReasonHeader, reason)
+		}
 
 		res.writeError(w, "getAccess", err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	var response struct {
-		AccessGrant string `json:"access_grant"`
-		SecretKey   string `json:"secret_key"`
-		Public      bool   `json:"public"`
+		AccessGrant string     `json:"access_grant"`
+		SecretKey   string     `json:"secret_key"`
+		Public      bool       `json:"public"`
+		ExpiresAt   *time.Time `json:"expires_at,omitempty"`
 	}
 
 	response.AccessGrant = accessGrant
 	response.SecretKey = secretKey.ToBase32()
 	response.Public = public
+	response.ExpiresAt = expiresAt
 
+	if maxAge, ok := res.cacheMaxAge(expiresAt); ok {
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int64(maxAge.Seconds())))
+	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(response)
 }
+
+// cacheMaxAge returns how long clients may cache a record expiring at
+// expiresAt, if they should be told.
+func (res *Resources) cacheMaxAge(expiresAt *time.Time) (time.Duration, bool) {
+	maxAge := res.accessCacheMaxAge
+	if expiresAt != nil {
+		untilExpired := time.Until(*expiresAt)
+		if untilExpired < 0 {
+			untilExpired = 0
+		}
+		if maxAge <= 0 || untilExpired < maxAge {
+			maxAge = untilExpired
+		}
+	} else if maxAge <= 0 {
+		return 0, false
+	}
+	return maxAge, true
+}
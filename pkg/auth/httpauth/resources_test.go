@@ -5,13 +5,18 @@ package httpauth
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -258,6 +263,135 @@ func TestResources_CORS(t *testing.T) {
 	require.False(t, check("DELETE", "/v1/access/someid"))
 }
 
+func TestResources_WritableEndpoint(t *testing.T) {
+	endpoint, err := url.Parse("http://endpoint.invalid/")
+	require.NoError(t, err)
+	writableEndpoint, err := url.Parse("https://writable.invalid")
+	require.NoError(t, err)
+
+	res := New(zaptest.NewLogger(t), nil, endpoint, "authToken", 4*memory.KiB)
+	res.SetWritableEndpoint(writableEndpoint)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/access", strings.NewReader(`{"access_grant":"`+minimalAccess+`"}`))
+	res.ServeHTTP(rec, req)
+
+	r := rec.Result()
+	require.NoError(t, r.Body.Close())
+	assert.Equal(t, http.StatusTemporaryRedirect, r.StatusCode)
+	assert.Equal(t, "https://writable.invalid/v1/access", r.Header.Get("Location"))
+	assert.Equal(t, "*", r.Header.Get("Access-Control-Allow-Origin"))
+}
+
+func TestResources_SatelliteEndpoints(t *testing.T) {
+	endpoint, err := url.Parse("http://endpoint.invalid/")
+	require.NoError(t, err)
+
+	allowed := map[storj.NodeURL]struct{}{minimalAccessSatelliteID: {}}
+	res := newResource(t, authdb.NewDatabase(memauth.New(), allowed), endpoint)
+
+	endpoints, err := authdb.ParseEndpoints(endpoint.String(), []string{minimalAccessSatelliteURL + "=https://region.invalid"})
+	require.NoError(t, err)
+	res.SetEndpoints(endpoints)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/access", strings.NewReader(`{"access_grant":"`+minimalAccess+`"}`))
+	res.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		Endpoint string `json:"endpoint"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.Equal(t, "https://region.invalid", response.Endpoint)
+}
+
+func TestResources_Satellites(t *testing.T) {
+	res := New(zaptest.NewLogger(t), nil, nil, "authToken", 4*memory.KiB)
+
+	get := func(authorization string) *http.Response {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/satellites", nil)
+		req.Header.Set("Authorization", authorization)
+		res.ServeHTTP(rec, req)
+		return rec.Result()
+	}
+
+	r := get("Bearer authToken")
+	require.NoError(t, r.Body.Close())
+	assert.Equal(t, http.StatusNotFound, r.StatusCode)
+
+	loadedAt := time.Now().Add(-time.Hour)
+	res.SetSatelliteList(func() *satellitelist.List {
+		return &satellitelist.List{
+			Satellites: map[storj.NodeURL]satellitelist.Satellite{
+				minimalAccessSatelliteID: {URL: minimalAccessSatelliteID, Region: "test", Endpoint: "https://region.invalid"},
+			},
+			Source:   satellitelist.SourceCache,
+			LoadedAt: loadedAt,
+		}
+	})
+
+	r = get("Bearer wrong")
+	require.NoError(t, r.Body.Close())
+	assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+
+	r = get("Bearer authToken")
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+
+	var response struct {
+		Source     string    `json:"source"`
+		LoadedAt   time.Time `json:"loaded_at"`
+		AgeSeconds int64     `json:"age_seconds"`
+		Satellites []struct {
+			URL      string `json:"url"`
+			Region   string `json:"region"`
+			Endpoint string `json:"endpoint"`
+		} `json:"satellites"`
+	}
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&response))
+	require.NoError(t, r.Body.Close())
+
+	assert.Equal(t, "cache", response.Source)
+	assert.True(t, loadedAt.Equal(response.LoadedAt))
+	assert.GreaterOrEqual(t, response.AgeSeconds, int64(3600))
+	require.Len(t, response.Satellites, 1)
+	assert.Equal(t, minimalAccessSatelliteID.String(), response.Satellites[0].URL)
+	assert.Equal(t, "test", response.Satellites[0].Region)
+	assert.Equal(t, "https://region.invalid", response.Satellites[0].Endpoint)
+}
+
+func TestResources_Migration(t *testing.T) {
+	res := New(zaptest.NewLogger(t), nil, nil, "", 4*memory.KiB)
+
+	check := func(expectedStatus int, expectedBody string) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/health/migration", nil)
+		res.ServeHTTP(rec, req)
+
+		r := rec.Result()
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, r.Body.Close())
+		assert.Equal(t, expectedStatus, r.StatusCode)
+		if expectedBody != "" {
+			assert.JSONEq(t, expectedBody, string(body))
+		}
+	}
+
+	check(http.StatusNotFound, "")
+
+	var complete bool
+	res.SetMigrationProgress(func(ctx context.Context) (interface{}, bool, error) {
+		return map[string]int{"migrated": 5}, complete, nil
+	})
+
+	check(http.StatusServiceUnavailable, `{"migrated":5}`)
+
+	complete = true
+	check(http.StatusOK, `{"migrated":5}`)
+}
+
 func TestResources_EntityTooLarge(t *testing.T) {
 	const path = "/v1/access"
 
@@ -306,3 +440,175 @@ func newResource(t *testing.T, db *authdb.Database, endpoint *url.URL) *Resource
 
 	return New(zaptest.NewLogger(t), db, endpoint, "authToken", 4*memory.KiB)
 }
+
+func TestResources_ClientCertScopes(t *testing.T) {
+	res := New(zaptest.NewLogger(t), nil, nil, "authToken", 4*memory.KiB)
+	res.SetSatelliteList(func() *satellitelist.List {
+		return &satellitelist.List{Source: satellitelist.SourceCache}
+	})
+
+	scopes, err := ParseClientCertScopes([]string{"gateway=access", "gateway=satellites", "monitor=satellites"})
+	require.NoError(t, err)
+	res.SetClientCertScopes(scopes)
+
+	get := func(path string, subject string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer authToken")
+		if subject != "" {
+			req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+				{Subject: pkix.Name{CommonName: subject}},
+			}}}
+		}
+		res.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// the token alone isn't enough.
+	assert.Equal(t, http.StatusUnauthorized, get("/v1/satellites", ""))
+	assert.Equal(t, http.StatusUnauthorized, get("/v1/access/abc", ""))
+
+	assert.Equal(t, http.StatusOK, get("/v1/satellites", "monitor"))
+	assert.Equal(t, http.StatusUnauthorized, get("/v1/access/abc", "monitor"))
+	assert.Equal(t, http.StatusUnauthorized, get("/v1/satellites", "unknown"))
+
+	// authorized, but the key is malformed.
+	assert.Equal(t, http.StatusBadRequest, get("/v1/access/abc", "gateway"))
+
+	_, err = ParseClientCertScopes([]string{"gateway"})
+	require.Error(t, err)
+	_, err = ParseClientCertScopes([]string{"gateway=everything"})
+	require.Error(t, err)
+}
+
+func TestResources_AccessCacheMaxAge(t *testing.T) {
+	endpoint, err := url.Parse("http://endpoint.invalid/")
+	require.NoError(t, err)
+
+	allowed := map[storj.NodeURL]struct{}{minimalAccessSatelliteID: {}}
+	res := newResource(t, authdb.NewDatabase(memauth.New(), allowed), endpoint)
+	res.SetAccessCacheMaxAge(24 * time.Hour)
+
+	register := func(notAfter *time.Time) string {
+		mac, err := macaroon.NewAPIKey(nil)
+		require.NoError(t, err)
+		if notAfter != nil {
+			mac, err = mac.Restrict(macaroon.Caveat{NotAfter: notAfter})
+			require.NoError(t, err)
+		}
+		access, err := (&grant.Access{
+			SatelliteAddress: minimalAccessSatelliteURL,
+			APIKey:           mac,
+			EncAccess:        grant.NewEncryptionAccess(),
+		}).Serialize()
+		require.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		res.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/access", strings.NewReader(fmt.Sprintf(`{"access_grant": %q}`, access))))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var out map[string]interface{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+		return out["access_key_id"].(string)
+	}
+
+	get := func(accessKeyID string) (cacheControl string, expiresAt *time.Time) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/access/"+accessKeyID, nil)
+		req.Header.Set("Authorization", "Bearer authToken")
+		res.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var out struct {
+			ExpiresAt *time.Time `json:"expires_at"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+		return rec.Header().Get("Cache-Control"), out.ExpiresAt
+	}
+
+	cacheControl, expiresAt := get(register(nil))
+	assert.Equal(t, "private, max-age=86400", cacheControl)
+	assert.Nil(t, expiresAt)
+
+	notAfter := time.Now().Add(time.Hour)
+	cacheControl, expiresAt = get(register(&notAfter))
+	require.NotNil(t, expiresAt)
+	assert.WithinDuration(t, notAfter, *expiresAt, time.Second)
+	assert.Contains(t, []string{"private, max-age=3599", "private, max-age=3600"}, cacheControl)
+}
+
+func TestResources_Invalidations(t *testing.T) {
+	res := New(zaptest.NewLogger(t), nil, nil, "authToken", 4*memory.KiB)
+
+	type response struct {
+		Feed          string `json:"feed"`
+		Next          uint64 `json:"next"`
+		Reset         bool   `json:"reset"`
+		Invalidations []struct {
+			KeyHash string `json:"key_hash"`
+			Reason  string `json:"reason"`
+		} `json:"invalidations"`
+	}
+
+	get := func(authorization, query string) (int, response) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/invalidations?"+query, nil)
+		req.Header.Set("Authorization", authorization)
+		res.ServeHTTP(rec, req)
+
+		var out response
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+		}
+		return rec.Code, out
+	}
+
+	code, _ := get("Bearer authToken", "")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	feed := authdb.NewInvalidationFeed(10)
+	res.SetInvalidationFeed(feed)
+
+	code, _ = get("Bearer wrong", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = get("Bearer authToken", "since=x")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = get("Bearer authToken", "wait=-1s")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, out := get("Bearer authToken", "")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, feed.ID(), out.Feed)
+	assert.True(t, out.Reset)
+
+	feed.Publish(authdb.KeyHash{1}, authdb.InvalidationDeleted)
+
+	code, out = get("Bearer authToken", "feed="+feed.ID()+"&since=0&wait=1m")
+	require.Equal(t, http.StatusOK, code)
+	assert.False(t, out.Reset)
+	assert.EqualValues(t, 1, out.Next)
+	require.Len(t, out.Invalidations, 1)
+	assert.Equal(t, authdb.KeyHash{1}.ToHex(), out.Invalidations[0].KeyHash)
+	assert.Equal(t, "deleted", out.Invalidations[0].Reason)
+
+	code, out = get("Bearer authToken", "feed="+feed.ID()+"&since=1&wait=1ms")
+	require.Equal(t, http.StatusOK, code)
+	assert.False(t, out.Reset)
+	assert.Empty(t, out.Invalidations)
+
+	code, _ = get("Bearer authToken", "cursor=x")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// clients pass their position in every feed they follow; the position in
+	// this one is used.
+	code, out = get("Bearer authToken", "cursor=other:5&cursor="+feed.ID()+":0&wait=1ms")
+	require.Equal(t, http.StatusOK, code)
+	assert.False(t, out.Reset)
+	assert.EqualValues(t, 1, out.Next)
+	require.Len(t, out.Invalidations, 1)
+
+	code, out = get("Bearer authToken", "cursor=other:5&wait=1ms")
+	require.Equal(t, http.StatusOK, code)
+	assert.True(t, out.Reset)
+}
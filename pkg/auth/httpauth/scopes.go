@@ -0,0 +1,65 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package httpauth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/zeebo/errs"
+)
+
+// Scope is a set of endpoints a client certificate can be granted access to.
+type Scope string
+
+const (
+	// ScopeAccess allows resolving access grants.
+	ScopeAccess Scope = "access"
+	// ScopeSatellites allows reading the allowed satellite list.
+	ScopeSatellites Scope = "satellites"
+)
+
+// ClientCertScopes maps subject common names of client certificates to the
+// scopes they are granted.
+type ClientCertScopes map[string]map[Scope]struct{}
+
+// ParseClientCertScopes parses scopes given as subject=scope pairs. A subject
+// can be listed more than once to grant it multiple scopes.
+func ParseClientCertScopes(pairs []string) (ClientCertScopes, error) {
+	scopes := make(ClientCertScopes, len(pairs))
+	for _, p := range pairs {
+		subject, scope, ok := strings.Cut(strings.TrimSpace(p), "=")
+		if !ok || subject == "" {
+			return nil, errs.New("client certificate scope %q isn't subject=scope", p)
+		}
+
+		switch s := Scope(scope); s {
+		case ScopeAccess, ScopeSatellites:
+			if scopes[subject] == nil {
+				scopes[subject] = make(map[Scope]struct{})
+			}
+			scopes[subject][s] = struct{}{}
+		default:
+			return nil, errs.New("unknown client certificate scope %q", scope)
+		}
+	}
+	return scopes, nil
+}
+
+// Allowed returns whether req came with a verified client certificate granted
+// scope.
+func (s ClientCertScopes) Allowed(req *http.Request, scope Scope) bool {
+	if req.TLS == nil {
+		return false
+	}
+	for _, chain := range req.TLS.VerifiedChains {
+		if len(chain) == 0 {
+			continue
+		}
+		if _, ok := s[chain[0].Subject.CommonName][scope]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -14,6 +14,7 @@ import (
 	"storj.io/gateway-mt/pkg/auth/badgerauth/badgerauthmigration"
 	"storj.io/gateway-mt/pkg/auth/memauth"
 	"storj.io/gateway-mt/pkg/auth/sqlauth"
+	"storj.io/gateway-mt/pkg/auth/sqliteauth"
 	"storj.io/private/dbutil"
 )
 
@@ -28,17 +29,26 @@ func OpenKV(ctx context.Context, log *zap.Logger, config Config) (_ authdb.KV, e
 
 	switch driver {
 	case "memory":
-		return memauth.New(), nil
+		return memauth.Open(ctx, log, config.Memory)
 	case "pgxcockroach", "postgres", "cockroach", "pgx":
 		return sqlauth.Open(ctx, log, config.KVBackend, sqlauth.Options{
 			ApplicationName: "authservice",
+			ReadReplicas:    config.KVBackendReadReplicas,
 		})
+	case "sqlite", "sqlite3":
+		return sqliteauth.Open(ctx, log, config.KVBackend)
 	case "badger":
 		kv, err := badgerauth.New(log, config.Node)
 		if err != nil {
 			return nil, err
 		}
+		if config.NodeMigration.SourceSQLAuthKVBackend != "" && config.NodeMigration.DestinationSQLAuthKVBackend != "" {
+			return nil, errs.Combine(errs.New("migration can't have both source and destination backends"), kv.Close())
+		}
 		if config.NodeMigration.SourceSQLAuthKVBackend != "" {
+			if config.Node.ReadOnly {
+				return nil, errs.Combine(errs.New("migration isn't supported on read-only nodes"), kv.Close())
+			}
 			src, err := sqlauth.Open(ctx, log, config.NodeMigration.SourceSQLAuthKVBackend, sqlauth.Options{
 				ApplicationName: "authservice (sqlauth->badgerauth migration)",
 			})
@@ -47,6 +57,18 @@ func OpenKV(ctx context.Context, log *zap.Logger, config Config) (_ authdb.KV, e
 			}
 			return badgerauthmigration.New(log, src, kv, config.NodeMigration), nil
 		}
+		if config.NodeMigration.DestinationSQLAuthKVBackend != "" {
+			if config.Node.ReadOnly {
+				return nil, errs.Combine(errs.New("migration isn't supported on read-only nodes"), kv.Close())
+			}
+			dst, err := sqlauth.Open(ctx, log, config.NodeMigration.DestinationSQLAuthKVBackend, sqlauth.Options{
+				ApplicationName: "authservice (badgerauth->sqlauth migration)",
+			})
+			if err != nil {
+				return nil, errs.Combine(err, kv.Close())
+			}
+			return badgerauthmigration.NewReverse(log, kv, dst, config.NodeMigration), nil
+		}
 		return kv, nil
 	default:
 		return nil, errs.New("unknown scheme: %q", config.KVBackend)
@@ -4,32 +4,107 @@
 package memauth
 
 import (
+	"container/list"
 	"context"
 	"sync"
 	"time"
 
 	"github.com/spacemonkeygo/monkit/v3"
 	"github.com/zeebo/errs"
+	"go.uber.org/zap"
 
+	"storj.io/common/sync2"
 	"storj.io/gateway-mt/pkg/auth/authdb"
 )
 
 var mon = monkit.Package()
 
+// Error is default error class for memauth package.
+var Error = errs.Class("memauth")
+
+// Config configures the in-memory key/value store.
+type Config struct {
+	MaxEntries int `user:"true" help:"maximum number of records kept in memory; the least recently used ones are evicted (0 means unlimited)" default:"0"`
+
+	// SnapshotPath is where records are saved to and loaded from, so they
+	// survive restarts.
+	SnapshotPath     string        `user:"true" help:"file to load records from at start and to save them to periodically and at shutdown (empty disables snapshots)" default:""`
+	SnapshotInterval time.Duration `user:"true" help:"how often to save records to snapshot-path (0 saves only at shutdown)" default:"5m"`
+}
+
 // KV is a key/value store backed by an in memory map.
 type KV struct {
+	log    *zap.Logger
+	config Config
+
 	mu      sync.Mutex
-	entries map[authdb.KeyHash]*authdb.Record
+	entries map[authdb.KeyHash]*list.Element
+	// lru holds *entry values, the most recently used first.
+	lru *list.List
+	// dirty is whether entries have changed since the last snapshot.
+	dirty bool
+
+	// snapshotMu serializes snapshot writes.
+	snapshotMu sync.Mutex
+
+	// invalidations, if set, is where invalidated records are published.
+	invalidations *authdb.InvalidationFeed
 }
 
-// New constructs a KV.
+// entry is a record with its key hash and invalidation state.
+type entry struct {
+	keyHash       authdb.KeyHash
+	record        *authdb.Record
+	invalidReason string
+	invalidAt     time.Time
+}
+
+// Below is a compile-time check ensuring KV implements the KV interface.
+var (
+	_ authdb.KV     = (*KV)(nil)
+	_ authdb.Ranger = (*KV)(nil)
+)
+
+// New constructs an unbounded KV that isn't persisted.
 func New() *KV {
+	return newKV(zap.NewNop(), Config{})
+}
+
+// Open constructs a KV with config, loading records from the snapshot if
+// config.SnapshotPath is set and the file exists.
+func Open(ctx context.Context, log *zap.Logger, config Config) (_ *KV, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if config.MaxEntries < 0 {
+		return nil, Error.New("max entries must not be negative")
+	}
+
+	kv := newKV(log, config)
+
+	if config.SnapshotPath != "" {
+		count, err := kv.loadSnapshot(ctx)
+		if err != nil {
+			return nil, err
+		}
+		log.Info("loaded snapshot", zap.String("path", config.SnapshotPath), zap.Int64("records", count))
+	} else {
+		log.Warn("snapshots are disabled. All data will be lost on shutdown!")
+	}
+
+	return kv, nil
+}
+
+func newKV(log *zap.Logger, config Config) *KV {
 	return &KV{
-		entries: make(map[authdb.KeyHash]*authdb.Record),
+		log:     log,
+		config:  config,
+		entries: make(map[authdb.KeyHash]*list.Element),
+		lru:     list.New(),
 	}
 }
 
-// Put stores the record in the key/value store.
+// Put stores the record in the key/value store. If the store is full, the least
+// recently used record is evicted.
 // It is an error if the key already exists.
 func (d *KV) Put(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record) (err error) {
 	defer mon.Task()(&ctx)(&err)
@@ -38,22 +113,125 @@ func (d *KV) Put(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Rec
 	defer d.mu.Unlock()
 
 	if _, ok := d.entries[keyHash]; ok {
-		return errs.New("record already exists")
+		return authdb.ErrKeyAlreadyExists
 	}
 
-	d.entries[keyHash] = record
+	d.insert(&entry{keyHash: keyHash, record: record})
 	return nil
 }
 
+// insert adds e as the most recently used entry and evicts the least recently
+// used ones over the limit. d.mu must be held.
+func (d *KV) insert(e *entry) {
+	d.entries[e.keyHash] = d.lru.PushFront(e)
+	d.dirty = true
+
+	for d.config.MaxEntries > 0 && d.lru.Len() > d.config.MaxEntries {
+		d.remove(d.lru.Back())
+		mon.Event("as_memauth_evicted")
+	}
+}
+
+// remove removes the entry in element. d.mu must be held.
+func (d *KV) remove(element *list.Element) {
+	delete(d.entries, d.lru.Remove(element).(*entry).keyHash)
+	d.dirty = true
+}
+
 // Get retrieves the record from the key/value store.
-// It returns nil if the key does not exist.
+// It returns nil if the key does not exist or the record has expired.
+// If the record is invalid, the error contains why.
 func (d *KV) Get(ctx context.Context, keyHash authdb.KeyHash) (record *authdb.Record, err error) {
 	defer mon.Task()(&ctx)(&err)
 
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	return d.entries[keyHash], nil
+	element, ok := d.entries[keyHash]
+	if !ok {
+		return nil, nil
+	}
+	d.lru.MoveToFront(element)
+
+	e := element.Value.(*entry)
+	if e.invalidReason != "" {
+		return nil, authdb.Invalid.New("%s", e.invalidReason)
+	}
+	if expired(e.record, time.Now()) {
+		return nil, nil
+	}
+
+	return e.record, nil
+}
+
+// Range calls fn for each record in the key/value store, skipping invalid ones.
+// It stops and returns the error if fn returns one.
+func (d *KV) Range(ctx context.Context, fn func(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record) error) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	// Copy the entries, so fn can use the store.
+	for _, e := range d.copyEntries() {
+		if e.invalidReason != "" {
+			continue
+		}
+		if err = fn(ctx, e.keyHash, e.record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyEntries returns copies of all entries, the least recently used first.
+func (d *KV) copyEntries() []entry {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entries := make([]entry, 0, d.lru.Len())
+	for element := d.lru.Back(); element != nil; element = element.Prev() {
+		entries = append(entries, *element.Value.(*entry))
+	}
+
+	return entries
+}
+
+// SetInvalidationFeed makes the store publish records invalidated with
+// Invalidate to feed. It must be called before the store is used.
+func (d *KV) SetInvalidationFeed(feed *authdb.InvalidationFeed) {
+	d.invalidations = feed
+}
+
+// Invalidate causes the record to become invalid.
+// It is not an error if the key does not exist.
+// It does not update the invalid reason if the record is already invalid.
+func (d *KV) Invalidate(ctx context.Context, keyHash authdb.KeyHash, reason string) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if d.invalidate(keyHash, reason) && d.invalidations != nil {
+		d.invalidations.Publish(keyHash, authdb.InvalidationInvalidated)
+	}
+
+	return nil
+}
+
+// invalidate invalidates the record with keyHash and returns whether it
+// became invalid.
+func (d *KV) invalidate(keyHash authdb.KeyHash, reason string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	element, ok := d.entries[keyHash]
+	if !ok {
+		return false
+	}
+
+	e := element.Value.(*entry)
+	if e.invalidReason != "" {
+		return false
+	}
+	e.invalidReason, e.invalidAt = reason, time.Now()
+	d.dirty = true
+	return true
 }
 
 // DeleteUnused deletes expired and invalid records from the key/value store and
@@ -66,22 +244,57 @@ func (d *KV) DeleteUnused(ctx context.Context, _ time.Duration, _, _ int) (count
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	for k, v := range d.entries {
-		if v != nil && v.ExpiresAt != nil && time.Now().After(*v.ExpiresAt) {
+	now := time.Now()
+
+	for element := d.lru.Front(); element != nil; {
+		next := element.Next()
+
+		e := element.Value.(*entry)
+		if expired(e.record, now) || (e.invalidReason != "" && e.invalidAt.Before(now)) {
 			count++
-			deletesPerHead[string(v.MacaroonHead)]++
-			delete(d.entries, k)
+			if e.record != nil {
+				deletesPerHead[string(e.record.MacaroonHead)]++
+			}
+			d.remove(element)
 		}
+
+		element = next
 	}
 
 	return count, 1, deletesPerHead, nil
 }
 
+// expired returns whether record has expired at now.
+func expired(record *authdb.Record, now time.Time) bool {
+	return record != nil && record.ExpiresAt != nil && now.After(*record.ExpiresAt)
+}
+
 // PingDB attempts to do a database roundtrip and returns an error if it can't.
 func (d *KV) PingDB(context.Context) error { return nil }
 
-// Close closes the database.
-func (d *KV) Close() error { return nil }
+// Close closes the database, saving the snapshot if it's enabled.
+func (d *KV) Close() error {
+	if d.config.SnapshotPath == "" {
+		return nil
+	}
+	return d.SaveSnapshot(context.Background())
+}
 
-// Run runs the database.
-func (d *KV) Run(ctx context.Context) error { return nil }
+// Run runs the database. It saves the snapshot every SnapshotInterval if
+// snapshots are enabled.
+func (d *KV) Run(ctx context.Context) error {
+	if d.config.SnapshotPath == "" || d.config.SnapshotInterval <= 0 {
+		return nil
+	}
+
+	cycle := sync2.NewCycle(d.config.SnapshotInterval)
+	cycle.SetDelayStart()
+	defer cycle.Close()
+
+	return cycle.Run(ctx, func(ctx context.Context) error {
+		if err := d.SaveSnapshot(ctx); err != nil {
+			d.log.Error("failed to save snapshot", zap.Error(err))
+		}
+		return nil
+	})
+}
@@ -4,15 +4,20 @@
 package memauth
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
 
 	"storj.io/common/testcontext"
 	"storj.io/common/testrand"
 	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/authdb/kvtest"
 )
 
 func TestKV(t *testing.T) {
@@ -49,7 +54,7 @@ func TestKV(t *testing.T) {
 
 	for i := 0; i < 100; i += 2 {
 		oneSecondAgo := time.Now().Add(-1 * time.Second)
-		kv.entries[authdb.KeyHash{byte(i)}].ExpiresAt = &oneSecondAgo
+		kv.entries[authdb.KeyHash{byte(i)}].Value.(*entry).record.ExpiresAt = &oneSecondAgo
 	}
 
 	maxTime := time.Unix(1<<62, 0)
@@ -91,6 +96,31 @@ func TestKV(t *testing.T) {
 	}
 }
 
+func TestKV_InvalidationFeed(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	kv := New()
+	defer func() { require.NoError(t, kv.Close()) }()
+
+	feed := authdb.NewInvalidationFeed(10)
+	kv.SetInvalidationFeed(feed)
+
+	require.NoError(t, kv.Put(ctx, authdb.KeyHash{1}, &authdb.Record{SatelliteAddress: "abc"}))
+
+	// missing and already invalid records aren't published.
+	require.NoError(t, kv.Invalidate(ctx, authdb.KeyHash{2}, "test"))
+	require.NoError(t, kv.Invalidate(ctx, authdb.KeyHash{1}, "test"))
+	require.NoError(t, kv.Invalidate(ctx, authdb.KeyHash{1}, "again"))
+
+	events, next, reset := feed.Wait(ctx, feed.ID(), 0, 0)
+	require.False(t, reset)
+	require.EqualValues(t, 1, next)
+	require.Equal(t, []authdb.Invalidation{
+		{Seq: 1, KeyHash: authdb.KeyHash{1}, Reason: authdb.InvalidationInvalidated},
+	}, events)
+}
+
 // TestKVParallel is mainly to check for any race conditions.
 func TestKVParallel(t *testing.T) {
 	ctx := testcontext.New(t)
@@ -132,3 +162,111 @@ func TestKVParallel(t *testing.T) {
 
 	ctx.Wait()
 }
+
+func TestKV_Conformance(t *testing.T) {
+	kvtest.Run(t, func(*testcontext.Context, *testing.T) authdb.KV {
+		return New()
+	}, kvtest.Options{
+		Invalidate: func(ctx context.Context, kv authdb.KV, keyHash authdb.KeyHash, reason string) error {
+			return kv.(*KV).Invalidate(ctx, keyHash, reason)
+		},
+	})
+}
+
+func TestKV_Conformance_Snapshot(t *testing.T) {
+	kvtest.Run(t, func(ctx *testcontext.Context, t *testing.T) authdb.KV {
+		kv, err := Open(ctx, zaptest.NewLogger(t), Config{
+			MaxEntries:       1000,
+			SnapshotPath:     filepath.Join(ctx.Dir("snapshot"), "auth.jsonl"),
+			SnapshotInterval: time.Millisecond,
+		})
+		require.NoError(t, err)
+		return kv
+	}, kvtest.Options{
+		Invalidate: func(ctx context.Context, kv authdb.KV, keyHash authdb.KeyHash, reason string) error {
+			return kv.(*KV).Invalidate(ctx, keyHash, reason)
+		},
+	})
+}
+
+func TestKV_Eviction(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	kv, err := Open(ctx, zaptest.NewLogger(t), Config{MaxEntries: 3})
+	require.NoError(t, err)
+	defer ctx.Check(kv.Close)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, kv.Put(ctx, authdb.KeyHash{byte(i)}, kvtest.NewRecord(nil)))
+	}
+
+	// Reading 0 makes 1 the least recently used record.
+	r, err := kv.Get(ctx, authdb.KeyHash{0})
+	require.NoError(t, err)
+	require.NotNil(t, r)
+
+	require.NoError(t, kv.Put(ctx, authdb.KeyHash{3}, kvtest.NewRecord(nil)))
+
+	for i := 0; i < 4; i++ {
+		r, err := kv.Get(ctx, authdb.KeyHash{byte(i)})
+		require.NoError(t, err)
+		if i == 1 {
+			assert.Nil(t, r, i)
+		} else {
+			assert.NotNil(t, r, i)
+		}
+	}
+	assert.Len(t, kv.entries, 3)
+
+	_, err = Open(ctx, zaptest.NewLogger(t), Config{MaxEntries: -1})
+	require.Error(t, err)
+}
+
+func TestKV_Snapshot(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	config := Config{SnapshotPath: filepath.Join(ctx.Dir("snapshot"), "auth.jsonl")}
+
+	// A missing snapshot is fine.
+	kv, err := Open(ctx, zaptest.NewLogger(t), config)
+	require.NoError(t, err)
+
+	inAnHour, aMinuteAgo := time.Now().Add(time.Hour), time.Now().Add(-time.Minute)
+
+	valid := kvtest.NewRecord(&inAnHour)
+	require.NoError(t, kv.Put(ctx, authdb.KeyHash{'v'}, valid))
+	require.NoError(t, kv.Put(ctx, authdb.KeyHash{'i'}, kvtest.NewRecord(nil)))
+	require.NoError(t, kv.Put(ctx, authdb.KeyHash{'e'}, kvtest.NewRecord(&aMinuteAgo)))
+	require.NoError(t, kv.Invalidate(ctx, authdb.KeyHash{'i'}, "snapshot"))
+
+	require.NoError(t, kv.Close())
+
+	kv, err = Open(ctx, zaptest.NewLogger(t), config)
+	require.NoError(t, err)
+	defer ctx.Check(kv.Close)
+
+	r, err := kv.Get(ctx, authdb.KeyHash{'v'})
+	require.NoError(t, err)
+	kvtest.RequireRecordEqual(t, valid, r)
+
+	_, err = kv.Get(ctx, authdb.KeyHash{'i'})
+	require.True(t, authdb.Invalid.Has(err), "%+v", err)
+	require.Contains(t, err.Error(), "snapshot")
+
+	// Expired records aren't saved.
+	assert.Len(t, kv.entries, 2)
+
+	// The snapshot is only rewritten after changes.
+	info, err := os.Stat(config.SnapshotPath)
+	require.NoError(t, err)
+	require.NoError(t, kv.SaveSnapshot(ctx))
+	info2, err := os.Stat(config.SnapshotPath)
+	require.NoError(t, err)
+	assert.Equal(t, info.ModTime(), info2.ModTime())
+
+	require.NoError(t, os.WriteFile(config.SnapshotPath, []byte("{"), 0600))
+	_, err = Open(ctx, zaptest.NewLogger(t), config)
+	require.Error(t, err)
+}
@@ -0,0 +1,176 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package memauth
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"storj.io/gateway-mt/pkg/auth/authexport"
+)
+
+// snapshotEntry is a single line of the snapshot. It's an export entry (see
+// authexport) with the invalidation state.
+type snapshotEntry struct {
+	authexport.Entry
+
+	InvalidReason string     `json:"invalid_reason,omitempty"`
+	InvalidAt     *time.Time `json:"invalid_at,omitempty"`
+}
+
+// SaveSnapshot writes unexpired records to SnapshotPath if they have changed
+// since the last snapshot. The file is replaced atomically.
+func (d *KV) SaveSnapshot(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	d.snapshotMu.Lock()
+	defer d.snapshotMu.Unlock()
+
+	d.mu.Lock()
+	dirty := d.dirty
+	d.dirty = false
+	d.mu.Unlock()
+
+	if !dirty {
+		return nil
+	}
+
+	defer func() {
+		if err != nil {
+			d.mu.Lock()
+			d.dirty = true
+			d.mu.Unlock()
+		}
+	}()
+
+	count, err := d.writeSnapshot(ctx)
+	if err != nil {
+		return Error.New("failed to save snapshot: %w", err)
+	}
+
+	d.log.Debug("saved snapshot", zap.String("path", d.config.SnapshotPath), zap.Int64("records", count))
+	mon.IntVal("as_memauth_snapshot_records").Observe(count)
+
+	return nil
+}
+
+func (d *KV) writeSnapshot(ctx context.Context) (count int64, err error) {
+	f, err := os.CreateTemp(filepath.Dir(d.config.SnapshotPath), filepath.Base(d.config.SnapshotPath)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			err = errs.Combine(err, f.Close(), os.Remove(f.Name()))
+		}
+	}()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+
+	now := time.Now()
+
+	// Entries are written the least recently used first, so loading them
+	// restores the order.
+	for _, e := range d.copyEntries() {
+		if err = ctx.Err(); err != nil {
+			return count, err
+		}
+		if e.record == nil || expired(e.record, now) {
+			continue
+		}
+
+		line := snapshotEntry{
+			Entry:         authexport.NewEntry(e.keyHash, e.record),
+			InvalidReason: e.invalidReason,
+		}
+		if e.invalidReason != "" {
+			invalidAt := e.invalidAt
+			line.InvalidAt = &invalidAt
+		}
+
+		if err = enc.Encode(line); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if err = w.Flush(); err != nil {
+		return count, err
+	}
+	if err = f.Sync(); err != nil {
+		return count, err
+	}
+	if err = f.Close(); err != nil {
+		return count, errs.Combine(err, os.Remove(f.Name()))
+	}
+
+	return count, os.Rename(f.Name(), d.config.SnapshotPath)
+}
+
+// loadSnapshot loads unexpired records from SnapshotPath. It's not an error if
+// the file doesn't exist.
+func (d *KV) loadSnapshot(ctx context.Context) (count int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	f, err := os.Open(d.config.SnapshotPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, Error.New("failed to load snapshot: %w", err)
+	}
+	defer func() { err = errs.Combine(err, Error.Wrap(f.Close())) }()
+
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for line := 1; ; line++ {
+		var s snapshotEntry
+		if err = dec.Decode(&s); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return count, Error.New("failed to load snapshot: entry %d: %w", line, err)
+		}
+
+		keyHash, record, err := s.Record()
+		if err != nil {
+			return count, Error.New("failed to load snapshot: entry %d: %w", line, err)
+		}
+
+		if expired(record, now) {
+			continue
+		}
+
+		e := &entry{keyHash: keyHash, record: record, invalidReason: s.InvalidReason}
+		if s.InvalidAt != nil {
+			e.invalidAt = *s.InvalidAt
+		}
+
+		if element, ok := d.entries[keyHash]; ok {
+			d.remove(element)
+		}
+		d.insert(e)
+		count++
+	}
+
+	// Loaded records are already saved.
+	d.dirty = false
+
+	return count, nil
+}
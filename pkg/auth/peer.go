@@ -29,6 +29,7 @@ import (
 	"storj.io/gateway-mt/pkg/auth/badgerauth/badgerauthmigration"
 	"storj.io/gateway-mt/pkg/auth/drpcauth"
 	"storj.io/gateway-mt/pkg/auth/httpauth"
+	"storj.io/gateway-mt/pkg/auth/memauth"
 	"storj.io/gateway-mt/pkg/auth/satellitelist"
 	"storj.io/gateway-mt/pkg/middleware"
 	"storj.io/gateway-mt/pkg/trustedip"
@@ -40,14 +41,25 @@ const serverShutdownTimeout = 10 * time.Second
 
 // Config holds authservice's configuration.
 type Config struct {
-	Endpoint          string        `help:"Gateway endpoint URL to return to clients" default:""`
-	AuthToken         string        `help:"auth security token to validate requests" releaseDefault:"" devDefault:""`
-	POSTSizeLimit     memory.Size   `help:"maximum size that the incoming POST request body with access grant can be" default:"4KiB"`
-	AllowedSatellites []string      `help:"list of satellite NodeURLs allowed for incoming access grants" default:"https://www.storj.io/dcs-satellites"`
-	CacheExpiration   time.Duration `help:"length of time satellite addresses are cached for" default:"10m"`
-
-	KVBackend string `help:"key/value store backend url" default:""`
-	Migration bool   `help:"create or update the database schema, and then continue service startup" default:"false"`
+	Endpoint           string        `help:"Gateway endpoint URL to return to clients" default:""`
+	WritableEndpoint   string        `help:"URL of a writable authservice to redirect registration requests to (read-only nodes only)" default:""`
+	SatelliteEndpoints []string      `help:"comma delimited list of satellite=endpoint pairs; access grants of the satellite (a NodeURL) get the endpoint instead of --endpoint" default:""`
+	AuthToken          string        `help:"auth security token to validate requests" releaseDefault:"" devDefault:""`
+	POSTSizeLimit      memory.Size   `help:"maximum size that the incoming POST request body with access grant can be" default:"4KiB"`
+	AllowedSatellites  []string      `help:"list of satellite NodeURLs allowed for incoming access grants" default:"https://www.storj.io/dcs-satellites"`
+	CacheExpiration    time.Duration `help:"length of time satellite addresses are cached for" default:"10m"`
+	AccessCacheMaxAge  time.Duration `help:"how long gateways may cache resolved access grants, capped by their expiration (0 means only their expiration is advertised)" default:"24h"`
+	// InvalidationFeedSize applies to key/value store backends that publish
+	// invalidations (badgerauth nodes, memory and sqlite) only. sqlauth
+	// records are changed by other processes too, so it has no feed.
+	InvalidationFeedSize int `help:"how many recent record invalidations to keep for gateways evicting them from their caches (0 disables the feed)" default:"10000"`
+	// AllowedSatellitesCache keeps authservice working (and starting) if
+	// allowed satellite lists are unreachable.
+	AllowedSatellitesCache string `help:"file to cache the last successfully loaded allowed satellite list in, used if lists are unreachable (empty disables caching)" default:""`
+
+	KVBackend             string   `help:"key/value store backend url" default:""`
+	KVBackendReadReplicas []string `help:"comma delimited list of read replica urls that postgres and cockroach key/value store backends read from" default:""`
+	Migration             bool     `help:"create or update the database schema, and then continue service startup" default:"false"`
 
 	ListenAddr    string `user:"true" help:"public HTTP address to listen on" default:":20000"`
 	ListenAddrTLS string `user:"true" help:"public HTTPS address to listen on" default:":20001"`
@@ -60,8 +72,12 @@ type Config struct {
 	KeyFile     string `user:"true" help:"server key file" default:""`
 	PublicURL   string `user:"true" help:"public url for the server, for the TLS certificate" devDefault:"http://localhost:20000" releaseDefault:""`
 
+	ClientCAFile     string   `user:"true" help:"CA bundle to verify client certificates against on TLS listeners" default:""`
+	ClientCertScopes []string `user:"true" help:"comma delimited list of subject=scope pairs granting client certificates with the subject common name a scope (access, satellites); if set, these endpoints require such a certificate in addition to the auth token" default:""`
+
 	DeleteUnused DeleteUnusedConfig
 
+	Memory        memauth.Config
 	Node          badgerauth.Config
 	NodeMigration badgerauthmigration.Config
 }
@@ -83,6 +99,9 @@ type Peer struct {
 	adb *authdb.Database
 	res *httpauth.Resources
 
+	satelliteList *satellitelist.Loader
+	endpoints     *authdb.Endpoints
+
 	handler       http.Handler
 	httpListener  net.Listener
 	httpsListener net.Listener
@@ -108,10 +127,12 @@ func New(ctx context.Context, log *zap.Logger, config Config, configDir string)
 	if len(config.AllowedSatellites) == 0 {
 		return nil, errs.New("allowed satellites parameter '--allowed-satellites' is required")
 	}
-	allowedSats, areSatsDynamic, err := satellitelist.LoadSatelliteURLs(ctx, config.AllowedSatellites)
+	satelliteList := satellitelist.NewLoader(log.Named("satellitelist"), config.AllowedSatellites, config.AllowedSatellitesCache)
+	list, areSatsDynamic, err := satelliteList.Load(ctx)
 	if err != nil {
 		return nil, errs.Wrap(err)
 	}
+	allowedSats := satellitelist.URLs(list.Satellites)
 	if len(allowedSats) == 0 {
 		return nil, errs.New("allowed satellites parameter '--allowed-satellites' resolved to zero satellites")
 	}
@@ -123,12 +144,24 @@ func New(ctx context.Context, log *zap.Logger, config Config, configDir string)
 	if config.Endpoint == "" {
 		return nil, errs.New("endpoint parameter '--endpoint' is required")
 	}
-	endpoint, err := url.Parse(config.Endpoint)
+	endpoints, err := authdb.ParseEndpoints(config.Endpoint, config.SatelliteEndpoints)
 	if err != nil {
 		return nil, errs.Wrap(err)
 	}
-	if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
-		return nil, errs.New("unexpected scheme found in endpoint parameter %s", endpoint.Scheme)
+	endpoints.SetListed(list.Satellites)
+	endpoint := endpoints.Default()
+
+	var writableEndpoint *url.URL
+	if config.WritableEndpoint != "" {
+		if !config.Node.ReadOnly {
+			return nil, errs.New("writable endpoint parameter '--writable-endpoint' requires '--node.read-only'")
+		}
+		if writableEndpoint, err = url.Parse(config.WritableEndpoint); err != nil {
+			return nil, errs.Wrap(err)
+		}
+		if writableEndpoint.Scheme != "http" && writableEndpoint.Scheme != "https" {
+			return nil, errs.New("unexpected scheme found in writable endpoint parameter %s", writableEndpoint.Scheme)
+		}
 	}
 
 	kv, err := OpenKV(ctx, log.Named("db"), config)
@@ -138,13 +171,47 @@ func New(ctx context.Context, log *zap.Logger, config Config, configDir string)
 
 	adb := authdb.NewDatabase(kv, allowedSats)
 	res := httpauth.New(log.Named("resources"), adb, endpoint, config.AuthToken, config.POSTSizeLimit)
+	res.SetEndpoints(endpoints)
+	res.SetSatelliteList(satelliteList.Active)
+	res.SetAccessCacheMaxAge(config.AccessCacheMaxAge)
+	if writableEndpoint != nil {
+		res.SetWritableEndpoint(writableEndpoint)
+	}
+	if migration, ok := kv.(interface {
+		Progress(context.Context) (badgerauthmigration.Progress, error)
+	}); ok {
+		res.SetMigrationProgress(func(ctx context.Context) (interface{}, bool, error) {
+			progress, err := migration.Progress(ctx)
+			return progress, progress.Complete(), err
+		})
+	}
+
+	if publisher, ok := kv.(interface {
+		SetInvalidationFeed(*authdb.InvalidationFeed)
+	}); ok && config.InvalidationFeedSize > 0 {
+		feed := authdb.NewInvalidationFeed(config.InvalidationFeedSize)
+		publisher.SetInvalidationFeed(feed)
+		res.SetInvalidationFeed(feed)
+	}
+
+	if len(config.ClientCertScopes) > 0 {
+		if config.ClientCAFile == "" {
+			return nil, errs.New("client certificate scopes parameter '--client-cert-scopes' requires '--client-ca-file'")
+		}
+		scopes, err := httpauth.ParseClientCertScopes(config.ClientCertScopes)
+		if err != nil {
+			return nil, errs.Wrap(err)
+		}
+		res.SetClientCertScopes(scopes)
+	}
 
 	tlsInfo := &TLSInfo{
-		LetsEncrypt: config.LetsEncrypt,
-		CertFile:    config.CertFile,
-		KeyFile:     config.KeyFile,
-		PublicURL:   config.PublicURL,
-		ConfigDir:   configDir,
+		LetsEncrypt:  config.LetsEncrypt,
+		CertFile:     config.CertFile,
+		KeyFile:      config.KeyFile,
+		PublicURL:    config.PublicURL,
+		ConfigDir:    configDir,
+		ClientCAFile: config.ClientCAFile,
 	}
 
 	tlsConfig, handler, err := configureTLS(tlsInfo, res)
@@ -156,6 +223,10 @@ func New(ctx context.Context, log *zap.Logger, config Config, configDir string)
 	handler = middleware.AddRequestID(LogResponses(log, LogRequests(log, handler)))
 
 	drpcServer := drpcauth.NewServer(log, adb, endpoint, config.POSTSizeLimit)
+	drpcServer.SetEndpoints(endpoints)
+	if writableEndpoint != nil {
+		drpcServer.SetWritableEndpoint(writableEndpoint)
+	}
 
 	httpListener, err := net.Listen("tcp", config.ListenAddr)
 	if err != nil {
@@ -184,6 +255,9 @@ func New(ctx context.Context, log *zap.Logger, config Config, configDir string)
 		adb: adb,
 		res: res,
 
+		satelliteList: satelliteList,
+		endpoints:     endpoints,
+
 		handler:       handler,
 		httpListener:  httpListener,
 		httpsListener: httpsListener,
@@ -257,7 +331,7 @@ func (p *Peer) Run(ctx context.Context) (err error) {
 
 	if p.areSatsDynamic {
 		p.satelliteListReload.Start(groupCtx, group, func(ctx context.Context) error {
-			reloadSatelliteList(ctx, p.log, p.adb, p.config.AllowedSatellites)
+			reloadSatelliteList(ctx, p.log, p.adb, p.endpoints, p.satelliteList)
 			return nil
 		})
 		defer p.satelliteListReload.Close()
@@ -383,13 +457,14 @@ func (p *Peer) DRPCTLSAddress() string {
 	return p.drpcTLSListener.Addr().String()
 }
 
-func reloadSatelliteList(ctx context.Context, log *zap.Logger, adb *authdb.Database, allowedSatellites []string) {
+func reloadSatelliteList(ctx context.Context, log *zap.Logger, adb *authdb.Database, endpoints *authdb.Endpoints, loader *satellitelist.Loader) {
 	log.Debug("Reloading allowed satellite list")
-	allowedSatelliteURLs, _, err := satellitelist.LoadSatelliteURLs(ctx, allowedSatellites)
+	list, _, err := loader.Load(ctx)
 	if err != nil {
 		log.Warn("Error reloading allowed satellite list", zap.Error(err))
 	} else {
-		adb.SetAllowedSatellites(allowedSatelliteURLs)
+		adb.SetAllowedSatellites(satellitelist.URLs(list.Satellites))
+		endpoints.SetListed(list.Satellites)
 	}
 }
 
@@ -0,0 +1,202 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package satellitelist
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"storj.io/common/storj"
+)
+
+// Source is where a List was loaded from.
+type Source string
+
+const (
+	// SourceConfigured means the list was loaded from the configured values.
+	SourceConfigured Source = "configured"
+	// SourceCache means the configured values couldn't be loaded, and the list
+	// was loaded from the cache of the last successfully loaded list.
+	SourceCache Source = "cache"
+)
+
+// List is a loaded allowed satellite list.
+type List struct {
+	Satellites map[storj.NodeURL]Satellite
+	Source     Source
+	// LoadedAt is when the list was loaded from the configured values (also
+	// if it's been loaded from the cache since).
+	LoadedAt time.Time
+}
+
+// Loader loads the allowed satellite list and caches the last successfully
+// loaded list on disk, so it's still available if lists it's loaded from can't
+// be reached (e.g., at startup).
+type Loader struct {
+	log          *zap.Logger
+	configValues []string
+	cachePath    string
+
+	mu     sync.Mutex
+	active *List
+}
+
+// NewLoader returns a Loader loading configValues (see LoadSatellites). Caching
+// is disabled if cachePath is empty.
+func NewLoader(log *zap.Logger, configValues []string, cachePath string) *Loader {
+	return &Loader{
+		log:          log,
+		configValues: configValues,
+		cachePath:    cachePath,
+	}
+}
+
+// Load loads the list from the configured values and falls back to the cache
+// if that fails. It returns whether the configured values include lists that
+// should be polled for updates.
+func (l *Loader) Load(ctx context.Context) (_ *List, hasNodeList bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	satellites, hasNodeList, err := LoadSatellites(ctx, l.configValues)
+	if err == nil {
+		list := &List{
+			Satellites: satellites,
+			Source:     SourceConfigured,
+			LoadedAt:   time.Now(),
+		}
+		if l.cachePath != "" {
+			if cacheErr := l.writeCache(list); cacheErr != nil {
+				l.log.Warn("failed to cache allowed satellite list", zap.String("path", l.cachePath), zap.Error(cacheErr))
+			}
+		}
+		l.setActive(list)
+		return list, hasNodeList, nil
+	}
+
+	if l.cachePath == "" {
+		return nil, hasNodeList, err
+	}
+
+	list, cacheErr := l.readCache()
+	if cacheErr != nil {
+		return nil, hasNodeList, errs.Combine(err, cacheErr)
+	}
+
+	l.log.Warn("using cached allowed satellite list",
+		zap.String("path", l.cachePath),
+		zap.Time("loaded at", list.LoadedAt),
+		zap.Error(err))
+	mon.Event("as_satellitelist_cache_used")
+
+	// The cached list might be replaced by one loaded from the configured
+	// values later on, so they need to be polled even if they're static.
+	l.setActive(list)
+	return list, true, nil
+}
+
+// Active returns the last loaded list or nil if none has been loaded.
+func (l *Loader) Active() *List {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.active
+}
+
+func (l *Loader) setActive(list *List) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active = list
+}
+
+// cachedSatellite is Satellite as it's stored in the cache.
+type cachedSatellite struct {
+	URL      string `json:"url"`
+	Region   string `json:"region,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// cachedList is List as it's stored in the cache.
+type cachedList struct {
+	LoadedAt   time.Time         `json:"loaded_at"`
+	Satellites []cachedSatellite `json:"satellites"`
+}
+
+// writeCache writes list to the cache, replacing it atomically.
+func (l *Loader) writeCache(list *List) (err error) {
+	cached := cachedList{LoadedAt: list.LoadedAt}
+	for _, s := range list.Satellites {
+		cached.Satellites = append(cached.Satellites, cachedSatellite{
+			URL:      s.URL.String(),
+			Region:   s.Region,
+			Name:     s.Name,
+			Endpoint: s.Endpoint,
+		})
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return ErrAllowedSatelliteList.Wrap(err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(l.cachePath), filepath.Base(l.cachePath)+".*.tmp")
+	if err != nil {
+		return ErrAllowedSatelliteList.Wrap(err)
+	}
+	defer func() {
+		if err != nil {
+			err = errs.Combine(err, os.Remove(f.Name()))
+		}
+	}()
+
+	if _, err = f.Write(data); err != nil {
+		return ErrAllowedSatelliteList.Wrap(errs.Combine(err, f.Close()))
+	}
+	if err = f.Close(); err != nil {
+		return ErrAllowedSatelliteList.Wrap(err)
+	}
+
+	return ErrAllowedSatelliteList.Wrap(os.Rename(f.Name(), l.cachePath))
+}
+
+// readCache reads the list from the cache.
+func (l *Loader) readCache() (_ *List, err error) {
+	data, err := os.ReadFile(l.cachePath)
+	if err != nil {
+		return nil, ErrAllowedSatelliteList.New("failed to read cache: %w", err)
+	}
+
+	var cached cachedList
+	if err = json.Unmarshal(data, &cached); err != nil {
+		return nil, ErrAllowedSatelliteList.New("failed to read cache: %w", err)
+	}
+
+	list := &List{
+		Satellites: make(map[storj.NodeURL]Satellite, len(cached.Satellites)),
+		Source:     SourceCache,
+		LoadedAt:   cached.LoadedAt,
+	}
+	for _, s := range cached.Satellites {
+		url, err := ParseSatelliteURL(s.URL)
+		if err != nil {
+			return nil, ErrAllowedSatelliteList.New("failed to read cache: %w", err)
+		}
+		list.Satellites[url] = Satellite{
+			URL:      url,
+			Region:   s.Region,
+			Name:     s.Name,
+			Endpoint: s.Endpoint,
+		}
+	}
+
+	return list, nil
+}
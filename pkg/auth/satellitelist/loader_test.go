@@ -0,0 +1,75 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package satellitelist
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/common/storj"
+	"storj.io/common/testcontext"
+)
+
+func TestLoader(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	const line = "12EayRS2V1kEsWESU9QMRseFhdxYxKicsiFmxrsLZHeLUtdps3S@us1.storj.io:7777 region=us endpoint=https://gateway.us1.test"
+
+	us1, err := storj.ParseNodeURL("12EayRS2V1kEsWESU9QMRseFhdxYxKicsiFmxrsLZHeLUtdps3S@us1.storj.io:7777")
+	require.NoError(t, err)
+	expected := map[storj.NodeURL]Satellite{
+		us1: {URL: us1, Region: "us", Endpoint: "https://gateway.us1.test"},
+	}
+
+	up := true
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprintln(w, line)
+	}))
+	defer testServer.Close()
+
+	cachePath := filepath.Join(ctx.Dir("cache"), "satellites.json")
+
+	// Without a cache, the list must be loaded.
+	up = false
+	_, _, err = NewLoader(zaptest.NewLogger(t), []string{testServer.URL}, cachePath).Load(ctx)
+	require.Error(t, err)
+
+	up = true
+	loader := NewLoader(zaptest.NewLogger(t), []string{testServer.URL}, cachePath)
+	assert.Nil(t, loader.Active())
+
+	list, hasNodeList, err := loader.Load(ctx)
+	require.NoError(t, err)
+	assert.True(t, hasNodeList)
+	assert.Equal(t, SourceConfigured, list.Source)
+	assert.Equal(t, expected, list.Satellites)
+	assert.Equal(t, list, loader.Active())
+
+	// A new loader (e.g., after a restart) falls back to the cache.
+	up = false
+	loader = NewLoader(zaptest.NewLogger(t), []string{testServer.URL}, cachePath)
+	cached, hasNodeList, err := loader.Load(ctx)
+	require.NoError(t, err)
+	assert.True(t, hasNodeList)
+	assert.Equal(t, SourceCache, cached.Source)
+	assert.Equal(t, expected, cached.Satellites)
+	assert.True(t, list.LoadedAt.Equal(cached.LoadedAt), "the cache keeps when the list was loaded")
+	assert.Equal(t, cached, loader.Active())
+
+	// Without caching, failures are returned.
+	_, _, err = NewLoader(zaptest.NewLogger(t), []string{testServer.URL}, "").Load(ctx)
+	require.Error(t, err)
+}
@@ -8,9 +8,12 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/spacemonkeygo/monkit/v3"
 	"github.com/zeebo/errs"
@@ -24,6 +27,16 @@ var mon = monkit.Package()
 // ErrAllowedSatelliteList is an error class for allowed satellite list errors.
 var ErrAllowedSatelliteList = errs.Class("allowed satellite list")
 
+// Satellite is an allowed satellite with optional metadata from the list.
+type Satellite struct {
+	URL storj.NodeURL
+	// Region, Name and Endpoint are optional. Endpoint is the gateway that
+	// clients registering access grants for the satellite are pointed to.
+	Region   string
+	Name     string
+	Endpoint string
+}
+
 // LoadSatelliteURLs takes a list of configuration paths and returns a list of
 // satellites URLs suitable for calling ("*Database).SetAllowedSatellites().
 // ConfigValues may be satellite address URLs with a node id.  Alternatively,
@@ -33,55 +46,160 @@ var ErrAllowedSatelliteList = errs.Class("allowed satellite list")
 func LoadSatelliteURLs(ctx context.Context, configValues []string) (satMap map[storj.NodeURL]struct{}, hasNodeList bool, err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	satMap = make(map[storj.NodeURL]struct{})
+	satellites, hasNodeList, err := LoadSatellites(ctx, configValues)
+	return URLs(satellites), hasNodeList, err
+}
+
+// LoadSatellites is like LoadSatelliteURLs, but it also returns metadata of the
+// satellites.
+//
+// Each satellite address (in configValues or in lists) may be followed by
+// whitespace separated key=value fields, where the keys are region, name and
+// endpoint, and values may be double-quoted, e.g.:
+//
+//	12EayRS2V1kEsWESU9QMRseFhdxYxKicsiFmxrsLZHeLUtdps3S@us1.storj.io:7777 region=us name="US1" endpoint=https://gateway.us1.example.com
+//
+// Unknown keys are ignored.
+func LoadSatellites(ctx context.Context, configValues []string) (satellites map[storj.NodeURL]Satellite, hasNodeList bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	satellites = make(map[storj.NodeURL]Satellite)
 	for _, c := range configValues {
 		c = strings.TrimSpace(c)
 		if strings.HasPrefix(c, "http") {
 			hasNodeList = true
 			fileContent, err := getHTTPList(ctx, c)
 			if err != nil {
-				return satMap, hasNodeList, err
+				return satellites, hasNodeList, err
 			}
-			err = readSatelliteList(fileContent, satMap)
+			err = readSatelliteList(fileContent, satellites)
 			if err != nil {
-				return satMap, hasNodeList, ErrAllowedSatelliteList.Wrap(err)
+				return satellites, hasNodeList, ErrAllowedSatelliteList.Wrap(err)
 			}
 		} else if _, err := os.Stat(c); err == nil {
 			hasNodeList = true
 			bodyBytes, err := os.ReadFile(c)
 			if err != nil {
-				return satMap, hasNodeList, ErrAllowedSatelliteList.Wrap(err)
+				return satellites, hasNodeList, ErrAllowedSatelliteList.Wrap(err)
 			}
-			err = readSatelliteList(bodyBytes, satMap)
+			err = readSatelliteList(bodyBytes, satellites)
 			if err != nil {
-				return satMap, hasNodeList, ErrAllowedSatelliteList.Wrap(err)
+				return satellites, hasNodeList, ErrAllowedSatelliteList.Wrap(err)
 			}
-		} else if nodeURL, err := ParseSatelliteURL(c); err == nil {
-			satMap[nodeURL] = struct{}{}
+		} else if satellite, err := parseSatellite(c); err == nil {
+			satellites[satellite.URL] = satellite
 		} else {
-			return satMap, hasNodeList, ErrAllowedSatelliteList.New("unknown config value '%s'", c)
+			return satellites, hasNodeList, ErrAllowedSatelliteList.New("unknown config value '%s'", c)
 		}
 	}
-	return satMap, hasNodeList, nil
+	return satellites, hasNodeList, nil
+}
+
+// URLs returns the URLs of satellites.
+func URLs(satellites map[storj.NodeURL]Satellite) map[storj.NodeURL]struct{} {
+	urls := make(map[storj.NodeURL]struct{}, len(satellites))
+	for url := range satellites {
+		urls[url] = struct{}{}
+	}
+	return urls
 }
 
 // readSatelliteList populates a map from a newline separated list of Satellite
 // addresses.  Empty lines or lines starting with '#' (comments) are ignored.
-func readSatelliteList(input []byte, satellites map[storj.NodeURL]struct{}) (err error) {
+func readSatelliteList(input []byte, satellites map[storj.NodeURL]Satellite) (err error) {
 	for _, line := range bytes.Split(input, []byte{'\n'}) {
 		line = bytes.TrimSpace(line)
 		if len(line) == 0 || line[0] == '#' {
 			continue
 		}
-		nodeURL, err := ParseSatelliteURL(string(line))
+		satellite, err := parseSatellite(string(line))
 		if err != nil {
 			return err // already wrapped
 		}
-		satellites[nodeURL] = struct{}{}
+		satellites[satellite.URL] = satellite
 	}
 	return nil
 }
 
+// parseSatellite parses a satellite address optionally followed by key=value
+// fields.
+func parseSatellite(s string) (satellite Satellite, err error) {
+	fields, err := splitFields(s)
+	if err != nil {
+		return satellite, ErrAllowedSatelliteList.New("%q: %w", s, err)
+	}
+	if len(fields) == 0 {
+		return satellite, ErrAllowedSatelliteList.New("empty satellite address")
+	}
+
+	if satellite.URL, err = ParseSatelliteURL(fields[0]); err != nil {
+		return satellite, err
+	}
+
+	for _, field := range fields[1:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return satellite, ErrAllowedSatelliteList.New("%q: field %q isn't key=value", s, field)
+		}
+		if strings.HasPrefix(value, `"`) {
+			if value, err = strconv.Unquote(value); err != nil {
+				return satellite, ErrAllowedSatelliteList.New("%q: field %q: %w", s, field, err)
+			}
+		}
+
+		switch key {
+		case "region":
+			satellite.Region = value
+		case "name":
+			satellite.Name = value
+		case "endpoint":
+			endpoint, err := url.Parse(value)
+			if err != nil {
+				return satellite, ErrAllowedSatelliteList.New("%q: %w", s, err)
+			}
+			if endpoint.Scheme != "http" && endpoint.Scheme != "https" {
+				return satellite, ErrAllowedSatelliteList.New("%q: unexpected scheme found in endpoint %q", s, value)
+			}
+			satellite.Endpoint = value
+		}
+	}
+
+	return satellite, nil
+}
+
+// splitFields splits s around whitespace outside of double-quoted strings.
+func splitFields(s string) (fields []string, err error) {
+	var (
+		field   strings.Builder
+		quoted  bool
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && unicode.IsSpace(r):
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+			continue
+		}
+		field.WriteRune(r)
+	}
+	if quoted {
+		return nil, errs.New("unterminated quoted string")
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
 // getHTTPList downloads and returns bytes served under url and any error
 // encountered.
 func getHTTPList(ctx context.Context, url string) (_ []byte, err error) {
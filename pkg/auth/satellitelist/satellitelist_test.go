@@ -174,10 +174,10 @@ func TestReadSatelliteList(t *testing.T) {
 	}
 
 	for i, tc := range tests {
-		satellites := make(map[storj.NodeURL]struct{})
+		satellites := make(map[storj.NodeURL]Satellite)
 		err = readSatelliteList(tc.input, satellites)
 		require.NoError(t, err, tc.name)
-		require.Equal(t, tc.expectedSatellites, satellites, i, tc.name)
+		require.Equal(t, tc.expectedSatellites, URLs(satellites), i, tc.name)
 	}
 }
 
@@ -190,7 +190,7 @@ func TestReadSatelliteList_Invalid(t *testing.T) {
 			"71wFTAgs9DP5RSnCqKV1eLf6N9wtk4EAtmN5DpSxcs8EjT69tGE@saltlake.tar" +
 			"digrade.io:7777\n",
 	} {
-		require.Error(t, readSatelliteList([]byte(input), make(map[storj.NodeURL]struct{})), i)
+		require.Error(t, readSatelliteList([]byte(input), make(map[storj.NodeURL]Satellite)), i)
 	}
 }
 
@@ -269,3 +269,30 @@ func TestParseSatelliteURL_Invalid(t *testing.T) {
 		require.Error(t, err, i)
 	}
 }
+
+func TestReadSatelliteList_Metadata(t *testing.T) {
+	us1, err := storj.ParseNodeURL("12EayRS2V1kEsWESU9QMRseFhdxYxKicsiFmxrsLZHeLUtdps3S@us1.storj.io:7777")
+	require.NoError(t, err)
+	ap1, err := storj.ParseNodeURL("121RTSDpyNZVcEU84Ticf2L1ntiuUimbWgfATz21tuvgk3vzoA6@ap1.storj.io:7777")
+	require.NoError(t, err)
+
+	satellites := make(map[storj.NodeURL]Satellite)
+	require.NoError(t, readSatelliteList([]byte(
+		us1.String()+"\tregion=us name=\"US1 \\\"Central\\\"\" endpoint=https://gateway.us1.test unknown=ignored\n"+
+			ap1.String()+"\n",
+	), satellites))
+
+	require.Equal(t, map[storj.NodeURL]Satellite{
+		us1: {URL: us1, Region: "us", Name: `US1 "Central"`, Endpoint: "https://gateway.us1.test"},
+		ap1: {URL: ap1},
+	}, satellites)
+
+	for i, input := range []string{
+		us1.String() + " region",
+		us1.String() + ` name="unterminated`,
+		us1.String() + " endpoint=gateway.us1.test",
+		us1.String() + ` name="\x"`,
+	} {
+		require.Error(t, readSatelliteList([]byte(input), make(map[storj.NodeURL]Satellite)), i)
+	}
+}
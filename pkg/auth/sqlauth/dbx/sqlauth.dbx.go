@@ -285,7 +285,9 @@ func (obj *pgxDB) Schema() string {
 	invalid_reason text,
 	invalid_at timestamp with time zone,
 	PRIMARY KEY ( encryption_key_hash )
-);`
+);
+CREATE INDEX records_created_at_index ON records ( created_at ) ;
+CREATE INDEX records_macaroon_head_created_at_index ON records ( macaroon_head, created_at ) ;`
 }
 
 func (obj *pgxDB) wrapTx(tx tagsql.Tx) txMethods {
@@ -361,7 +363,9 @@ func (obj *pgxcockroachDB) Schema() string {
 	invalid_reason text,
 	invalid_at timestamp with time zone,
 	PRIMARY KEY ( encryption_key_hash )
-);`
+);
+CREATE INDEX records_created_at_index ON records ( created_at ) ;
+CREATE INDEX records_macaroon_head_created_at_index ON records ( macaroon_head, created_at ) ;`
 }
 
 func (obj *pgxcockroachDB) wrapTx(tx tagsql.Tx) txMethods {
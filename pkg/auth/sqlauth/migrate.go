@@ -6,38 +6,94 @@ package sqlauth
 import (
 	"context"
 
+	"go.uber.org/zap"
+
 	"storj.io/private/migrate"
 	"storj.io/private/tagsql"
 )
 
+// MigrationStep is a step-wise change to the records table.
+type MigrationStep struct {
+	Description string
+	SQL         migrate.SQL
+
+	// OutsideTx makes the statements run one by one outside of the step's
+	// transaction, as PostgreSQL can build indexes without blocking writes
+	// (CREATE INDEX CONCURRENTLY) only there. They aren't rolled back if the
+	// step fails, so they must be safe to run again.
+	OutsideTx bool
+}
+
+// MigrationSteps returns the step-wise changes to the records table in
+// PostgreSQL's dialect, ordered by version. They're checked against the dbx
+// schema (see sqlauth.dbx), and sqliteauth translates them for SQLite.
+func MigrationSteps() []MigrationStep {
+	return []MigrationStep{
+		{
+			Description: "Initial setup",
+			SQL: migrate.SQL{
+				`CREATE TABLE records (
+					encryption_key_hash bytea NOT NULL,
+					created_at timestamp with time zone NOT NULL,
+					public boolean NOT NULL,
+					satellite_address text NOT NULL,
+					macaroon_head bytea NOT NULL,
+					expires_at timestamp with time zone,
+					encrypted_secret_key bytea NOT NULL,
+					encrypted_access_grant bytea NOT NULL,
+					invalid_reason text,
+					invalid_at timestamp with time zone,
+					PRIMARY KEY ( encryption_key_hash )
+				);`,
+			},
+		},
+		{
+			// records are written while the indexes are built. A failed
+			// concurrent build leaves an invalid index behind, so indexes are
+			// dropped first in case a previous run failed.
+			Description: "Index records for listing",
+			SQL: migrate.SQL{
+				`DROP INDEX CONCURRENTLY IF EXISTS records_created_at_index;`,
+				`CREATE INDEX CONCURRENTLY records_created_at_index ON records ( created_at );`,
+				`DROP INDEX CONCURRENTLY IF EXISTS records_macaroon_head_created_at_index;`,
+				`CREATE INDEX CONCURRENTLY records_macaroon_head_created_at_index ON records ( macaroon_head, created_at );`,
+			},
+			OutsideTx: true,
+		},
+	}
+}
+
 // Migration returns table migrations.
 // The SQL here represent the step-wise changes to the database.
 func (d *KV) Migration(ctx context.Context) *migrate.Migration {
 	d.db.DB = &RebindableTagSQL{DB: d.db.DB, rebind: d.db.Rebind}
-	return &migrate.Migration{
-		Table: "versions",
-		Steps: []*migrate.Step{
-			{
-				DB:          &d.db.DB,
-				Description: "Initial setup",
-				Version:     0,
-				Action: migrate.SQL{
-					`CREATE TABLE records (
-						encryption_key_hash bytea NOT NULL,
-						created_at timestamp with time zone NOT NULL,
-						public boolean NOT NULL,
-						satellite_address text NOT NULL,
-						macaroon_head bytea NOT NULL,
-						expires_at timestamp with time zone,
-						encrypted_secret_key bytea NOT NULL,
-						encrypted_access_grant bytea NOT NULL,
-						invalid_reason text,
-						invalid_at timestamp with time zone,
-						PRIMARY KEY ( encryption_key_hash )
-					);`,
-				},
-			},
-		},
+
+	migration := &migrate.Migration{Table: "versions"}
+	for version, step := range MigrationSteps() {
+		var action migrate.Action = step.SQL
+		if step.OutsideTx {
+			action = outsideTx(step.SQL)
+		}
+		migration.Steps = append(migration.Steps, &migrate.Step{
+			DB:          &d.db.DB,
+			Description: step.Description,
+			Version:     version,
+			Action:      action,
+		})
+	}
+	return migration
+}
+
+// outsideTx returns an action running statements on the database rather than
+// in the step's transaction.
+func outsideTx(statements migrate.SQL) migrate.Func {
+	return func(ctx context.Context, log *zap.Logger, db tagsql.DB, tx tagsql.Tx) error {
+		for _, query := range statements {
+			if _, err := db.ExecContext(ctx, query); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 }
 
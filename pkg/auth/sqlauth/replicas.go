@@ -0,0 +1,183 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package sqlauth
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"storj.io/common/sync2"
+	"storj.io/gateway-mt/pkg/auth/sqlauth/dbx"
+	"storj.io/private/dbutil"
+	"storj.io/private/dbutil/pgutil"
+)
+
+// defaultReplicaHealthCheckInterval is used if
+// Options.ReplicaHealthCheckInterval isn't set.
+const defaultReplicaHealthCheckInterval = 10 * time.Second
+
+// replica is a read replica of the primary database.
+type replica struct {
+	// index identifies the replica in logs without its credentials.
+	index int
+	db    *dbx.DB
+	impl  dbutil.Implementation
+
+	// healthy is 1 if the replica should be used and 0 otherwise.
+	healthy int32
+}
+
+func (r *replica) isHealthy() bool { return atomic.LoadInt32(&r.healthy) == 1 }
+
+func (r *replica) setHealthy(healthy bool) (changed bool) {
+	var v int32
+	if healthy {
+		v = 1
+	}
+	return atomic.SwapInt32(&r.healthy, v) != v
+}
+
+// replicas load-balances reads across read replicas.
+type replicas struct {
+	log  *zap.Logger
+	list []*replica
+	next uint32
+
+	healthCheckInterval time.Duration
+}
+
+func openReplicas(ctx context.Context, log *zap.Logger, connstrs []string, opts Options) (_ *replicas, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rs := &replicas{
+		log:                 log,
+		healthCheckInterval: opts.ReplicaHealthCheckInterval,
+	}
+	if rs.healthCheckInterval <= 0 {
+		rs.healthCheckInterval = defaultReplicaHealthCheckInterval
+	}
+
+	defer func() {
+		if err != nil {
+			err = errs.Combine(err, rs.Close())
+		}
+	}()
+
+	for i, connstr := range connstrs {
+		driver, source, impl, err := dbutil.SplitConnStr(connstr)
+		if err != nil {
+			return nil, Error.Wrap(err)
+		}
+		if impl != dbutil.Postgres && impl != dbutil.Cockroach {
+			return nil, Error.New("unsupported read replica driver %q", driver)
+		}
+
+		source, err = pgutil.CheckApplicationName(source, opts.ApplicationName+" (read replica)")
+		if err != nil {
+			return nil, Error.Wrap(err)
+		}
+
+		db, err := dbx.Open(driver, source)
+		if err != nil {
+			return nil, Error.New("failed opening read replica %d: %v", i, err)
+		}
+		dbutil.Configure(ctx, db.DB, "sqlauth-replica", mon)
+
+		// Replicas are assumed healthy until the first failed check, so reads
+		// are balanced from the start.
+		rs.list = append(rs.list, &replica{
+			index:   i,
+			db:      db,
+			impl:    impl,
+			healthy: 1,
+		})
+		log.Debug("Connected to read replica:", zap.Int("replica", i))
+	}
+
+	return rs, nil
+}
+
+// pick returns the next healthy replica or nil if there's none.
+func (rs *replicas) pick() *replica {
+	if rs == nil {
+		return nil
+	}
+
+	n := uint32(len(rs.list))
+	for i := uint32(0); i < n; i++ {
+		r := rs.list[(atomic.AddUint32(&rs.next, 1)-1)%n]
+		if r.isHealthy() {
+			return r
+		}
+	}
+
+	return nil
+}
+
+// markUnhealthy stops using r until a health check succeeds.
+func (rs *replicas) markUnhealthy(r *replica, err error) {
+	if r.setHealthy(false) {
+		rs.log.Warn("read replica is unhealthy; failing over to primary", zap.Int("replica", r.index), zap.Error(err))
+	}
+	mon.Event("as_sqlauth_replica_failover")
+}
+
+// checkHealth pings all replicas and updates their health.
+func (rs *replicas) checkHealth(ctx context.Context) {
+	for _, r := range rs.list {
+		pingCtx, cancel := context.WithTimeout(ctx, rs.healthCheckInterval)
+		err := r.db.PingContext(pingCtx)
+		cancel()
+
+		if err != nil {
+			if ctx.Err() == nil {
+				rs.markUnhealthy(r, err)
+			}
+			continue
+		}
+		if r.setHealthy(true) {
+			rs.log.Info("read replica is healthy again", zap.Int("replica", r.index))
+		}
+	}
+
+	var healthy int64
+	for _, r := range rs.list {
+		if r.isHealthy() {
+			healthy++
+		}
+	}
+	mon.IntVal("as_sqlauth_replicas_healthy").Observe(healthy)
+}
+
+// Run checks the health of replicas every healthCheckInterval.
+func (rs *replicas) Run(ctx context.Context) error {
+	if rs == nil || len(rs.list) == 0 {
+		return nil
+	}
+
+	cycle := sync2.NewCycle(rs.healthCheckInterval)
+	defer cycle.Close()
+
+	return cycle.Run(ctx, func(ctx context.Context) error {
+		rs.checkHealth(ctx)
+		return nil
+	})
+}
+
+// Close closes connections to all replicas.
+func (rs *replicas) Close() error {
+	if rs == nil {
+		return nil
+	}
+
+	var group errs.Group
+	for _, r := range rs.list {
+		group.Add(Error.Wrap(r.db.Close()))
+	}
+	return group.Err()
+}
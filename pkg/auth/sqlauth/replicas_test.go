@@ -0,0 +1,45 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package sqlauth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestReplicasPick(t *testing.T) {
+	var rs *replicas
+	assert.Nil(t, rs.pick(), "no replicas")
+
+	rs = &replicas{log: zaptest.NewLogger(t)}
+	for i := 0; i < 3; i++ {
+		rs.list = append(rs.list, &replica{index: i, healthy: 1})
+	}
+
+	picked := func(n int) (counts map[int]int) {
+		counts = make(map[int]int)
+		for i := 0; i < n; i++ {
+			if r := rs.pick(); r != nil {
+				counts[r.index]++
+			}
+		}
+		return counts
+	}
+
+	assert.Equal(t, map[int]int{0: 2, 1: 2, 2: 2}, picked(6))
+
+	rs.markUnhealthy(rs.list[1], errors.New("down"))
+	assert.Equal(t, map[int]int{0: 3, 2: 3}, picked(6))
+
+	rs.markUnhealthy(rs.list[0], errors.New("down"))
+	rs.markUnhealthy(rs.list[2], errors.New("down"))
+	assert.Nil(t, rs.pick(), "all replicas are unhealthy")
+
+	assert.True(t, rs.list[1].setHealthy(true))
+	assert.False(t, rs.list[1].setHealthy(true))
+	assert.Equal(t, map[int]int{1: 3}, picked(3))
+}
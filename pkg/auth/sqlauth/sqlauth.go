@@ -5,8 +5,10 @@ package sqlauth
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/spacemonkeygo/monkit/v3"
@@ -32,12 +34,22 @@ var Error = errs.Class("sqlauth")
 type KV struct {
 	db          *dbx.DB // DBX
 	impl        dbutil.Implementation
+	replicas    *replicas
 	testCleanup func() error
 }
 
 // Options includes options for how a connection is made.
 type Options struct {
 	ApplicationName string
+
+	// ReadReplicas are connection strings of read replicas that Get and
+	// DeleteUnused's selection of unused records are load-balanced across.
+	// Reads fail over to the primary if a replica is unhealthy, and records
+	// missing on a replica are read from the primary.
+	ReadReplicas []string
+	// ReplicaHealthCheckInterval is how often replicas are checked. It
+	// defaults to 10s.
+	ReplicaHealthCheckInterval time.Duration
 }
 
 // Open creates instance of KV.
@@ -65,9 +77,18 @@ func Open(ctx context.Context, log *zap.Logger, connstr string, opts Options) (_
 
 	dbutil.Configure(ctx, dbxDB.DB, "sqlauth", mon)
 
+	var rs *replicas
+	if len(opts.ReadReplicas) > 0 {
+		rs, err = openReplicas(ctx, log, opts.ReadReplicas, opts)
+		if err != nil {
+			return nil, errs.Combine(err, Error.Wrap(dbxDB.Close()))
+		}
+	}
+
 	return &KV{
 		db:          dbxDB,
 		impl:        impl,
+		replicas:    rs,
 		testCleanup: func() error { return nil },
 	}, nil
 }
@@ -100,11 +121,12 @@ func (d *KV) TagSQL() tagsql.DB { return d.db.DB }
 
 // Close closes the connection to database.
 func (d *KV) Close() error {
-	return errs.Combine(Error.Wrap(d.db.Close()), Error.Wrap(d.testCleanup()))
+	return errs.Combine(d.replicas.Close(), Error.Wrap(d.db.Close()), Error.Wrap(d.testCleanup()))
 }
 
-// Run runs the database.
-func (d *KV) Run(ctx context.Context) error { return nil }
+// Run runs the database. It checks the health of read replicas if there are
+// any.
+func (d *KV) Run(ctx context.Context) error { return d.replicas.Run(ctx) }
 
 // TestingSetCleanup is used to set the callback for cleaning up test database.
 func (d *KV) TestingSetCleanup(cleanup func() error) {
@@ -128,7 +150,7 @@ func (d *KV) MigrateToLatest(ctx context.Context) (err error) {
 func (d *KV) Put(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record) (err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	return Error.Wrap(d.db.CreateNoReturn_Record(ctx,
+	return putError(d.db.CreateNoReturn_Record(ctx,
 		dbx.Record_EncryptionKeyHash(keyHash[:]),
 		dbx.Record_CreatedAt(time.Now().UTC()),
 		dbx.Record_Public(record.Public),
@@ -146,7 +168,7 @@ func (d *KV) Put(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Rec
 func (d *KV) PutAtTime(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record, createdAt time.Time) (err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	return Error.Wrap(d.db.CreateNoReturn_Record(ctx,
+	return putError(d.db.CreateNoReturn_Record(ctx,
 		dbx.Record_EncryptionKeyHash(keyHash[:]),
 		dbx.Record_CreatedAt(createdAt),
 		dbx.Record_Public(record.Public),
@@ -159,17 +181,51 @@ func (d *KV) PutAtTime(ctx context.Context, keyHash authdb.KeyHash, record *auth
 		}))
 }
 
+// putError wraps err, translating unique violations to
+// authdb.ErrKeyAlreadyExists.
+func putError(err error) error {
+	if pgerrcode.FromError(err) == "23505" {
+		return Error.Wrap(authdb.ErrKeyAlreadyExists)
+	}
+	return Error.Wrap(err)
+}
+
 // Get retrieves the record from the key/value store.
 func (d *KV) Get(ctx context.Context, keyHash authdb.KeyHash) (*authdb.Record, error) {
 	return d.GetWithNonDefaultAsOfInterval(ctx, keyHash, -10*time.Second)
 }
 
 // GetWithNonDefaultAsOfInterval retrieves the record from the key/value store
-// using the specific asOfSystemInterval.
+// using the specific asOfSystemInterval. It reads from a read replica if there
+// is a healthy one and from the primary if the replica fails or doesn't have
+// the record.
 func (d *KV) GetWithNonDefaultAsOfInterval(ctx context.Context, keyHash authdb.KeyHash, asOfSystemInterval time.Duration) (_ *authdb.Record, err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	if d.impl == dbutil.Cockroach {
+	if r := d.replicas.pick(); r != nil {
+		record, err := get(ctx, r.db, r.impl, keyHash, asOfSystemInterval)
+		switch {
+		case err == nil && record != nil, authdb.Invalid.Has(err):
+			mon.Event("as_sqlauth_replica_hit")
+			return record, err
+		case err == nil:
+			// The record might not have been replicated yet.
+			mon.Event("as_sqlauth_replica_miss")
+		case ctx.Err() != nil:
+			return nil, Error.Wrap(err)
+		default:
+			d.replicas.markUnhealthy(r, err)
+		}
+	}
+
+	return get(ctx, d.db, d.impl, keyHash, asOfSystemInterval)
+}
+
+// get retrieves the record from db.
+func get(ctx context.Context, db *dbx.DB, impl dbutil.Implementation, keyHash authdb.KeyHash, asOfSystemInterval time.Duration) (_ *authdb.Record, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if impl == dbutil.Cockroach {
 		query := `SELECT
 					satellite_address,
 					macaroon_head,
@@ -178,9 +234,9 @@ func (d *KV) GetWithNonDefaultAsOfInterval(ctx context.Context, keyHash authdb.K
 					expires_at,
 					public,
 					invalid_reason
-		 	  FROM records ` + d.impl.AsOfSystemInterval(asOfSystemInterval) +
+		 	  FROM records ` + impl.AsOfSystemInterval(asOfSystemInterval) +
 			` WHERE encryption_key_hash = $1`
-		row := d.db.DB.QueryRowContext(ctx, query, keyHash[:])
+		row := db.DB.QueryRowContext(ctx, query, keyHash[:])
 
 		var (
 			record        authdb.Record
@@ -214,7 +270,7 @@ func (d *KV) GetWithNonDefaultAsOfInterval(ctx context.Context, keyHash authdb.K
 		// No results, then run a query without 'AS OF SYSTEM TIME' clause
 	}
 
-	dbRecord, err := d.db.Find_Record_By_EncryptionKeyHash(ctx,
+	dbRecord, err := db.Find_Record_By_EncryptionKeyHash(ctx,
 		dbx.Record_EncryptionKeyHash(keyHash[:]))
 	if err != nil {
 		return nil, Error.Wrap(err)
@@ -236,6 +292,333 @@ func (d *KV) GetWithNonDefaultAsOfInterval(ctx context.Context, keyHash authdb.K
 	}, nil
 }
 
+// rangePageSize is the number of records Range reads at once.
+const rangePageSize = 1000
+
+// Range calls fn for each record in the key/value store, skipping invalid ones.
+// It stops and returns the error if fn returns one.
+func (d *KV) Range(ctx context.Context, fn func(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record) error) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var next *dbx.Paged_Record_Continuation
+	for {
+		var rows []*dbx.Record
+		rows, next, err = d.db.Paged_Record(ctx, rangePageSize, next)
+		if err != nil {
+			return Error.Wrap(err)
+		}
+
+		for _, r := range rows {
+			if r.InvalidReason != nil {
+				continue
+			}
+
+			var keyHash authdb.KeyHash
+			if err = keyHash.SetBytes(r.EncryptionKeyHash); err != nil {
+				return Error.Wrap(err)
+			}
+
+			if err = fn(ctx, keyHash, &authdb.Record{
+				SatelliteAddress:     r.SatelliteAddress,
+				MacaroonHead:         r.MacaroonHead,
+				EncryptedSecretKey:   r.EncryptedSecretKey,
+				EncryptedAccessGrant: r.EncryptedAccessGrant,
+				ExpiresAt:            r.ExpiresAt,
+				Public:               r.Public,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+	}
+}
+
+// recordColumns are the columns of records in the order scanRecords expects.
+const recordColumns = `encryption_key_hash, created_at, public, satellite_address, macaroon_head,
+	expires_at, encrypted_secret_key, encrypted_access_grant, invalid_reason, invalid_at`
+
+// RecordsAfter returns up to limit records with key hashes greater than after
+// (all if after is empty), ordered by key hash. Unlike dbx's paged reads, it
+// allows resuming from any key hash.
+func (d *KV) RecordsAfter(ctx context.Context, after []byte, limit int) (_ []*dbx.Record, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if after == nil {
+		after = []byte{}
+	}
+
+	rows, err := d.db.QueryContext(ctx, d.db.Rebind(`SELECT `+recordColumns+`
+		FROM records WHERE encryption_key_hash > ? ORDER BY encryption_key_hash LIMIT ?`), after, limit)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	records, err := scanRecords(rows)
+	return records, Error.Wrap(err)
+}
+
+// SampleRecords returns up to n random records. Tables of at most n records
+// are returned whole. Otherwise, it seeks to n random key hashes (SHA-256
+// hashes, so evenly distributed) and takes the record at or after each of
+// them, which, unlike sorting randomly, doesn't scan the table. Records taken
+// more than once are returned once, so there might be fewer than n.
+func (d *KV) SampleRecords(ctx context.Context, n int) (_ []*dbx.Record, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	records, err := d.RecordsAfter(ctx, nil, n+1)
+	if err != nil || len(records) <= n {
+		return records, err
+	}
+
+	records = make([]*dbx.Record, 0, n)
+	sampled := make(map[string]struct{}, n)
+	for i := 0; i < n; i++ {
+		var start authdb.KeyHash
+		if _, err = rand.Read(start[:]); err != nil {
+			return nil, Error.Wrap(err)
+		}
+
+		var r *dbx.Record
+		if r, err = d.recordAtOrAfter(ctx, start[:]); err != nil {
+			return nil, err
+		}
+		if r == nil { // wrap around past the last record.
+			if r, err = d.recordAtOrAfter(ctx, []byte{}); err != nil || r == nil {
+				return records, err
+			}
+		}
+
+		if _, ok := sampled[string(r.EncryptionKeyHash)]; !ok {
+			sampled[string(r.EncryptionKeyHash)] = struct{}{}
+			records = append(records, r)
+		}
+	}
+
+	return records, nil
+}
+
+// recordAtOrAfter returns the record with the lowest key hash at or after
+// keyHash, or nil if there is none.
+func (d *KV) recordAtOrAfter(ctx context.Context, keyHash []byte) (_ *dbx.Record, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rows, err := d.db.QueryContext(ctx, d.db.Rebind(`SELECT `+recordColumns+`
+		FROM records WHERE encryption_key_hash >= ? ORDER BY encryption_key_hash LIMIT 1`), keyHash)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	records, err := scanRecords(rows)
+	if err != nil || len(records) == 0 {
+		return nil, Error.Wrap(err)
+	}
+	return records[0], nil
+}
+
+// InsertRecord stores the record with all of its columns (including creation
+// and invalidation times) unless a record with the same key hash exists. It
+// returns whether the record was inserted.
+func (d *KV) InsertRecord(ctx context.Context, r *dbx.Record) (inserted bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := d.db.ExecContext(ctx, d.db.Rebind(`INSERT INTO records (`+recordColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (encryption_key_hash) DO NOTHING`),
+		r.EncryptionKeyHash, r.CreatedAt, r.Public, r.SatelliteAddress, r.MacaroonHead,
+		r.ExpiresAt, r.EncryptedSecretKey, r.EncryptedAccessGrant, r.InvalidReason, r.InvalidAt)
+	if err != nil {
+		return false, Error.Wrap(err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, Error.Wrap(err)
+	}
+
+	return affected > 0, nil
+}
+
+// FindRecord returns the record with all of its columns, or nil if it doesn't
+// exist.
+func (d *KV) FindRecord(ctx context.Context, keyHash authdb.KeyHash) (_ *dbx.Record, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	r, err := d.db.Find_Record_By_EncryptionKeyHash(ctx, dbx.Record_EncryptionKeyHash(keyHash[:]))
+	return r, Error.Wrap(err)
+}
+
+// InvalidateAtTime is like Invalidate, but it stores a specific invalidation
+// time.
+func (d *KV) InvalidateAtTime(ctx context.Context, keyHash authdb.KeyHash, reason string, invalidAt time.Time) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	return Error.Wrap(d.db.UpdateNoReturn_Record_By_EncryptionKeyHash_And_InvalidReason_Is_Null(ctx,
+		dbx.Record_EncryptionKeyHash(keyHash[:]),
+		dbx.Record_Update_Fields{
+			InvalidReason: dbx.Record_InvalidReason(reason),
+			InvalidAt:     dbx.Record_InvalidAt(invalidAt),
+		}))
+}
+
+// Unpublish makes the record private.
+// It is not an error if the key does not exist.
+func (d *KV) Unpublish(ctx context.Context, keyHash authdb.KeyHash) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	_, err = d.db.ExecContext(ctx, d.db.Rebind(`UPDATE records SET public = false WHERE encryption_key_hash = ?`), keyHash[:])
+	return Error.Wrap(err)
+}
+
+// List returns up to limit records matching filter, ordered by creation time
+// and key hash, after cursor (from the start if it's zero). Expired records
+// aren't listed. next is the cursor to continue listing from if more records
+// match, and zero otherwise.
+func (d *KV) List(ctx context.Context, filter authdb.ListFilter, cursor authdb.ListCursor, limit int) (_ []authdb.ListedRecord, next authdb.ListCursor, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if limit <= 0 {
+		return nil, next, Error.New("limit must be positive")
+	}
+
+	query, args := ListQuery(filter, cursor, limit, time.Now())
+
+	rows, err := d.db.QueryContext(ctx, d.db.Rebind(query), args...)
+	if err != nil {
+		return nil, next, Error.Wrap(err)
+	}
+
+	listed, next, err := ScanListedRecords(rows, limit)
+	return listed, next, Error.Wrap(err)
+}
+
+// listedColumns are the columns of records in the order ScanListedRecords
+// expects. Listings don't need the encrypted secrets.
+const listedColumns = `encryption_key_hash, created_at, public, satellite_address, macaroon_head,
+	expires_at, invalid_reason, invalid_at`
+
+// ListQuery returns the query (with ? placeholders) and its arguments that
+// select records for List at time now. It selects one record more than limit,
+// so ScanListedRecords can tell whether there are more. Times are passed in
+// UTC.
+//
+// It's shared with sqliteauth, which stores the same records table.
+func ListQuery(filter authdb.ListFilter, cursor authdb.ListCursor, limit int, now time.Time) (query string, args []interface{}) {
+	var b strings.Builder
+
+	b.WriteString(`SELECT ` + listedColumns + `
+		FROM records WHERE (expires_at IS NULL OR expires_at > ?)`)
+	args = append(args, now.UTC())
+
+	if len(filter.MacaroonHead) > 0 {
+		b.WriteString(` AND macaroon_head = ?`)
+		args = append(args, filter.MacaroonHead)
+	}
+	if filter.SatelliteAddress != "" {
+		b.WriteString(` AND satellite_address = ?`)
+		args = append(args, filter.SatelliteAddress)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		b.WriteString(` AND created_at >= ?`)
+		args = append(args, filter.CreatedAfter.UTC())
+	}
+	if !filter.CreatedBefore.IsZero() {
+		b.WriteString(` AND created_at < ?`)
+		args = append(args, filter.CreatedBefore.UTC())
+	}
+	if filter.Public != nil {
+		b.WriteString(` AND public = ?`)
+		args = append(args, *filter.Public)
+	}
+	if filter.Invalidated != nil {
+		if *filter.Invalidated {
+			b.WriteString(` AND invalid_reason IS NOT NULL`)
+		} else {
+			b.WriteString(` AND invalid_reason IS NULL`)
+		}
+	}
+	if !cursor.IsZero() {
+		b.WriteString(` AND (created_at > ? OR (created_at = ? AND encryption_key_hash > ?))`)
+		args = append(args, cursor.CreatedAt.UTC(), cursor.CreatedAt.UTC(), cursor.KeyHash.Bytes())
+	}
+
+	b.WriteString(` ORDER BY created_at, encryption_key_hash LIMIT ?`)
+	args = append(args, limit+1)
+
+	return b.String(), args
+}
+
+// ScanListedRecords scans and closes rows selected with ListQuery, returning up
+// to limit records and the cursor to continue from if there are more.
+func ScanListedRecords(rows tagsql.Rows, limit int) (listed []authdb.ListedRecord, next authdb.ListCursor, err error) {
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	var records []*dbx.Record
+	for rows.Next() {
+		var r dbx.Record
+		if err = rows.Scan(
+			&r.EncryptionKeyHash, &r.CreatedAt, &r.Public, &r.SatelliteAddress, &r.MacaroonHead,
+			&r.ExpiresAt, &r.InvalidReason, &r.InvalidAt,
+		); err != nil {
+			return nil, next, err
+		}
+		records = append(records, &r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, next, err
+	}
+
+	more := len(records) > limit
+	if more {
+		records = records[:limit]
+	}
+
+	for _, r := range records {
+		var keyHash authdb.KeyHash
+		if err = keyHash.SetBytes(r.EncryptionKeyHash); err != nil {
+			return nil, next, err
+		}
+
+		l := authdb.ListedRecord{
+			KeyHash:          keyHash,
+			CreatedAt:        r.CreatedAt,
+			SatelliteAddress: r.SatelliteAddress,
+			MacaroonHead:     r.MacaroonHead,
+			ExpiresAt:        r.ExpiresAt,
+			Public:           r.Public,
+			InvalidatedAt:    r.InvalidAt,
+		}
+		if r.InvalidReason != nil {
+			l.InvalidationReason = *r.InvalidReason
+		}
+		listed = append(listed, l)
+	}
+
+	if more {
+		next = listed[len(listed)-1].Cursor()
+	}
+
+	return listed, next, nil
+}
+
+func scanRecords(rows tagsql.Rows) (records []*dbx.Record, err error) {
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	for rows.Next() {
+		var r dbx.Record
+		if err = rows.Scan(
+			&r.EncryptionKeyHash, &r.CreatedAt, &r.Public, &r.SatelliteAddress, &r.MacaroonHead,
+			&r.ExpiresAt, &r.EncryptedSecretKey, &r.EncryptedAccessGrant, &r.InvalidReason, &r.InvalidAt,
+		); err != nil {
+			return nil, err
+		}
+		records = append(records, &r)
+	}
+
+	return records, rows.Err()
+}
+
 // Delete removes the record from the key/value store.
 // It is not an error if the key does not exist.
 func (d *KV) Delete(ctx context.Context, keyHash authdb.KeyHash) (err error) {
@@ -247,12 +630,31 @@ func (d *KV) Delete(ctx context.Context, keyHash authdb.KeyHash) (err error) {
 }
 
 // selectUnused returns up to selectSize pkvals corresponding to unused (expired
-// or invalid) records in a read-only transaction in the past as specified by
-// the asOfSystemInterval interval.
-func (d *KV) selectUnused(ctx context.Context, asOfSystemInterval time.Duration, selectSize int) (pkvals, heads [][]byte, err error) {
+// or invalid) records. If useReplicas is true, it selects them from a read
+// replica if there is a healthy one, in which case fromReplica is true, and
+// from the primary otherwise.
+func (d *KV) selectUnused(ctx context.Context, useReplicas bool, asOfSystemInterval time.Duration, selectSize int) (pkvals, heads [][]byte, fromReplica bool, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if r := d.replicas.pick(); useReplicas && r != nil {
+		pkvals, heads, err = selectUnusedFrom(ctx, r.db, r.impl, asOfSystemInterval, selectSize)
+		if err == nil || ctx.Err() != nil {
+			return pkvals, heads, true, err
+		}
+		d.replicas.markUnhealthy(r, err)
+	}
+
+	pkvals, heads, err = selectUnusedFrom(ctx, d.db, d.impl, asOfSystemInterval, selectSize)
+	return pkvals, heads, false, err
+}
+
+// selectUnusedFrom returns up to selectSize pkvals corresponding to unused
+// (expired or invalid) records from db in a read-only transaction in the past
+// as specified by the asOfSystemInterval interval.
+func selectUnusedFrom(ctx context.Context, db *dbx.DB, impl dbutil.Implementation, asOfSystemInterval time.Duration, selectSize int) (pkvals, heads [][]byte, err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	tx, err := d.db.DB.BeginTx(ctx, nil)
+	tx, err := db.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, nil, Error.Wrap(err)
 	}
@@ -261,10 +663,10 @@ func (d *KV) selectUnused(ctx context.Context, asOfSystemInterval time.Duration,
 	// release any read locks early.
 	defer func() { err = errs.Combine(err, Error.Wrap(tx.Rollback())) }()
 
-	if d.impl == dbutil.Cockroach {
+	if impl == dbutil.Cockroach {
 		if _, err = tx.ExecContext(
 			ctx,
-			"SET TRANSACTION"+d.impl.AsOfSystemInterval(-asOfSystemInterval),
+			"SET TRANSACTION"+impl.AsOfSystemInterval(-asOfSystemInterval),
 		); err != nil {
 			return nil, nil, Error.Wrap(err)
 		}
@@ -309,13 +711,19 @@ func (d *KV) selectUnused(ctx context.Context, asOfSystemInterval time.Duration,
 // batches as specified by the selectSize and deleteSize parameters and returns
 // any error encountered. It uses database time to avoid problems with invalid
 // time on the server.
+//
+// Unused records are selected on read replicas if there are any and deleted on
+// the primary. A lagging replica keeps selecting records that are already
+// deleted, so once a selection from a replica deletes nothing, the rest are
+// selected on the primary.
 func (d *KV) DeleteUnused(ctx context.Context, asOfSystemInterval time.Duration, selectSize, deleteSize int) (count, rounds int64, deletesPerHead map[string]int64, err error) {
 	defer mon.Task()(&ctx)(&err)
 
 	deletesPerHead = make(map[string]int64)
 
+	useReplicas := true
 	for {
-		pkvals, heads, err := d.selectUnused(ctx, asOfSystemInterval, selectSize)
+		pkvals, heads, fromReplica, err := d.selectUnused(ctx, useReplicas, asOfSystemInterval, selectSize)
 		if err != nil {
 			return count, rounds, deletesPerHead, Error.Wrap(err)
 		}
@@ -324,6 +732,9 @@ func (d *KV) DeleteUnused(ctx context.Context, asOfSystemInterval time.Duration,
 			return count, rounds, deletesPerHead, nil
 		}
 
+		// deleted is -1 if the driver can't tell how many rows were deleted.
+		var deleted int64
+
 		for len(pkvals) > 0 {
 			var pkvalsBatch, headsBatch [][]byte
 
@@ -346,6 +757,11 @@ func (d *KV) DeleteUnused(ctx context.Context, asOfSystemInterval time.Duration,
 			c, err := res.RowsAffected()
 			if err == nil { // Not every database or database driver may support RowsAffected.
 				count += c
+				if deleted >= 0 {
+					deleted += c
+				}
+			} else {
+				deleted = -1
 			}
 
 			rounds++
@@ -355,6 +771,13 @@ func (d *KV) DeleteUnused(ctx context.Context, asOfSystemInterval time.Duration,
 			}
 		}
 
+		if fromReplica && deleted <= 0 {
+			// the replica lags behind and selected records that are already
+			// deleted (or we can't tell), so we stop relying on it.
+			useReplicas = false
+			mon.Event("as_sqlauth_delete_unused_replica_lagging")
+		}
+
 		if d.impl == dbutil.Cockroach {
 			time.Sleep(asOfSystemInterval)
 		}
@@ -376,12 +799,7 @@ func BatchValues(pkvals [][]byte, threshold int) ([][]byte, [][]byte) {
 func (d *KV) Invalidate(ctx context.Context, keyHash authdb.KeyHash, reason string) (err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	return Error.Wrap(d.db.UpdateNoReturn_Record_By_EncryptionKeyHash_And_InvalidReason_Is_Null(ctx,
-		dbx.Record_EncryptionKeyHash(keyHash[:]),
-		dbx.Record_Update_Fields{
-			InvalidReason: dbx.Record_InvalidReason(reason),
-			InvalidAt:     dbx.Record_InvalidAt(time.Now()),
-		}))
+	return d.InvalidateAtTime(ctx, keyHash, reason, time.Now())
 }
 
 // PingDB attempts to do a database roundtrip and returns an error if it can't.
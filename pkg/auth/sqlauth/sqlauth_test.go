@@ -4,19 +4,24 @@
 package sqlauth_test
 
 import (
+	"context"
 	"strconv"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/zeebo/errs"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
 
 	"storj.io/common/testcontext"
 	"storj.io/common/testrand"
 	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/authdb/kvtest"
 	"storj.io/gateway-mt/pkg/auth/sqlauth"
 	"storj.io/private/dbutil/pgtest"
+	"storj.io/private/dbutil/tempdb"
 )
 
 func TestKVFullCycle_Postgres(t *testing.T) {
@@ -67,11 +72,24 @@ func testKVFullCycle(t *testing.T, connStr string) {
 	retrievedRecord.ExpiresAt = &retrievedExpAt
 	require.Equal(t, record, *retrievedRecord)
 
+	var ranged int
+	require.NoError(t, kv.Range(ctx, func(_ context.Context, kh authdb.KeyHash, r *authdb.Record) error {
+		require.Equal(t, keyHash, kh, "range")
+		require.Equal(t, record.MacaroonHead, r.MacaroonHead, "range")
+		ranged++
+		return nil
+	}), "range")
+	require.Equal(t, 1, ranged, "range")
+
 	require.NoError(t, kv.Invalidate(ctx, keyHash, "invalidated for testing purpose"), "invalidate")
 	_, err = kv.Get(ctx, keyHash)
 	require.Error(t, err, "get-invalid")
 	require.EqualError(t, err, authdb.Invalid.New("%s", "invalidated for testing purpose").Error(), "get-invalid")
 
+	require.NoError(t, kv.Range(ctx, func(context.Context, authdb.KeyHash, *authdb.Record) error {
+		return errs.New("invalid records must be skipped")
+	}), "range-invalid")
+
 	require.NoError(t, kv.Delete(ctx, keyHash), "delete")
 	retrievedRecord, err = kv.Get(ctx, keyHash)
 	require.Nil(t, retrievedRecord, "get-after-deleted")
@@ -363,3 +381,170 @@ func testKVDeleteUnusedBatching(t *testing.T, connstr string, selectSize, delete
 	assert.Equal(t, expectedRounds, rounds)
 	assert.Equal(t, map[string]int64{string([]byte{0}): expectedCount}, heads)
 }
+
+func TestKV_SampleRecords_Postgres(t *testing.T) {
+	testKVSampleRecords(t, pgtest.PickPostgres(t))
+}
+
+func TestKV_SampleRecords_Cockroach(t *testing.T) {
+	testKVSampleRecords(t, pgtest.PickCockroachAlt(t))
+}
+
+func testKVSampleRecords(t *testing.T, connstr string) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	kv, err := sqlauth.OpenTest(ctx, zap.NewNop(), t.Name(), connstr)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, kv.Close()) }()
+
+	require.NoError(t, kv.MigrateToLatest(ctx))
+
+	stored := make(map[authdb.KeyHash]bool)
+	for i := 0; i < 50; i++ {
+		var keyHash authdb.KeyHash
+		testrand.Read(keyHash[:])
+		require.NoError(t, kv.Put(ctx, keyHash, &authdb.Record{
+			SatelliteAddress:     "abc",
+			MacaroonHead:         []byte{0},
+			EncryptedSecretKey:   []byte{1},
+			EncryptedAccessGrant: []byte{2},
+		}))
+		stored[keyHash] = true
+	}
+
+	// tables no larger than the sample are sampled whole.
+	sample, err := kv.SampleRecords(ctx, 50)
+	require.NoError(t, err)
+	assert.Len(t, sample, 50)
+
+	sample, err = kv.SampleRecords(ctx, 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, sample)
+	require.LessOrEqual(t, len(sample), 10)
+
+	sampled := make(map[authdb.KeyHash]bool)
+	for _, r := range sample {
+		var keyHash authdb.KeyHash
+		require.NoError(t, keyHash.SetBytes(r.EncryptionKeyHash))
+		assert.True(t, stored[keyHash])
+		assert.False(t, sampled[keyHash], "records are sampled once")
+		sampled[keyHash] = true
+	}
+}
+
+func TestKV_Conformance_Postgres(t *testing.T) {
+	testKVConformance(t, pgtest.PickPostgres(t))
+}
+
+func TestKV_Conformance_Cockroach(t *testing.T) {
+	testKVConformance(t, pgtest.PickCockroachAlt(t))
+}
+
+func testKVConformance(t *testing.T, connStr string) {
+	kvtest.Run(t, func(ctx *testcontext.Context, t *testing.T) authdb.KV {
+		kv, err := sqlauth.OpenTest(ctx, zap.NewNop(), t.Name(), connStr)
+		require.NoError(t, err)
+		require.NoError(t, kv.MigrateToLatest(ctx))
+		return kv
+	}, kvtest.Options{
+		Invalidate: func(ctx context.Context, kv authdb.KV, keyHash authdb.KeyHash, reason string) error {
+			return kv.(*sqlauth.KV).Invalidate(ctx, keyHash, reason)
+		},
+	})
+}
+
+func TestKV_ReadReplicas_Postgres(t *testing.T) {
+	testKVReadReplicas(t, pgtest.PickPostgres(t))
+}
+
+func TestKV_ReadReplicas_Cockroach(t *testing.T) {
+	testKVReadReplicas(t, pgtest.PickCockroachAlt(t))
+}
+
+func testKVReadReplicas(t *testing.T, connstr string) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	log := zaptest.NewLogger(t)
+
+	// The replica is a separate database, so we can tell which one a record
+	// was read from.
+	primaryDB, err := tempdb.OpenUnique(ctx, connstr, "primary")
+	require.NoError(t, err)
+	defer ctx.Check(primaryDB.Close)
+
+	replicaDB, err := tempdb.OpenUnique(ctx, connstr, "replica")
+	require.NoError(t, err)
+	defer ctx.Check(replicaDB.Close)
+
+	replica, err := sqlauth.Open(ctx, log, replicaDB.ConnStr, sqlauth.Options{ApplicationName: "test"})
+	require.NoError(t, err)
+	defer ctx.Check(replica.Close)
+	require.NoError(t, replica.MigrateToLatest(ctx))
+
+	kv, err := sqlauth.Open(ctx, log, primaryDB.ConnStr, sqlauth.Options{
+		ApplicationName:            "test",
+		ReadReplicas:               []string{replicaDB.ConnStr},
+		ReplicaHealthCheckInterval: time.Hour,
+	})
+	require.NoError(t, err)
+	defer ctx.Check(kv.Close)
+	require.NoError(t, kv.MigrateToLatest(ctx))
+
+	onlyReplica, onlyPrimary := kvtest.NewRecord(nil), kvtest.NewRecord(nil)
+	require.NoError(t, replica.Put(ctx, authdb.KeyHash{'r'}, onlyReplica))
+	require.NoError(t, kv.Put(ctx, authdb.KeyHash{'p'}, onlyPrimary))
+
+	// Reads go to the replica.
+	r, err := kv.GetWithNonDefaultAsOfInterval(ctx, authdb.KeyHash{'r'}, 0)
+	require.NoError(t, err)
+	kvtest.RequireRecordEqual(t, onlyReplica, r)
+
+	// Puts go to the primary, and reads that miss on the replica fall back to
+	// it.
+	r, err = kv.GetWithNonDefaultAsOfInterval(ctx, authdb.KeyHash{'p'}, 0)
+	require.NoError(t, err)
+	kvtest.RequireRecordEqual(t, onlyPrimary, r)
+
+	// Unused records are selected on the replica and deleted on the primary.
+	// The replica lags behind: it still has record r, which is already
+	// deleted on the primary, and misses record u, which is unused on the
+	// primary. Record x is unused on both.
+	require.NoError(t, replica.Invalidate(ctx, authdb.KeyHash{'r'}, "invalid"))
+	require.NoError(t, kv.Put(ctx, authdb.KeyHash{'u'}, kvtest.NewRecord(nil)))
+	require.NoError(t, kv.Invalidate(ctx, authdb.KeyHash{'u'}, "invalid"))
+	for _, store := range []*sqlauth.KV{kv, replica} {
+		require.NoError(t, store.Put(ctx, authdb.KeyHash{'x'}, kvtest.NewRecord(nil)))
+		require.NoError(t, store.Invalidate(ctx, authdb.KeyHash{'x'}, "invalid"))
+	}
+	time.Sleep(time.Millisecond)
+
+	// The first selection on the replica (r and x) deletes x, and the second
+	// one (the same, as the replica doesn't catch up) deletes nothing, so u is
+	// selected on the primary.
+	count, rounds, _, err := kv.DeleteUnused(ctx, time.Microsecond, 10, 10)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+	assert.EqualValues(t, 3, rounds)
+
+	for _, keyHash := range []authdb.KeyHash{{'u'}, {'x'}} {
+		deleted, err := kv.FindRecord(ctx, keyHash)
+		require.NoError(t, err)
+		assert.Nil(t, deleted)
+	}
+
+	// Reads fail over to the primary if the replica fails.
+	_, err = replicaDB.ExecContext(ctx, "DROP TABLE records")
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		r, err = kv.GetWithNonDefaultAsOfInterval(ctx, authdb.KeyHash{'p'}, 0)
+		require.NoError(t, err)
+		kvtest.RequireRecordEqual(t, onlyPrimary, r)
+
+		r, err = kv.GetWithNonDefaultAsOfInterval(ctx, authdb.KeyHash{'r'}, 0)
+		require.NoError(t, err)
+		require.Nil(t, r)
+	}
+}
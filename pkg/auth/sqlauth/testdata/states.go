@@ -11,6 +11,7 @@ import (
 // The SQL here represent the final schemas after each step is performed.
 var States = []*DBState{
 	v0,
+	v1,
 }
 
 // DBState allows you to define the desired state of the DB using SQL commands.
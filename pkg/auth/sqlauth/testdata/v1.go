@@ -0,0 +1,23 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package testdata
+
+var v1 = &DBState{
+	Version: 1,
+	SQL: `CREATE TABLE records (
+		encryption_key_hash bytea NOT NULL,
+		created_at timestamp with time zone NOT NULL,
+		public boolean NOT NULL,
+		satellite_address text NOT NULL,
+		macaroon_head bytea NOT NULL,
+		expires_at timestamp with time zone,
+		encrypted_secret_key bytea NOT NULL,
+		encrypted_access_grant bytea NOT NULL,
+		invalid_reason text,
+		invalid_at timestamp with time zone,
+		PRIMARY KEY ( encryption_key_hash )
+	);
+	CREATE INDEX records_created_at_index ON records ( created_at );
+	CREATE INDEX records_macaroon_head_created_at_index ON records ( macaroon_head, created_at );`,
+}
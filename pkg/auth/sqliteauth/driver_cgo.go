@@ -0,0 +1,23 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+//go:build cgo
+// +build cgo
+
+package sqliteauth
+
+import (
+	"errors"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// checkSupported returns an error if SQLite can't be used.
+func checkSupported() error { return nil }
+
+// isPrimaryKeyViolation returns whether err is caused by inserting a duplicate
+// primary key.
+func isPrimaryKeyViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
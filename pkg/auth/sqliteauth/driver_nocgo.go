@@ -0,0 +1,15 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+//go:build !cgo
+// +build !cgo
+
+package sqliteauth
+
+// checkSupported returns an error if SQLite can't be used. The SQLite driver
+// requires cgo, and release binaries are built without it.
+func checkSupported() error {
+	return Error.New("SQLite isn't supported by this binary as it was built without cgo")
+}
+
+func isPrimaryKeyViolation(error) bool { return false }
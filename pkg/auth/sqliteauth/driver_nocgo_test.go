@@ -0,0 +1,26 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+//go:build !cgo
+// +build !cgo
+
+package sqliteauth_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"storj.io/common/testcontext"
+	"storj.io/gateway-mt/pkg/auth/sqliteauth"
+)
+
+func TestOpenWithoutCgo(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	_, err := sqliteauth.OpenTest(ctx, zap.NewNop())
+	require.Error(t, err)
+	require.True(t, sqliteauth.Error.Has(err))
+}
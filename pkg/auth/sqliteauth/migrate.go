@@ -0,0 +1,41 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package sqliteauth
+
+import (
+	"context"
+	"strings"
+
+	"storj.io/gateway-mt/pkg/auth/sqlauth"
+	"storj.io/private/migrate"
+)
+
+// sqliteTypes translates PostgreSQL types and syntax used by sqlauth to
+// SQLite's. SQLite has no concurrent index builds; its single writer runs all
+// steps in transactions.
+var sqliteTypes = strings.NewReplacer(
+	"timestamp with time zone", "timestamp",
+	"bytea", "blob",
+	" CONCURRENTLY", "",
+)
+
+// Migration returns table migrations.
+// The steps are sqlauth's (see sqlauth.MigrationSteps) with SQLite types, so
+// both backends share the records table.
+func (d *KV) Migration(ctx context.Context) *migrate.Migration {
+	migration := &migrate.Migration{Table: "versions"}
+	for version, step := range sqlauth.MigrationSteps() {
+		var action migrate.SQL
+		for _, query := range step.SQL {
+			action = append(action, sqliteTypes.Replace(query))
+		}
+		migration.Steps = append(migration.Steps, &migrate.Step{
+			DB:          &d.db,
+			Description: step.Description,
+			Version:     version,
+			Action:      action,
+		})
+	}
+	return migration
+}
@@ -0,0 +1,408 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package sqliteauth implements a key/value store backed by a single SQLite
+// database file for single-node deployments.
+package sqliteauth
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/spacemonkeygo/monkit/v3"
+	"github.com/zeebo/errs"
+	"go.uber.org/zap"
+
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/sqlauth"
+	"storj.io/gateway-mt/pkg/auth/sqlauth/dbx"
+	"storj.io/private/dbutil"
+	"storj.io/private/tagsql"
+)
+
+var mon = monkit.Package()
+
+// Error is default error class for sqliteauth package.
+var Error = errs.Class("sqliteauth")
+
+// KV is a key/value store backed by a SQLite database. It uses the same
+// records table as sqlauth.
+type KV struct {
+	db tagsql.DB
+
+	// invalidations, if set, is where invalidated and deleted records are
+	// published.
+	invalidations *authdb.InvalidationFeed
+}
+
+// Below is a compile-time check ensuring KV implements the KV interface.
+var (
+	_ authdb.KV     = (*KV)(nil)
+	_ authdb.Ranger = (*KV)(nil)
+	_ authdb.Lister = (*KV)(nil)
+)
+
+// Open creates instance of KV. connstr is sqlite://path/to/file.db (or
+// sqlite3://...), with optional go-sqlite3 connection parameters. It fails if
+// the binary was built without cgo.
+func Open(ctx context.Context, log *zap.Logger, connstr string) (_ *KV, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if err = checkSupported(); err != nil {
+		return nil, err
+	}
+
+	_, source, impl, err := dbutil.SplitConnStr(connstr)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+	if impl != dbutil.SQLite3 {
+		return nil, Error.New("unsupported connection string %q", connstr)
+	}
+
+	db, err := tagsql.Open(ctx, "sqlite3", source)
+	if err != nil {
+		return nil, Error.New("failed opening database at %q: %v", source, err)
+	}
+	log.Debug("Connected to:", zap.String("db source", source))
+
+	// SQLite allows a single writer at a time, so a single connection avoids
+	// "database is locked" errors (and keeps in-memory databases shared).
+	db.SetMaxOpenConns(1)
+
+	return &KV{db: db}, nil
+}
+
+// OpenTest creates an in-memory instance of KV suitable for testing.
+func OpenTest(ctx context.Context, log *zap.Logger) (*KV, error) {
+	return Open(ctx, log, "sqlite3://file::memory:")
+}
+
+// TagSQL returns *tagsql.DB.
+func (d *KV) TagSQL() tagsql.DB { return d.db }
+
+// Close closes the connection to database.
+func (d *KV) Close() error {
+	return Error.Wrap(d.db.Close())
+}
+
+// Run runs the database.
+func (d *KV) Run(ctx context.Context) error { return nil }
+
+// MigrateToLatest migrates the kv store to the latest version of the schema.
+func (d *KV) MigrateToLatest(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	log := zap.L().Named("migrate")
+	migration := d.Migration(ctx)
+	err = migration.Run(ctx, log)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	return migration.ValidateVersions(ctx, log)
+}
+
+// Put is like PutAtTime, but it uses current time to store the record.
+func (d *KV) Put(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	return d.PutAtTime(ctx, keyHash, record, time.Now())
+}
+
+// PutAtTime stores the record at a specific time.
+// It is an error if the key already exists.
+func (d *KV) PutAtTime(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record, createdAt time.Time) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	_, err = d.db.ExecContext(ctx, `INSERT INTO records (`+recordColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULL, NULL)`,
+		keyHash[:], createdAt.UTC(), record.Public, record.SatelliteAddress, record.MacaroonHead,
+		utcOrNil(record.ExpiresAt), record.EncryptedSecretKey, record.EncryptedAccessGrant)
+
+	if isPrimaryKeyViolation(err) {
+		return Error.Wrap(authdb.ErrKeyAlreadyExists)
+	}
+	return Error.Wrap(err)
+}
+
+// Get retrieves the record from the key/value store. It returns nil if the key
+// does not exist. If the record is invalid, the error contains why.
+func (d *KV) Get(ctx context.Context, keyHash authdb.KeyHash) (_ *authdb.Record, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rows, err := d.db.QueryContext(ctx, `SELECT `+recordColumns+`
+		FROM records WHERE encryption_key_hash = ?`, keyHash[:])
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	records, err := scanRecords(rows)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	r := records[0]
+	if r.InvalidReason != nil {
+		return nil, authdb.Invalid.New("%s", *r.InvalidReason)
+	} else if r.ExpiresAt != nil && r.ExpiresAt.Before(time.Now()) {
+		return nil, nil
+	}
+
+	return toRecord(r), nil
+}
+
+// rangePageSize is the number of records Range reads at once.
+const rangePageSize = 1000
+
+// Range calls fn for each record in the key/value store, skipping invalid ones.
+// It stops and returns the error if fn returns one.
+func (d *KV) Range(ctx context.Context, fn func(ctx context.Context, keyHash authdb.KeyHash, record *authdb.Record) error) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	after := []byte{}
+	for {
+		rows, err := d.db.QueryContext(ctx, `SELECT `+recordColumns+`
+			FROM records WHERE encryption_key_hash > ? ORDER BY encryption_key_hash LIMIT ?`, after, rangePageSize)
+		if err != nil {
+			return Error.Wrap(err)
+		}
+
+		records, err := scanRecords(rows)
+		if err != nil {
+			return Error.Wrap(err)
+		}
+
+		for _, r := range records {
+			if r.InvalidReason != nil {
+				continue
+			}
+
+			var keyHash authdb.KeyHash
+			if err = keyHash.SetBytes(r.EncryptionKeyHash); err != nil {
+				return Error.Wrap(err)
+			}
+
+			if err = fn(ctx, keyHash, toRecord(r)); err != nil {
+				return err
+			}
+		}
+
+		if len(records) < rangePageSize {
+			return nil
+		}
+		after = records[len(records)-1].EncryptionKeyHash
+	}
+}
+
+// List returns up to limit records matching filter, ordered by creation time
+// and key hash, after cursor (from the start if it's zero). Expired records
+// aren't listed. next is the cursor to continue listing from if more records
+// match, and zero otherwise.
+func (d *KV) List(ctx context.Context, filter authdb.ListFilter, cursor authdb.ListCursor, limit int) (_ []authdb.ListedRecord, next authdb.ListCursor, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if limit <= 0 {
+		return nil, next, Error.New("limit must be positive")
+	}
+
+	query, args := sqlauth.ListQuery(filter, cursor, limit, time.Now())
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, next, Error.Wrap(err)
+	}
+
+	listed, next, err := sqlauth.ScanListedRecords(rows, limit)
+	return listed, next, Error.Wrap(err)
+}
+
+// SetInvalidationFeed makes the store publish records invalidated with
+// Invalidate and deleted with Delete to feed. It must be called before the
+// store is used. SQLite databases have a single process writing to them, so
+// no invalidation is missed.
+func (d *KV) SetInvalidationFeed(feed *authdb.InvalidationFeed) {
+	d.invalidations = feed
+}
+
+// Delete removes the record from the key/value store.
+// It is not an error if the key does not exist.
+func (d *KV) Delete(ctx context.Context, keyHash authdb.KeyHash) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := d.db.ExecContext(ctx, `DELETE FROM records WHERE encryption_key_hash = ?`, keyHash[:])
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	return Error.Wrap(d.publishIfAffected(result, keyHash, authdb.InvalidationDeleted))
+}
+
+// Invalidate causes the record to become invalid.
+// It is not an error if the key does not exist.
+// It does not update the invalid reason if the record is already invalid.
+func (d *KV) Invalidate(ctx context.Context, keyHash authdb.KeyHash, reason string) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	result, err := d.db.ExecContext(ctx, `UPDATE records SET invalid_reason = ?, invalid_at = ?
+		WHERE encryption_key_hash = ? AND invalid_reason IS NULL`,
+		reason, time.Now().UTC(), keyHash[:])
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	return Error.Wrap(d.publishIfAffected(result, keyHash, authdb.InvalidationInvalidated))
+}
+
+// publishIfAffected publishes keyHash to the invalidation feed if there is one
+// and result affected a record.
+func (d *KV) publishIfAffected(result sql.Result, keyHash authdb.KeyHash, reason authdb.InvalidationReason) error {
+	if d.invalidations == nil {
+		return nil
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		d.invalidations.Publish(keyHash, reason)
+	}
+	return nil
+}
+
+// selectUnused returns up to selectSize pkvals corresponding to unused (expired
+// or invalid) records.
+func (d *KV) selectUnused(ctx context.Context, now time.Time, selectSize int) (pkvals, heads [][]byte, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT encryption_key_hash, macaroon_head
+		FROM records
+		WHERE expires_at < ?
+		  OR invalid_at < ?
+		ORDER BY encryption_key_hash
+		LIMIT ?
+		`, now, now, selectSize)
+	if err != nil {
+		return nil, nil, Error.Wrap(err)
+	}
+
+	defer func() { err = errs.Combine(err, Error.Wrap(rows.Close())) }()
+
+	for rows.Next() {
+		var pkval, head []byte
+
+		if err = rows.Scan(&pkval, &head); err != nil {
+			return nil, nil, Error.Wrap(err)
+		}
+
+		pkvals, heads = append(pkvals, pkval), append(heads, head)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, nil, Error.Wrap(err)
+	}
+
+	return pkvals, heads, nil
+}
+
+// DeleteUnused deletes expired and invalid records from the key/value store in
+// batches as specified by the selectSize and deleteSize parameters and returns
+// any error encountered. asOfSystemInterval is ignored.
+func (d *KV) DeleteUnused(ctx context.Context, _ time.Duration, selectSize, deleteSize int) (count, rounds int64, deletesPerHead map[string]int64, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	deletesPerHead = make(map[string]int64)
+
+	// Times are stored in UTC, so they compare correctly as text.
+	now := time.Now().UTC()
+
+	for {
+		pkvals, heads, err := d.selectUnused(ctx, now, selectSize)
+		if err != nil {
+			return count, rounds, deletesPerHead, Error.Wrap(err)
+		}
+
+		if len(pkvals) == 0 {
+			return count, rounds, deletesPerHead, nil
+		}
+
+		for len(pkvals) > 0 {
+			var pkvalsBatch, headsBatch [][]byte
+
+			pkvalsBatch, pkvals = sqlauth.BatchValues(pkvals, deleteSize)
+			headsBatch, heads = sqlauth.BatchValues(heads, deleteSize)
+
+			args := make([]interface{}, len(pkvalsBatch))
+			for i, pkval := range pkvalsBatch {
+				args[i] = pkval
+			}
+
+			res, err := d.db.ExecContext(ctx, `DELETE FROM records WHERE encryption_key_hash IN (?`+
+				strings.Repeat(", ?", len(pkvalsBatch)-1)+`)`, args...)
+			if err != nil {
+				return count, rounds, deletesPerHead, Error.Wrap(err)
+			}
+
+			c, err := res.RowsAffected()
+			if err == nil {
+				count += c
+			}
+
+			rounds++
+
+			for _, h := range headsBatch {
+				deletesPerHead[string(h)]++
+			}
+		}
+	}
+}
+
+// PingDB attempts to do a database roundtrip and returns an error if it can't.
+func (d *KV) PingDB(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	return Error.Wrap(d.db.PingContext(ctx))
+}
+
+// recordColumns are the columns of records in the order scanRecords expects.
+const recordColumns = `encryption_key_hash, created_at, public, satellite_address, macaroon_head,
+	expires_at, encrypted_secret_key, encrypted_access_grant, invalid_reason, invalid_at`
+
+func scanRecords(rows tagsql.Rows) (records []*dbx.Record, err error) {
+	defer func() { err = errs.Combine(err, rows.Close()) }()
+
+	for rows.Next() {
+		var r dbx.Record
+		if err = rows.Scan(
+			&r.EncryptionKeyHash, &r.CreatedAt, &r.Public, &r.SatelliteAddress, &r.MacaroonHead,
+			&r.ExpiresAt, &r.EncryptedSecretKey, &r.EncryptedAccessGrant, &r.InvalidReason, &r.InvalidAt,
+		); err != nil {
+			return nil, err
+		}
+		records = append(records, &r)
+	}
+
+	return records, rows.Err()
+}
+
+func toRecord(r *dbx.Record) *authdb.Record {
+	return &authdb.Record{
+		SatelliteAddress:     r.SatelliteAddress,
+		MacaroonHead:         r.MacaroonHead,
+		EncryptedSecretKey:   r.EncryptedSecretKey,
+		EncryptedAccessGrant: r.EncryptedAccessGrant,
+		ExpiresAt:            r.ExpiresAt,
+		Public:               r.Public,
+	}
+}
+
+func utcOrNil(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
@@ -0,0 +1,218 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+//go:build cgo
+// +build cgo
+
+package sqliteauth_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/authdb/kvtest"
+	"storj.io/gateway-mt/pkg/auth/sqliteauth"
+)
+
+func openTest(ctx *testcontext.Context, t *testing.T) *sqliteauth.KV {
+	kv, err := sqliteauth.OpenTest(ctx, zap.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, kv.MigrateToLatest(ctx))
+	return kv
+}
+
+func newRecord(head byte, expiresAt *time.Time) *authdb.Record {
+	return &authdb.Record{
+		SatelliteAddress:     "sat.storj.test",
+		MacaroonHead:         []byte{head},
+		EncryptedSecretKey:   []byte{'s'},
+		EncryptedAccessGrant: []byte{'g'},
+		ExpiresAt:            expiresAt,
+	}
+}
+
+func TestKVFullCycle(t *testing.T) {
+	t.Parallel()
+
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	kv := openTest(ctx, t)
+	defer ctx.Check(kv.Close)
+
+	require.NoError(t, kv.PingDB(ctx), "ping")
+
+	var keyHash authdb.KeyHash
+	testrand.Read(keyHash[:])
+
+	expiresAt := time.Unix(time.Now().Add(time.Hour).Unix(), 0).UTC()
+	record := authdb.Record{
+		SatelliteAddress:     "sat.storj.test",
+		MacaroonHead:         testrand.Bytes(32),
+		EncryptedSecretKey:   testrand.Bytes(32),
+		EncryptedAccessGrant: testrand.Bytes(32),
+		ExpiresAt:            &expiresAt,
+		Public:               true,
+	}
+
+	require.NoError(t, kv.Put(ctx, keyHash, &record), "put")
+	require.ErrorIs(t, kv.Put(ctx, keyHash, &record), authdb.ErrKeyAlreadyExists, "duplicate put")
+
+	retrieved, err := kv.Get(ctx, keyHash)
+	require.NoError(t, err, "get")
+	assert.Equal(t, &record, retrieved)
+
+	var ranged int
+	require.NoError(t, kv.Range(ctx, func(_ context.Context, kh authdb.KeyHash, r *authdb.Record) error {
+		assert.Equal(t, keyHash, kh)
+		assert.Equal(t, &record, r)
+		ranged++
+		return nil
+	}))
+	assert.Equal(t, 1, ranged)
+
+	require.NoError(t, kv.Invalidate(ctx, keyHash, "go away"), "invalidate")
+	require.NoError(t, kv.Invalidate(ctx, keyHash, "now!"), "invalidate")
+
+	_, err = kv.Get(ctx, keyHash)
+	require.True(t, authdb.Invalid.Has(err), "get after invalidate")
+	assert.Contains(t, err.Error(), "go away")
+
+	require.NoError(t, kv.Delete(ctx, keyHash), "delete")
+
+	retrieved, err = kv.Get(ctx, keyHash)
+	require.NoError(t, err, "get after delete")
+	assert.Nil(t, retrieved)
+
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, kv.Put(ctx, keyHash, newRecord(0, &past)))
+
+	retrieved, err = kv.Get(ctx, keyHash)
+	require.NoError(t, err, "get expired")
+	assert.Nil(t, retrieved)
+}
+
+func TestKV_InvalidationFeed(t *testing.T) {
+	t.Parallel()
+
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	kv := openTest(ctx, t)
+	defer ctx.Check(kv.Close)
+
+	feed := authdb.NewInvalidationFeed(10)
+	kv.SetInvalidationFeed(feed)
+
+	require.NoError(t, kv.Put(ctx, authdb.KeyHash{1}, newRecord(0, nil)))
+	require.NoError(t, kv.Put(ctx, authdb.KeyHash{2}, newRecord(0, nil)))
+
+	// missing and already invalid records aren't published.
+	require.NoError(t, kv.Invalidate(ctx, authdb.KeyHash{3}, "test"))
+	require.NoError(t, kv.Invalidate(ctx, authdb.KeyHash{1}, "test"))
+	require.NoError(t, kv.Invalidate(ctx, authdb.KeyHash{1}, "again"))
+	require.NoError(t, kv.Delete(ctx, authdb.KeyHash{2}))
+	require.NoError(t, kv.Delete(ctx, authdb.KeyHash{2}))
+
+	events, next, reset := feed.Wait(ctx, feed.ID(), 0, 0)
+	require.False(t, reset)
+	require.EqualValues(t, 2, next)
+	require.Equal(t, []authdb.Invalidation{
+		{Seq: 1, KeyHash: authdb.KeyHash{1}, Reason: authdb.InvalidationInvalidated},
+		{Seq: 2, KeyHash: authdb.KeyHash{2}, Reason: authdb.InvalidationDeleted},
+	}, events)
+}
+
+func TestDeleteUnused(t *testing.T) {
+	t.Parallel()
+
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	kv := openTest(ctx, t)
+	defer ctx.Check(kv.Close)
+
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+
+	for i := 0; i < 30; i++ {
+		r := newRecord(byte(i%3), nil)
+		switch i % 3 {
+		case 0:
+			r.ExpiresAt = &past
+		case 1:
+			r.ExpiresAt = &future
+		}
+		require.NoError(t, kv.Put(ctx, authdb.KeyHash{byte(i)}, r))
+	}
+	for i := 2; i < 30; i += 6 {
+		require.NoError(t, kv.Invalidate(ctx, authdb.KeyHash{byte(i)}, "invalid"))
+	}
+
+	// Invalidation times must be in the past.
+	time.Sleep(time.Millisecond)
+
+	count, rounds, deletesPerHead, err := kv.DeleteUnused(ctx, 0, 4, 3)
+	require.NoError(t, err)
+	assert.EqualValues(t, 15, count)
+	assert.EqualValues(t, 7, rounds) // selects of 4, 4, 4 and 3 records deleted by 3
+	assert.Equal(t, map[string]int64{"\x00": 10, "\x02": 5}, deletesPerHead)
+
+	for i := 0; i < 30; i++ {
+		r, err := kv.Get(ctx, authdb.KeyHash{byte(i)})
+		require.NoError(t, err)
+		if i%3 == 0 || i%6 == 2 {
+			assert.Nil(t, r, i)
+		} else {
+			assert.NotNil(t, r, i)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	path := filepath.Join(ctx.Dir("sqlite"), "auth.db")
+
+	kv, err := sqliteauth.Open(ctx, zap.NewNop(), "sqlite://"+path)
+	require.NoError(t, err)
+	require.NoError(t, kv.MigrateToLatest(ctx))
+	require.NoError(t, kv.Put(ctx, authdb.KeyHash{1}, newRecord(1, nil)))
+	require.NoError(t, kv.Close())
+
+	kv, err = sqliteauth.Open(ctx, zap.NewNop(), "sqlite3://"+path)
+	require.NoError(t, err)
+	defer ctx.Check(kv.Close)
+
+	// Migrating again is a no-op.
+	require.NoError(t, kv.MigrateToLatest(ctx))
+
+	r, err := kv.Get(ctx, authdb.KeyHash{1})
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, []byte{1}, r.MacaroonHead)
+
+	_, err = sqliteauth.Open(ctx, zap.NewNop(), "postgres://localhost")
+	require.Error(t, err)
+}
+
+func TestKV_Conformance(t *testing.T) {
+	kvtest.Run(t, func(ctx *testcontext.Context, t *testing.T) authdb.KV {
+		return openTest(ctx, t)
+	}, kvtest.Options{
+		Invalidate: func(ctx context.Context, kv authdb.KV, keyHash authdb.KeyHash, reason string) error {
+			return kv.(*sqliteauth.KV).Invalidate(ctx, keyHash, reason)
+		},
+	})
+}
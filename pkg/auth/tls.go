@@ -11,6 +11,8 @@ import (
 
 	"github.com/zeebo/errs"
 	"golang.org/x/crypto/acme/autocert"
+
+	"storj.io/gateway-mt/pkg/certreload"
 )
 
 // TLSInfo is a struct to handle the preferred/configured TLS options.
@@ -20,9 +22,42 @@ type TLSInfo struct {
 	KeyFile     string
 	PublicURL   string
 	ConfigDir   string
+
+	// ClientCAFile, if set, makes TLS listeners verify client certificates
+	// against it.
+	ClientCAFile string
 }
 
 func configureTLS(config *TLSInfo, handler http.Handler) (*tls.Config, http.Handler, error) {
+	tlsConfig, handler, err := configureServerTLS(config, handler)
+	if err != nil || config.ClientCAFile == "" {
+		return tlsConfig, handler, err
+	}
+	if tlsConfig == nil {
+		return nil, nil, errs.New("client CA file requires TLS to be configured")
+	}
+
+	clientCAs, err := certreload.New("", "", config.ClientCAFile)
+	if err != nil {
+		return nil, nil, errs.New("unable to load client CA file: %v", err)
+	}
+
+	// client certificates are optional at the TLS level so that, e.g.,
+	// browsers can still register access; endpoints requiring them check
+	// verified chains themselves.
+	serverConfig := tlsConfig
+	tlsConfig = serverConfig.Clone()
+	tlsConfig.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := serverConfig.Clone()
+		c.ClientAuth = tls.VerifyClientCertIfGiven
+		c.ClientCAs = clientCAs.CAPool()
+		return c, nil
+	}
+
+	return tlsConfig, handler, nil
+}
+
+func configureServerTLS(config *TLSInfo, handler http.Handler) (*tls.Config, http.Handler, error) {
 	if config.LetsEncrypt {
 		return configureLetsEncrypt(config, handler)
 	}
@@ -37,14 +72,15 @@ func configureTLS(config *TLSInfo, handler http.Handler) (*tls.Config, http.Hand
 		return nil, nil, errs.New("cert file must be provided with key file")
 	}
 
-	cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	// the keypair is reloaded when the files change.
+	certs, err := certreload.New(config.CertFile, config.KeyFile, "")
 	if err != nil {
 		return nil, nil, errs.New("unable to load server keypair: %v", err)
 	}
 
 	return &tls.Config{
-		MinVersion:   tls.VersionTLS12,
-		Certificates: []tls.Certificate{cert},
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
 	}, handler, nil
 }
 
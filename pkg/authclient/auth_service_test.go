@@ -8,15 +8,20 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/zeebo/errs"
+	"golang.org/x/sync/errgroup"
 
 	"storj.io/common/lrucache"
 	"storj.io/common/testcontext"
 	"storj.io/common/testrand"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/httpauth"
 	"storj.io/gateway-mt/pkg/errdata"
 )
 
@@ -177,3 +182,309 @@ func checkRequestMeta(t *testing.T, r *http.Request, accessKeyID, token, clientI
 	assert.Equal(t, "Bearer "+token, r.Header.Get("Authorization"))
 	assert.Equal(t, "for="+clientIP, r.Header.Get("Forwarded"))
 }
+
+func TestAuthClient_ResolveWithCacheExpiry(t *testing.T) {
+	const (
+		accessKeyID = "access-key-id"
+		token       = "token"
+		clientIP    = "192.168.50.1"
+		accessGrant = "access-grant"
+	)
+
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	t.Run("stale while unreachable", func(t *testing.T) {
+		var hits, down int32
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			checkRequestMeta(t, r, accessKeyID, token, clientIP)
+			atomic.AddInt32(&hits, 1)
+
+			if atomic.LoadInt32(&down) == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			// the auth service doesn't allow caching at all.
+			w.Header().Set("Cache-Control", "private, max-age=0")
+			require.NoError(t, json.NewEncoder(w).Encode(AuthServiceResponse{AccessGrant: accessGrant}))
+		}))
+		defer ts.Close()
+
+		service := New(Config{
+			BaseURL: ts.URL,
+			Token:   token,
+			Cache:   AuthServiceCacheConfig{Expiration: time.Hour, Capacity: 10, StaleGrace: time.Hour},
+		})
+		service.BackOff.Max = 10 * time.Millisecond
+
+		for i := 0; i < 3; i++ {
+			resp, err := service.ResolveWithCache(ctx, accessKeyID, clientIP)
+			require.NoError(t, err)
+			assert.Equal(t, accessGrant, resp.AccessGrant)
+		}
+		assert.EqualValues(t, 3, atomic.LoadInt32(&hits))
+
+		atomic.StoreInt32(&down, 1)
+
+		resp, err := service.ResolveWithCache(ctx, accessKeyID, clientIP)
+		require.NoError(t, err)
+		assert.Equal(t, accessGrant, resp.AccessGrant)
+		assert.Greater(t, atomic.LoadInt32(&hits), int32(3))
+
+		// the stale response is kept for further outages.
+		resp, err = service.ResolveWithCache(ctx, accessKeyID, clientIP)
+		require.NoError(t, err)
+		assert.Equal(t, accessGrant, resp.AccessGrant)
+	})
+
+	t.Run("stale for concurrent lookups", func(t *testing.T) {
+		var hits, down int32
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			checkRequestMeta(t, r, accessKeyID, token, clientIP)
+			atomic.AddInt32(&hits, 1)
+
+			if atomic.LoadInt32(&down) == 1 {
+				// keep the lookups waiting on each other.
+				time.Sleep(50 * time.Millisecond)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Cache-Control", "private, max-age=0")
+			require.NoError(t, json.NewEncoder(w).Encode(AuthServiceResponse{AccessGrant: accessGrant}))
+		}))
+		defer ts.Close()
+
+		service := New(Config{
+			BaseURL: ts.URL,
+			Token:   token,
+			Cache:   AuthServiceCacheConfig{Expiration: time.Hour, Capacity: 10, StaleGrace: time.Hour},
+		})
+		service.BackOff.Max = 10 * time.Millisecond
+
+		_, err := service.ResolveWithCache(ctx, accessKeyID, clientIP)
+		require.NoError(t, err)
+
+		atomic.StoreInt32(&down, 1)
+
+		var group errgroup.Group
+		for i := 0; i < 10; i++ {
+			group.Go(func() error {
+				resp, err := service.ResolveWithCache(ctx, accessKeyID, clientIP)
+				if err != nil {
+					return err
+				}
+				if resp.AccessGrant != accessGrant {
+					return errs.New("unexpected access grant %q", resp.AccessGrant)
+				}
+				return nil
+			})
+		}
+		require.NoError(t, group.Wait())
+	})
+
+	t.Run("no stale for answered errors", func(t *testing.T) {
+		var state int32 // 0 up, 1 invalid, 2 down
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			checkRequestMeta(t, r, accessKeyID, token, clientIP)
+
+			switch atomic.LoadInt32(&state) {
+			case 1:
+				w.Header().Set(httpauth.InvalidReasonHeader, "revoked")
+				w.WriteHeader(http.StatusInternalServerError)
+			case 2:
+				w.WriteHeader(http.StatusInternalServerError)
+			default:
+				w.Header().Set("Cache-Control", "private, max-age=0")
+				require.NoError(t, json.NewEncoder(w).Encode(AuthServiceResponse{AccessGrant: accessGrant}))
+			}
+		}))
+		defer ts.Close()
+
+		service := New(Config{
+			BaseURL: ts.URL,
+			Token:   token,
+			Cache:   AuthServiceCacheConfig{Expiration: time.Hour, Capacity: 10, StaleGrace: time.Hour},
+		})
+		service.BackOff.Max = 10 * time.Millisecond
+
+		_, err := service.ResolveWithCache(ctx, accessKeyID, clientIP)
+		require.NoError(t, err)
+
+		// the record is invalidated after it was cached.
+		atomic.StoreInt32(&state, 1)
+
+		_, err = service.ResolveWithCache(ctx, accessKeyID, clientIP)
+		require.Error(t, err)
+		reason, ok := authdb.InvalidReason(err)
+		require.True(t, ok)
+		assert.Equal(t, "revoked", reason)
+
+		_, cached := service.Cache.GetCached(cacheKeyFor(accessKeyID))
+		assert.False(t, cached, "the invalidated response must be evicted")
+
+		// so it isn't served stale during a later outage either.
+		atomic.StoreInt32(&state, 2)
+
+		_, err = service.ResolveWithCache(ctx, accessKeyID, clientIP)
+		require.Error(t, err)
+	})
+
+	t.Run("no stale after evicting everything", func(t *testing.T) {
+		var down int32
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			checkRequestMeta(t, r, accessKeyID, token, clientIP)
+
+			if atomic.LoadInt32(&down) == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Cache-Control", "private, max-age=0")
+			require.NoError(t, json.NewEncoder(w).Encode(AuthServiceResponse{AccessGrant: accessGrant}))
+		}))
+		defer ts.Close()
+
+		service := New(Config{
+			BaseURL: ts.URL,
+			Token:   token,
+			Cache:   AuthServiceCacheConfig{Expiration: time.Hour, Capacity: 10, StaleGrace: time.Hour},
+		})
+		service.BackOff.Max = 10 * time.Millisecond
+
+		_, err := service.ResolveWithCache(ctx, accessKeyID, clientIP)
+		require.NoError(t, err)
+
+		// invalidations might have been missed, e.g., the auth service
+		// restarted, so the cached response might be invalid.
+		service.EvictAll()
+		atomic.StoreInt32(&down, 1)
+
+		_, err = service.ResolveWithCache(ctx, accessKeyID, clientIP)
+		require.Error(t, err)
+	})
+
+	t.Run("refresh", func(t *testing.T) {
+		var hits int32
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			checkRequestMeta(t, r, accessKeyID, token, clientIP)
+			atomic.AddInt32(&hits, 1)
+
+			w.Header().Set("Cache-Control", "private, max-age=60")
+			require.NoError(t, json.NewEncoder(w).Encode(AuthServiceResponse{AccessGrant: accessGrant}))
+		}))
+		defer ts.Close()
+
+		service := New(Config{
+			BaseURL: ts.URL,
+			Token:   token,
+			Cache:   AuthServiceCacheConfig{Expiration: time.Hour, Capacity: 10, RefreshBefore: 2 * time.Minute},
+		})
+
+		for i := 0; i < 2; i++ {
+			resp, err := service.ResolveWithCache(ctx, accessKeyID, clientIP)
+			require.NoError(t, err)
+			assert.Equal(t, accessGrant, resp.AccessGrant)
+		}
+
+		// the cached response was served, and refreshed in the background.
+		require.Eventually(t, func() bool {
+			return atomic.LoadInt32(&hits) == 2
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("no refresh of fetched responses", func(t *testing.T) {
+		var hits int32
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			checkRequestMeta(t, r, accessKeyID, token, clientIP)
+			atomic.AddInt32(&hits, 1)
+
+			w.Header().Set("Cache-Control", "private, max-age=60")
+			require.NoError(t, json.NewEncoder(w).Encode(AuthServiceResponse{AccessGrant: accessGrant}))
+		}))
+		defer ts.Close()
+
+		service := New(Config{
+			BaseURL: ts.URL,
+			Token:   token,
+			Cache:   AuthServiceCacheConfig{Expiration: time.Hour, Capacity: 10, RefreshBefore: 2 * time.Minute},
+		})
+
+		resp, err := service.ResolveWithCache(ctx, accessKeyID, clientIP)
+		require.NoError(t, err)
+		assert.Equal(t, accessGrant, resp.AccessGrant)
+
+		time.Sleep(100 * time.Millisecond)
+		assert.EqualValues(t, 1, atomic.LoadInt32(&hits))
+	})
+
+	t.Run("no capacity", func(t *testing.T) {
+		var hits int32
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			checkRequestMeta(t, r, accessKeyID, token, clientIP)
+			atomic.AddInt32(&hits, 1)
+
+			w.Header().Set("Cache-Control", "private, max-age=60")
+			require.NoError(t, json.NewEncoder(w).Encode(AuthServiceResponse{AccessGrant: accessGrant}))
+		}))
+		defer ts.Close()
+
+		service := New(Config{
+			BaseURL: ts.URL,
+			Token:   token,
+			Cache:   AuthServiceCacheConfig{Expiration: time.Hour, RefreshBefore: 2 * time.Minute, StaleGrace: time.Hour},
+		})
+
+		for i := 0; i < 3; i++ {
+			resp, err := service.ResolveWithCache(ctx, accessKeyID, clientIP)
+			require.NoError(t, err)
+			assert.Equal(t, accessGrant, resp.AccessGrant)
+		}
+		assert.EqualValues(t, 3, atomic.LoadInt32(&hits))
+	})
+}
+
+func TestCachedAuthServiceResponse_SetExpiration(t *testing.T) {
+	now := time.Now()
+	config := AuthServiceCacheConfig{Expiration: time.Hour, StaleGrace: 10 * time.Minute}
+
+	var resp cachedAuthServiceResponse
+	resp.setExpiration(now, config, -1, nil)
+	assert.Equal(t, now.Add(time.Hour), resp.freshUntil)
+	assert.Equal(t, now.Add(70*time.Minute), resp.staleUntil)
+
+	resp.setExpiration(now, config, time.Minute, nil)
+	assert.Equal(t, now.Add(time.Minute), resp.freshUntil)
+	assert.Equal(t, now.Add(11*time.Minute), resp.staleUntil)
+
+	// a record expiring earlier can't be served stale past its expiration.
+	expiresAt := now.Add(5 * time.Minute)
+	resp.setExpiration(now, config, -1, &expiresAt)
+	assert.Equal(t, expiresAt, resp.freshUntil)
+	assert.Equal(t, expiresAt, resp.staleUntil)
+	assert.False(t, resp.fresh(expiresAt))
+	assert.False(t, resp.usableStale(expiresAt))
+	assert.True(t, resp.refreshDue(expiresAt.Add(-time.Minute), 2*time.Minute))
+	assert.False(t, resp.refreshDue(expiresAt.Add(-3*time.Minute), 2*time.Minute))
+
+	// without any bound, responses don't expire.
+	resp = cachedAuthServiceResponse{}
+	resp.setExpiration(now, AuthServiceCacheConfig{}, -1, nil)
+	assert.True(t, resp.fresh(now.Add(1000*time.Hour)))
+}
+
+func TestParseMaxAge(t *testing.T) {
+	assert.Equal(t, time.Duration(-1), parseMaxAge(""))
+	assert.Equal(t, time.Duration(-1), parseMaxAge("private"))
+	assert.Equal(t, time.Duration(-1), parseMaxAge("max-age=oops"))
+	assert.Equal(t, time.Duration(0), parseMaxAge("private, max-age=0"))
+	assert.Equal(t, 90*time.Second, parseMaxAge("Max-Age=90, private"))
+}
@@ -5,140 +5,289 @@ package authclient
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"hash/fnv"
 	"net/http"
-	"net/url"
 	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/spacemonkeygo/monkit/v3"
 	"github.com/zeebo/errs"
 
 	"storj.io/common/lrucache"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/httpauth"
 	"storj.io/gateway-mt/pkg/errdata"
 	"storj.io/gateway-mt/pkg/middleware"
 )
 
 var mon = monkit.Package()
 
+// evictionStripes is the number of counters evictions of cache keys are
+// counted by.
+const evictionStripes = 64
+
 // AuthClient communicates with the Auth Service.
 type AuthClient struct {
 	Config
 	// Cache is used for caching authservice's responses.
 	Cache *lrucache.ExpiringLRU
+
+	// generation is incremented by EvictAll; responses cached with an older
+	// generation have expired.
+	generation uint64
+
+	// evictions counts evictions by Evict of cache keys, by stripe; responses
+	// fetched before an eviction of their key aren't cached.
+	evictMu   sync.Mutex
+	evictions [evictionStripes]uint64
+
+	// local resolves access keys in-process if set (see SetLocal).
+	local *authdb.Database
+
+	mu           sync.Mutex
+	client       *http.Client
+	endpointList []*endpoint
+	evictors     []Evictor
 }
 
 // New returns a new auth client.
 func New(config Config) *AuthClient {
+	// cached responses expire on their own, but we keep them around while
+	// they can still be served stale.
+	expiration := config.Cache.Expiration
+	if expiration > 0 {
+		expiration += config.Cache.StaleGrace
+	}
 	return &AuthClient{Config: config, Cache: lrucache.New(
-		lrucache.Options{Expiration: config.Cache.Expiration, Capacity: config.Cache.Capacity})}
+		lrucache.Options{Expiration: expiration, Capacity: config.Cache.Capacity})}
 }
 
 // Resolve maps an access key into an auth service response. clientIP is the IP
 // of the client that originated the request and it's required to be sent to the
 // Auth Service.
+//
+// Auth services are tried in order, skipping ones whose circuit breaker is
+// open, and failing over to the next one on failures. Once all of them fail,
+// Resolve backs off and starts over until the back off maxes out or Deadline
+// passes.
 func (a *AuthClient) Resolve(ctx context.Context, accessKeyID string, clientIP string) (_ AuthServiceResponse, err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	reqURL, err := url.Parse(a.BaseURL)
-	if err != nil {
-		return AuthServiceResponse{}, errdata.WithStatus(AuthServiceError.Wrap(err), http.StatusInternalServerError)
+	resp, _, err := a.resolveWithMaxAge(ctx, accessKeyID, clientIP)
+	return resp, err
+}
+
+// resolveWithMaxAge is like Resolve, but it also returns how long the response
+// may be cached for according to the auth service, or -1 if it didn't say.
+func (a *AuthClient) resolveWithMaxAge(ctx context.Context, accessKeyID string, clientIP string) (_ AuthServiceResponse, maxAge time.Duration, err error) {
+	if a.local != nil {
+		resp, err := a.resolveLocal(ctx, accessKeyID)
+		return resp, -1, err
 	}
 
-	reqURL.Path = path.Join(reqURL.Path, "/v1/access", accessKeyID)
-	req, err := http.NewRequestWithContext(ctx, "GET", reqURL.String(), nil)
+	client, err := a.httpClient()
 	if err != nil {
-		return AuthServiceResponse{}, errdata.WithStatus(AuthServiceError.Wrap(err),
+		return AuthServiceResponse{}, -1, errdata.WithStatus(AuthServiceError.Wrap(err),
 			http.StatusInternalServerError)
 	}
-	req.Header.Set("Authorization", "Bearer "+a.Token)
-	req.Header.Set("Forwarded", "for="+clientIP)
-	middleware.AddRequestIDToHeaders(req)
+	endpoints, err := a.endpoints()
+	if err != nil {
+		return AuthServiceResponse{}, -1, errdata.WithStatus(AuthServiceError.Wrap(err), http.StatusInternalServerError)
+	}
 
-	delay := a.BackOff
-	client := http.Client{
-		Timeout:   a.Timeout,
-		Transport: &http.Transport{ResponseHeaderTimeout: a.Timeout},
+	parentCtx := ctx
+	if a.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.Deadline)
+		defer cancel()
 	}
+
+	delay := a.BackOff
 	for {
-		resp, err := client.Do(req)
-		if err != nil {
-			if !delay.Maxed() {
-				if err := delay.Wait(ctx); err != nil {
-					return AuthServiceResponse{}, errdata.WithStatus(AuthServiceError.Wrap(err), errdata.HTTPStatusClientClosedRequest)
-				}
+		lastErr, tried := error(nil), false
+		for _, e := range endpoints {
+			if !e.breaker.allow() {
 				continue
 			}
-			return AuthServiceResponse{}, errdata.WithStatus(AuthServiceError.Wrap(err),
-				http.StatusInternalServerError)
-		}
+			tried = true
 
-		// Use an anonymous function for deferring the response close before the
-		// next retry and not pilling it up when the method returns.
-		retry, authResp, err := func() (retry bool, _ AuthServiceResponse, _ error) {
-			defer func() { _ = resp.Body.Close() }()
-
-			if resp.StatusCode == http.StatusInternalServerError {
-				return true, AuthServiceResponse{}, nil // auth only returns this for unexpected issues
+			failed, authResp, maxAge, err := a.resolve(ctx, client, e, accessKeyID, clientIP)
+			if ctx.Err() != nil {
+				e.breaker.abort()
+				lastErr = err
+				break
 			}
-
-			if resp.StatusCode != http.StatusOK {
-				return false, AuthServiceResponse{}, errdata.WithStatus(
-					AuthServiceError.New("invalid status code: %d", resp.StatusCode),
-					resp.StatusCode)
+			e.breaker.finish(failed)
+			if !failed {
+				return authResp, maxAge, err
 			}
+			lastErr = err
+		}
 
-			var authResp AuthServiceResponse
-			if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
-				if !delay.Maxed() {
-					return true, AuthServiceResponse{}, nil
-				}
-				return false, AuthServiceResponse{}, errdata.WithStatus(AuthServiceError.Wrap(err),
+		if !tried {
+			// all circuit breakers are open, so we fail fast instead of
+			// blocking the request.
+			return AuthServiceResponse{}, -1, errdata.WithStatus(unavailable(AuthServiceError.New("no auth service available")),
+				http.StatusInternalServerError)
+		}
+		if ctx.Err() == nil && delay.Maxed() {
+			return AuthServiceResponse{}, -1, errdata.WithStatus(unavailable(AuthServiceError.Wrap(lastErr)),
+				http.StatusInternalServerError)
+		}
+		if err := delay.Wait(ctx); err != nil {
+			if parentCtx.Err() == nil {
+				// it's our deadline that passed, not the client that left.
+				return AuthServiceResponse{}, -1, errdata.WithStatus(unavailable(AuthServiceError.New("deadline exceeded: %v", lastErr)),
 					http.StatusInternalServerError)
 			}
+			return AuthServiceResponse{}, -1, errdata.WithStatus(AuthServiceError.Wrap(err), errdata.HTTPStatusClientClosedRequest)
+		}
+	}
+}
 
-			return false, authResp, nil
-		}()
+// unavailableKey annotates errors of auth services failing to answer (e.g.,
+// being unreachable or timing out), as opposed to answering with an error.
+type unavailableKey struct{}
 
-		if retry {
-			if err := delay.Wait(ctx); err != nil {
-				return AuthServiceResponse{}, errdata.WithStatus(AuthServiceError.Wrap(err), errdata.HTTPStatusClientClosedRequest)
-			}
-			continue
+func unavailable(err error) error {
+	return errdata.Annotate(err, unavailableKey{}, true)
+}
+
+// isUnavailable returns whether err is because auth services failed to answer.
+func isUnavailable(err error) bool {
+	v, _ := errdata.Value(err, unavailableKey{}).(bool)
+	return v
+}
+
+// resolve sends a single request resolving accessKeyID to e. It reports
+// whether the auth service failed, so it should be retried elsewhere, as
+// opposed to responding, even with an error like 401 or a 500 for an invalid
+// record.
+func (a *AuthClient) resolve(ctx context.Context, client *http.Client, e *endpoint, accessKeyID string, clientIP string) (failed bool, _ AuthServiceResponse, maxAge time.Duration, _ error) {
+	reqURL := *e.baseURL
+	reqURL.Path = path.Join(reqURL.Path, "/v1/access", accessKeyID)
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL.String(), nil)
+	if err != nil {
+		return false, AuthServiceResponse{}, -1, errdata.WithStatus(AuthServiceError.Wrap(err),
+			http.StatusInternalServerError)
+	}
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	req.Header.Set("Forwarded", "for="+clientIP)
+	middleware.AddRequestIDToHeaders(req)
+
+	resp, err := do(client, req)
+	if err != nil {
+		return true, AuthServiceResponse{}, -1, err
+	}
+	defer func() { _ = closeBody(resp.Body) }()
+
+	if resp.StatusCode == http.StatusInternalServerError {
+		if reasons := resp.Header.Values(httpauth.InvalidReasonHeader); len(reasons) > 0 {
+			// the record is invalid, which is an answer as definitive as
+			// 401, so it's neither retried nor counted as a failure.
+			return false, AuthServiceResponse{}, -1, errdata.WithStatus(
+				AuthServiceError.Wrap(authdb.Invalid.New("%s", reasons[0])), resp.StatusCode)
 		}
+		// auth only returns this otherwise for unexpected issues
+		return true, AuthServiceResponse{}, -1, errs.New("invalid status code: %d", resp.StatusCode)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return false, AuthServiceResponse{}, -1, errdata.WithStatus(
+			AuthServiceError.New("invalid status code: %d", resp.StatusCode),
+			resp.StatusCode)
+	}
+
+	var authResp AuthServiceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
+		return true, AuthServiceResponse{}, -1, err
+	}
 
-		return authResp, err
+	return false, authResp, parseMaxAge(resp.Header.Get("Cache-Control")), nil
+}
+
+// parseMaxAge returns max-age of a Cache-Control header or -1 if it's missing.
+func parseMaxAge(cacheControl string) time.Duration {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if !strings.EqualFold(name, "max-age") {
+			continue
+		}
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || seconds < 0 {
+			return -1
+		}
+		return time.Duration(seconds) * time.Second
 	}
+	return -1
 }
 
 // ResolveWithCache is like Resolve, but it uses the underlying LRU cache to
 // cache and returns cached authservice's successful responses if caching is
-// enabled.
+// enabled and access keys aren't resolved locally.
+//
+// Responses are cached for Cache.Expiration at most, and no longer than the
+// auth service allows or the record expires. Expired responses are still
+// served for Cache.StaleGrace if the auth service is unreachable, and cached
+// responses requested less than Cache.RefreshBefore before they expire are
+// refreshed in the background. Cached responses are evicted once the auth
+// service answers with an error instead, e.g., because the record has been
+// invalidated.
+//
+// Responses are cached under the key hash of accessKeyID, so that they can be
+// evicted without knowing accessKeyID (see Evict).
 func (a *AuthClient) ResolveWithCache(ctx context.Context, accessKeyID string, clientIP string) (_ AuthServiceResponse, err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	if a.Cache == nil {
+	if a.Cache == nil || a.local != nil {
 		return a.Resolve(ctx, accessKeyID, clientIP)
 	}
 
-	v, err := a.Cache.Get(accessKeyID, func() (interface{}, error) {
-		response, err := a.Resolve(ctx, accessKeyID, clientIP)
+	cacheKey := cacheKeyFor(accessKeyID)
 
-		switch errdata.GetStatus(err, http.StatusOK) {
-		case http.StatusOK, http.StatusNotFound:
-			encResp, encErr := encryptResponse(accessKeyID, response, err)
-			if encErr != nil {
-				return cachedAuthServiceResponse{err: err}, encErr
-			}
-			return encResp, nil
-		default:
-			return cachedAuthServiceResponse{}, err // err is already wrapped
+	// Get would fill again values that were added (e.g., renewed), so we
+	// look up cached values first. A nil value is still being filled.
+	v, cached := a.Cache.GetCached(cacheKey)
+	if !cached || v == nil {
+		if v, err = a.Cache.Get(cacheKey, func() (interface{}, error) {
+			return a.fetch(ctx, cacheKey, accessKeyID, clientIP)
+		}); err != nil {
+			return AuthServiceResponse{}, err // err is already wrapped
 		}
-	})
-	if err != nil {
-		return AuthServiceResponse{}, err // err is already wrapped
 	}
 
-	response := v.(cachedAuthServiceResponse)
+	response := v.(*cachedAuthServiceResponse)
+
+	// only cached responses are renewed or refreshed, which also keeps us from
+	// adding to a cache without capacity.
+	if cached {
+		now := time.Now()
+		if !response.fresh(now) || response.generation != a.cacheGeneration() {
+			// the expired response stays cached while it's renewed, so that
+			// concurrent lookups share a single fetch and can all fall back
+			// to it if the auth service is unreachable.
+			renewed, err := a.renew(ctx, cacheKey, accessKeyID, clientIP, response)
+			if err != nil {
+				// evicted responses might have been invalidated, so they're
+				// never served stale.
+				if !response.usableStale(now) || !isUnavailable(err) ||
+					response.generation != a.cacheGeneration() || a.evictedSince(cacheKey, response.evictions) {
+					return AuthServiceResponse{}, err // err is already wrapped
+				}
+				mon.Event("authclient_cache_stale_served")
+			} else {
+				response = renewed
+			}
+		} else if response.refreshDue(now, a.Config.Cache.RefreshBefore) {
+			a.refresh(cacheKey, accessKeyID, clientIP, response)
+		}
+	}
 
 	decResp, err := response.decrypt(accessKeyID)
 	if err != nil {
@@ -148,34 +297,202 @@ func (a *AuthClient) ResolveWithCache(ctx context.Context, accessKeyID string, c
 	return decResp, response.err
 }
 
-// GetHealthLive returns the auth service health live status.
+// cacheKeyFor returns the key the response for accessKeyID is cached under,
+// which is its key hash.
+func cacheKeyFor(accessKeyID string) string {
+	var key authdb.EncryptionKey
+	if err := key.FromBase32(accessKeyID); err != nil {
+		// the auth service rejects it, but we cache that too. we keep the
+		// key space apart from key hashes.
+		sum := sha256.Sum256([]byte(accessKeyID))
+		return "invalid:" + hex.EncodeToString(sum[:])
+	}
+	return key.Hash().ToHex()
+}
+
+// fetch resolves accessKeyID into a response to cache. Only successful and
+// not found responses are cached, other errors are returned.
+func (a *AuthClient) fetch(ctx context.Context, cacheKey, accessKeyID string, clientIP string) (*cachedAuthServiceResponse, error) {
+	// responses resolved while everything or cacheKey is evicted are evicted
+	// too.
+	generation, evictions := a.cacheGeneration(), a.evictionCount(cacheKey)
+
+	response, maxAge, err := a.resolveWithMaxAge(ctx, accessKeyID, clientIP)
+
+	switch errdata.GetStatus(err, http.StatusOK) {
+	case http.StatusOK, http.StatusNotFound:
+		encResp, encErr := encryptResponse(accessKeyID, response, err)
+		if encErr != nil {
+			return nil, encErr
+		}
+		encResp.setExpiration(time.Now(), a.Config.Cache, maxAge, response.ExpiresAt)
+		encResp.generation = generation
+		encResp.evictions = evictions
+		return encResp, nil
+	default:
+		return nil, err // err is already wrapped
+	}
+}
+
+// renew fetches a response replacing cached and caches it. Concurrent calls
+// share a single fetch.
+func (a *AuthClient) renew(ctx context.Context, cacheKey, accessKeyID string, clientIP string, cached *cachedAuthServiceResponse) (*cachedAuthServiceResponse, error) {
+	for {
+		r, first := cached.startRenewal()
+		if first {
+			a.doRenewal(ctx, r, cacheKey, accessKeyID, clientIP, cached)
+			return r.response, r.err
+		}
+
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			return nil, errdata.WithStatus(AuthServiceError.Wrap(ctx.Err()), errdata.HTTPStatusClientClosedRequest)
+		}
+
+		if errdata.GetStatus(r.err, http.StatusOK) == errdata.HTTPStatusClientClosedRequest {
+			continue // the request that fetched it went away, so we try again.
+		}
+		return r.response, r.err
+	}
+}
+
+// refresh replaces cached with a freshly resolved response in the background
+// unless it's already being renewed.
+func (a *AuthClient) refresh(cacheKey, accessKeyID string, clientIP string, cached *cachedAuthServiceResponse) {
+	r, first := cached.startRenewal()
+	if !first {
+		return
+	}
+
+	timeout := a.Deadline
+	if timeout <= 0 {
+		timeout = a.Timeout
+	}
+
+	go func() {
+		ctx := context.Background()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		a.doRenewal(ctx, r, cacheKey, accessKeyID, clientIP, cached)
+		if r.err != nil {
+			mon.Event("authclient_cache_refresh_failed")
+		}
+	}()
+}
+
+// doRenewal fetches the response of r, caches it unless cacheKey has been
+// evicted since and finishes r. If the auth service answers with an error,
+// cached is evicted.
+func (a *AuthClient) doRenewal(ctx context.Context, r *renewal, cacheKey, accessKeyID string, clientIP string, cached *cachedAuthServiceResponse) {
+	r.response, r.err = a.fetch(ctx, cacheKey, accessKeyID, clientIP)
+	if r.err != nil && !isUnavailable(r.err) && errdata.GetStatus(r.err, http.StatusOK) != errdata.HTTPStatusClientClosedRequest {
+		a.evict(cacheKey)
+		mon.Event("authclient_cache_answered_evicted")
+	}
+	cached.finishRenewal(r, r.err == nil && a.add(cacheKey, r.response))
+}
+
+// add caches response under cacheKey unless cacheKey has been evicted since
+// response was fetched.
+func (a *AuthClient) add(cacheKey string, response *cachedAuthServiceResponse) bool {
+	a.evictMu.Lock()
+	defer a.evictMu.Unlock()
+
+	if a.evictions[evictionStripe(cacheKey)] != response.evictions {
+		mon.Event("authclient_cache_evicted_add_skipped")
+		return false
+	}
+	a.Cache.Add(cacheKey, response)
+	return true
+}
+
+// evictionCount returns the count of evictions of cacheKey's stripe.
+func (a *AuthClient) evictionCount(cacheKey string) uint64 {
+	a.evictMu.Lock()
+	defer a.evictMu.Unlock()
+
+	return a.evictions[evictionStripe(cacheKey)]
+}
+
+// evictedSince returns whether cacheKey might have been evicted since its
+// stripe's eviction count was count.
+func (a *AuthClient) evictedSince(cacheKey string, count uint64) bool {
+	return a.evictionCount(cacheKey) != count
+}
+
+// evictionStripe returns the stripe of evictions cacheKey is counted by.
+func evictionStripe(cacheKey string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(cacheKey))
+	return int(h.Sum32() % evictionStripes)
+}
+
+// GetHealthLive returns the auth service health live status. It's healthy if
+// any of the auth services is or, if it resolves access keys locally, the
+// local database is reachable.
 func (a *AuthClient) GetHealthLive(ctx context.Context) (_ bool, err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	baseURL, err := url.Parse(a.BaseURL)
+	if a.local != nil {
+		if err := a.local.PingDB(ctx); err != nil {
+			return false, AuthServiceError.Wrap(err)
+		}
+		return true, nil
+	}
+
+	client, err := a.httpClient()
 	if err != nil {
-		return false, errdata.WithStatus(AuthServiceError.Wrap(err), http.StatusBadRequest)
+		return false, AuthServiceError.Wrap(err)
 	}
-	healthLiveURL, err := baseURL.Parse("/v1/health/live")
+	endpoints, err := a.endpoints()
 	if err != nil {
 		return false, errdata.WithStatus(AuthServiceError.Wrap(err), http.StatusBadRequest)
 	}
+
+	var group errs.Group
+	for _, e := range endpoints {
+		if !e.breaker.allow() {
+			continue
+		}
+		err := a.getHealthLive(ctx, client, e)
+		if ctx.Err() != nil {
+			e.breaker.abort()
+			return false, AuthServiceError.Wrap(ctx.Err())
+		}
+		e.breaker.finish(err != nil)
+		if err == nil {
+			return true, nil
+		}
+		group.Add(err)
+	}
+	if err := group.Err(); err != nil {
+		return false, err
+	}
+	return false, AuthServiceError.New("no auth service available")
+}
+
+func (a *AuthClient) getHealthLive(ctx context.Context, client *http.Client, e *endpoint) (err error) {
+	healthLiveURL, err := e.baseURL.Parse("/v1/health/live")
+	if err != nil {
+		return errdata.WithStatus(AuthServiceError.Wrap(err), http.StatusBadRequest)
+	}
 	req, err := http.NewRequestWithContext(ctx, "GET", healthLiveURL.String(), nil)
 	if err != nil {
-		return false, AuthServiceError.Wrap(err)
+		return AuthServiceError.Wrap(err)
 	}
 	req.Header.Set("Authorization", "Bearer "+a.Token)
-	client := http.Client{
-		Timeout:   a.Timeout,
-		Transport: &http.Transport{ResponseHeaderTimeout: a.Timeout},
-	}
-	res, err := client.Do(req)
+	res, err := do(client, req)
 	if err != nil {
-		return false, AuthServiceError.Wrap(err)
+		return AuthServiceError.Wrap(err)
 	}
-	defer func() { err = errs.Combine(err, AuthServiceError.Wrap(res.Body.Close())) }()
+	defer func() { err = errs.Combine(err, AuthServiceError.Wrap(closeBody(res.Body))) }()
 	if res.StatusCode != http.StatusOK {
-		return false, AuthServiceError.New("unexpected response code %d %s", res.StatusCode, res.Status)
+		return AuthServiceError.New("unexpected response code %d %s", res.StatusCode, res.Status)
 	}
-	return true, nil
+	return nil
 }
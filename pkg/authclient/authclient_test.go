@@ -5,9 +5,21 @@ package authclient
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -106,3 +118,156 @@ func TestLoadUserNotFound(t *testing.T) {
 func GetTestAuthClient(t *testing.T, baseURL, token string, timeout time.Duration) (*AuthClient, error) {
 	return New(Config{BaseURL: baseURL, Token: token, Timeout: timeout}), nil
 }
+
+func TestLoadUserClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "ca"},
+		SerialNumber:          big.NewInt(1),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0600))
+
+	issue := func(name string, usage x509.ExtKeyUsage) (certFile, keyFile string) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			Subject:      pkix.Name{CommonName: name},
+			SerialNumber: big.NewInt(2),
+			NotAfter:     time.Now().Add(time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caTemplate, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+		require.NoError(t, err)
+
+		certFile, keyFile = filepath.Join(dir, name+"-cert.pem"), filepath.Join(dir, name+"-key.pem")
+		require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+		require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600))
+		return certFile, keyFile
+	}
+
+	serverCert, serverKey := issue("server", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := issue("gateway", x509.ExtKeyUsageClientAuth)
+
+	pool := x509.NewCertPool()
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+	pool.AddCert(caCert)
+	serverKeyPair, err := tls.LoadX509KeyPair(serverCert, serverKey)
+	require.NoError(t, err)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "gateway", r.TLS.PeerCertificates[0].Subject.CommonName)
+		_, err := w.Write([]byte(`{"public":true, "secret_key":"mysecretkey", "access_grant":"myaccessgrant"}`))
+		require.NoError(t, err)
+	}))
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverKeyPair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	config := Config{BaseURL: ts.URL, Token: "token", Timeout: 2 * time.Second, CertFile: clientCert, KeyFile: clientKey, CAFile: caFile}
+	require.NoError(t, config.Validate())
+
+	access, err := New(config).Resolve(context.Background(), "fakeUser", "127.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, "myaccessgrant", access.AccessGrant)
+
+	// without a client certificate the handshake fails.
+	config.CertFile, config.KeyFile = "", ""
+	client := New(config)
+	client.BackOff.Max = 100 * time.Millisecond
+	_, err = client.Resolve(context.Background(), "fakeUser", "127.0.0.1")
+	require.Error(t, err)
+
+	config.CertFile = clientCert
+	require.Error(t, config.Validate())
+}
+
+func TestLoadUserReusesConnections(t *testing.T) {
+	var newConns int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"public":true, "secret_key":"mysecretkey", "access_grant":"myaccessgrant"}`))
+		require.NoError(t, err)
+	}))
+	ts.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&newConns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	client, err := GetTestAuthClient(t, ts.URL, "token", 2*time.Second)
+	require.NoError(t, err)
+	defer client.CloseIdleConnections()
+
+	for i := 0; i < 10; i++ {
+		_, err = client.Resolve(context.Background(), "fakeUser", "127.0.0.1")
+		require.NoError(t, err)
+		_, err = client.GetHealthLive(context.Background())
+		require.NoError(t, err)
+	}
+
+	require.EqualValues(t, 1, atomic.LoadInt32(&newConns))
+}
+
+func BenchmarkResolve(b *testing.B) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"public":true, "secret_key":"mysecretkey", "access_grant":"myaccessgrant"}`))
+	}))
+	defer ts.Close()
+
+	caFile := filepath.Join(b.TempDir(), "ca.pem")
+	require.NoError(b, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0600))
+
+	config := Config{
+		BaseURL:   ts.URL,
+		Token:     "token",
+		Timeout:   5 * time.Second,
+		CAFile:    caFile,
+		Transport: TransportConfig{MaxIdleConns: 100, IdleConnTimeout: time.Minute, KeepAlive: 30 * time.Second, HTTP2: true},
+	}
+
+	// new client per resolve is how every resolve behaved before the
+	// transport became long-lived.
+	b.Run("new client", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				client := New(config)
+				if _, err := client.Resolve(context.Background(), "fakeUser", "127.0.0.1"); err != nil {
+					b.Error(err)
+				}
+				client.CloseIdleConnections()
+			}
+		})
+	})
+
+	b.Run("shared client", func(b *testing.B) {
+		client := New(config)
+		defer client.CloseIdleConnections()
+
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := client.Resolve(context.Background(), "fakeUser", "127.0.0.1"); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+}
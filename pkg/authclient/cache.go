@@ -4,6 +4,9 @@
 package authclient
 
 import (
+	"sync"
+	"time"
+
 	"github.com/zeebo/errs"
 
 	"storj.io/common/encryption"
@@ -19,29 +22,84 @@ type cachedAuthServiceResponse struct {
 	accessGrant []byte
 	secretKey   []byte
 	public      bool
+	expiresAt   *time.Time
 	err         error
+
+	// freshUntil is when the response expires and staleUntil is until when
+	// it can still be served if the auth service is unreachable. Zero means
+	// never.
+	freshUntil time.Time
+	staleUntil time.Time
+
+	// generation is the client's cache generation when the response was
+	// fetched.
+	generation uint64
+	// evictions is the client's count of evictions of the response's cache
+	// key stripe when the response was fetched.
+	evictions uint64
+
+	// mu protects renewal, the fetch of a response replacing this one. It's
+	// kept once it succeeds, so that callers still holding this response use
+	// the new one.
+	mu      sync.Mutex
+	renewal *renewal
+}
+
+// renewal is a fetch of a response replacing an expiring or expired one,
+// shared by concurrent callers.
+type renewal struct {
+	done     chan struct{}
+	response *cachedAuthServiceResponse
+	err      error
+}
+
+// startRenewal returns the renewal of resp in progress (or succeeded), or
+// starts a new one, in which case first is true and the caller must fetch the
+// response and call finishRenewal.
+func (resp *cachedAuthServiceResponse) startRenewal() (r *renewal, first bool) {
+	resp.mu.Lock()
+	defer resp.mu.Unlock()
+
+	if resp.renewal != nil {
+		return resp.renewal, false
+	}
+	resp.renewal = &renewal{done: make(chan struct{})}
+	return resp.renewal, true
+}
+
+// finishRenewal completes r. Unless keep is true, the next startRenewal
+// starts a new renewal.
+func (resp *cachedAuthServiceResponse) finishRenewal(r *renewal, keep bool) {
+	resp.mu.Lock()
+	defer resp.mu.Unlock()
+
+	if !keep {
+		resp.renewal = nil
+	}
+	close(r.done)
 }
 
-func encryptResponse(accessKeyID string, resp AuthServiceResponse, respErr error) (cachedAuthServiceResponse, error) {
+func encryptResponse(accessKeyID string, resp AuthServiceResponse, respErr error) (*cachedAuthServiceResponse, error) {
 	key, err := storj.NewKey([]byte(accessKeyID))
 	if err != nil {
-		return cachedAuthServiceResponse{}, cacheEncryptError.Wrap(err)
+		return nil, cacheEncryptError.Wrap(err)
 	}
 
 	// note: we always use the same nonce here - all zero's for secret keys, one then all zero's for access grants
 	secretKey, err := encryption.Encrypt([]byte(resp.SecretKey), storj.EncAESGCM, key, &storj.Nonce{})
 	if err != nil {
-		return cachedAuthServiceResponse{}, cacheEncryptError.Wrap(err)
+		return nil, cacheEncryptError.Wrap(err)
 	}
 	accessGrant, err := encryption.Encrypt([]byte(resp.AccessGrant), storj.EncAESGCM, key, &storj.Nonce{1})
 	if err != nil {
-		return cachedAuthServiceResponse{}, cacheEncryptError.Wrap(err)
+		return nil, cacheEncryptError.Wrap(err)
 	}
 
-	return cachedAuthServiceResponse{
+	return &cachedAuthServiceResponse{
 		accessGrant: accessGrant,
 		secretKey:   secretKey,
 		public:      resp.Public,
+		expiresAt:   resp.ExpiresAt,
 		err:         respErr,
 	}, nil
 }
@@ -66,5 +124,42 @@ func (resp *cachedAuthServiceResponse) decrypt(accessKeyID string) (AuthServiceR
 		AccessGrant: string(accessGrant),
 		SecretKey:   string(secretKey),
 		Public:      resp.public,
+		ExpiresAt:   resp.expiresAt,
 	}, nil
 }
+
+// setExpiration sets until when the response, fetched at now, is fresh and
+// can be served stale. It's fresh for config.Expiration, capped by maxAge (if
+// not negative) and the record's expiration. It can't be served stale past
+// the record's expiration.
+func (resp *cachedAuthServiceResponse) setExpiration(now time.Time, config AuthServiceCacheConfig, maxAge time.Duration, expiresAt *time.Time) {
+	ttl, bounded := config.Expiration, config.Expiration > 0
+	if maxAge >= 0 && (!bounded || maxAge < ttl) {
+		ttl, bounded = maxAge, true
+	}
+	if expiresAt != nil && (!bounded || expiresAt.Sub(now) < ttl) {
+		ttl, bounded = expiresAt.Sub(now), true
+	}
+	if !bounded {
+		return
+	}
+
+	resp.freshUntil = now.Add(ttl)
+	resp.staleUntil = resp.freshUntil.Add(config.StaleGrace)
+	if expiresAt != nil && expiresAt.Before(resp.staleUntil) {
+		resp.staleUntil = *expiresAt
+	}
+}
+
+func (resp *cachedAuthServiceResponse) fresh(now time.Time) bool {
+	return resp.freshUntil.IsZero() || now.Before(resp.freshUntil)
+}
+
+func (resp *cachedAuthServiceResponse) usableStale(now time.Time) bool {
+	return resp.staleUntil.IsZero() || now.Before(resp.staleUntil)
+}
+
+// refreshDue returns whether the response expires within refreshBefore.
+func (resp *cachedAuthServiceResponse) refreshDue(now time.Time, refreshBefore time.Duration) bool {
+	return refreshBefore > 0 && !resp.freshUntil.IsZero() && !now.Before(resp.freshUntil.Add(-refreshBefore))
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/zeebo/errs"
 
 	"storj.io/gateway-mt/pkg/backoff"
+	"storj.io/gateway-mt/pkg/certreload"
 	"storj.io/gateway-mt/pkg/errdata"
 )
 
@@ -22,8 +23,20 @@ type Config struct {
 	BaseURL string        `user:"true" help:"base url to use for resolving access key ids" releaseDefault:"" devDefault:"http://localhost:20000"`
 	Token   string        `user:"true" help:"auth token for giving access to the auth service" releaseDefault:"" devDefault:"super-secret"`
 	Timeout time.Duration `user:"true" help:"how long to wait for a single auth service connection" default:"10s"`
-	BackOff backoff.ExponentialBackoff
-	Cache   AuthServiceCacheConfig
+	// FailoverURLs are tried in order if BaseURL (or the previous one) is
+	// unavailable, e.g., auth services in other regions.
+	FailoverURLs []string      `user:"true" help:"comma delimited list of base urls to fail over to, in order, if the base url is unavailable" default:""`
+	Deadline     time.Duration `user:"true" help:"how long to try resolving an access key id across all auth services and retries before giving up (0 means until the back off maxes out)" default:"30s"`
+	// CertFile and KeyFile are the client certificate presented to the auth
+	// service (mutual TLS). They are reloaded when the files change.
+	CertFile      string `user:"true" help:"client certificate file to authenticate to the auth service with" default:""`
+	KeyFile       string `user:"true" help:"client key file to authenticate to the auth service with" default:""`
+	CAFile        string `user:"true" help:"CA bundle to verify the auth service certificate against instead of system roots" default:""`
+	BackOff       backoff.ExponentialBackoff
+	Cache         AuthServiceCacheConfig
+	Transport     TransportConfig
+	Breaker       BreakerConfig
+	Invalidations InvalidationsConfig
 }
 
 // Validate checks if the configuration value are valid.
@@ -31,15 +44,32 @@ func (a Config) Validate() error {
 	if a.Token == "" {
 		return AuthServiceError.New("token parameter is missing")
 	}
-	reqURL, err := url.Parse(a.BaseURL)
-	if err != nil {
-		return errdata.WithStatus(AuthServiceError.Wrap(err), http.StatusInternalServerError)
+	var scheme string
+	for _, rawURL := range append([]string{a.BaseURL}, a.FailoverURLs...) {
+		reqURL, err := url.Parse(rawURL)
+		if err != nil {
+			return errdata.WithStatus(AuthServiceError.Wrap(err), http.StatusInternalServerError)
+		}
+		if reqURL.Scheme != "http" && reqURL.Scheme != "https" {
+			return AuthServiceError.New("unexpected scheme found in endpoint parameter %s", reqURL.Scheme)
+		}
+		if reqURL.Host == "" {
+			return AuthServiceError.New("host missing in parameter %s", reqURL.Host)
+		}
+		if scheme == "" || reqURL.Scheme == "http" {
+			scheme = reqURL.Scheme
+		}
 	}
-	if reqURL.Scheme != "http" && reqURL.Scheme != "https" {
-		return AuthServiceError.New("unexpected scheme found in endpoint parameter %s", reqURL.Scheme)
+	if a.Invalidations.Follow && a.Timeout > 0 && a.Invalidations.Wait >= a.Timeout {
+		return AuthServiceError.New("invalidations wait parameter must be shorter than the timeout")
 	}
-	if reqURL.Host == "" {
-		return AuthServiceError.New("host missing in parameter %s", reqURL.Host)
+	if a.CertFile != "" || a.KeyFile != "" || a.CAFile != "" {
+		if scheme != "https" {
+			return AuthServiceError.New("client certificate and CA parameters require https base urls")
+		}
+		if _, err := certreload.New(a.CertFile, a.KeyFile, a.CAFile); err != nil {
+			return AuthServiceError.Wrap(err)
+		}
 	}
 	return nil
 }
@@ -48,6 +78,10 @@ func (a Config) Validate() error {
 type AuthServiceCacheConfig struct {
 	Expiration time.Duration `user:"true" help:"how long to keep cached access grants in cache" default:"24h"`
 	Capacity   int           `user:"true" help:"how many cached access grants to keep in cache" default:"10000"`
+	// StaleGrace and RefreshBefore apply to access grants cached for less
+	// than Expiration too, e.g., because they expire earlier.
+	StaleGrace    time.Duration `user:"true" help:"how long to keep serving expired cached access grants if the auth service is unreachable" default:"1h"`
+	RefreshBefore time.Duration `user:"true" help:"how long before cached access grants expire to refresh them in the background when requested (0 disables)" default:"5m"`
 }
 
 // AuthServiceResponse is the struct representing the response from the auth service.
@@ -55,4 +89,6 @@ type AuthServiceResponse struct {
 	AccessGrant string `json:"access_grant"`
 	SecretKey   string `json:"secret_key"`
 	Public      bool   `json:"public"`
+	// ExpiresAt is when the access grant's record expires if it does.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
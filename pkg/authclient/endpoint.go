@@ -0,0 +1,113 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package authclient
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/spacemonkeygo/monkit/v3"
+)
+
+// BreakerConfig describes configuration of circuit breakers of auth services.
+type BreakerConfig struct {
+	Failures int           `user:"true" help:"how many consecutive failures open the circuit breaker of an auth service (0 disables circuit breaking)" default:"5"`
+	Cooldown time.Duration `user:"true" help:"how long an open circuit breaker skips its auth service before letting a single probe request through" default:"30s"`
+}
+
+// endpoint is an auth service Resolve can send requests to.
+type endpoint struct {
+	baseURL *url.URL
+	breaker *breaker
+}
+
+// endpoints returns the auth services in the order they should be tried,
+// parsing them on first use.
+func (a *AuthClient) endpoints() ([]*endpoint, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.endpointList != nil {
+		return a.endpointList, nil
+	}
+
+	for _, rawURL := range append([]string{a.BaseURL}, a.FailoverURLs...) {
+		baseURL, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, err
+		}
+		a.endpointList = append(a.endpointList, &endpoint{
+			baseURL: baseURL,
+			breaker: &breaker{
+				name:      baseURL.Host,
+				threshold: a.Breaker.Failures,
+				cooldown:  a.Breaker.Cooldown,
+				now:       time.Now,
+			},
+		})
+	}
+
+	return a.endpointList, nil
+}
+
+// breaker is a circuit breaker. It opens after threshold consecutive failures
+// and then rejects requests until cooldown passes. After that, it lets a
+// single probe request through (half-open) and closes again if it succeeds.
+type breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// allow returns whether a request can be sent. Every allowed request must be
+// followed by finish or abort.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return true
+	}
+	if b.probing || b.now().Before(b.openUntil) {
+		return false
+	}
+
+	b.probing = true
+	return true
+}
+
+// finish records the outcome of an allowed request.
+func (b *breaker) finish(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+		mon.Event("authclient_breaker_open", monkit.NewSeriesTag("endpoint", b.name))
+	}
+}
+
+// abort releases an allowed request without an outcome, e.g., because it was
+// canceled.
+func (b *breaker) abort() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
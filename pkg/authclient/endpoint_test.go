@@ -0,0 +1,148 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package authclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/httpauth"
+	"storj.io/gateway-mt/pkg/errdata"
+)
+
+func TestResolveFailover(t *testing.T) {
+	var downHits int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downHits, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer down.Close()
+
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{"public":true, "secret_key":"mysecretkey", "access_grant":"myaccessgrant"}`))
+		require.NoError(t, err)
+	}))
+	defer up.Close()
+
+	client := New(Config{
+		BaseURL:      down.URL,
+		FailoverURLs: []string{up.URL},
+		Token:        "token",
+		Timeout:      2 * time.Second,
+		Breaker:      BreakerConfig{Failures: 2, Cooldown: time.Hour},
+	})
+
+	for i := 0; i < 5; i++ {
+		access, err := client.Resolve(context.Background(), "fakeUser", "127.0.0.1")
+		require.NoError(t, err)
+		require.Equal(t, "myaccessgrant", access.AccessGrant)
+	}
+
+	// the breaker opened after two failures, so the next resolves skipped it.
+	require.EqualValues(t, 2, atomic.LoadInt32(&downHits))
+
+	// with all breakers open, resolves fail fast.
+	client = New(Config{
+		BaseURL: down.URL,
+		Token:   "token",
+		Timeout: 2 * time.Second,
+		Breaker: BreakerConfig{Failures: 1, Cooldown: time.Hour},
+	})
+	client.BackOff.Max = time.Minute
+
+	_, err := client.Resolve(context.Background(), "fakeUser", "127.0.0.1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no auth service available")
+}
+
+func TestResolveInvalidRecord(t *testing.T) {
+	var hits int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set(httpauth.InvalidReasonHeader, "revoked")
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	client := New(Config{
+		BaseURL: ts.URL,
+		Token:   "token",
+		Timeout: 2 * time.Second,
+		Breaker: BreakerConfig{Failures: 2, Cooldown: time.Hour},
+	})
+	client.BackOff.Max = time.Minute
+
+	for i := 0; i < 5; i++ {
+		_, err := client.Resolve(context.Background(), "fakeUser", "127.0.0.1")
+		require.Error(t, err)
+		require.Equal(t, http.StatusInternalServerError, errdata.GetStatus(err, http.StatusOK))
+		reason, ok := authdb.InvalidReason(err)
+		require.True(t, ok)
+		require.Equal(t, "revoked", reason)
+	}
+
+	// invalid records are answered once each and leave the breaker closed.
+	require.EqualValues(t, 5, atomic.LoadInt32(&hits))
+	endpoints, err := client.endpoints()
+	require.NoError(t, err)
+	require.True(t, endpoints[0].breaker.allow())
+	endpoints[0].breaker.abort()
+}
+
+func TestResolveDeadline(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	client := New(Config{BaseURL: ts.URL, Token: "token", Timeout: 2 * time.Second, Deadline: 200 * time.Millisecond})
+	client.BackOff.Max = time.Minute
+
+	start := time.Now()
+	_, err := client.Resolve(context.Background(), "fakeUser", "127.0.0.1")
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 2*time.Second)
+	require.Equal(t, http.StatusInternalServerError, errdata.GetStatus(err, http.StatusOK))
+	require.Contains(t, err.Error(), "deadline exceeded")
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := &breaker{threshold: 2, cooldown: time.Minute, now: func() time.Time { return now }}
+
+	require.True(t, b.allow())
+	b.finish(true)
+	require.True(t, b.allow())
+	b.finish(true)
+
+	// open
+	require.False(t, b.allow())
+
+	now = now.Add(time.Minute)
+
+	// half-open lets a single probe through
+	require.True(t, b.allow())
+	require.False(t, b.allow())
+	b.abort()
+	require.True(t, b.allow())
+	b.finish(true)
+
+	require.False(t, b.allow())
+
+	now = now.Add(time.Minute)
+
+	require.True(t, b.allow())
+	b.finish(false)
+
+	// closed
+	require.True(t, b.allow())
+	require.True(t, b.allow())
+}
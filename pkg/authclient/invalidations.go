@@ -0,0 +1,233 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package authclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/common/sync2"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+)
+
+// InvalidationsConfig describes configuration of following the auth service's
+// feed of invalidated records.
+type InvalidationsConfig struct {
+	Follow        bool          `user:"true" help:"whether to evict access grants from caches as soon as the auth service invalidates, unpublishes or deletes them" default:"false"`
+	Wait          time.Duration `user:"true" help:"how long a single request for invalidations waits for one (must be shorter than the timeout)" default:"5s"`
+	RetryInterval time.Duration `user:"true" help:"how long to wait before requesting invalidations again after a failure" default:"10s"`
+}
+
+// Evictor evicts cached access grants the auth service invalidated.
+type Evictor interface {
+	// Evict evicts the access grant of the access key ID with keyHash.
+	Evict(keyHash authdb.KeyHash)
+	// EvictAll evicts all access grants resolved through the auth service.
+	// It's called when invalidations might have been missed.
+	EvictAll()
+}
+
+var _ Evictor = (*AuthClient)(nil)
+
+// AddEvictor makes FollowInvalidations notify e about invalidations too,
+// e.g., because e caches access grants resolved by ResolveWithCache.
+func (a *AuthClient) AddEvictor(e Evictor) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.evictors = append(a.evictors, e)
+}
+
+// Evict evicts the cached response for the access key ID with keyHash.
+func (a *AuthClient) Evict(keyHash authdb.KeyHash) {
+	a.evict(keyHash.ToHex())
+}
+
+// evict evicts the cached response under cacheKey.
+func (a *AuthClient) evict(cacheKey string) {
+	// responses being fetched aren't cached once we count the eviction.
+	a.evictMu.Lock()
+	defer a.evictMu.Unlock()
+
+	a.evictions[evictionStripe(cacheKey)]++
+	if a.Cache != nil {
+		a.Cache.Delete(cacheKey)
+	}
+}
+
+// EvictAll makes all cached responses expire. As invalidations might have been
+// missed, they aren't served stale either, even if the auth service is
+// unreachable.
+func (a *AuthClient) EvictAll() {
+	atomic.AddUint64(&a.generation, 1)
+}
+
+// cacheGeneration returns the generation of cached responses that haven't been
+// evicted by EvictAll.
+func (a *AuthClient) cacheGeneration() uint64 {
+	return atomic.LoadUint64(&a.generation)
+}
+
+// FollowInvalidations follows the feed of records invalidated, unpublished or
+// deleted by the auth service and evicts them from the cache and evictors
+// added with AddEvictor until ctx is canceled. Auth services are followed in
+// order, moving to the next one on failure. Positions in the feeds of several
+// auth services (e.g., behind a load balancer) are kept, so that alternating
+// between them doesn't miss invalidations. If invalidations might have been
+// missed (e.g., because an auth service restarted or is followed for the first
+// time), everything is evicted.
+//
+// It does nothing if Invalidations.Follow is false or access keys are resolved
+// locally.
+func (a *AuthClient) FollowInvalidations(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if !a.Invalidations.Follow || a.local != nil {
+		return nil
+	}
+
+	client, err := a.httpClient()
+	if err != nil {
+		return AuthServiceError.Wrap(err)
+	}
+	endpoints, err := a.endpoints()
+	if err != nil {
+		return AuthServiceError.Wrap(err)
+	}
+
+	var (
+		current int
+		cursors []feedCursor
+	)
+	for ctx.Err() == nil {
+		resp, err := a.getInvalidations(ctx, client, endpoints[current], cursors)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			mon.Event("authclient_invalidations_failed")
+			current = (current + 1) % len(endpoints)
+			if !sync2.Sleep(ctx, a.Invalidations.RetryInterval) {
+				break
+			}
+			continue
+		}
+
+		var keyHashes []authdb.KeyHash
+		for _, invalidation := range resp.Invalidations {
+			var keyHash authdb.KeyHash
+			if err := keyHash.FromHex(invalidation.KeyHash); err != nil {
+				resp.Reset = true // we can't tell which one to evict
+				continue
+			}
+			keyHashes = append(keyHashes, keyHash)
+		}
+
+		a.mu.Lock()
+		evictors := append([]Evictor{a}, a.evictors...)
+		a.mu.Unlock()
+
+		for _, e := range evictors {
+			if resp.Reset {
+				e.EvictAll()
+				continue
+			}
+			for _, keyHash := range keyHashes {
+				e.Evict(keyHash)
+			}
+		}
+
+		if resp.Reset {
+			mon.Event("authclient_invalidations_reset")
+		}
+		mon.Meter("authclient_invalidations_evicted").Mark(len(keyHashes))
+
+		cursors = advanceCursors(cursors, resp.Feed, resp.Next)
+	}
+
+	return nil
+}
+
+// maxFollowedFeeds is how many feeds (e.g., of auth services behind a load
+// balancer) we keep our position in.
+const maxFollowedFeeds = 32
+
+// feedCursor is the position in a feed of invalidations.
+type feedCursor struct {
+	feed string
+	next uint64
+}
+
+// advanceCursors returns cursors with the position in feed set to next. The
+// most recently advanced cursors come first, and only maxFollowedFeeds are
+// kept, so that feeds of restarted auth services are eventually forgotten.
+func advanceCursors(cursors []feedCursor, feed string, next uint64) []feedCursor {
+	advanced := append(make([]feedCursor, 0, len(cursors)+1), feedCursor{feed: feed, next: next})
+	for _, c := range cursors {
+		if c.feed != feed && len(advanced) < maxFollowedFeeds {
+			advanced = append(advanced, c)
+		}
+	}
+	return advanced
+}
+
+type invalidationsResponse struct {
+	Feed          string `json:"feed"`
+	Next          uint64 `json:"next"`
+	Reset         bool   `json:"reset"`
+	Invalidations []struct {
+		KeyHash string `json:"key_hash"`
+		Reason  string `json:"reason"`
+	} `json:"invalidations"`
+}
+
+// getInvalidations requests invalidations after our positions in cursors from
+// e. The auth service answers from its own feed, using our position in it if
+// it's among cursors.
+func (a *AuthClient) getInvalidations(ctx context.Context, client *http.Client, e *endpoint, cursors []feedCursor) (_ invalidationsResponse, err error) {
+	query := url.Values{"wait": {a.Invalidations.Wait.String()}}
+	for _, c := range cursors {
+		query.Add("cursor", c.feed+":"+strconv.FormatUint(c.next, 10))
+	}
+	if len(cursors) > 0 {
+		// auth services that don't know cursors only know the most recent
+		// position.
+		query.Set("feed", cursors[0].feed)
+		query.Set("since", strconv.FormatUint(cursors[0].next, 10))
+	}
+
+	reqURL := *e.baseURL
+	reqURL.Path = path.Join(reqURL.Path, "/v1/invalidations")
+	reqURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL.String(), nil)
+	if err != nil {
+		return invalidationsResponse{}, AuthServiceError.Wrap(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+
+	resp, err := do(client, req)
+	if err != nil {
+		return invalidationsResponse{}, AuthServiceError.Wrap(err)
+	}
+	defer func() { err = errs.Combine(err, AuthServiceError.Wrap(closeBody(resp.Body))) }()
+
+	if resp.StatusCode != http.StatusOK {
+		return invalidationsResponse{}, AuthServiceError.New("invalid status code: %d", resp.StatusCode)
+	}
+
+	var invalidations invalidationsResponse
+	if err = json.NewDecoder(resp.Body).Decode(&invalidations); err != nil {
+		return invalidationsResponse{}, AuthServiceError.Wrap(err)
+	}
+	return invalidations, nil
+}
@@ -0,0 +1,288 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package authclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.uber.org/zap/zaptest"
+
+	"storj.io/common/memory"
+	"storj.io/common/testcontext"
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/auth/httpauth"
+)
+
+type recordingEvictor struct {
+	mu      sync.Mutex
+	evicted []authdb.KeyHash
+	all     int
+}
+
+func (r *recordingEvictor) Evict(keyHash authdb.KeyHash) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.evicted = append(r.evicted, keyHash)
+}
+
+func (r *recordingEvictor) EvictAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.all++
+}
+
+func (r *recordingEvictor) state() ([]authdb.KeyHash, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]authdb.KeyHash(nil), r.evicted...), r.all
+}
+
+func TestAuthClient_FollowInvalidations(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	encKey, err := authdb.NewEncryptionKey()
+	require.NoError(t, err)
+	accessKeyID := encKey.ToBase32()
+
+	var lookups int32
+	invalidate := make(chan struct{})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/v1/access/") {
+			atomic.AddInt32(&lookups, 1)
+			require.NoError(t, json.NewEncoder(w).Encode(AuthServiceResponse{AccessGrant: "grant", Public: true}))
+			return
+		}
+
+		assert.Equal(t, "/v1/invalidations", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		resp := invalidationsResponse{Feed: "feed"}
+		switch r.URL.Query().Get("feed") {
+		case "":
+			resp.Reset = true
+		default:
+			select {
+			case <-invalidate:
+				resp.Next = 1
+				resp.Invalidations = append(resp.Invalidations, struct {
+					KeyHash string `json:"key_hash"`
+					Reason  string `json:"reason"`
+				}{KeyHash: encKey.Hash().ToHex(), Reason: "invalidated"})
+			case <-time.After(10 * time.Millisecond):
+				resp.Next = 0
+			case <-r.Context().Done():
+				return
+			}
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+	defer ts.Close()
+
+	client := New(Config{
+		BaseURL: ts.URL,
+		Token:   "token",
+		Timeout: 5 * time.Second,
+		Cache:   AuthServiceCacheConfig{Expiration: time.Hour, Capacity: 10},
+		Invalidations: InvalidationsConfig{
+			Follow:        true,
+			Wait:          time.Second,
+			RetryInterval: 10 * time.Millisecond,
+		},
+	})
+	evictor := new(recordingEvictor)
+	client.AddEvictor(evictor)
+
+	followCtx, cancel := context.WithCancel(ctx)
+	ctx.Go(func() error {
+		return client.FollowInvalidations(followCtx)
+	})
+	defer cancel()
+
+	// following a feed for the first time evicts everything.
+	require.Eventually(t, func() bool {
+		_, all := evictor.state()
+		return all == 1
+	}, 5*time.Second, 5*time.Millisecond)
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.ResolveWithCache(ctx, accessKeyID, "127.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, "grant", resp.AccessGrant)
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&lookups))
+
+	close(invalidate)
+
+	require.Eventually(t, func() bool {
+		evicted, _ := evictor.state()
+		return len(evicted) > 0
+	}, 5*time.Second, 5*time.Millisecond)
+	evicted, _ := evictor.state()
+	assert.Equal(t, encKey.Hash(), evicted[0])
+
+	_, err = client.ResolveWithCache(ctx, accessKeyID, "127.0.0.1")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&lookups))
+}
+
+func TestAuthClient_FollowInvalidationsBehindLoadBalancer(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	// two auth services with their own feeds behind a load balancer
+	// alternating between them.
+	var (
+		feeds    []*authdb.InvalidationFeed
+		services []*httpauth.Resources
+		requests int32
+	)
+	for i := 0; i < 2; i++ {
+		feed := authdb.NewInvalidationFeed(10)
+		res := httpauth.New(zaptest.NewLogger(t), nil, nil, "token", 4*memory.KiB)
+		res.SetInvalidationFeed(feed)
+		feeds, services = append(feeds, feed), append(services, res)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		services[atomic.AddInt32(&requests, 1)%2].ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	client := New(Config{
+		BaseURL: ts.URL,
+		Token:   "token",
+		Timeout: 5 * time.Second,
+		Cache:   AuthServiceCacheConfig{Expiration: time.Hour, Capacity: 10},
+		Invalidations: InvalidationsConfig{
+			Follow:        true,
+			Wait:          10 * time.Millisecond,
+			RetryInterval: 10 * time.Millisecond,
+		},
+	})
+	evictor := new(recordingEvictor)
+	client.AddEvictor(evictor)
+
+	followCtx, cancel := context.WithCancel(ctx)
+	ctx.Go(func() error {
+		return client.FollowInvalidations(followCtx)
+	})
+	defer cancel()
+
+	// each feed followed for the first time evicts everything, but only once.
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&requests) > 10
+	}, 5*time.Second, 5*time.Millisecond)
+	_, all := evictor.state()
+	assert.Equal(t, 2, all)
+
+	for i, feed := range feeds {
+		feed.Publish(authdb.KeyHash{byte(i + 1)}, authdb.InvalidationInvalidated)
+	}
+
+	require.Eventually(t, func() bool {
+		evicted, _ := evictor.state()
+		return len(evicted) == 2
+	}, 5*time.Second, 5*time.Millisecond)
+	evicted, all := evictor.state()
+	assert.ElementsMatch(t, []authdb.KeyHash{{1}, {2}}, evicted)
+	assert.Equal(t, 2, all)
+}
+
+func TestAuthClient_EvictAll(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	var lookups int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lookups, 1)
+		require.NoError(t, json.NewEncoder(w).Encode(AuthServiceResponse{AccessGrant: "grant", Public: true}))
+	}))
+	defer ts.Close()
+
+	client := New(Config{
+		BaseURL: ts.URL,
+		Token:   "token",
+		Cache:   AuthServiceCacheConfig{Expiration: time.Hour, Capacity: 10},
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := client.ResolveWithCache(ctx, "access-key-id", "127.0.0.1")
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&lookups))
+
+	client.EvictAll()
+
+	for i := 0; i < 2; i++ {
+		_, err := client.ResolveWithCache(ctx, "access-key-id", "127.0.0.1")
+		require.NoError(t, err)
+	}
+	assert.EqualValues(t, 2, atomic.LoadInt32(&lookups))
+}
+
+func TestAuthClient_EvictDuringRefresh(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	encKey, err := authdb.NewEncryptionKey()
+	require.NoError(t, err)
+	accessKeyID := encKey.ToBase32()
+
+	var lookups int32
+	refreshing, evicted := make(chan struct{}), make(chan struct{})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		maxAge := "3600"
+		switch atomic.AddInt32(&lookups, 1) {
+		case 1:
+			maxAge = "60"
+		case 2:
+			close(refreshing)
+			<-evicted
+		}
+		w.Header().Set("Cache-Control", "private, max-age="+maxAge)
+		require.NoError(t, json.NewEncoder(w).Encode(AuthServiceResponse{AccessGrant: "grant", Public: true}))
+	}))
+	defer ts.Close()
+
+	client := New(Config{
+		BaseURL: ts.URL,
+		Token:   "token",
+		Timeout: 5 * time.Second,
+		Cache:   AuthServiceCacheConfig{Expiration: time.Hour, Capacity: 10, RefreshBefore: 2 * time.Minute},
+	})
+
+	// the second lookup is served from the cache and refreshes it.
+	for i := 0; i < 2; i++ {
+		_, err := client.ResolveWithCache(ctx, accessKeyID, "127.0.0.1")
+		require.NoError(t, err)
+	}
+
+	<-refreshing
+	client.Evict(encKey.Hash())
+	close(evicted)
+
+	// the refreshed response was fetched before the eviction, so it isn't
+	// cached.
+	time.Sleep(100 * time.Millisecond)
+	_, cached := client.Cache.GetCached(encKey.Hash().ToHex())
+	assert.False(t, cached)
+
+	_, err = client.ResolveWithCache(ctx, accessKeyID, "127.0.0.1")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&lookups))
+}
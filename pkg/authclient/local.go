@@ -0,0 +1,46 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package authclient
+
+import (
+	"context"
+	"net/http"
+
+	"storj.io/gateway-mt/pkg/auth/authdb"
+	"storj.io/gateway-mt/pkg/errdata"
+)
+
+// SetLocal makes the client resolve access keys with db in the same process
+// instead of requesting the auth service, e.g., because authservice is
+// embedded in the gateway. Responses aren't cached then. It must be called
+// before resolving.
+func (a *AuthClient) SetLocal(db *authdb.Database) {
+	a.local = db
+}
+
+// resolveLocal resolves accessKeyID with the local database, returning the
+// same errors as the auth service would.
+func (a *AuthClient) resolveLocal(ctx context.Context, accessKeyID string) (_ AuthServiceResponse, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var key authdb.EncryptionKey
+	if err = key.FromBase32(accessKeyID); err != nil {
+		return AuthServiceResponse{}, errdata.WithStatus(AuthServiceError.Wrap(err), http.StatusBadRequest)
+	}
+
+	accessGrant, public, secretKey, expiresAt, err := a.local.Get(ctx, key)
+	if err != nil {
+		if authdb.NotFound.Has(err) {
+			return AuthServiceResponse{}, errdata.WithStatus(AuthServiceError.Wrap(err), http.StatusUnauthorized)
+		}
+		return AuthServiceResponse{}, errdata.WithStatus(AuthServiceError.Wrap(err), http.StatusInternalServerError)
+	}
+
+	return AuthServiceResponse{
+		AccessGrant: accessGrant,
+		SecretKey:   secretKey.ToBase32(),
+		Public:      public,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
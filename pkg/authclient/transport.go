@@ -0,0 +1,133 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package authclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"time"
+
+	"github.com/spacemonkeygo/monkit/v3"
+
+	"storj.io/gateway-mt/pkg/certreload"
+)
+
+// TransportConfig describes configuration of connections to the auth service.
+type TransportConfig struct {
+	MaxIdleConns    int           `user:"true" help:"how many idle connections to the auth service to keep open for reuse" default:"100"`
+	IdleConnTimeout time.Duration `user:"true" help:"how long to keep idle connections to the auth service open" default:"90s"`
+	KeepAlive       time.Duration `user:"true" help:"interval between keep-alive probes of connections to the auth service" default:"30s"`
+	HTTP2           bool          `user:"true" help:"whether to use HTTP/2 for TLS connections to the auth service" default:"true"`
+}
+
+// CloseIdleConnections closes idle connections to the auth service.
+func (a *AuthClient) CloseIdleConnections() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.client != nil {
+		a.client.CloseIdleConnections()
+	}
+}
+
+// httpClient returns the HTTP client used for all requests to the auth
+// service, creating it on first use so that connections are reused.
+func (a *AuthClient) httpClient() (*http.Client, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.client != nil {
+		return a.client, nil
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   a.Timeout,
+			KeepAlive: a.Transport.KeepAlive,
+		}).DialContext,
+		ForceAttemptHTTP2:     a.Transport.HTTP2,
+		MaxIdleConns:          a.Transport.MaxIdleConns,
+		MaxIdleConnsPerHost:   a.Transport.MaxIdleConns,
+		IdleConnTimeout:       a.Transport.IdleConnTimeout,
+		ResponseHeaderTimeout: a.Timeout,
+		TLSHandshakeTimeout:   a.Timeout,
+	}
+
+	if a.CertFile != "" || a.KeyFile != "" || a.CAFile != "" {
+		certs, err := certreload.New(a.CertFile, a.KeyFile, a.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = newTLSConfig(certs, a.CAFile != "")
+	}
+
+	a.client = &http.Client{Timeout: a.Timeout, Transport: transport}
+
+	return a.client, nil
+}
+
+// newTLSConfig returns a TLS config presenting the current client certificate
+// of certs and, if verifyCA, verifying the auth service against its current CA
+// bundle. Certificates are read on every handshake, so they can change without
+// recreating the transport.
+func newTLSConfig(certs *certreload.Reloader, verifyCA bool) *tls.Config {
+	config := &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		GetClientCertificate: certs.GetClientCertificate,
+	}
+	if !verifyCA {
+		return config
+	}
+
+	// RootCAs is fixed for the lifetime of the config, so we skip the default
+	// verification and verify against the current CA bundle instead.
+	config.InsecureSkipVerify = true //nolint:gosec // verified in VerifyConnection.
+	config.VerifyConnection = func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return AuthServiceError.New("no server certificate")
+		}
+		opts := x509.VerifyOptions{
+			Roots:         certs.CAPool(),
+			DNSName:       state.ServerName,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range state.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := state.PeerCertificates[0].Verify(opts)
+		return err
+	}
+
+	return config
+}
+
+// do sends req with client and records whether the connection was reused and
+// how long the request took.
+func do(client *http.Client, req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			mon.Event("authclient_conn", monkit.NewSeriesTag("reused", strconv.FormatBool(info.Reused)))
+		},
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	mon.DurationVal("authclient_request_duration",
+		monkit.NewSeriesTag("successful", strconv.FormatBool(err == nil))).Observe(time.Since(start))
+
+	return resp, err
+}
+
+// closeBody drains (up to a limit) and closes body so that its connection can
+// be reused.
+func closeBody(body io.ReadCloser) error {
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, 4096))
+	return body.Close()
+}
@@ -0,0 +1,168 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package certreload keeps TLS certificates and CA bundles loaded from files
+// up to date with the files they were loaded from.
+package certreload
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/zeebo/errs"
+)
+
+// Error is the error class for this package.
+var Error = errs.Class("certreload")
+
+// checkInterval is how often files are checked for changes at most.
+const checkInterval = time.Second
+
+// Reloader holds a certificate with its key and a CA bundle loaded from files
+// and reloads them once any of the files change. Any of the files might be
+// left empty. If reloading fails, the previously loaded certificates are
+// kept.
+//
+// Reloader is safe for concurrent use.
+type Reloader struct {
+	certFile, keyFile, caFile string
+
+	checkInterval time.Duration
+
+	mu        sync.Mutex
+	lastCheck time.Time
+	stamps    [3]stamp
+	cert      *tls.Certificate
+	pool      *x509.CertPool
+}
+
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// New returns a new Reloader for certFile, keyFile and caFile. certFile and
+// keyFile must be both either set or empty. It loads the files immediately.
+func New(certFile, keyFile, caFile string) (*Reloader, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, Error.New("cert file and key file must be provided together")
+	}
+
+	r := &Reloader{
+		certFile:      certFile,
+		keyFile:       keyFile,
+		caFile:        caFile,
+		checkInterval: checkInterval,
+	}
+
+	stamps, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err = r.load(stamps); err != nil {
+		return nil, err
+	}
+	r.lastCheck = time.Now()
+
+	return r, nil
+}
+
+// Certificate returns the current certificate or nil if no cert file was
+// given.
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.maybeReload()
+
+	return r.cert
+}
+
+// CAPool returns the current CA bundle or nil if no CA file was given.
+func (r *Reloader) CAPool() *x509.CertPool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.maybeReload()
+
+	return r.pool
+}
+
+// GetCertificate can be used as tls.Config's GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if cert := r.Certificate(); cert != nil {
+		return cert, nil
+	}
+	return nil, Error.New("no certificate configured")
+}
+
+// GetClientCertificate can be used as tls.Config's GetClientCertificate.
+func (r *Reloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if cert := r.Certificate(); cert != nil {
+		return cert, nil
+	}
+	// sending no certificate is signalled with an empty one.
+	return &tls.Certificate{}, nil
+}
+
+// maybeReload reloads files if they changed since they were loaded and enough
+// time passed since the last check. It must be called with mu held.
+func (r *Reloader) maybeReload() {
+	if time.Since(r.lastCheck) < r.checkInterval {
+		return
+	}
+	r.lastCheck = time.Now()
+
+	stamps, err := r.stat()
+	if err != nil || stamps == r.stamps {
+		return
+	}
+
+	// if loading fails (e.g., because files are mid-rotation), we keep the
+	// previous certificates and retry with the next check.
+	_ = r.load(stamps)
+}
+
+func (r *Reloader) stat() (stamps [3]stamp, err error) {
+	for i, name := range []string{r.certFile, r.keyFile, r.caFile} {
+		if name == "" {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return stamps, Error.Wrap(err)
+		}
+		stamps[i] = stamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
+
+func (r *Reloader) load(stamps [3]stamp) error {
+	var cert *tls.Certificate
+	if r.certFile != "" {
+		c, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return Error.New("unable to load keypair: %v", err)
+		}
+		cert = &c
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return Error.Wrap(err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return Error.New("no certificates found in %s", r.caFile)
+		}
+	}
+
+	r.cert, r.pool, r.stamps = cert, pool, stamps
+
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright (C) 2022 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package certreload
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	certPEM, keyPEM := createCertificate(t, "first")
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+
+	r, err := New(certFile, keyFile, certFile)
+	require.NoError(t, err)
+	r.checkInterval = 0
+
+	require.Equal(t, "first", leafCommonName(t, r.Certificate().Certificate[0]))
+	require.NotNil(t, r.CAPool())
+
+	// a broken write keeps the previous certificate.
+	require.NoError(t, os.WriteFile(certFile, []byte("garbage"), 0600))
+	require.Equal(t, "first", leafCommonName(t, r.Certificate().Certificate[0]))
+
+	certPEM, keyPEM = createCertificate(t, "second")
+	require.NoError(t, os.WriteFile(certFile, certPEM, 0600))
+	require.NoError(t, os.WriteFile(keyFile, keyPEM, 0600))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, "second", leafCommonName(t, cert.Certificate[0]))
+}
+
+func TestReloader_Empty(t *testing.T) {
+	r, err := New("", "", "")
+	require.NoError(t, err)
+
+	require.Nil(t, r.Certificate())
+	require.Nil(t, r.CAPool())
+
+	_, err = r.GetCertificate(nil)
+	require.Error(t, err)
+
+	cert, err := r.GetClientCertificate(nil)
+	require.NoError(t, err)
+	require.Empty(t, cert.Certificate)
+
+	_, err = New("cert.pem", "", "")
+	require.Error(t, err)
+}
+
+func leafCommonName(t *testing.T, der []byte) string {
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert.Subject.CommonName
+}
+
+func createCertificate(t *testing.T, commonName string) (certPEM, keyPEM []byte) {
+	template := x509.Certificate{
+		Subject:               pkix.Name{CommonName: commonName},
+		SerialNumber:          big.NewInt(1337),
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		NotAfter:              time.Now().Add(time.Hour),
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/spacemonkeygo/monkit/v3/http"
 	"github.com/zeebo/errs"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 
 	"storj.io/gateway-mt/pkg/authclient"
 	"storj.io/gateway-mt/pkg/httpserver"
@@ -38,6 +39,8 @@ type Peer struct {
 	Mapper     *objectmap.IPDB
 	Server     *httpserver.Server
 	TXTRecords *sharing.TXTRecords
+
+	authClient *authclient.AuthClient
 }
 
 // New is a constructor for Linksharing Peer.
@@ -52,6 +55,7 @@ func New(log *zap.Logger, config Config) (_ *Peer, err error) {
 	peer := &Peer{
 		Log:        log,
 		TXTRecords: txtRecords,
+		authClient: authClient,
 	}
 
 	if config.GeoLocationDB != "" {
@@ -82,7 +86,20 @@ func New(log *zap.Logger, config Config) (_ *Peer, err error) {
 func (peer *Peer) Run(ctx context.Context) (err error) {
 	defer mon.Task()(&ctx)(&err)
 
-	return peer.Server.Run(ctx)
+	// invalidations are followed for as long as the server runs.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		defer cancel()
+		return peer.Server.Run(groupCtx)
+	})
+	group.Go(func() error {
+		return peer.authClient.FollowInvalidations(groupCtx)
+	})
+
+	return group.Wait()
 }
 
 // Close shuts down the server and all underlying resources.
@@ -12,6 +12,7 @@ import (
 	"github.com/miekg/dns"
 	"github.com/zeebo/errs"
 
+	"storj.io/gateway-mt/pkg/auth/authdb"
 	"storj.io/gateway-mt/pkg/authclient"
 	"storj.io/uplink"
 )
@@ -33,20 +34,54 @@ type txtRecord struct {
 	// live until TTL *even though* the access key it used may have gotten
 	// revoked sooner. this is a troubling problem for access keys, and
 	// implies we should only support revoking access grants and not support
-	// revoking access keys due to this confusion.
+	// revoking access keys due to this confusion. records of access keys
+	// are evicted when the auth service invalidates them if the auth client
+	// follows invalidations, though.
 	access     *uplink.Access
 	root       string
 	tls        bool
 	expiration time.Time
+
+	// keyHash is the key hash of the access key if the record has one
+	// instead of an access grant.
+	keyHash    authdb.KeyHash
+	hasKeyHash bool
 }
 
-// NewTXTRecords constructs a TXTRecords.
+var _ authclient.Evictor = (*TXTRecords)(nil)
+
+// NewTXTRecords constructs a TXTRecords. It evicts records of access keys
+// that auth invalidates.
 func NewTXTRecords(maxTTL time.Duration, dns *DNSClient, auth *authclient.AuthClient) *TXTRecords {
-	return &TXTRecords{
+	records := &TXTRecords{
 		maxTTL: maxTTL,
 		dns:    dns,
 		auth:   auth,
 	}
+	if auth != nil {
+		auth.AddEvictor(records)
+	}
+	return records
+}
+
+// Evict evicts records of the access key with keyHash.
+func (records *TXTRecords) Evict(keyHash authdb.KeyHash) {
+	records.cache.Range(func(hostname, val interface{}) bool {
+		if record := val.(*txtRecord); record.hasKeyHash && record.keyHash == keyHash {
+			records.cache.Delete(hostname)
+		}
+		return true
+	})
+}
+
+// EvictAll evicts records of all access keys.
+func (records *TXTRecords) EvictAll() {
+	records.cache.Range(func(hostname, val interface{}) bool {
+		if val.(*txtRecord).hasKeyHash {
+			records.cache.Delete(hostname)
+		}
+		return true
+	})
 }
 
 // FetchAccessForHost fetches the root and access grant from the cache or dns
@@ -155,5 +190,12 @@ func (records *TXTRecords) queryAccessFromDNS(ctx context.Context, hostname stri
 		ttl = records.maxTTL
 	}
 
-	return &txtRecord{access: access, root: root, tls: tls, expiration: time.Now().Add(ttl)}, nil
+	record = &txtRecord{access: access, root: root, tls: tls, expiration: time.Now().Add(ttl)}
+
+	var key authdb.EncryptionKey
+	if err := key.FromBase32(serializedAccess); err == nil {
+		record.keyHash, record.hasKeyHash = key.Hash(), true
+	}
+
+	return record, nil
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"storj.io/common/memory"
+	"storj.io/gateway-mt/pkg/auth"
 	"storj.io/gateway-mt/pkg/authclient"
 	"storj.io/gateway/miniogw"
 )
@@ -31,6 +32,7 @@ type Config struct {
 	ConcurrentAllowed    uint     `help:"number of allowed concurrent uploads or downloads per macaroon head" default:"500"` // see S3 CLI's max_concurrent_requests
 
 	Auth            authclient.Config
+	EmbeddedAuth    auth.EmbeddedConfig
 	S3Compatibility miniogw.S3CompatibilityConfig
 	Client          ClientConfig
 	ConnectionPool  ConnectionPoolConfig
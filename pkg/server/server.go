@@ -16,8 +16,10 @@ import (
 	mhttp "github.com/spacemonkeygo/monkit/v3/http"
 	"github.com/zeebo/errs"
 	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
 
 	"storj.io/common/rpc/rpcpool"
+	"storj.io/gateway-mt/pkg/auth"
 	"storj.io/gateway-mt/pkg/authclient"
 	"storj.io/gateway-mt/pkg/httpserver"
 	"storj.io/gateway-mt/pkg/minio"
@@ -49,12 +51,16 @@ type Peer struct {
 	server     *httpserver.Server
 	log        *zap.Logger
 	config     Config
+	authClient *authclient.AuthClient
 	closeLayer func(context.Context) error
+
+	// embeddedAuth is authservice running in-process if enabled.
+	embeddedAuth *auth.Embedded
 }
 
 // New returns new instance of an S3 compatible http server.
 func New(config Config, log *zap.Logger, trustedIPs trustedip.List, corsAllowedOrigins []string,
-	authClient *authclient.AuthClient, domainNames []string, concurrentAllowed uint) (*Peer, error) {
+	authClient *authclient.AuthClient, domainNames []string, concurrentAllowed uint) (_ *Peer, err error) {
 	r := mux.NewRouter()
 	r.SkipClean(true)
 	r.UseEncodedPath()
@@ -63,6 +69,27 @@ func New(config Config, log *zap.Logger, trustedIPs trustedip.List, corsAllowedO
 	publicServices.HandleFunc("/health", healthCheck)
 	publicServices.HandleFunc("/version", versionInfo)
 
+	var embeddedAuth *auth.Embedded
+	if config.EmbeddedAuth.Enabled {
+		if authClient == nil {
+			return nil, Error.New("embedded authservice requires an auth client")
+		}
+		// New doesn't take a context; loading the allowed satellite list is
+		// bounded by its own timeout.
+		embeddedAuth, err = auth.NewEmbedded(context.Background(), log.Named("auth"), config.EmbeddedAuth)
+		if err != nil {
+			return nil, Error.Wrap(err)
+		}
+		defer func() {
+			if err != nil {
+				err = errs.Combine(err, embeddedAuth.Close())
+			}
+		}()
+		authClient.SetLocal(embeddedAuth.Database())
+		// bucket names can't start with a dash, so these don't shadow any.
+		publicServices.PathPrefix("/auth/").Handler(http.StripPrefix("/-/auth", embeddedAuth.Handler()))
+	}
+
 	if config.EncodeInMemory {
 		r.Use(middleware.SetInMemory)
 	}
@@ -115,7 +142,10 @@ func New(config Config, log *zap.Logger, trustedIPs trustedip.List, corsAllowedO
 		log:        log,
 		server:     server,
 		config:     config,
+		authClient: authClient,
 		closeLayer: layer.Shutdown,
+
+		embeddedAuth: embeddedAuth,
 	}, nil
 }
 
@@ -157,7 +187,27 @@ func (s *Peer) Run(ctx context.Context) (err error) {
 		minio.StartMinio(!s.config.InsecureDisableTLS)
 	})
 
-	return s.server.Run(ctx)
+	// invalidations are followed for as long as the server runs.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		defer cancel()
+		return s.server.Run(groupCtx)
+	})
+	if s.authClient != nil {
+		group.Go(func() error {
+			return s.authClient.FollowInvalidations(groupCtx)
+		})
+	}
+	if s.embeddedAuth != nil {
+		group.Go(func() error {
+			return s.embeddedAuth.Run(groupCtx)
+		})
+	}
+
+	return group.Wait()
 }
 
 // Close shuts down the server and all underlying resources.
@@ -166,7 +216,11 @@ func (s *Peer) Close() error {
 	defer cancel()
 
 	// note: httpserver.Shutdown has its own configured timeout
-	return Error.Wrap(errs.Combine(s.closeLayer(ctx), s.server.Shutdown()))
+	err := errs.Combine(s.closeLayer(ctx), s.server.Shutdown())
+	if s.embeddedAuth != nil {
+		err = errs.Combine(err, s.embeddedAuth.Close())
+	}
+	return Error.Wrap(err)
 }
 
 // Address returns the web address the peer is listening on.
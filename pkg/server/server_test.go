@@ -9,20 +9,25 @@ import (
 	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"io"
 	"math/big"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
 
+	"storj.io/common/memory"
 	"storj.io/common/pkcrypto"
 	"storj.io/common/testcontext"
+	"storj.io/gateway-mt/pkg/auth"
+	"storj.io/gateway-mt/pkg/authclient"
 	"storj.io/gateway-mt/pkg/server"
 	"storj.io/gateway-mt/pkg/trustedip"
 )
@@ -114,6 +119,50 @@ func testServer(t *testing.T, useTLS, vHostStyle bool) {
 	testVersionInfo(ctx, t, urlBase+"-/version", client)
 }
 
+func TestEmbeddedAuth(t *testing.T) {
+	ctx := testcontext.New(t)
+	defer ctx.Cleanup()
+
+	config := server.Config{
+		Server: server.AddrConfig{
+			Address:    "127.0.0.1:0",
+			AddressTLS: "127.0.0.1:0",
+		},
+		InsecureDisableTLS: true,
+		EmbeddedAuth: auth.EmbeddedConfig{
+			Enabled:           true,
+			Endpoint:          "http://gateway.invalid",
+			AllowedSatellites: []string{"1SYXsAycDPUu4z2ZksJD5fh5nTDcH3vCFHnpcVye5XuL1NrYV@s"},
+			POSTSizeLimit:     4 * memory.KiB,
+			KVBackend:         "memory://",
+		},
+	}
+	s, err := server.New(config, zaptest.NewLogger(t), trustedip.NewListTrustAll(), []string{}, authclient.New(authclient.Config{}), []string{}, 10)
+	require.NoError(t, err)
+
+	defer ctx.Check(s.Close)
+
+	ctx.Go(func() error {
+		return s.Run(ctx)
+	})
+
+	const access = "13J4Upun87ATb3T5T5sDXVeQaCzWFZeF9Ly4ELfxS5hUwTL8APEkwahTEJ1wxZjyErimiDs3kgid33kDLuYPYtwaY7Toy32mCTapfrUB814X13RiA844HPWK3QLKZb9cAoVceTowmNZXWbcUMKNbkMHCURE4hn8ZrdHPE3S86yngjvDxwKmarfGx"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+s.Address()+"/-/auth/v1/access",
+		strings.NewReader(`{"access_grant": "`+access+`"}`))
+	require.NoError(t, err)
+	response, err := (&http.Client{Timeout: 5 * time.Second}).Do(req)
+	require.NoError(t, err)
+	defer func() { _ = response.Body.Close() }()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	var registered struct {
+		AccessKeyID string `json:"access_key_id"`
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&registered))
+	require.NotEmpty(t, registered.AccessKeyID)
+}
+
 func testHealthCheck(ctx context.Context, t *testing.T, url string, client *http.Client) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	require.NoError(t, err)